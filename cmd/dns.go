@@ -7,6 +7,9 @@ import (
 	"HosterCore/internal/pkg/emojlog"
 	FreeBSDKill "HosterCore/internal/pkg/freebsd/kill"
 	FreeBSDPgrep "HosterCore/internal/pkg/freebsd/pgrep"
+	HosterCliJson "HosterCore/internal/pkg/hoster/cli_json"
+	HosterTables "HosterCore/internal/pkg/hoster/cli_tables"
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
 	"errors"
 	"fmt"
 	"os"
@@ -116,6 +119,56 @@ var (
 	}
 )
 
+var (
+	dnsZoneUnix       bool
+	dnsZoneJson       bool
+	dnsZoneJsonPretty bool
+
+	dnsZoneCmd = &cobra.Command{
+		Use:   "zone",
+		Short: "Show the records served by the DNS Server",
+		Long:  `Show the static, VM and Jail records currently served by the integrated DNS Server.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			if dnsZoneJson || dnsZoneJsonPretty {
+				err := HosterCliJson.GenerateDnsZoneJson(dnsZoneJsonPretty)
+				if err != nil {
+					emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+					os.Exit(1)
+				}
+			} else {
+				err := HosterTables.GenerateDnsZoneTable(dnsZoneUnix)
+				if err != nil {
+					emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+					os.Exit(1)
+				}
+			}
+		},
+	}
+)
+
+var (
+	dnsUpstreamsUnix bool
+
+	dnsUpstreamsCmd = &cobra.Command{
+		Use:   "upstreams",
+		Short: "Show the upstream DNS servers health",
+		Long:  `Show the upstream DNS servers (plain, DNS-over-TLS and DNS-over-HTTPS) health and latency stats.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := HosterTables.GenerateDnsUpstreamsTable(dnsUpstreamsUnix)
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
 func startDnsServer() error {
 	execPath, err := os.Executable()
 	if err != nil {
@@ -156,17 +209,7 @@ func stopDnsServer() error {
 }
 
 func ReloadDnsServer() error {
-	serviceInfo, err := dnsServerServiceInfo()
-	if err != nil {
-		reMatchExit1 := regexp.MustCompile(`exit status 1`)
-		if reMatchExit1.MatchString(err.Error()) {
-			return errors.New("DNS server is not running")
-		} else {
-			return err
-		}
-	}
-
-	err = FreeBSDKill.KillProcess(FreeBSDKill.KillSignalHUP, serviceInfo.Pid)
+	err := HosterHostUtils.ReloadDns()
 	if err != nil {
 		return err
 	}
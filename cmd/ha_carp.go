@@ -53,21 +53,27 @@ var (
 )
 
 var (
+	haStatusJson bool
+
 	haStatusCmd = &cobra.Command{
 		Use:   "status",
-		Short: "HA Mode Status",
-		Long:  `HA Mode Status.`,
+		Short: "HA cluster status",
+		Long:  `HA cluster status: nodes, resources with their backup holders, and pending failovers.`,
 		Args:  cobra.NoArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			checkInitFile()
 
+			// Not running is a valid status, and not an error (scripts rely on the exit code)
 			pid, _ := FreeBSDPgrep.FindHaCarp()
-			if pid > 0 {
-				logVal := fmt.Sprintf("HA service is running with PID: %d", pid)
-				emojlog.PrintInfoMessage(logVal)
-			} else {
-				logVal := "HA service is not running"
-				emojlog.PrintWarningMessage(logVal)
+			if pid < 1 {
+				emojlog.PrintWarningMessage("HA service is not running")
+				os.Exit(0)
+			}
+
+			err := printHaClusterStatus(pid)
+			if err != nil {
+				emojlog.PrintErrorMessage("could not read HA status -> " + err.Error())
+				os.Exit(1)
 			}
 
 			os.Exit(0)
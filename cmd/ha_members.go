@@ -0,0 +1,194 @@
+//go:build freebsd
+// +build freebsd
+
+package cmd
+
+import (
+	CarpClient "HosterCore/internal/app/ha_carp/client"
+	ApiV2client "HosterCore/internal/pkg/api_v2_client"
+	"HosterCore/internal/pkg/emojlog"
+	FreeBSDPgrep "HosterCore/internal/pkg/freebsd/pgrep"
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	haJoinCmd = &cobra.Command{
+		Use:   "join [masterIpAddress] [masterFingerprint]",
+		Short: "Ask the CARP master to let this node join the cluster",
+		Long: `Ask the CARP master to let this node join the cluster.
+The master's key fingerprint is listed by "hoster ha members" on the master, and it's checked before the master is trusted.
+The request stays pending until it's approved on the master with "hoster ha approve".`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := joinHaCluster(args[0], args[1])
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+var (
+	haApproveCmd = &cobra.Command{
+		Use:   "approve [hostname] [fingerprint]",
+		Short: "Approve the pending join request",
+		Long: `Approve the pending join request.
+The fingerprint is printed by "hoster ha join" on the joining node, and must match the key of the pending request.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			member, err := CarpClient.MemberApprove(args[0], args[1])
+			if err != nil {
+				emojlog.PrintLogMessage("could not approve "+args[0]+" -> "+err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("Host "+member.HostName+" is now a member of the cluster", emojlog.Changed)
+		},
+	}
+)
+
+var (
+	haRemoveCmd = &cobra.Command{
+		Use:   "remove [hostname]",
+		Short: "Remove the member from the cluster",
+		Long: `Remove the member from the cluster.
+It's pings and state syncs are rejected from now on, and it will have to join again.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := CarpClient.MemberRemove(args[0])
+			if err != nil {
+				emojlog.PrintLogMessage("could not remove "+args[0]+" -> "+err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("Host "+args[0]+" has been removed from the cluster", emojlog.Changed)
+		},
+	}
+)
+
+var (
+	haMembersCmd = &cobra.Command{
+		Use:   "members",
+		Short: "List the cluster members and pending join requests",
+		Long:  `List the cluster members and pending join requests.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			res, err := CarpClient.ListMembers()
+			if err != nil {
+				emojlog.PrintLogMessage("could not list the cluster members -> "+err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			printHaMembers(res.Members, res.Self.HostName)
+		},
+	}
+)
+
+// Sends the join request to the master, and trusts the master's key from now on (if it matches the fingerprint)
+func joinHaCluster(masterIp string, masterFingerprint string) error {
+	key, err := HaMembership.LoadOrCreateKey()
+	if err != nil {
+		return err
+	}
+	hostname, err := FreeBSDsysctls.SysctlKernHostname()
+	if err != nil {
+		return err
+	}
+
+	res, err := ApiV2client.CarpJoin(masterIp, HaMembership.JoinRequest{HostName: hostname, PublicKey: HaMembership.PublicKeyString(key)})
+	if err != nil {
+		return err
+	}
+	if HaMembership.Fingerprint(res.PublicKey) != masterFingerprint {
+		return fmt.Errorf("master key fingerprint %s doesn't match %s, refusing to trust %s", HaMembership.Fingerprint(res.PublicKey), masterFingerprint, res.HostName)
+	}
+
+	master := HaMembership.Member{HostName: res.HostName, PublicKey: res.PublicKey, IpAddress: masterIp}
+	// The running HA service keeps the membership in memory, so it has to be the one to save it
+	pid, _ := FreeBSDPgrep.FindHaCarp()
+	if pid > 0 {
+		err = CarpClient.MemberTrust(master)
+	} else {
+		var members *HaMembership.Members
+		members, err = HaMembership.ReadMembers()
+		if err != nil {
+			return err
+		}
+		err = members.Trust(master)
+	}
+	if err != nil {
+		return err
+	}
+
+	if res.Status == HaMembership.STATUS_APPROVED {
+		emojlog.PrintLogMessage("This node is already a member of "+res.HostName+"'s cluster", emojlog.Info)
+		return nil
+	}
+	emojlog.PrintLogMessage("Join request has been sent, approve it on the master: hoster ha approve "+hostname+" "+HaMembership.Fingerprint(HaMembership.PublicKeyString(key)), emojlog.Changed)
+	return nil
+}
+
+func printHaMembers(members []HaMembership.Member, self string) {
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft, // Hostname
+		table.AlignLeft, // IP Address
+		table.AlignLeft, // Status
+		table.AlignLeft, // Fingerprint
+		table.AlignLeft, // Since
+	)
+
+	t.SetHeaders("HA Cluster Members")
+	t.SetHeaderColSpans(0, 6)
+	t.AddHeaders(
+		"#",
+		"Hostname",
+		"IP Address",
+		"Status",
+		"Fingerprint",
+		"Since",
+	)
+
+	t.SetLineStyle(table.StyleBrightCyan)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetHeaderStyle(table.StyleBold)
+
+	for _, v := range members {
+		ID = ID + 1
+
+		hostname := v.HostName
+		if v.HostName == self {
+			hostname = hostname + " (this node)"
+		}
+		since := v.Approved
+		if v.Status == HaMembership.STATUS_PENDING {
+			since = v.Requested
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			hostname,
+			v.IpAddress,
+			v.Status,
+			HaMembership.Fingerprint(v.PublicKey),
+			time.Unix(since, 0).Format(time.DateTime),
+		)
+	}
+
+	t.Render()
+}
@@ -0,0 +1,225 @@
+//go:build freebsd
+// +build freebsd
+
+package cmd
+
+import (
+	CarpClient "HosterCore/internal/app/ha_carp/client"
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	"HosterCore/internal/pkg/emojlog"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/table"
+)
+
+func printHaClusterStatus(pid int) error {
+	ha, err := CarpClient.GetHaStatus()
+	if err != nil {
+		return err
+	}
+	// CARP state is informational, the status is still useful without it
+	carp, _ := CarpUtils.ParseIfconfig()
+	status := CarpUtils.NewClusterStatus(ha, carp)
+
+	if haStatusJson {
+		jsonOut, err := json.MarshalIndent(status, "", "   ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonOut))
+		return nil
+	}
+
+	carpState := []string{}
+	for _, v := range status.Carp {
+		carpState = append(carpState, fmt.Sprintf("%s vhid %d %s", v.Interface, v.Vhid, v.Status))
+	}
+	if len(carpState) < 1 {
+		carpState = append(carpState, "no CARP interfaces")
+	}
+
+	emojlog.PrintLogMessage(fmt.Sprintf("HA service is running with PID: %d, this node is %s (%s)", pid, status.Status, strings.Join(carpState, ", ")), emojlog.Info)
+	if status.Quorum {
+		emojlog.PrintLogMessage("Current master: "+status.CurrentMaster+", quorum: yes", emojlog.Info)
+	} else {
+		emojlog.PrintLogMessage("Current master: "+status.CurrentMaster+", quorum: no", emojlog.Warning)
+	}
+
+	printHaNodes(status.Nodes)
+	printHaResources(status.Resources)
+	if len(status.PendingFailovers) > 0 {
+		printHaPendingFailovers(status.PendingFailovers)
+	}
+
+	return nil
+}
+
+func printHaNodes(nodes []CarpUtils.NodeStatus) {
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft,  // Hostname
+		table.AlignLeft,  // IP Address
+		table.AlignLeft,  // Role
+		table.AlignLeft,  // State
+		table.AlignRight, // Last Seen
+		table.AlignRight, // Running
+	)
+
+	t.SetHeaders("HA Nodes")
+	t.SetHeaderColSpans(0, 7)
+	t.AddHeaders(
+		"#",
+		"Hostname",
+		"IP Address",
+		"Role",
+		"State",
+		"Last Seen",
+		"Running",
+	)
+
+	t.SetLineStyle(table.StyleBrightCyan)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetHeaderStyle(table.StyleBold)
+
+	now := time.Now().Unix()
+	for _, v := range nodes {
+		ID = ID + 1
+
+		state := "online"
+		if v.Offline {
+			state = "OFFLINE"
+		}
+		if v.Fenced {
+			state = state + ", fenced"
+		}
+		if v.Maintenance {
+			state = state + ", maintenance"
+		}
+		if len(v.Member) > 0 && v.Member != HaMembership.STATUS_APPROVED {
+			state = state + ", " + v.Member
+		}
+
+		lastSeen := "-"
+		if v.LastSeen > 0 {
+			lastSeen = CarpUtils.FormatAge(now-v.LastSeen) + " ago"
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			v.HostName,
+			v.IpAddress,
+			v.Role,
+			state,
+			lastSeen,
+			strconv.Itoa(v.Running),
+		)
+	}
+
+	t.Render()
+}
+
+func printHaResources(resources []CarpUtils.ResourceStatus) {
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft,  // Resource
+		table.AlignLeft,  // Type
+		table.AlignLeft,  // Parent
+		table.AlignLeft,  // Backups
+		table.AlignLeft,  // Last Snapshot
+		table.AlignRight, // Snapshot Age
+	)
+
+	t.SetHeaders("HA Resources")
+	t.SetHeaderColSpans(0, 7)
+	t.AddHeaders(
+		"#",
+		"Resource",
+		"Type",
+		"Parent",
+		"Backups",
+		"Last Snapshot",
+		"Snapshot Age",
+	)
+
+	t.SetLineStyle(table.StyleBrightCyan)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetHeaderStyle(table.StyleBold)
+
+	for _, v := range resources {
+		ID = ID + 1
+
+		lastSnapshot := v.LastSnapshot
+		if len(lastSnapshot) < 1 {
+			lastSnapshot = "-"
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			v.ResourceName,
+			v.ResourceType,
+			v.ParentHost,
+			strings.Join(v.BackupHosts, ", "),
+			lastSnapshot,
+			CarpUtils.FormatAge(v.SnapshotAge),
+		)
+	}
+
+	t.Render()
+}
+
+func printHaPendingFailovers(pending []CarpUtils.PendingFailover) {
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft,  // Resource
+		table.AlignLeft,  // Type
+		table.AlignLeft,  // Old Parent
+		table.AlignRight, // Attempts
+		table.AlignLeft,  // Last Error
+	)
+
+	t.SetHeaders("Pending Failovers")
+	t.SetHeaderColSpans(0, 6)
+	t.AddHeaders(
+		"#",
+		"Resource",
+		"Type",
+		"Old Parent",
+		"Attempts",
+		"Last Error",
+	)
+
+	t.SetLineStyle(table.StyleBrightCyan)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetHeaderStyle(table.StyleBold)
+
+	for _, v := range pending {
+		ID = ID + 1
+
+		lastError := v.Error
+		if len(lastError) < 1 {
+			lastError = "-"
+		} else if len(v.NewParent) > 0 {
+			lastError = v.NewParent + ": " + lastError
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			v.ResourceName,
+			v.ResourceType,
+			v.OldParent,
+			strconv.Itoa(v.Attempts),
+			lastError,
+		)
+	}
+
+	t.Render()
+}
@@ -0,0 +1,263 @@
+//go:build freebsd
+// +build freebsd
+
+package cmd
+
+import (
+	CarpClient "HosterCore/internal/app/ha_carp/client"
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	"HosterCore/internal/pkg/emojlog"
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
+	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterMigration "HosterCore/internal/pkg/hoster/migration"
+	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	hostMaintenanceTarget         string
+	hostMaintenanceFallbackTarget string
+	hostMaintenanceSshKey         string
+	hostMaintenanceSshPort        int
+	hostMaintenanceSpeedLimit     int
+
+	hostMaintenanceCmd = &cobra.Command{
+		Use:   "maintenance",
+		Short: "Host maintenance mode",
+		Long:  `Host maintenance mode: drain the resources to the other nodes, and keep HA from failing this node over.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			cmd.Help()
+		},
+	}
+
+	hostMaintenanceEnterCmd = &cobra.Command{
+		Use:   "enter",
+		Short: "Enter the maintenance mode, and migrate all resources away",
+		Long: `Enter the maintenance mode, and migrate all resources away.
+Every VM and Jail this host is a parent of is replicated to it's target (chosen by the HA placement rules, or set with --target),
+stopped, handed over to the target as a new parent, and started there (if it was running).
+Resources without a backup copy on the other HA nodes are fully replicated to the node picked by the HA placement rules
+(among the nodes holding the backups), or to --fallback-target if it's set.
+HA doesn't fail over this node until the maintenance mode is exited, even if it goes offline.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			err := enterHostMaintenance()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+
+	hostMaintenanceExitCmd = &cobra.Command{
+		Use:   "exit",
+		Short: "Exit the maintenance mode",
+		Long:  `Exit the maintenance mode. The resources are not migrated back automatically.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			err := exitHostMaintenance()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+type maintenanceMigration struct {
+	resType string
+	resName string
+	target  HosterMigration.Target
+	initial bool // There is no copy on the target yet, the first replication sends the whole dataset
+}
+
+func enterHostMaintenance() error {
+	if FileExists(CarpUtils.MAINTENANCE_FILE) {
+		emojlog.PrintLogMessage("Host is already in the maintenance mode, draining the remaining resources", emojlog.Info)
+	} else {
+		err := os.WriteFile(CarpUtils.MAINTENANCE_FILE, []byte{}, 0640)
+		if err != nil {
+			return err
+		}
+		emojlog.PrintLogMessage("Host has entered the maintenance mode, HA failover is suppressed for this node", emojlog.Changed)
+	}
+
+	migrations, pending, err := planHostMaintenance()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, v := range migrations {
+		if v.initial {
+			emojlog.PrintLogMessage(fmt.Sprintf("Migrating %s %s to %s, it has no backup copy there, so the initial replication sends the whole dataset", v.resType, v.resName, v.target.Endpoint), emojlog.Info)
+		} else {
+			emojlog.PrintLogMessage(fmt.Sprintf("Migrating %s %s to %s", v.resType, v.resName, v.target.Endpoint), emojlog.Info)
+		}
+		state, err := HosterMigration.Migrate(v.resType, v.resName, v.target, HosterMigration.Options{})
+		if err != nil {
+			failed += 1
+			emojlog.PrintLogMessage(fmt.Sprintf("Could not migrate %s %s: %s", v.resType, v.resName, err.Error()), emojlog.Error)
+			continue
+		}
+		emojlog.PrintLogMessage(fmt.Sprintf("%s %s has been migrated to %s, downtime: %s", v.resType, v.resName, state.NewParent, state.Downtime().Round(time.Millisecond)), emojlog.Changed)
+	}
+
+	for resource, reason := range pending {
+		emojlog.PrintLogMessage(fmt.Sprintf("No target is available for %s: %s", resource, reason), emojlog.Warning)
+	}
+
+	if failed > 0 || len(pending) > 0 {
+		return fmt.Errorf("%d resource(s) failed to migrate, and %d have no target, the host stays in the maintenance mode", failed, len(pending))
+	}
+
+	emojlog.PrintLogMessage("Host has been drained, it's now safe to take it down", emojlog.Changed)
+	return nil
+}
+
+func exitHostMaintenance() error {
+	if !FileExists(CarpUtils.MAINTENANCE_FILE) {
+		return errors.New("host is not in the maintenance mode")
+	}
+
+	err := os.Remove(CarpUtils.MAINTENANCE_FILE)
+	if err != nil {
+		return err
+	}
+
+	emojlog.PrintLogMessage("Host has exited the maintenance mode", emojlog.Changed)
+	return nil
+}
+
+// Returns the resources to migrate and their targets, and the resources that can't be placed anywhere (with the reasons)
+func planHostMaintenance() (r []maintenanceMigration, pending map[string]string, e error) {
+	pending = make(map[string]string)
+
+	hostname, err := FreeBSDsysctls.SysctlKernHostname()
+	if err != nil {
+		e = err
+		return
+	}
+
+	vms, err := HosterVmUtils.ListJsonApi()
+	if err != nil {
+		e = err
+		return
+	}
+	jails, err := HosterJailUtils.ListJsonApi()
+	if err != nil {
+		e = err
+		return
+	}
+
+	local := []string{}
+	settings := make(map[string]*HaPlacement.Settings)
+	for _, v := range vms {
+		if !v.Backup {
+			key := HaCore.ResourceKey(HosterMigration.RESOURCE_VM, v.Name)
+			local = append(local, key)
+			settings[key] = v.Ha
+		}
+	}
+	for _, v := range jails {
+		if !v.Backup {
+			key := HaCore.ResourceKey(HosterMigration.RESOURCE_JAIL, v.Name)
+			local = append(local, key)
+			settings[key] = v.Ha
+		}
+	}
+
+	target := HosterMigration.Target{Key: hostMaintenanceSshKey, Port: hostMaintenanceSshPort, SpeedLimit: hostMaintenanceSpeedLimit}
+
+	// Everything goes to the same node
+	if len(hostMaintenanceTarget) > 0 {
+		target.Endpoint = hostMaintenanceTarget
+		for _, v := range local {
+			resType, resName, _ := strings.Cut(v, "/")
+			r = append(r, maintenanceMigration{resType: resType, resName: resName, target: target})
+		}
+		return
+	}
+
+	// Otherwise let the HA placement rules pick the targets, as if this host has gone offline
+	haStatus, err := CarpClient.GetHaStatus()
+	if err != nil {
+		e = fmt.Errorf("could not get the HA status (use --target to migrate without HA): %s", err.Error())
+		return
+	}
+
+	copies := []CarpUtils.BackupInfo{}
+	copied := make(map[string]bool)
+	holders := []string{}
+	for _, v := range haStatus.Resources {
+		if v.ParentHost == hostname && v.CurrentHost != hostname {
+			copies = append(copies, v)
+			copied[HaCore.ResourceKey(v.ResourceType, v.ResourceName)] = true
+		}
+		if v.CurrentHost != hostname && v.CurrentHost != v.ParentHost && !slices.Contains(holders, v.CurrentHost) {
+			holders = append(holders, v.CurrentHost)
+		}
+	}
+	hosts := []CarpUtils.HostInfo{}
+	for _, v := range haStatus.Hosts {
+		if v.HostName == hostname {
+			v.Offline = true
+		}
+		hosts = append(hosts, v)
+	}
+
+	// Resources without a backup copy go to the fallback target, or to any node holding the backups (those take part
+	// in the failover), which gets the whole dataset with the initial replication
+	initial := make(map[string]bool)
+	for _, v := range local {
+		if copied[v] {
+			continue
+		}
+		resType, resName, _ := strings.Cut(v, "/")
+		if len(hostMaintenanceFallbackTarget) > 0 {
+			resourceTarget := target
+			resourceTarget.Endpoint = hostMaintenanceFallbackTarget
+			r = append(r, maintenanceMigration{resType: resType, resName: resName, target: resourceTarget, initial: true})
+			continue
+		}
+
+		initial[v] = true
+		for _, vv := range holders {
+			copies = append(copies, CarpUtils.BackupInfo{ResourceType: resType, ResourceName: resName, CurrentHost: vv, ParentHost: hostname, Ha: settings[v]})
+		}
+	}
+
+	plans, _, reasons := CarpUtils.PlanFailover(copies, hosts)
+	planned := make(map[string]bool)
+	for _, v := range plans {
+		key := HaCore.ResourceKey(v.Backup.ResourceType, v.Backup.ResourceName)
+		planned[key] = true
+
+		resourceTarget := target
+		resourceTarget.Endpoint = v.Target.IpAddress
+		r = append(r, maintenanceMigration{resType: v.Backup.ResourceType, resName: v.Backup.ResourceName, target: resourceTarget, initial: initial[key]})
+	}
+
+	for _, v := range local {
+		if planned[v] || (!copied[v] && len(hostMaintenanceFallbackTarget) > 0) {
+			continue
+		}
+		reason, found := reasons[v]
+		if !found {
+			reason = "no backup copies on the other HA nodes, and no nodes holding the backups (use --fallback-target)"
+		}
+		pending[v] = reason
+	}
+
+	return
+}
@@ -8,6 +8,7 @@ import (
 	FreeBSDOsInfo "HosterCore/internal/pkg/freebsd/info"
 	HosterNetwork "HosterCore/internal/pkg/hoster/network"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
@@ -63,6 +64,12 @@ var (
 				err = nil
 			}
 
+			err = applyPortForwards()
+			if err != nil {
+				emojlog.PrintLogMessage("Could not apply the port forwards: "+err.Error(), emojlog.Error)
+				err = nil
+			}
+
 			err = startSchedulerService()
 			if err != nil {
 				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
@@ -225,61 +232,76 @@ func applySysctls() error {
 }
 
 func loadNetworkConfig() error {
-	networkInfoVar, err := HosterNetwork.GetNetworkConfig()
+	networks, err := HosterNetwork.GetNetworkConfig()
 	if err != nil {
 		return err
 	}
 
-	stdout, stderr := exec.Command("ifconfig").CombinedOutput()
-	if stderr != nil {
-		return errors.New("error running ifconfig: " + string(stdout) + " " + stderr.Error())
+	err = HosterNetwork.ValidateNetworkConfig(networks)
+	if err != nil {
+		return err
 	}
 
-	// reSplitSpace := regexp.MustCompile(`\s+`)
-	reMatchVmInterface := regexp.MustCompile(`vm-.*:`)
-	reReplacePrefix := regexp.MustCompile(`vm-`)
-	reReplaceSuffix := regexp.MustCompile(`:.*`)
-	var loadedInterfaceList []string
-	for _, v := range strings.Split(string(stdout), "\n") {
-		if reMatchVmInterface.MatchString(v) {
-			v = reReplacePrefix.ReplaceAllLiteralString(v, "")
-			v = reReplaceSuffix.ReplaceAllLiteralString(v, "")
-			loadedInterfaceList = append(loadedInterfaceList, v)
-		}
+	live, err := HosterNetwork.GetLiveNetworkState()
+	if err != nil {
+		return errors.New("error running ifconfig: " + err.Error())
 	}
 
-	for _, v := range networkInfoVar {
-		if slices.Contains(loadedInterfaceList, v.NetworkName) {
-			emojlog.PrintLogMessage("Interface is up-to-date: vm-"+v.NetworkName, emojlog.Debug)
-		} else {
-			stdout, stderr := exec.Command("ifconfig", "bridge", "create", "name", "vm-"+v.NetworkName).CombinedOutput()
-			if stderr != nil {
-				return errors.New("error running ifconfig bridge create: " + string(stdout) + " " + stderr.Error())
-			}
-			emojlog.PrintLogMessage("Created a network bridge for VM use: vm-"+v.NetworkName, emojlog.Changed)
+	for _, v := range HosterNetwork.PlanNetworkChanges(networks, live) {
+		if v.Action == HosterNetwork.NETWORK_CHANGE_UNCHANGED {
+			emojlog.PrintLogMessage("Interface is up-to-date: vm-"+v.Network, emojlog.Debug)
+			continue
+		}
+		if v.Blocked {
+			emojlog.PrintLogMessage(fmt.Sprintf("Skipping %s of vm-%s, %s", v.Action, v.Network, v.Detail), emojlog.Warning)
+			continue
+		}
 
-			if v.BridgeInterface != "None" {
-				stdout, stderr := exec.Command("ifconfig", "vm-"+v.NetworkName, "addm", v.BridgeInterface).CombinedOutput()
-				if stderr != nil {
-					return errors.New("error running ifconfig bridge create: " + string(stdout) + " " + stderr.Error())
-				}
-				emojlog.PrintLogMessage("Bridged external interface with our VM network: "+v.BridgeInterface, emojlog.Changed)
-			}
+		err := HosterNetwork.ApplyNetworkChange(networks, v)
+		if err != nil {
+			return err
+		}
+		emojlog.PrintLogMessage(networkChangeMessage(v), emojlog.Changed)
+	}
 
-			if v.ApplyBridgeAddr {
-				subnet := strings.Split(v.Subnet, "/")[1]
-				stdout, stderr := exec.Command("ifconfig", "vm-"+v.NetworkName, "inet", v.Gateway+"/"+subnet).CombinedOutput()
-				if stderr != nil {
-					return errors.New("error running ifconfig bridge create: " + string(stdout) + " " + stderr.Error())
-				}
-				emojlog.PrintLogMessage("Added IP address for vm-"+v.NetworkName+" - "+v.Gateway+"/"+subnet, emojlog.Changed)
-			}
+	for _, v := range networks {
+		if v.ApplyBridgeAddr && v.Ipv6Slaac() && !rtadvdEnabledFor("vm-"+v.NetworkName) {
+			emojlog.PrintLogMessage("SLAAC network vm-"+v.NetworkName+" requires rtadvd: sysrc rtadvd_enable=YES rtadvd_interfaces+=vm-"+v.NetworkName, emojlog.Warning)
 		}
 	}
 
 	return nil
 }
 
+func networkChangeMessage(change HosterNetwork.NetworkChange) string {
+	switch change.Action {
+	case HosterNetwork.NETWORK_CHANGE_CREATE:
+		return "Created a network bridge for VM use: vm-" + change.Network
+	case HosterNetwork.NETWORK_CHANGE_REMOVE:
+		return "Removed a stale network bridge: vm-" + change.Network
+	case HosterNetwork.NETWORK_CHANGE_ADD_UPLINK:
+		return fmt.Sprintf("Bridged external interface with our VM network: %s (%s)", change.Target, change.Detail)
+	case HosterNetwork.NETWORK_CHANGE_REMOVE_UPLINK:
+		return "Removed a stale uplink from vm-" + change.Network + ": " + change.Target
+	case HosterNetwork.NETWORK_CHANGE_SET_ADDRESS:
+		return "Added IP address for vm-" + change.Network + " - " + change.Target
+	case HosterNetwork.NETWORK_CHANGE_REMOVE_ADDRESS:
+		return "Removed a stale IP address from vm-" + change.Network + " - " + change.Target
+	case HosterNetwork.NETWORK_CHANGE_SET_MTU:
+		return "Set the MTU for vm-" + change.Network + " - " + change.Target
+	}
+	return change.Action + " vm-" + change.Network
+}
+
+// Checks if rtadvd is already configured to serve the interface
+func rtadvdEnabledFor(iface string) bool {
+	out, err := exec.Command("sysrc", "-n", "rtadvd_interfaces").CombinedOutput()
+	if err != nil {
+		return false
+	}
+	return slices.Contains(strings.Fields(string(out)), iface)
+}
+
 func applyPfSettings() error {
 	stdout, stderr := exec.Command("pfctl", "-f", "/etc/pf.conf").CombinedOutput()
 	if stderr != nil {
@@ -99,6 +99,26 @@ var (
 	}
 )
 
+var (
+	jailParentCmdNewParent string
+
+	jailParentCmd = &cobra.Command{
+		Use:   "parent [jailName]",
+		Short: "Change Jail's parent",
+		Long:  `Change Jail's parent, in order to start it on a new host.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := HosterJail.ChangeParent(args[0], jailParentCmdNewParent, false)
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
 var (
 	jailListCmdUnixStyle bool
 
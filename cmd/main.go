@@ -8,6 +8,8 @@ import (
 	"os"
 
 	HosterTables "HosterCore/internal/pkg/hoster/cli_tables"
+	HosterMigration "HosterCore/internal/pkg/hoster/migration"
+	HosterWireGuard "HosterCore/internal/pkg/hoster/wireguard"
 
 	"github.com/spf13/cobra"
 )
@@ -39,11 +41,69 @@ func init() {
 	hostCmd.Flags().BoolVarP(&jsonHostInfoOutput, "json", "j", false, "Output as JSON (useful for automation)")
 	hostCmd.Flags().BoolVarP(&jsonPrettyHostInfoOutput, "json-pretty", "", false, "Pretty JSON Output")
 
+	// Host -> maintenance
+	hostCmd.AddCommand(hostMaintenanceCmd)
+	hostMaintenanceCmd.AddCommand(hostMaintenanceEnterCmd)
+	hostMaintenanceEnterCmd.Flags().StringVarP(&hostMaintenanceTarget, "target", "t", "", "Migrate all resources to this SSH endpoint, instead of the targets picked by HA")
+	hostMaintenanceEnterCmd.Flags().StringVarP(&hostMaintenanceFallbackTarget, "fallback-target", "f", "", "Migrate the resources without a backup copy to this SSH endpoint, instead of the node picked by HA")
+	hostMaintenanceEnterCmd.Flags().StringVarP(&hostMaintenanceSshKey, "ssh-key", "k", HosterMigration.DEFAULT_SSH_KEY, "SSH key location")
+	hostMaintenanceEnterCmd.Flags().IntVarP(&hostMaintenanceSshPort, "ssh-port", "p", HosterMigration.DEFAULT_SSH_PORT, "SSH port")
+	hostMaintenanceEnterCmd.Flags().IntVarP(&hostMaintenanceSpeedLimit, "speed-limit", "l", HosterMigration.DEFAULT_SPEED_LIMIT, "Replication speed limit, in MB/s")
+	hostMaintenanceCmd.AddCommand(hostMaintenanceExitCmd)
+
 	// Host Network Section
 	rootCmd.AddCommand(networkCmd)
 	networkCmd.AddCommand(networkListCmd)
 	networkListCmd.Flags().BoolVarP(&networkListUnixStyleTable, "unix-style", "u", false, "Show Unix style table (useful for scripting)")
 	networkCmd.AddCommand(networkInitCmd)
+	networkCmd.AddCommand(networkDiffCmd)
+	networkDiffCmd.Flags().BoolVarP(&networkDiffUnixStyleTable, "unix-style", "u", false, "Show Unix style table (useful for scripting)")
+	networkCmd.AddCommand(networkTeardownCmd)
+	networkTeardownCmd.Flags().BoolVarP(&networkTeardownForce, "force", "", false, "Tear down the network even if VMs or Jails are still connected to it")
+
+	networkCmd.AddCommand(networkIpCmd)
+	networkIpCmd.AddCommand(networkIpListCmd)
+	networkIpListCmd.Flags().BoolVarP(&networkIpListUnixStyleTable, "unix-style", "u", false, "Show Unix style table (useful for scripting)")
+	networkIpCmd.AddCommand(networkIpReserveCmd)
+	networkIpReserveCmd.Flags().StringVarP(&networkIpReserveOwner, "owner", "o", "", "VM or Jail name, which is allowed to use this address")
+	networkIpReserveCmd.Flags().StringVarP(&networkIpReserveComment, "comment", "c", "", "Reservation comment")
+	networkIpCmd.AddCommand(networkIpReleaseCmd)
+
+	networkCmd.AddCommand(networkForwardCmd)
+	networkForwardCmd.AddCommand(networkForwardListCmd)
+	networkForwardListCmd.Flags().BoolVarP(&networkForwardListUnixStyleTable, "unix-style", "u", false, "Show Unix style table (useful for scripting)")
+	networkForwardCmd.AddCommand(networkForwardAddCmd)
+	networkForwardAddCmd.Flags().StringVarP(&networkForwardProtocol, "protocol", "p", "tcp", "Protocol to forward: tcp or udp")
+	networkForwardAddCmd.Flags().StringVarP(&networkForwardInterface, "interface", "i", "", "Public interface (defaults to the default route interface)")
+	networkForwardAddCmd.Flags().StringVarP(&networkForwardComment, "comment", "c", "", "Port forward comment")
+	networkForwardCmd.AddCommand(networkForwardRemoveCmd)
+	networkForwardRemoveCmd.Flags().StringVarP(&networkForwardProtocol, "protocol", "p", "tcp", "Protocol of the port forward: tcp or udp")
+	networkForwardRemoveCmd.Flags().StringVarP(&networkForwardInterface, "interface", "i", "", "Public interface the forward was created with (leave empty for the forwards without one)")
+	networkForwardCmd.AddCommand(networkForwardApplyCmd)
+
+	// WireGuard mesh
+	rootCmd.AddCommand(wireguardCmd)
+	wireguardCmd.AddCommand(wireguardInitCmd)
+	wireguardInitCmd.Flags().StringVarP(&wireguardInitName, "name", "n", "", "Node name (defaults to the hostname)")
+	wireguardInitCmd.Flags().StringVarP(&wireguardInitGroup, "group", "g", HosterWireGuard.DEFAULT_GROUP, "Cluster group, the node is connected to all peers in the same group")
+	wireguardInitCmd.Flags().StringVarP(&wireguardInitAddress, "address", "a", "", "Mesh address of this node, e.g. 10.254.0.1/24")
+	wireguardInitCmd.Flags().StringVarP(&wireguardInitEndpoint, "endpoint", "e", "", "Public address the other nodes use to reach this node, e.g. 203.0.113.10:51820")
+	wireguardInitCmd.Flags().IntVarP(&wireguardInitPort, "port", "p", HosterWireGuard.DEFAULT_LISTEN_PORT, "WireGuard listen port")
+	wireguardCmd.AddCommand(wireguardInfoCmd)
+	wireguardCmd.AddCommand(wireguardPeerCmd)
+	wireguardPeerCmd.AddCommand(wireguardPeerListCmd)
+	wireguardPeerListCmd.Flags().BoolVarP(&wireguardPeerListUnixStyleTable, "unix-style", "u", false, "Show Unix style table (useful for scripting)")
+	wireguardPeerCmd.AddCommand(wireguardPeerAddCmd)
+	wireguardPeerAddCmd.Flags().StringVarP(&wireguardPeerGroup, "group", "g", HosterWireGuard.DEFAULT_GROUP, "Cluster group of the peer")
+	wireguardPeerAddCmd.Flags().StringVarP(&wireguardPeerPublicKey, "public-key", "k", "", "Public key of the peer")
+	wireguardPeerAddCmd.Flags().StringVarP(&wireguardPeerAddress, "address", "a", "", "Mesh address of the peer, e.g. 10.254.0.2/24")
+	wireguardPeerAddCmd.Flags().StringVarP(&wireguardPeerEndpoint, "endpoint", "e", "", "Public address of the peer, e.g. 203.0.113.11:51820")
+	wireguardPeerAddCmd.Flags().StringVarP(&wireguardPeerAllowedIps, "allowed-ips", "", "", "Comma separated list of the extra subnets routed to the peer, e.g. 10.0.102.0/24")
+	wireguardPeerAddCmd.Flags().IntVarP(&wireguardPeerKeepalive, "keepalive", "", 0, "Persistent keepalive interval in seconds (0 disables it)")
+	wireguardPeerCmd.AddCommand(wireguardPeerRemoveCmd)
+	wireguardPeerRemoveCmd.Flags().StringVarP(&wireguardPeerGroup, "group", "g", HosterWireGuard.DEFAULT_GROUP, "Cluster group of the peer")
+	wireguardCmd.AddCommand(wireguardRenderCmd)
+	wireguardCmd.AddCommand(wireguardApplyCmd)
 
 	// Host Dataset Info
 	rootCmd.AddCommand(datasetCmd)
@@ -97,10 +157,19 @@ func init() {
 	carpHaCmd.AddCommand(haStopCmd)
 	// HA -> status
 	carpHaCmd.AddCommand(haStatusCmd)
+	haStatusCmd.Flags().BoolVarP(&haStatusJson, "json", "j", false, "Output as JSON (useful for automation)")
 	// HA -> info
 	carpHaCmd.AddCommand(haInfoCmd)
 	// HA -> show-log
 	carpHaCmd.AddCommand(haShowLogCmd)
+	// HA -> join
+	carpHaCmd.AddCommand(haJoinCmd)
+	// HA -> approve
+	carpHaCmd.AddCommand(haApproveCmd)
+	// HA -> remove
+	carpHaCmd.AddCommand(haRemoveCmd)
+	// HA -> members
+	carpHaCmd.AddCommand(haMembersCmd)
 
 	// Jail Command Section
 	rootCmd.AddCommand(jailCmd)
@@ -119,6 +188,15 @@ func init() {
 	jailListCmd.Flags().BoolVarP(&jailListCmdUnixStyle, "unix", "u", false, "Show Unix style table (useful for scripting)")
 	// Jail -> destroy
 	jailCmd.AddCommand(jailDestroyCmd)
+	// Jail -> parent
+	jailCmd.AddCommand(jailParentCmd)
+	jailParentCmd.Flags().StringVarP(&jailParentCmdNewParent, "new-parent", "p", "", "New parent name (optional, current hostname used by default)")
+	// Jail -> migrate
+	jailCmd.AddCommand(jailMigrateCmd)
+	jailMigrateCmd.Flags().StringVarP(&migrateSshKey, "key", "k", HosterMigration.DEFAULT_SSH_KEY, "Set the absolute location for the SSH key")
+	jailMigrateCmd.Flags().IntVarP(&migrateSshPort, "port", "p", HosterMigration.DEFAULT_SSH_PORT, "Set the target SSH port")
+	jailMigrateCmd.Flags().IntVarP(&migrateSpeedLimit, "speed-limit", "", HosterMigration.DEFAULT_SPEED_LIMIT, "Set the replication speed limit in MB/s")
+	jailMigrateCmd.Flags().BoolVarP(&migrateRemoveSource, "remove-source", "", false, "Destroy the local copy once the Jail is running on the target")
 	// Jail -> bootstrap
 	jailCmd.AddCommand(jailBootstrapCmd)
 	jailBootstrapCmd.Flags().StringVarP(&jailBootstrapCmdOsRelease, "release", "r", "", "Pick a FreeBSD OS Release version (your own OS release will be used by default)")
@@ -226,6 +304,13 @@ func init() {
 	vmZfsReplicateCmd.Flags().StringVarP(&sshKeyLocation, "key", "k", "/root/.ssh/id_rsa", "Set the absolute location for the SSH key, for example: `'/home/user-name/id_rsa'`")
 	vmZfsReplicateCmd.Flags().StringVarP(&replicateScriptName, "script-name", "", "", "Set the replication script name (useful to run multiple jobs in parallel)")
 
+	// VM cmd -> vm migrate
+	vmCmd.AddCommand(vmMigrateCmd)
+	vmMigrateCmd.Flags().StringVarP(&migrateSshKey, "key", "k", HosterMigration.DEFAULT_SSH_KEY, "Set the absolute location for the SSH key")
+	vmMigrateCmd.Flags().IntVarP(&migrateSshPort, "port", "p", HosterMigration.DEFAULT_SSH_PORT, "Set the target SSH port")
+	vmMigrateCmd.Flags().IntVarP(&migrateSpeedLimit, "speed-limit", "", HosterMigration.DEFAULT_SPEED_LIMIT, "Set the replication speed limit in MB/s")
+	vmMigrateCmd.Flags().BoolVarP(&migrateRemoveSource, "remove-source", "", false, "Destroy the local copy once the VM is running on the target")
+
 	// VM cmd -> vm replicate all
 	vmCmd.AddCommand(vmReplicateAllCmd)
 	vmReplicateAllCmd.Flags().StringVarP(&vmReplicateAllFilter, "filter", "f", "", "Filter the VMs that will be included in the replication (uses coma separated VM names, or coma + space): `'test-vm-1,test-vm-2'`")
@@ -320,6 +405,12 @@ func init() {
 	dnsCmd.AddCommand(dnsReloadCmd)
 	dnsCmd.AddCommand(dnsShowLogCmd)
 	dnsCmd.AddCommand(dnsStatusCmd)
+	dnsCmd.AddCommand(dnsZoneCmd)
+	dnsZoneCmd.Flags().BoolVarP(&dnsZoneUnix, "unix-style", "u", false, "Show Unix style table (useful for scripting)")
+	dnsZoneCmd.Flags().BoolVarP(&dnsZoneJson, "json", "j", false, "Output as JSON (useful for automation)")
+	dnsZoneCmd.Flags().BoolVarP(&dnsZoneJsonPretty, "json-pretty", "", false, "Pretty JSON Output")
+	dnsCmd.AddCommand(dnsUpstreamsCmd)
+	dnsUpstreamsCmd.Flags().BoolVarP(&dnsUpstreamsUnix, "unix-style", "u", false, "Show Unix style table (useful for scripting)")
 
 	// Version command section
 	rootCmd.AddCommand(versionCmd)
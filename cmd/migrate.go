@@ -0,0 +1,75 @@
+//go:build freebsd
+// +build freebsd
+
+package cmd
+
+import (
+	"HosterCore/internal/pkg/emojlog"
+	HosterMigration "HosterCore/internal/pkg/hoster/migration"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateSshKey       string
+	migrateSshPort      int
+	migrateSpeedLimit   int
+	migrateRemoveSource bool
+
+	vmMigrateCmd = &cobra.Command{
+		Use:   "migrate [vmName] [targetEndpoint]",
+		Short: "Migrate the VM to another Hoster node",
+		Long: `Migrate the VM to another Hoster node (reached over SSH, e.g. 10.0.0.2).
+The VM is replicated while it's running, then it's stopped, the final changes are sent over, and it's started on the target node.
+Interrupted (or partially failed) migration is resumed by running the same command again.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			err := migrateResource(HosterMigration.RESOURCE_VM, args[0], args[1])
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+
+	jailMigrateCmd = &cobra.Command{
+		Use:   "migrate [jailName] [targetEndpoint]",
+		Short: "Migrate the Jail to another Hoster node",
+		Long: `Migrate the Jail to another Hoster node (reached over SSH, e.g. 10.0.0.2).
+The Jail is replicated while it's running, then it's stopped, the final changes are sent over, and it's started on the target node.
+Interrupted (or partially failed) migration is resumed by running the same command again.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			err := migrateResource(HosterMigration.RESOURCE_JAIL, args[0], args[1])
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func migrateResource(resType string, resName string, endpoint string) error {
+	target := HosterMigration.Target{Endpoint: endpoint, Key: migrateSshKey, Port: migrateSshPort, SpeedLimit: migrateSpeedLimit}
+	opts := HosterMigration.Options{RemoveSource: migrateRemoveSource}
+	opts.Progress = func(p HosterMigration.PhaseTiming) {
+		emojlog.PrintLogMessage(fmt.Sprintf("%s: done in %s", p.Phase, secondsToDuration(p.Seconds)), emojlog.Info)
+	}
+
+	state, err := HosterMigration.Migrate(resType, resName, target, opts)
+	if err != nil {
+		return err
+	}
+
+	emojlog.PrintLogMessage(fmt.Sprintf("%s %s has been migrated to %s, downtime: %s", resType, resName, state.NewParent, state.Downtime().Round(time.Millisecond)), emojlog.Changed)
+	return nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+}
@@ -44,7 +44,7 @@ var (
 	networkInitCmd = &cobra.Command{
 		Use:   "init",
 		Short: "Initialize or re-load Hoster network configuration",
-		Long:  `Initialize or re-load Hoster network configuration.`,
+		Long:  `Initialize or re-load Hoster network configuration. Only the networks that changed are touched, so the VMs and Jails running on the other networks are not disrupted.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			checkInitFile()
 
@@ -57,6 +57,70 @@ var (
 	}
 )
 
+var (
+	networkDiffUnixStyleTable bool
+
+	networkDiffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show the changes `hoster network init` would make",
+		Long:  `Validate the network config, and show the changes "hoster network init" would make on this host (dry run).`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := printNetworkDiffTable()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+var (
+	networkTeardownForce bool
+
+	networkTeardownCmd = &cobra.Command{
+		Use:   "teardown [networkName]",
+		Short: "Remove the network bridge and it's VLAN/VXLAN interface",
+		Long:  `Remove the network bridge and it's VLAN/VXLAN interface. Refuses to do so if any VMs or Jails are still connected to the network, unless --force is used.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := teardownNetwork(args[0], networkTeardownForce)
+			if err != nil {
+				emojlog.PrintLogMessage("Could not tear down the network: "+err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func teardownNetwork(networkName string, force bool) error {
+	networks, err := HosterNetwork.GetNetworkConfig()
+	if err != nil {
+		return err
+	}
+
+	// Networks that were already removed from the config are torn down as plain bridges
+	network := HosterNetwork.NetworkConfig{NetworkName: networkName}
+	for _, v := range networks {
+		if v.NetworkName == networkName {
+			network = v
+			break
+		}
+	}
+
+	err = HosterNetwork.DestroyNetworkInterfaces(network, force)
+	if err != nil {
+		return err
+	}
+
+	emojlog.PrintLogMessage("Network has been torn down: vm-"+networkName, emojlog.Changed)
+	return nil
+}
+
 func printNetworkInfoTable() {
 	netInfo, err := HosterNetwork.GetNetworkConfig()
 	if err != nil {
@@ -117,12 +181,30 @@ func printNetworkInfoTable() {
 		if v.BridgeInterface == "None" {
 			bridgeInterface = "NAT (no bridge)"
 		}
+		if v.VlanId > 0 {
+			bridgeInterface = fmt.Sprintf("%s (VLAN %d on %s)", v.UplinkInterface(), v.VlanId, v.BridgeInterface)
+		}
+		if v.VxlanId > 0 {
+			bridgeInterface = fmt.Sprintf("%s (VXLAN VNI %d)", v.UplinkInterface(), v.VxlanId)
+		}
+
+		gateway := v.Gateway
+		subnet := v.Subnet
+		if v.Ipv6Enabled() {
+			if len(v.Gateway6) > 0 {
+				gateway = gateway + ", " + v.Gateway6
+			}
+			subnet = subnet + ", " + v.Subnet6
+			if v.Ipv6Slaac() {
+				subnet = subnet + " (SLAAC)"
+			}
+		}
 
 		t.AddRow(
 			strconv.Itoa(ID),
 			v.NetworkName,
-			v.Gateway,
-			v.Subnet,
+			gateway,
+			subnet,
 			v.RangeStart+" - "+v.RangeEnd,
 			bridgeInterface,
 			v.Comment,
@@ -131,3 +213,88 @@ func printNetworkInfoTable() {
 
 	t.Render()
 }
+
+func printNetworkDiffTable() error {
+	networks, err := HosterNetwork.GetNetworkConfig()
+	if err != nil {
+		return err
+	}
+
+	err = HosterNetwork.ValidateNetworkConfig(networks)
+	if err != nil {
+		return err
+	}
+
+	live, err := HosterNetwork.GetLiveNetworkState()
+	if err != nil {
+		return err
+	}
+
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft, // Network
+		table.AlignLeft, // Action
+		table.AlignLeft, // Target
+		table.AlignLeft, // Detail
+	)
+
+	if networkDiffUnixStyleTable {
+		t.SetDividers(table.Dividers{
+			ALL: " ",
+			NES: " ",
+			NSW: " ",
+			NEW: " ",
+			ESW: " ",
+			NE:  " ",
+			NW:  " ",
+			SW:  " ",
+			ES:  " ",
+			EW:  " ",
+			NS:  " ",
+		})
+		t.SetRowLines(false)
+		t.SetBorderTop(false)
+		t.SetBorderBottom(false)
+	} else {
+		t.SetHeaders("Hoster Network Changes")
+		t.SetHeaderColSpans(0, 5)
+
+		t.AddHeaders(
+			"#",
+			"Network",
+			"Action",
+			"Target",
+			"Detail",
+		)
+
+		t.SetLineStyle(table.StyleBrightCyan)
+		t.SetDividers(table.UnicodeRoundedDividers)
+		t.SetHeaderStyle(table.StyleBold)
+	}
+
+	for _, v := range HosterNetwork.PlanNetworkChanges(networks, live) {
+		ID = ID + 1
+
+		action := v.Action
+		if v.Blocked {
+			action = action + " (blocked)"
+		}
+
+		detail := v.Detail
+		if len(detail) < 1 {
+			detail = "-"
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			v.Network,
+			action,
+			v.Target,
+			detail,
+		)
+	}
+
+	t.Render()
+	return nil
+}
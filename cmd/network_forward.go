@@ -0,0 +1,239 @@
+//go:build freebsd
+// +build freebsd
+
+package cmd
+
+import (
+	"HosterCore/internal/pkg/emojlog"
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aquasecurity/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	networkForwardCmd = &cobra.Command{
+		Use:   "forward",
+		Short: "Port forwarding (NAT) management",
+		Long:  `Port forwarding (NAT) management: expose the services running inside of the VMs and Jails on the host's public interface.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			cmd.Help()
+		},
+	}
+)
+
+var (
+	networkForwardListUnixStyleTable bool
+
+	networkForwardListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all port forwards",
+		Long:  `List all port forwards, together with the current IP address of their targets.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := printNetworkForwardTable()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+var (
+	networkForwardProtocol  string
+	networkForwardInterface string
+	networkForwardComment   string
+
+	networkForwardAddCmd = &cobra.Command{
+		Use:   "add [publicPort] [vmOrJailName:port]",
+		Short: "Add a new port forward",
+		Long:  `Forward the public port on this host to a port on the VM or Jail, e.g. "hoster network forward add 8080 test-vm-1:80". The forward follows the VM or Jail if it's IP address changes.`,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			publicPort, err := strconv.Atoi(args[0])
+			if err != nil {
+				emojlog.PrintLogMessage("invalid public port: "+args[0], emojlog.Error)
+				os.Exit(1)
+			}
+			target, targetPort, err := HosterNetwork.ParsePortForwardTarget(args[1])
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+
+			err = HosterHostUtils.AddPortForward(HosterNetwork.PortForward{
+				Protocol:   networkForwardProtocol,
+				PublicPort: publicPort,
+				Target:     target,
+				TargetPort: targetPort,
+				Interface:  networkForwardInterface,
+				Comment:    networkForwardComment,
+			})
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage(fmt.Sprintf("Port forward has been added: %d -> %s:%d", publicPort, target, targetPort), emojlog.Changed)
+		},
+	}
+)
+
+var (
+	networkForwardRemoveCmd = &cobra.Command{
+		Use:   "remove [publicPort]",
+		Short: "Remove a port forward",
+		Long:  `Remove a port forward using it's public port.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			publicPort, err := strconv.Atoi(args[0])
+			if err != nil {
+				emojlog.PrintLogMessage("invalid public port: "+args[0], emojlog.Error)
+				os.Exit(1)
+			}
+
+			err = HosterHostUtils.RemovePortForward(networkForwardProtocol, publicPort, networkForwardInterface)
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("Port forward has been removed: "+args[0], emojlog.Changed)
+		},
+	}
+)
+
+var (
+	networkForwardApplyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Re-apply all port forwards",
+		Long:  `Re-render all port forwards using the current VM and Jail IP addresses, and load them into PF.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := applyPortForwards()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("Port forwards have been applied", emojlog.Changed)
+		},
+	}
+)
+
+func applyPortForwards() error {
+	skipped, err := HosterHostUtils.ApplyPortForwards()
+	if err != nil {
+		return err
+	}
+	for _, v := range skipped {
+		emojlog.PrintLogMessage(fmt.Sprintf("Port forward %s/%d was skipped, could not find the target: %s", v.Protocol, v.PublicPort, v.Target), emojlog.Warning)
+	}
+
+	return nil
+}
+
+func printNetworkForwardTable() error {
+	forwards, err := HosterNetwork.GetPortForwards()
+	if err != nil {
+		return err
+	}
+
+	targets, err := HosterHostUtils.PortForwardTargets()
+	if err != nil {
+		return err
+	}
+
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft, // Protocol
+		table.AlignLeft, // Public Port
+		table.AlignLeft, // Interface
+		table.AlignLeft, // Target
+		table.AlignLeft, // Target Port
+		table.AlignLeft, // Target IP
+		table.AlignLeft, // Comment
+	)
+
+	if networkForwardListUnixStyleTable {
+		t.SetDividers(table.Dividers{
+			ALL: " ",
+			NES: " ",
+			NSW: " ",
+			NEW: " ",
+			ESW: " ",
+			NE:  " ",
+			NW:  " ",
+			SW:  " ",
+			ES:  " ",
+			EW:  " ",
+			NS:  " ",
+		})
+		t.SetRowLines(false)
+		t.SetBorderTop(false)
+		t.SetBorderBottom(false)
+	} else {
+		t.SetHeaders("Hoster Port Forwards")
+		t.SetHeaderColSpans(0, 8)
+
+		t.AddHeaders(
+			"#",
+			"Protocol",
+			"Public Port",
+			"Interface",
+			"Target",
+			"Target Port",
+			"Target IP",
+			"Comment",
+		)
+
+		t.SetLineStyle(table.StyleBrightCyan)
+		t.SetDividers(table.UnicodeRoundedDividers)
+		t.SetHeaderStyle(table.StyleBold)
+	}
+
+	for _, v := range forwards {
+		ID = ID + 1
+
+		iface := v.Interface
+		if len(iface) < 1 {
+			iface = "default"
+		}
+
+		targetIp, found := targets[v.Target]
+		if !found {
+			targetIp = "-"
+		}
+
+		comment := v.Comment
+		if len(comment) < 1 {
+			comment = "-"
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			v.Protocol,
+			strconv.Itoa(v.PublicPort),
+			iface,
+			v.Target,
+			strconv.Itoa(v.TargetPort),
+			targetIp,
+			comment,
+		)
+	}
+
+	t.Render()
+	return nil
+}
@@ -0,0 +1,188 @@
+//go:build freebsd
+// +build freebsd
+
+package cmd
+
+import (
+	"HosterCore/internal/pkg/emojlog"
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	networkIpCmd = &cobra.Command{
+		Use:   "ip",
+		Short: "IP address management",
+		Long:  `IP address management: list the used addresses, reserve and release them.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			cmd.Help()
+		},
+	}
+)
+
+var (
+	networkIpListUnixStyleTable bool
+
+	networkIpListCmd = &cobra.Command{
+		Use:   "list [networkName]",
+		Short: "List used, reserved and quarantined IP addresses",
+		Long:  `List used, reserved, quarantined and excluded IP addresses, as well as any address conflicts between the VMs and Jails.`,
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			networkName := ""
+			if len(args) > 0 {
+				networkName = args[0]
+			}
+
+			err := printNetworkIpTable(networkName)
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+var (
+	networkIpReserveOwner   string
+	networkIpReserveComment string
+
+	networkIpReserveCmd = &cobra.Command{
+		Use:   "reserve [networkName] [ipAddress]",
+		Short: "Reserve an IP address",
+		Long:  `Reserve an IP address, so it's never picked up by the automatic allocation (unless it's done for the reservation owner). The next available address is reserved if the IP address is not set.`,
+		Args:  cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			ipAddress := ""
+			if len(args) > 1 {
+				ipAddress = args[1]
+			}
+
+			reserved, err := HosterHostUtils.ReserveIp(args[0], ipAddress, networkIpReserveOwner, networkIpReserveComment)
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("IP address has been reserved: "+reserved, emojlog.Changed)
+		},
+	}
+)
+
+var (
+	networkIpReleaseCmd = &cobra.Command{
+		Use:   "release [networkName] [ipAddress]",
+		Short: "Release an IP address",
+		Long:  `Remove the IP address reservation. The address will stay in quarantine for the period set in the network config.`,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := HosterHostUtils.ReleaseIp(args[0], args[1])
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("IP address has been released: "+args[1], emojlog.Changed)
+		},
+	}
+)
+
+func printNetworkIpTable(networkName string) error {
+	addresses, err := HosterHostUtils.ListIps(networkName)
+	if err != nil {
+		return err
+	}
+
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft, // Network
+		table.AlignLeft, // IP Address
+		table.AlignLeft, // State
+		table.AlignLeft, // Owner
+		table.AlignLeft, // Expires
+		table.AlignLeft, // Comment
+	)
+
+	if networkIpListUnixStyleTable {
+		t.SetDividers(table.Dividers{
+			ALL: " ",
+			NES: " ",
+			NSW: " ",
+			NEW: " ",
+			ESW: " ",
+			NE:  " ",
+			NW:  " ",
+			SW:  " ",
+			ES:  " ",
+			EW:  " ",
+			NS:  " ",
+		})
+		t.SetRowLines(false)
+		t.SetBorderTop(false)
+		t.SetBorderBottom(false)
+	} else {
+		t.SetHeaders("Hoster IP Addresses")
+		t.SetHeaderColSpans(0, 7)
+
+		t.AddHeaders(
+			"#",
+			"Network",
+			"IP Address",
+			"State",
+			"Owner",
+			"Expires",
+			"Comment",
+		)
+
+		t.SetLineStyle(table.StyleBrightCyan)
+		t.SetDividers(table.UnicodeRoundedDividers)
+		t.SetHeaderStyle(table.StyleBold)
+	}
+
+	for _, v := range addresses {
+		ID = ID + 1
+
+		owner := "-"
+		if len(v.Owner) > 0 {
+			owner = v.Owner
+			if len(v.OwnerType) > 0 {
+				owner = owner + " (" + v.OwnerType + ")"
+			}
+		}
+
+		expires := "-"
+		if v.State == HosterNetwork.IP_STATE_QUARANTINED && v.ExpiresAt > 0 {
+			expires = time.Unix(v.ExpiresAt, 0).Format(time.DateTime)
+		}
+
+		comment := v.Comment
+		if len(comment) < 1 {
+			comment = "-"
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			v.Network,
+			v.IpAddress,
+			v.State,
+			owner,
+			expires,
+			comment,
+		)
+	}
+
+	t.Render()
+	return nil
+}
@@ -5,14 +5,9 @@ package cmd
 
 import (
 	"HosterCore/internal/pkg/emojlog"
-	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
 	HosterTables "HosterCore/internal/pkg/hoster/cli_tables"
 	HosterVm "HosterCore/internal/pkg/hoster/vm"
-	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
-	"fmt"
 	"os"
-	"regexp"
-	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -145,12 +140,6 @@ var (
 				emojlog.PrintLogMessage("Could not destroy the VM: "+err.Error(), emojlog.Error)
 				os.Exit(1)
 			}
-
-			err = ReloadDnsServer()
-			if err != nil {
-				emojlog.PrintLogMessage("Could not reload the DNS server: "+err.Error(), emojlog.Error)
-				os.Exit(1)
-			}
 		},
 	}
 )
@@ -185,44 +174,12 @@ var (
 		Run: func(cmd *cobra.Command, args []string) {
 			checkInitFile()
 
-			UnlockAllVms()
+			_, err := HosterVm.UnlockAllVms()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
 			emojlog.PrintLogMessage("All VMs have now been unlocked", emojlog.Debug)
 		},
 	}
 )
-
-func LockAllVms() error {
-	vms, err := HosterVmUtils.ListJsonApi()
-	if err != nil {
-		return err
-	}
-
-	timeNow := time.Now().Format("2006-01-02_15-04-05")
-	haLockedString := fmt.Sprintf("__HA_LOCKED_%s__", timeNow)
-
-	hostname, _ := FreeBSDsysctls.SysctlKernHostname()
-	for _, v := range vms {
-		if v.Production && v.CurrentHost == hostname {
-			_ = HosterVm.ChangeParent(v.Name, haLockedString, true)
-		}
-	}
-
-	return nil
-}
-
-func UnlockAllVms() error {
-	reHaLockedString := regexp.MustCompile(`__HA_LOCKED_.*`)
-	vms, err := HosterVmUtils.ListJsonApi()
-	if err != nil {
-		return err
-	}
-
-	hostname, _ := FreeBSDsysctls.SysctlKernHostname()
-	for _, v := range vms {
-		if v.Production && reHaLockedString.MatchString(v.CurrentHost) {
-			_ = HosterVm.ChangeParent(v.Name, hostname, true)
-		}
-	}
-
-	return nil
-}
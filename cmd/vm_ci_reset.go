@@ -66,16 +66,17 @@ func CiReset(oldVmName string, newVmName string) error {
 		c.VmName = oldVmName
 	}
 
-	c.MacAddress, err = HosterVmUtils.GenerateMacAddress()
+	// The MAC address is derived from the VM UUID, so the reset VM keeps it's MAC address
+	c.MacAddress, err = HosterVmUtils.AllocateMacAddress(HosterVmUtils.MacSeed(vmConf.VmConfig, oldVmName), 0, oldVmName)
 	if err != nil {
-		return errors.New("could not generate vm name: " + err.Error())
+		return errors.New("could not generate the MAC address: " + err.Error())
 	}
 
 	if len(ciResetCmdIpAddress) > 1 {
 		c.IpAddress = ciResetCmdIpAddress
 	} else {
 		// Generate and set random IP address (which is free in the pool of addresses)
-		c.IpAddress, err = HosterHostUtils.GenerateNewRandomIp(ciResetCmdNetworkName)
+		c.IpAddress, err = HosterHostUtils.AllocateIp(ciResetCmdNetworkName, c.VmName)
 		if err != nil {
 			return errors.New("could not generate the IP: " + err.Error())
 		}
@@ -85,26 +86,35 @@ func CiReset(oldVmName string, newVmName string) error {
 	if err != nil {
 		return errors.New("could not read the network config")
 	}
+	network := HosterNetwork.NetworkConfig{}
 	if len(ciResetCmdNetworkName) < 1 {
-		c.NetworkName = networkInfo[0].NetworkName
-		c.Subnet = networkInfo[0].Subnet
-		c.NakedSubnet = strings.Split(networkInfo[0].Subnet, "/")[1]
-		c.Gateway = networkInfo[0].Gateway
-		c.NetworkComment = networkInfo[0].Comment
+		network = networkInfo[0]
 	} else {
 		for _, v := range networkInfo {
 			if ciResetCmdNetworkName == v.NetworkName {
-				c.NetworkName = v.NetworkName
-				c.Subnet = v.Subnet
-				c.NakedSubnet = strings.Split(v.Subnet, "/")[1]
-				c.Gateway = v.Gateway
-				c.NetworkComment = v.Comment
+				network = v
 			}
 		}
-		if len(c.NetworkName) < 1 {
+		if len(network.NetworkName) < 1 {
 			return errors.New("network name supplied doesn't exist")
 		}
 	}
+	c.NetworkName = network.NetworkName
+	c.Subnet = network.Subnet
+	c.NakedSubnet = strings.Split(network.Subnet, "/")[1]
+	c.Gateway = network.Gateway
+	c.NetworkComment = network.Comment
+
+	// Dual-stack networks
+	if network.Ipv6Enabled() {
+		c.Ipv6Slaac = network.Ipv6Slaac()
+		c.NakedSubnet6 = HosterNetwork.PrefixLength(network.Subnet6)
+		c.Gateway6 = network.Gateway6
+		c.Ipv6Address, err = HosterHostUtils.AllocateIp6(c.NetworkName, c.VmName, c.MacAddress)
+		if err != nil {
+			return errors.New("could not generate the IPv6 address: " + err.Error())
+		}
+	}
 
 	if len(ciResetCmdDnsServer) > 1 {
 		c.DnsServer = ciResetCmdDnsServer
@@ -191,6 +201,7 @@ func CiReset(oldVmName string, newVmName string) error {
 	vmConf.Networks[0].NetworkBridge = c.NetworkName
 	vmConf.Networks[0].NetworkMac = c.MacAddress
 	vmConf.Networks[0].IPAddress = c.IpAddress
+	vmConf.Networks[0].IPv6Address = c.Ipv6Address
 	vmConf.Networks[0].Comment = c.NetworkComment
 	vmConf.ParentHost = c.ParentHost
 	vmConf.VncPort = c.VncPort
@@ -19,6 +19,7 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
@@ -111,6 +112,10 @@ type ConfigOutputStruct struct {
 	Subnet            string
 	NakedSubnet       string
 	Gateway           string
+	Ipv6Address       string
+	NakedSubnet6      string
+	Gateway6          string
+	Ipv6Slaac         bool
 	DnsServer         string
 	Production        bool
 	OsType            string
@@ -160,17 +165,18 @@ func deployVmFromIso(vmName string, networkName string, osType string, dsParent
 
 	emojlog.PrintLogMessage("Deploying new VM: "+c.VmName, emojlog.Info)
 
-	// Generate and set random MAC address
-	c.MacAddress, err = HosterVmUtils.GenerateMacAddress()
+	// Generate the VM UUID, and derive the MAC address from it
+	vmUuid := uuid.New().String()
+	c.MacAddress, err = HosterVmUtils.AllocateMacAddress(vmUuid, 0, "")
 	if err != nil {
-		return errors.New("could not generate a random MAC address: " + err.Error())
+		return errors.New("could not generate the MAC address: " + err.Error())
 	}
 
 	if len(vmDeployCmdIpAddress) > 1 {
 		c.IpAddress = vmDeployCmdIpAddress
 	} else {
 		// Generate and set random IP address (which is free in the pool of addresses)
-		c.IpAddress, err = HosterHostUtils.GenerateNewRandomIp(networkName)
+		c.IpAddress, err = HosterHostUtils.AllocateIp(networkName, c.VmName)
 		if err != nil {
 			return errors.New("could not generate the IP: " + err.Error())
 		}
@@ -180,26 +186,35 @@ func deployVmFromIso(vmName string, networkName string, osType string, dsParent
 	if err != nil {
 		return errors.New("could not read the network config")
 	}
+	network := HosterNetwork.NetworkConfig{}
 	if len(networkName) < 1 {
-		c.NetworkName = netInfo[0].NetworkName
-		c.Subnet = netInfo[0].Subnet
-		c.NakedSubnet = strings.Split(netInfo[0].Subnet, "/")[1]
-		c.Gateway = netInfo[0].Gateway
-		c.NetworkComment = netInfo[0].Comment
+		network = netInfo[0]
 	} else {
 		for _, v := range netInfo {
 			if networkName == v.NetworkName {
-				c.NetworkName = v.NetworkName
-				c.Subnet = v.Subnet
-				c.NakedSubnet = strings.Split(v.Subnet, "/")[1]
-				c.Gateway = v.Gateway
-				c.NetworkComment = v.Comment
+				network = v
 			}
 		}
-		if len(c.NetworkName) < 1 {
+		if len(network.NetworkName) < 1 {
 			return errors.New("network name supplied doesn't exist")
 		}
 	}
+	c.NetworkName = network.NetworkName
+	c.Subnet = network.Subnet
+	c.NakedSubnet = strings.Split(network.Subnet, "/")[1]
+	c.Gateway = network.Gateway
+	c.NetworkComment = network.Comment
+
+	// Dual-stack networks
+	if network.Ipv6Enabled() {
+		c.Ipv6Slaac = network.Ipv6Slaac()
+		c.NakedSubnet6 = HosterNetwork.PrefixLength(network.Subnet6)
+		c.Gateway6 = network.Gateway6
+		c.Ipv6Address, err = HosterHostUtils.AllocateIp6(c.NetworkName, c.VmName, c.MacAddress)
+		if err != nil {
+			return errors.New("could not generate the IPv6 address: " + err.Error())
+		}
+	}
 
 	if len(vmDeployCmdDnsServer) > 1 {
 		c.DnsServer = vmDeployCmdDnsServer
@@ -290,6 +305,7 @@ func deployVmFromIso(vmName string, networkName string, osType string, dsParent
 	networkConfig.NetworkBridge = c.NetworkName
 	networkConfig.NetworkMac = c.MacAddress
 	networkConfig.IPAddress = c.IpAddress
+	networkConfig.IPv6Address = c.Ipv6Address
 	networkConfig.Comment = c.NetworkComment
 	vmConfig.Networks = append(vmConfig.Networks, networkConfig)
 
@@ -318,6 +334,7 @@ func deployVmFromIso(vmName string, networkName string, osType string, dsParent
 	vmConfig.VmSshKeys = c.SshKeys
 	vmConfig.VncPort = c.VncPort
 	vmConfig.VncPassword = c.VncPassword
+	vmConfig.UUID = vmUuid
 	vmConfig.Description = "-"
 
 	err = HosterVmUtils.ConfigFileWriter(vmConfig, vmConfigFileLocation)
@@ -463,8 +480,19 @@ ethernets:
 	{{- end }}
     addresses:
     - {{ .IpAddress }}/{{ .NakedSubnet }}
+    {{- if and .Ipv6Address (not .Ipv6Slaac) }}
+    - {{ .Ipv6Address }}/{{ .NakedSubnet6 }}
+    {{- end }}
  
     gateway4: {{ .Gateway }}
+    {{- if and .Gateway6 (not .Ipv6Slaac) }}
+    gateway6: {{ .Gateway6 }}
+    {{- end }}
+    {{- if .Ipv6Slaac }}
+    dhcp6: false
+    accept-ra: true
+    ipv6-address-generation: eui64
+    {{- end }}
  
     nameservers:
       search: [ {{ .ParentHost }}.internal.lan, ]
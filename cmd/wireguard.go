@@ -0,0 +1,348 @@
+//go:build freebsd
+// +build freebsd
+
+package cmd
+
+import (
+	"HosterCore/internal/pkg/emojlog"
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HosterWireGuard "HosterCore/internal/pkg/hoster/wireguard"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aquasecurity/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	wireguardCmd = &cobra.Command{
+		Use:   "wireguard",
+		Short: "WireGuard mesh management",
+		Long:  `WireGuard mesh management: connect this node to all other Hoster nodes in the same cluster group.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			cmd.Help()
+		},
+	}
+)
+
+var (
+	wireguardInitName     string
+	wireguardInitGroup    string
+	wireguardInitAddress  string
+	wireguardInitEndpoint string
+	wireguardInitPort     int
+
+	wireguardInitCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Generate the WireGuard keys and config for this node",
+		Long:  `Generate the WireGuard keys and config for this node, e.g. "hoster wireguard init --address 10.254.0.1/24 --endpoint 203.0.113.10:51820".`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := wireguardInit()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+var (
+	wireguardInfoCmd = &cobra.Command{
+		Use:   "info",
+		Short: "Show the public WireGuard info of this node",
+		Long:  `Show the public WireGuard info of this node in JSON format, which can be added as a peer on the other nodes.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := printWireguardInfo()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+var (
+	wireguardPeerCmd = &cobra.Command{
+		Use:   "peer",
+		Short: "WireGuard peer inventory management",
+		Long:  `WireGuard peer inventory management.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+			cmd.Help()
+		},
+	}
+)
+
+var (
+	wireguardPeerListUnixStyleTable bool
+
+	wireguardPeerListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all WireGuard peers",
+		Long:  `List all WireGuard peers in the inventory, and show which of them this node is connected to.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := printWireguardPeerTable()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+var (
+	wireguardPeerGroup      string
+	wireguardPeerPublicKey  string
+	wireguardPeerAddress    string
+	wireguardPeerEndpoint   string
+	wireguardPeerAllowedIps string
+	wireguardPeerKeepalive  int
+
+	wireguardPeerAddCmd = &cobra.Command{
+		Use:   "add [peerName]",
+		Short: "Add or update a WireGuard peer",
+		Long:  `Add a WireGuard peer to the inventory (or update an existing one), and apply the updated mesh config.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			peer := HosterWireGuard.Peer{
+				Name:                args[0],
+				Group:               wireguardPeerGroup,
+				PublicKey:           wireguardPeerPublicKey,
+				Address:             wireguardPeerAddress,
+				Endpoint:            wireguardPeerEndpoint,
+				PersistentKeepalive: wireguardPeerKeepalive,
+			}
+			for _, v := range strings.Split(wireguardPeerAllowedIps, ",") {
+				if len(strings.TrimSpace(v)) > 0 {
+					peer.AllowedIps = append(peer.AllowedIps, strings.TrimSpace(v))
+				}
+			}
+
+			err := HosterWireGuard.UpdatePeers(func(c *HosterWireGuard.Config) error {
+				c.SetPeer(peer)
+				return nil
+			})
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("WireGuard peer has been saved: "+peer.Name, emojlog.Changed)
+		},
+	}
+)
+
+var (
+	wireguardPeerRemoveCmd = &cobra.Command{
+		Use:   "remove [peerName]",
+		Short: "Remove a WireGuard peer",
+		Long:  `Remove a WireGuard peer from the inventory, and apply the updated mesh config.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			err := HosterWireGuard.UpdatePeers(func(c *HosterWireGuard.Config) error {
+				return c.RemovePeer(args[0], wireguardPeerGroup)
+			})
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("WireGuard peer has been removed: "+args[0], emojlog.Changed)
+		},
+	}
+)
+
+var (
+	wireguardRenderCmd = &cobra.Command{
+		Use:   "render",
+		Short: "Print the wg-quick config for this node",
+		Long:  `Print the wg-quick config for this node, without applying it.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			conf, err := HosterWireGuard.GetConfig()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			fmt.Print(HosterWireGuard.Render(conf))
+		},
+	}
+)
+
+var (
+	wireguardApplyCmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Apply the WireGuard mesh config",
+		Long:  `Render the wg-quick config for this node, and bring up (or update) the mesh interface.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkInitFile()
+
+			conf, err := HosterWireGuard.GetConfig()
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			err = HosterWireGuard.Apply(conf)
+			if err != nil {
+				emojlog.PrintLogMessage(err.Error(), emojlog.Error)
+				os.Exit(1)
+			}
+			emojlog.PrintLogMessage("WireGuard mesh config has been applied: "+conf.Interface, emojlog.Changed)
+		},
+	}
+)
+
+func wireguardInit() error {
+	if HosterWireGuard.Initialized() {
+		return fmt.Errorf("WireGuard is already initialized on this node")
+	}
+
+	name := wireguardInitName
+	if len(name) < 1 {
+		hostname, err := FreeBSDsysctls.SysctlKernHostname()
+		if err != nil {
+			return err
+		}
+		name = hostname
+	}
+
+	conf, err := HosterWireGuard.NewConfig(name, wireguardInitGroup, wireguardInitAddress, wireguardInitEndpoint, wireguardInitPort)
+	if err != nil {
+		return err
+	}
+
+	err = HosterWireGuard.SaveConfig(conf)
+	if err != nil {
+		return err
+	}
+	emojlog.PrintLogMessage("WireGuard has been initialized, add this node as a peer on the other nodes: hoster wireguard info", emojlog.Changed)
+
+	return nil
+}
+
+func printWireguardInfo() error {
+	conf, err := HosterWireGuard.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	self, err := conf.Self()
+	if err != nil {
+		return err
+	}
+
+	jsonOut, err := json.MarshalIndent(self, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(jsonOut))
+	return nil
+}
+
+func printWireguardPeerTable() error {
+	conf, err := HosterWireGuard.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	connected := make(map[string]bool)
+	for _, v := range conf.MeshPeers() {
+		connected[v.PublicKey] = true
+	}
+
+	var ID = 0
+	var t = table.New(os.Stdout)
+	t.SetAlignment(table.AlignRight, //ID
+		table.AlignLeft, // Name
+		table.AlignLeft, // Group
+		table.AlignLeft, // Address
+		table.AlignLeft, // Endpoint
+		table.AlignLeft, // Allowed IPs
+		table.AlignLeft, // Public Key
+		table.AlignLeft, // Connected
+	)
+
+	if wireguardPeerListUnixStyleTable {
+		t.SetDividers(table.Dividers{
+			ALL: " ",
+			NES: " ",
+			NSW: " ",
+			NEW: " ",
+			ESW: " ",
+			NE:  " ",
+			NW:  " ",
+			SW:  " ",
+			ES:  " ",
+			EW:  " ",
+			NS:  " ",
+		})
+		t.SetRowLines(false)
+		t.SetBorderTop(false)
+		t.SetBorderBottom(false)
+	} else {
+		t.SetHeaders("Hoster WireGuard Peers")
+		t.SetHeaderColSpans(0, 8)
+
+		t.AddHeaders(
+			"#",
+			"Name",
+			"Group",
+			"Address",
+			"Endpoint",
+			"Allowed IPs",
+			"Public Key",
+			"Connected",
+		)
+
+		t.SetLineStyle(table.StyleBrightCyan)
+		t.SetDividers(table.UnicodeRoundedDividers)
+		t.SetHeaderStyle(table.StyleBold)
+	}
+
+	for _, v := range conf.Peers {
+		ID = ID + 1
+
+		endpoint := v.Endpoint
+		if len(endpoint) < 1 {
+			endpoint = "-"
+		}
+
+		allowedIps := strings.Join(v.AllowedIps, ", ")
+		if len(allowedIps) < 1 {
+			allowedIps = "-"
+		}
+
+		t.AddRow(
+			strconv.Itoa(ID),
+			v.Name,
+			v.Group,
+			v.Address,
+			endpoint,
+			allowedIps,
+			v.PublicKey,
+			strconv.FormatBool(connected[v.PublicKey]),
+		)
+	}
+
+	t.Render()
+	return nil
+}
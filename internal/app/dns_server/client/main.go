@@ -0,0 +1,91 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package DnsServerClient
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Asks the running DNS server to re-read the VM/Jail/static records and atomically swap the zone.
+func ReloadZone() error {
+	input := DnsServerUtils.BasePayload{Type: DnsServerUtils.MSG_ZONE_RELOAD}
+
+	messageBytes, err := sendMessage(input)
+	if err != nil {
+		return err
+	}
+
+	resp := DnsServerUtils.SocketResponse{}
+	err = json.Unmarshal(messageBytes, &resp)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling JSON: %v", err)
+	}
+	if !resp.Success {
+		return errors.New("DNS zone reload failed: " + resp.Message)
+	}
+
+	return nil
+}
+
+// Returns the zone that is currently being served by the DNS server.
+func GetZoneStatus() (r DnsServerUtils.ZoneStatus, e error) {
+	input := DnsServerUtils.BasePayload{Type: DnsServerUtils.MSG_ZONE_STATUS}
+
+	messageBytes, err := sendMessage(input)
+	if err != nil {
+		e = err
+		return
+	}
+
+	err = json.Unmarshal(messageBytes, &r)
+	if err != nil {
+		e = fmt.Errorf("error unmarshaling JSON: %v", err)
+		return
+	}
+
+	return
+}
+
+func sendMessage(input any) (r []byte, e error) {
+	conn, err := net.DialTimeout("unix", DnsServerUtils.SOCKET_FILE, 5*time.Second)
+	if err != nil {
+		e = fmt.Errorf("can't connect to Unix socket: %s", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	// Marshal the payload to JSON
+	payloadBytes, err := json.Marshal(input)
+	if err != nil {
+		e = fmt.Errorf("error marshaling JSON: %v", err)
+		return
+	}
+
+	// Send the JSON payload to the server
+	jsonDataWithNewLine := append(payloadBytes, []byte("\n")...)
+	_, err = conn.Write(jsonDataWithNewLine)
+	if err != nil {
+		e = fmt.Errorf("error sending data: %v", err)
+		return
+	}
+
+	// Read the message until a newline character is encountered
+	reader := bufio.NewReader(conn)
+	messageBytes, err := reader.ReadBytes('\n')
+	if err != nil {
+		e = fmt.Errorf("error reading message: %v", err)
+		return
+	}
+	// Remove the newline before processing
+	r = messageBytes[:len(messageBytes)-1]
+
+	return
+}
@@ -2,25 +2,20 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
-	"regexp"
 	"strings"
 	"syscall"
+	"time"
 
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
 	"HosterCore/internal/pkg/emojlog"
-	HosterHost "HosterCore/internal/pkg/hoster/host"
 
 	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
 )
 
-// Global state vars
-var vmInfoList []VmInfoStruct
-var jailInfoList []JailInfoStruct
-var hostConf HosterHost.HostConfig
-var staticRecords []HosterHost.DnsStaticRecord
-var upstreamServers []string
-
 var version = "" // version is set by the build system
 
 func main() {
@@ -35,7 +30,6 @@ func main() {
 	}
 
 	log.Info("Starting the DNS Server")
-	var err error
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGHUP)
@@ -43,13 +37,9 @@ func main() {
 		for sig := range signals {
 			if sig == syscall.SIGHUP {
 				log.Info("Received a reload signal: SIGHUP")
-				vmInfoList = getVmsInfo()
-				jailInfoList = getJailsInfo()
-				loadUpstreamDnsServers()
-
-				hostConf, err = HosterHost.GetHostConfig()
+				err := reloadZone()
 				if err != nil {
-					log.Fatalf("Failed to read the host config: %s", err.Error())
+					log.Errorf("Failed to reload the DNS zone: %s", err.Error())
 				}
 			}
 			if sig == syscall.SIGKILL {
@@ -59,22 +49,22 @@ func main() {
 		}
 	}()
 
-	loadUpstreamDnsServers()
-	loadStaticRecords()
-
-	vmInfoList = getVmsInfo()
-	jailInfoList = getJailsInfo()
-
-	hostConf, err = HosterHost.GetHostConfig()
+	zoneFootprint = resourcesFingerprint()
+	err := reloadZone()
 	if err != nil {
-		log.Fatalf("Failed to read the host config: %s", err.Error())
+		log.Fatalf("Failed to load the DNS zone: %s", err.Error())
 		os.Exit(1)
 	}
 
+	go socketServer()
+	go watchZoneChanges()
+	go metricsServer()
+	go cleanupRateBuckets()
+
 	server := dns.Server{Addr: ":53", Net: "udp"}
 	server.Handler = dns.HandlerFunc(handleDNSRequest)
 
-	log.Info("DNS Server is listening on 0.0.0.0:53")
+	log.Info("DNS Server is listening on [::]:53 (IPv4 and IPv6)")
 	err = server.ListenAndServe()
 	if err != nil {
 		emojlog.PrintLogMessage("Failed to start the DNS Server", emojlog.Error)
@@ -82,193 +72,106 @@ func main() {
 	}
 }
 
-func loadStaticRecords() {
-	// Load host config
-	hostConf, err := HosterHost.GetHostConfig()
-	if err != nil {
-		log.Error("Error loading host config file:" + err.Error())
-	}
+// Resolves the request in the following order: local zone first (static records, VMs, Jails),
+// then the conditional forwarding rules, then the default upstream servers.
+func handleDNSRequest(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
 
-	// Load static DNS records from the host config
-	if len(hostConf.DnsStaticRecords) > 0 {
-		staticRecords = append(staticRecords, hostConf.DnsStaticRecords...)
-	}
-}
+	// Pick up the zone snapshot once, so that a reload in the middle of the request can't mix up the answers
+	z := currentZone()
+	clientIP := w.RemoteAddr().String()
+	view := z.clientView(w.RemoteAddr())
 
-// Parses and loads the list of upstream DNS servers from the host config file.
-func loadUpstreamDnsServers() {
-	// Load host config
-	hostConf, err := HosterHost.GetHostConfig()
+	clientHost, _, err := net.SplitHostPort(clientIP)
 	if err != nil {
-		log.Error("Error loading host config file:" + err.Error())
+		clientHost = clientIP
 	}
-
-	// Load upstream DNS servers from the host config
-	upstreamServers = []string{}
-	reMatchPort := regexp.MustCompile(`.*:\d+`)
-	for _, v := range hostConf.DnsServers {
-		if reMatchPort.MatchString(v) {
-			upstreamServers = append(upstreamServers, v)
-		} else {
-			upstreamServers = append(upstreamServers, v+":53")
-		}
+	if !z.clientAllowed(net.ParseIP(clientHost)) {
+		refuseRequest(w, r, clientIP, DnsServerUtils.SOURCE_REFUSED)
+		return
 	}
-
-	// If host config doesn't include any servers, use the public ones
-	if len(upstreamServers) < 1 {
-		upstreamServers = append(upstreamServers, DNS_SRV4_QUAD_NINE)
-		upstreamServers = append(upstreamServers, DNS_SRV4_CLOUD_FLARE)
+	if !allowQuery(clientHost, z.rateLimit) {
+		// Don't respond (or log) at all, so the server can't be used to amplify the traffic or flood the log file
+		for _, q := range r.Question {
+			observeQuery(DnsServerUtils.SOURCE_LIMITED, dns.TypeToString[q.Qtype], dns.RcodeToString[dns.RcodeRefused], 0)
+		}
+		return
 	}
 
-	log.Infof("Loaded these servers from the host config file: %s", upstreamServers)
-}
-
-func handleDNSRequest(w dns.ResponseWriter, r *dns.Msg) {
-	m := new(dns.Msg)
-	m.SetReply(r)
-
-	var logLine string
 	for _, q := range r.Question {
-		// Drop any IPv6 record requests
-		// TBD: add a config variable that controls this behavior
-		clientIP := w.RemoteAddr().String()
-		if q.Qtype == dns.TypeAAAA {
-			log.Info(fmt.Sprintf("%s -> IPv6 request was ignored: %s", clientIP, q.Name))
+		start := time.Now()
+		entry := queryLogEntry{client: clientIP, view: view, name: q.Name, qtype: dns.TypeToString[q.Qtype]}
+
+		blockedBy := z.blocked(q.Name)
+		if len(blockedBy) > 0 {
+			entry.rule = blockedBy
+			entry.source = DnsServerUtils.SOURCE_BLOCKED
+			if len(z.blockSinkhole) > 0 {
+				// The sinkhole is an IPv4 address, other record types get an empty (NODATA) answer
+				if q.Qtype == dns.TypeA {
+					rr, err := dns.NewRR(q.Name + " IN A " + z.blockSinkhole)
+					if err == nil {
+						m.Answer = append(m.Answer, rr)
+						entry.answers = append(entry.answers, rr)
+					}
+				}
+				entry.rcode = dns.RcodeSuccess
+			} else {
+				m.Rcode = dns.RcodeNameError
+				entry.rcode = dns.RcodeNameError
+			}
+			entry.log(start)
 			continue
 		}
 
-		requestIsVmName := false
-		requestIsJailName := false
-		requestIsPublic := false
-		requestIsStaticRecord := false
+		answers, source, found := z.resolveLocal(q, view)
+		if found {
+			m.Answer = append(m.Answer, answers...)
+			entry.source = source
+			entry.rcode = dns.RcodeSuccess
+			entry.answers = answers
+			entry.log(start)
+			continue
+		}
 
-		vmListIndex := 0
-		jailListIndex := 0
-		dnsNameSplit := strings.Split(q.Name, ".")
+		upstreams, rule := z.upstreamsFor(q.Name, view)
+		entry.source = DnsServerUtils.SOURCE_UPSTREAM
+		entry.rule = rule
 
-		if len(dnsNameSplit) > 1 {
-			if IsPublicDomain(dnsNameSplit[len(dnsNameSplit)-2]) {
-				requestIsPublic = true
-			}
+		response, server, err := queryExternalDNS(q, upstreams)
+		if err != nil {
+			m.Rcode = dns.RcodeServerFailure
+			entry.rcode = dns.RcodeServerFailure
+			entry.err = err
+			entry.log(start)
+			continue
 		}
 
-		if len(staticRecords) > 0 {
-			// Only handle A and CNAME records from the static records for now
-			for _, v := range staticRecords {
-				if q.Qtype == dns.TypeA && strings.ToUpper(v.Type) == "A" {
-					if q.Name == v.Domain+"." || q.Name == v.Domain+"."+hostConf.DnsSearchDomain+"." {
-						rr, err := dns.NewRR(q.Name + " IN A " + v.Data)
-						if err != nil {
-							log.Error("Failed to generate an A record (from the static records): " + err.Error())
-							continue
-						}
-						m.Answer = append(m.Answer, rr)
-						logLine = clientIP + " -> " + q.Name + "::." + parseAnswer(m.Answer) + " <- CACHE_HIT::STATIC_A_RECORD"
-						log.Info(logLine)
-						requestIsStaticRecord = true
-					}
-				} else if q.Qtype == dns.TypeA && strings.ToUpper(v.Type) == "CNAME" {
-					if q.Name == v.Domain+"." || q.Name == v.Domain+"."+hostConf.DnsSearchDomain+"." {
-						q.Name = v.Data + "."
-						response, server, err := queryExternalDNS(q)
-						if err != nil {
-							log.Error("Failed to query external DNS:", err)
-							continue
-						}
-						m.Answer = append(m.Answer, response.Answer...)
-						logLine = clientIP + " -> " + q.Name + "::." + parseAnswer(m.Answer) + " <- CACHE_MISS::STATIC_CNAME_RECORD::" + server
-						log.Info(logLine)
-						requestIsStaticRecord = true
-					}
-				} else if q.Qtype == dns.TypeCNAME && strings.ToUpper(v.Type) == "CNAME" {
-					if q.Name == v.Domain+"." || q.Name == v.Domain+"."+hostConf.DnsSearchDomain+"." {
-						rr, err := dns.NewRR(q.Name + " IN CNAME " + v.Data)
-						if err != nil {
-							log.Error("Failed to generate a CNAME record (from the static records): " + err.Error())
-							continue
-						}
-						m.Answer = append(m.Answer, rr)
-						logLine = clientIP + " -> " + q.Name + "::." + parseAnswer(m.Answer) + " <- CACHE_HIT::STATIC_CNAME_RECORD"
-						log.Info(logLine)
-						requestIsStaticRecord = true
-					}
-				}
-			}
+		m.Answer = append(m.Answer, response.Answer...)
+		if response.Rcode != dns.RcodeSuccess {
+			m.Rcode = response.Rcode
 		}
+		entry.upstream = server
+		entry.rcode = response.Rcode
+		entry.answers = response.Answer
+		entry.log(start)
+	}
 
-		if !requestIsStaticRecord {
-			for i, v := range vmInfoList {
-				dnsName := dnsNameSplit[0]
-				if dnsName == v.vmName {
-					if q.Name == v.vmName+"."+hostConf.DnsSearchDomain+"." {
-						requestIsPublic = false
-					}
-					requestIsVmName = true
-					vmListIndex = i
-				} else if dnsName == strings.ToLower(v.vmName) {
-					if q.Name == v.vmName+"."+hostConf.DnsSearchDomain+"." {
-						requestIsPublic = false
-					}
-					requestIsVmName = true
-					vmListIndex = i
-				}
-			}
+	err = w.WriteMsg(m)
+	if err != nil {
+		log.Error("Failed to send the DNS Response:" + err.Error())
+	}
+}
 
-			for i, v := range jailInfoList {
-				dnsName := dnsNameSplit[0]
-				if dnsName == v.JailName {
-					if q.Name == v.JailName+"."+hostConf.DnsSearchDomain+"." {
-						requestIsPublic = false
-					}
-					requestIsJailName = true
-					jailListIndex = i
-				} else if dnsName == strings.ToLower(v.JailName) {
-					if q.Name == v.JailName+"."+hostConf.DnsSearchDomain+"." {
-						requestIsPublic = false
-					}
-					requestIsJailName = true
-					jailListIndex = i
-				}
-			}
+// Responds with REFUSED to the clients that are not allowed to use this DNS server
+func refuseRequest(w dns.ResponseWriter, r *dns.Msg, clientIP string, source string) {
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeRefused)
 
-			if requestIsPublic {
-				response, server, err := queryExternalDNS(q)
-				if err != nil {
-					log.Error("Failed to query external DNS:", err)
-					continue
-				}
-				m.Answer = append(m.Answer, response.Answer...)
-				logLine = clientIP + " -> " + q.Name + "::." + parseAnswer(m.Answer) + " <- CACHE_MISS::" + server
-				log.Info(logLine)
-			} else if requestIsVmName {
-				rr, err := dns.NewRR(q.Name + " IN A " + vmInfoList[vmListIndex].vmAddress)
-				if err != nil {
-					log.Error("Failed to create an A record:", err)
-					continue
-				}
-				m.Answer = append(m.Answer, rr)
-				logLine = clientIP + " -> " + q.Name + "::." + parseAnswer(m.Answer) + " <- CACHE_HIT::VM"
-				log.Info(logLine)
-			} else if requestIsJailName {
-				rr, err := dns.NewRR(q.Name + " IN A " + jailInfoList[jailListIndex].JailAddress)
-				if err != nil {
-					log.Error("Failed to create an A record:", err)
-					continue
-				}
-				m.Answer = append(m.Answer, rr)
-				logLine = clientIP + " -> " + q.Name + "::." + parseAnswer(m.Answer) + " <- CACHE_HIT::Jail"
-				log.Info(logLine)
-			} else {
-				response, server, err := queryExternalDNS(q)
-				if err != nil {
-					log.Error("Failed to query external DNS:", err)
-					continue
-				}
-				m.Answer = append(m.Answer, response.Answer...)
-				logLine = clientIP + " -> " + q.Name + "::." + parseAnswer(m.Answer) + " <- CACHE_MISS::" + server
-				log.Info(logLine)
-			}
-		}
+	for _, q := range r.Question {
+		entry := queryLogEntry{client: clientIP, name: q.Name, qtype: dns.TypeToString[q.Qtype], source: source, rcode: dns.RcodeRefused}
+		entry.log(time.Now())
 	}
 
 	err := w.WriteMsg(m)
@@ -277,61 +180,104 @@ func handleDNSRequest(w dns.ResponseWriter, r *dns.Msg) {
 	}
 }
 
-// Returns a DNS message, a server that returned the response, or an error
-func queryExternalDNS(q dns.Question) (*dns.Msg, string, error) {
-	c := dns.Client{}
+type queryLogEntry struct {
+	client   string
+	view     string
+	name     string
+	qtype    string
+	source   string
+	rule     string // forwarding rule or blocklist entry that matched
+	upstream string
+	rcode    int
+	answers  []dns.RR
+	err      error
+}
+
+// Writes a structured query log line, and records the query metrics
+func (e queryLogEntry) log(start time.Time) {
+	latency := time.Since(start)
+	rcode := dns.RcodeToString[e.rcode]
+	observeQuery(e.source, e.qtype, rcode, latency)
+
+	fields := logrus.Fields{
+		"client":     e.client,
+		"name":       e.name,
+		"type":       e.qtype,
+		"source":     e.source,
+		"rcode":      rcode,
+		"latency_ms": float64(latency.Microseconds()) / 1000,
+		"answer":     parseAnswer(e.answers),
+	}
+	if len(e.view) > 0 {
+		fields["view"] = e.view
+	}
+	if len(e.rule) > 0 {
+		fields["rule"] = e.rule
+	}
+	if len(e.upstream) > 0 {
+		fields["upstream"] = e.upstream
+	}
+
+	if e.err != nil {
+		fields["error"] = e.err.Error()
+		log.WithFields(fields).Error("dns query")
+		return
+	}
+	log.WithFields(fields).Info("dns query")
+}
+
+// Returns a DNS message, a server that returned the response, or an error.
+//
+// Healthy upstreams are tried first (in the configured order), and the ones in the failure back-off period are only
+// used as the last resort, so a single dead upstream doesn't add a timeout to every query.
+func queryExternalDNS(q dns.Question, upstreamServers []string) (*dns.Msg, string, error) {
 	m := dns.Msg{}
 	m.SetQuestion(q.Name, q.Qtype)
 
-	var response *dns.Msg
+	var healthy []*upstream
+	var unhealthy []*upstream
+	for _, v := range upstreamServers {
+		u := getUpstream(v)
+		if u.healthy() {
+			healthy = append(healthy, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+		}
+	}
+
 	var err error
-	var responseServer string
-
-	// Try each DNS server until a response is received or all servers fail
-	for _, server := range upstreamServers {
-		response, _, err = c.Exchange(&m, server)
-		if err == nil && response != nil && response.Rcode != dns.RcodeServerFailure {
-			// Received a successful response, break the loop
-			responseServer = server
-			break
+	var lastResponse *dns.Msg
+	var lastServer string
+	for _, u := range append(healthy, unhealthy...) {
+		var response *dns.Msg
+		response, err = u.exchange(&m)
+		if err == nil && response != nil {
+			return response, u.address, nil
+		}
+		// Keep the SERVFAIL response, in case none of the other upstreams can do better
+		if response != nil {
+			lastResponse = response
+			lastServer = u.address
 		}
 	}
 
+	if lastResponse != nil {
+		return lastResponse, lastServer, nil
+	}
 	if err != nil {
 		return nil, "", err
 	}
-
-	return response, responseServer, nil
+	return nil, "", fmt.Errorf("no upstream servers responded")
 }
 
-// Regex DNS Answer splitter
-var reAnySpaceChar = regexp.MustCompile(`\s+`)
-
-// Parses the DNS answer to only extract the IP address resolved
+// Extracts the record data (e.g. the IP address resolved) from the DNS answers.
 //
 // Used purely for the logging purposes
-func parseAnswer(msg []dns.RR) string {
-	msgString := fmt.Sprintf("%s", msg)
-	splitAnswer := reAnySpaceChar.Split(msgString, -1)
-	result := ""
-	for i, v := range splitAnswer {
-		if i == len(splitAnswer)-1 {
-			result = strings.Split(v, "]")[0]
-		}
-	}
-	if result == "[" {
-		result = "nil"
-	}
-	return result
-}
-
-func IsPublicDomain(topLevelDomain string) bool {
-	for _, v := range publicDomainList {
-		if strings.EqualFold(v, topLevelDomain) {
-			// the above is the same as this:
-			// if strings.ToUpper(v) == strings.ToUpper(topLevelDomain) {
-			return true
-		}
+func parseAnswer(msg []dns.RR) (r []string) {
+	r = []string{}
+	for _, v := range msg {
+		header := v.Header().String()
+		r = append(r, strings.TrimSpace(strings.TrimPrefix(v.String(), header)))
 	}
-	return false
+	return
 }
@@ -1,41 +1,120 @@
 package main
 
 import (
+	HosterHost "HosterCore/internal/pkg/hoster/host"
 	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterLocations "HosterCore/internal/pkg/hoster/locations"
 	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+	"fmt"
+	"os"
 )
 
 type VmInfoStruct struct {
-	vmName    string
-	vmAddress string
+	vmName      string
+	vmAddress   string          // the main (first) IP address
+	vmAddress6  string          // the main (first) IPv6 address, empty if the VM is IPv4 only
+	vmAddresses []VmAddressInfo // all addresses, used to pick an answer for a specific network view
+}
+
+type VmAddressInfo struct {
+	network  string
+	address  string
+	address6 string
 }
 
 func getVmsInfo() []VmInfoStruct {
 	vmInfoVar := []VmInfoStruct{}
-	allVms, err := HosterVmUtils.ListJsonApi()
+	allVms, err := HosterVmUtils.ListAllSimple()
 	if err != nil {
 		return vmInfoVar
 	}
 
 	for _, v := range allVms {
-		vmInfoVar = append(vmInfoVar, VmInfoStruct{vmName: v.Name, vmAddress: v.Networks[0].IPAddress})
+		conf, err := HosterVmUtils.GetVmConfig(v.Mountpoint + "/" + v.VmName)
+		if err != nil {
+			continue
+		}
+		if len(conf.Networks) < 1 {
+			continue
+		}
+
+		vmInfo := VmInfoStruct{vmName: v.VmName, vmAddress: conf.Networks[0].IPAddress}
+		for _, vv := range conf.Networks {
+			vmInfo.vmAddresses = append(vmInfo.vmAddresses, VmAddressInfo{network: vv.NetworkBridge, address: vv.IPAddress, address6: vv.IPv6Address})
+			if len(vmInfo.vmAddress6) < 1 {
+				vmInfo.vmAddress6 = vv.IPv6Address
+			}
+		}
+		vmInfoVar = append(vmInfoVar, vmInfo)
 	}
 	return vmInfoVar
 }
 
 type JailInfoStruct struct {
-	JailName    string
-	JailAddress string
+	JailName     string
+	JailAddress  string
+	JailAddress6 string
+	JailNetwork  string
 }
 
 func getJailsInfo() (r []JailInfoStruct) {
-	jails, err := HosterJailUtils.ListAllExtendedTable()
+	jails, err := HosterJailUtils.ListAllSimple()
 	if err != nil {
 		return
 	}
 
 	for _, v := range jails {
-		r = append(r, JailInfoStruct{JailName: v.Name, JailAddress: v.MainIpAddress})
+		conf, err := HosterJailUtils.GetJailConfig(v.Mountpoint + "/" + v.JailName)
+		if err != nil {
+			continue
+		}
+
+		r = append(r, JailInfoStruct{JailName: v.JailName, JailAddress: conf.IPAddress, JailAddress6: conf.IPv6Address, JailNetwork: conf.Network})
 	}
 	return
 }
+
+// Builds a cheap fingerprint of the host config, blocklists and all VM/Jail config files (path + modification time + size),
+// which is later used to detect changes without re-parsing every single config file.
+func resourcesFingerprint() (r string) {
+	hostConfFile, err := HosterLocations.LocateConfig("host_config.json")
+	if err == nil {
+		r += fileFingerprint(hostConfFile)
+	}
+	networkConfFile, err := HosterLocations.LocateConfig("network_config.json")
+	if err == nil {
+		r += fileFingerprint(networkConfFile)
+	}
+
+	hostConf, err := HosterHost.GetHostConfig()
+	if err == nil {
+		for _, v := range hostConf.DnsBlocklists {
+			r += fileFingerprint(v)
+		}
+	}
+
+	vms, err := HosterVmUtils.ListAllSimple()
+	if err == nil {
+		for _, v := range vms {
+			r += fileFingerprint(v.Mountpoint + "/" + v.VmName + "/" + HosterVmUtils.VM_CONFIG_NAME)
+		}
+	}
+
+	jails, err := HosterJailUtils.ListAllSimple()
+	if err == nil {
+		for _, v := range jails {
+			r += fileFingerprint(v.Mountpoint + "/" + v.JailName + "/" + HosterJailUtils.JAIL_CONFIG_NAME)
+		}
+	}
+
+	return
+}
+
+func fileFingerprint(filePath string) string {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return filePath + ";"
+	}
+
+	return fmt.Sprintf("%s:%d:%d;", filePath, info.ModTime().UnixNano(), info.Size())
+}
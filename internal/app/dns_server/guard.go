@@ -0,0 +1,205 @@
+package main
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	HosterHost "HosterCore/internal/pkg/hoster/host"
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checks if the client is allowed to use this DNS server.
+func (z *dnsZone) clientAllowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, v := range z.allowedClients {
+		if v.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checks the query name (and all of it's parent domains) against the blocklist.
+//
+// Returns the blocklist entry that matched, or an empty string if the name is not blocked.
+func (z *dnsZone) blocked(qName string) string {
+	if len(z.blocklist) < 1 {
+		return ""
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(qName, "."))
+	for len(name) > 0 {
+		if z.blocklist[name] {
+			return name
+		}
+
+		i := strings.Index(name, ".")
+		if i < 0 {
+			break
+		}
+		name = name[i+1:]
+	}
+
+	return ""
+}
+
+// Builds the list of networks allowed to query this DNS server.
+//
+// Uses the host config if it's set, otherwise falls back to the Hoster networks (views) and localhost,
+// which makes sure that the DNS server is never an open resolver by default.
+func parseAllowedClients(hostConf HosterHost.HostConfig, views []dnsView) (r []*net.IPNet) {
+	for _, v := range hostConf.DnsAllowedClients {
+		if !strings.Contains(v, "/") {
+			if strings.Contains(v, ":") {
+				v = v + "/128"
+			} else {
+				v = v + "/32"
+			}
+		}
+
+		_, subnet, err := net.ParseCIDR(v)
+		if err != nil {
+			log.Warnf("Could not parse the allowed client network %s: %s", v, err.Error())
+			continue
+		}
+		r = append(r, subnet)
+	}
+	if len(r) > 0 {
+		return
+	}
+
+	for _, v := range views {
+		r = append(r, v.subnet)
+	}
+	_, loopback4, _ := net.ParseCIDR("127.0.0.0/8")
+	_, loopback6, _ := net.ParseCIDR("::1/128")
+	r = append(r, loopback4, loopback6)
+
+	return
+}
+
+// Returns the per-client rate limit, or 0 if rate limiting is disabled
+func parseRateLimit(hostConf HosterHost.HostConfig) int {
+	if hostConf.DnsRateLimit < 0 {
+		return 0
+	}
+	if hostConf.DnsRateLimit == 0 {
+		return DnsServerUtils.DEFAULT_RATE_LIMIT
+	}
+	return hostConf.DnsRateLimit
+}
+
+// Loads the hosts-format blocklist files, e.g.:
+//
+//	0.0.0.0 ads.example.com
+//	127.0.0.1 tracker.example.com # comment
+//	malware.example.com
+func parseBlocklists(hostConf HosterHost.HostConfig) (r map[string]bool) {
+	r = make(map[string]bool)
+
+	for _, v := range hostConf.DnsBlocklists {
+		file, err := os.Open(v)
+		if err != nil {
+			log.Warnf("Could not open the blocklist file %s: %s", v, err.Error())
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+
+			fields := strings.Fields(line)
+			if len(fields) < 1 {
+				continue
+			}
+			// Skip the address part in the "address domain [domain...]" lines
+			if net.ParseIP(fields[0]) != nil {
+				fields = fields[1:]
+			}
+
+			for _, vv := range fields {
+				domain := strings.ToLower(strings.Trim(vv, "."))
+				if len(domain) < 1 || domain == "localhost" || domain == "localhost.localdomain" {
+					continue
+				}
+				r[domain] = true
+			}
+		}
+
+		err = scanner.Err()
+		if err != nil {
+			log.Warnf("Could not read the blocklist file %s: %s", v, err.Error())
+		}
+		file.Close()
+	}
+
+	return
+}
+
+type rateBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+var (
+	rateBuckets = make(map[string]*rateBucket)
+	rateMutex   = &sync.Mutex{}
+)
+
+// Token bucket rate limiter, keyed by the client IP address.
+//
+// Every client can burst up to 2x of the per-second limit, which is then refilled at the limit rate.
+func allowQuery(clientIP string, limit int) bool {
+	if limit < 1 {
+		return true
+	}
+
+	rateMutex.Lock()
+	defer rateMutex.Unlock()
+
+	now := time.Now()
+	burst := float64(limit * 2)
+
+	bucket, ok := rateBuckets[clientIP]
+	if !ok {
+		bucket = &rateBucket{tokens: burst, lastSeen: now}
+		rateBuckets[clientIP] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.lastSeen).Seconds() * float64(limit)
+	if bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens -= 1
+	return true
+}
+
+// Removes the rate limiter state for the clients that were not seen for a while
+func cleanupRateBuckets() {
+	for {
+		time.Sleep(time.Minute)
+
+		rateMutex.Lock()
+		for k, v := range rateBuckets {
+			if time.Since(v.lastSeen) > 5*time.Minute {
+				delete(rateBuckets, k)
+			}
+		}
+		rateMutex.Unlock()
+	}
+}
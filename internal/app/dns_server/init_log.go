@@ -25,8 +25,8 @@ func init() {
 
 	logFile := os.Getenv("LOG_FILE")
 
-	// Log as JSON instead of the default ASCII/text formatter.
-	// log.SetFormatter(&logrus.JSONFormatter{})
+	// Log as JSON instead of the default ASCII/text formatter, which makes the query logs easy to aggregate
+	log.SetFormatter(&logrus.JSONFormatter{})
 
 	// Output to stdout instead of the default stderr
 	log.SetOutput(os.Stdout)
@@ -0,0 +1,167 @@
+package main
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latency buckets (in seconds) used by all DNS histograms
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+type histogram struct {
+	counts []uint64 // one per bucket, non-cumulative
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+
+	for i, v := range latencyBuckets {
+		if seconds <= v {
+			h.counts[i] += 1
+			break
+		}
+	}
+	h.sum += seconds
+	h.count += 1
+}
+
+// Renders the histogram in the Prometheus text format, labels must be pre-formatted, e.g. `source="vm"`
+func (h *histogram) render(name string, labels string) (r string) {
+	var cumulative uint64
+	for i, v := range latencyBuckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		r += fmt.Sprintf("%s_bucket{%s,le=\"%g\"} %d\n", name, labels, v, cumulative)
+	}
+	r += fmt.Sprintf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	r += fmt.Sprintf("%s_sum{%s} %g\n", name, labels, h.sum)
+	r += fmt.Sprintf("%s_count{%s} %d\n", name, labels, h.count)
+	return
+}
+
+type queryCounterKey struct {
+	source string
+	qtype  string
+	rcode  string
+}
+
+var (
+	metricsMutex     = &sync.Mutex{}
+	queryCounters    = make(map[queryCounterKey]uint64)
+	queryLatency     = make(map[string]*histogram) // key: answer source
+	upstreamLatency  = make(map[string]*histogram) // key: upstream address
+	upstreamFailures = make(map[string]uint64)     // key: upstream address
+)
+
+func observeQuery(source string, qtype string, rcode string, latency time.Duration) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	queryCounters[queryCounterKey{source: source, qtype: qtype, rcode: rcode}] += 1
+
+	h, ok := queryLatency[source]
+	if !ok {
+		h = &histogram{}
+		queryLatency[source] = h
+	}
+	h.observe(latency.Seconds())
+}
+
+func observeUpstream(address string, latency time.Duration, failed bool) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	h, ok := upstreamLatency[address]
+	if !ok {
+		h = &histogram{}
+		upstreamLatency[address] = h
+	}
+	h.observe(latency.Seconds())
+
+	if failed {
+		upstreamFailures[address] += 1
+	} else if _, ok := upstreamFailures[address]; !ok {
+		upstreamFailures[address] = 0
+	}
+}
+
+// Renders all DNS server metrics in the Prometheus text format
+func renderMetrics() string {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	var sb strings.Builder
+
+	sb.WriteString("# HELP hoster_dns_queries_total Number of DNS questions answered, by answer source, record type and response code.\n")
+	sb.WriteString("# TYPE hoster_dns_queries_total counter\n")
+	keys := []queryCounterKey{}
+	for k := range queryCounters {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].source+keys[i].qtype+keys[i].rcode < keys[j].source+keys[j].qtype+keys[j].rcode
+	})
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("hoster_dns_queries_total{source=\"%s\",type=\"%s\",rcode=\"%s\"} %d\n", k.source, k.qtype, k.rcode, queryCounters[k]))
+	}
+
+	sb.WriteString("# HELP hoster_dns_query_duration_seconds DNS question processing latency, by answer source.\n")
+	sb.WriteString("# TYPE hoster_dns_query_duration_seconds histogram\n")
+	for _, k := range sortedKeys(queryLatency) {
+		sb.WriteString(queryLatency[k].render("hoster_dns_query_duration_seconds", fmt.Sprintf("source=\"%s\"", k)))
+	}
+
+	sb.WriteString("# HELP hoster_dns_upstream_duration_seconds Upstream DNS server response latency.\n")
+	sb.WriteString("# TYPE hoster_dns_upstream_duration_seconds histogram\n")
+	for _, k := range sortedKeys(upstreamLatency) {
+		sb.WriteString(upstreamLatency[k].render("hoster_dns_upstream_duration_seconds", fmt.Sprintf("upstream=\"%s\"", k)))
+	}
+
+	sb.WriteString("# HELP hoster_dns_upstream_failures_total Number of failed upstream DNS server queries.\n")
+	sb.WriteString("# TYPE hoster_dns_upstream_failures_total counter\n")
+	for _, k := range sortedKeys(upstreamFailures) {
+		sb.WriteString(fmt.Sprintf("hoster_dns_upstream_failures_total{upstream=\"%s\"} %d\n", k, upstreamFailures[k]))
+	}
+
+	z := currentZone()
+	sb.WriteString("# HELP hoster_dns_zone_records Number of records in the local DNS zone, by source.\n")
+	sb.WriteString("# TYPE hoster_dns_zone_records gauge\n")
+	sb.WriteString(fmt.Sprintf("hoster_dns_zone_records{source=\"%s\"} %d\n", DnsServerUtils.SOURCE_STATIC, len(z.staticRecords)))
+	sb.WriteString(fmt.Sprintf("hoster_dns_zone_records{source=\"%s\"} %d\n", DnsServerUtils.SOURCE_VM, len(z.vmInfoList)))
+	sb.WriteString(fmt.Sprintf("hoster_dns_zone_records{source=\"%s\"} %d\n", DnsServerUtils.SOURCE_JAIL, len(z.jailInfoList)))
+
+	return sb.String()
+}
+
+func sortedKeys[T any](m map[string]T) (r []string) {
+	for k := range m {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return
+}
+
+// Serves the Prometheus metrics endpoint, which is also scraped by the Hoster node exporter
+func metricsServer() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, renderMetrics())
+	})
+
+	log.Infof("Metrics endpoint is listening on %s", DnsServerUtils.METRICS_ADDRESS)
+	err := http.ListenAndServe(DnsServerUtils.METRICS_ADDRESS, mux)
+	if err != nil {
+		log.Errorf("Failed to start the metrics endpoint: %s", err.Error())
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Returns the view (Hoster network name) the client belongs to.
+//
+// An empty string is returned for the clients outside of the Hoster networks (e.g. the host itself).
+func (z *dnsZone) clientView(remoteAddr net.Addr) string {
+	host, _, err := net.SplitHostPort(remoteAddr.String())
+	if err != nil {
+		host = remoteAddr.String()
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+
+	for _, v := range z.views {
+		if v.subnet.Contains(ip) {
+			return v.name
+		}
+	}
+
+	return ""
+}
+
+// Checks if the record (or rule) scoped to a list of networks is visible from a particular view
+func visibleInView(networks []string, view string) bool {
+	if len(networks) < 1 {
+		return true
+	}
+	return slices.Contains(networks, view)
+}
+
+// Checks the query name against a local record name, with or without the DNS search domain
+func (z *dnsZone) matchesName(qName string, recordName string) bool {
+	q := strings.ToLower(strings.TrimSuffix(qName, "."))
+	rec := strings.ToLower(strings.TrimSuffix(recordName, "."))
+
+	if q == rec {
+		return true
+	}
+
+	searchDomain := strings.ToLower(strings.Trim(z.hostConf.DnsSearchDomain, "."))
+	if len(searchDomain) > 0 && q == rec+"."+searchDomain {
+		return true
+	}
+
+	return false
+}
+
+// Looks up the question in the local zone: static records first, then VMs, then Jails.
+// VMs and Jails are answered with A and AAAA records.
+//
+// Returns the list of answers, the answer source (used for logging), and whether the name belongs to the local zone.
+// A local name with an unsupported record type returns no answers, but is still considered found,
+// so that it never leaks to the upstream servers.
+func (z *dnsZone) resolveLocal(q dns.Question, view string) (answers []dns.RR, source string, found bool) {
+	for _, v := range z.staticRecords {
+		recordType := strings.ToUpper(v.Type)
+		if recordType != "A" && recordType != "AAAA" && recordType != "CNAME" {
+			continue
+		}
+		if !visibleInView(v.Networks, view) || !z.matchesName(q.Name, v.Domain) {
+			continue
+		}
+
+		found = true
+		if recordType == "A" && q.Qtype == dns.TypeA {
+			rr, err := dns.NewRR(q.Name + " IN A " + v.Data)
+			if err != nil {
+				log.Error("Failed to generate an A record (from the static records): " + err.Error())
+				continue
+			}
+			answers = append(answers, rr)
+			source = DnsServerUtils.SOURCE_STATIC
+		}
+
+		if recordType == "AAAA" && q.Qtype == dns.TypeAAAA {
+			rr, err := dns.NewRR(q.Name + " IN AAAA " + v.Data)
+			if err != nil {
+				log.Error("Failed to generate an AAAA record (from the static records): " + err.Error())
+				continue
+			}
+			answers = append(answers, rr)
+			source = DnsServerUtils.SOURCE_STATIC
+		}
+
+		if recordType == "CNAME" && (q.Qtype == dns.TypeCNAME || q.Qtype == dns.TypeA || q.Qtype == dns.TypeAAAA) {
+			target := dns.Fqdn(v.Data)
+			rr, err := dns.NewRR(q.Name + " IN CNAME " + target)
+			if err != nil {
+				log.Error("Failed to generate a CNAME record (from the static records): " + err.Error())
+				continue
+			}
+			answers = append(answers, rr)
+			source = DnsServerUtils.SOURCE_STATIC
+
+			if q.Qtype == dns.TypeA || q.Qtype == dns.TypeAAAA {
+				answers = append(answers, z.resolveCnameTarget(dns.Question{Name: target, Qtype: q.Qtype, Qclass: q.Qclass}, view)...)
+			}
+		}
+	}
+	if found {
+		return
+	}
+
+	for _, v := range z.vmInfoList {
+		if !z.matchesName(q.Name, v.vmName) {
+			continue
+		}
+
+		found = true
+		source = DnsServerUtils.SOURCE_VM
+		if q.Qtype != dns.TypeA && q.Qtype != dns.TypeAAAA {
+			return
+		}
+
+		address := v.vmAddress
+		address6 := v.vmAddress6
+		for _, vv := range v.vmAddresses {
+			if len(view) > 0 && vv.network == view {
+				address = vv.address
+				address6 = vv.address6
+				break
+			}
+		}
+
+		rr := addressRecord(q, address, address6)
+		if rr != nil {
+			answers = append(answers, rr)
+		}
+		return
+	}
+
+	for _, v := range z.jailInfoList {
+		if !z.matchesName(q.Name, v.JailName) {
+			continue
+		}
+
+		found = true
+		source = DnsServerUtils.SOURCE_JAIL
+		if q.Qtype != dns.TypeA && q.Qtype != dns.TypeAAAA {
+			return
+		}
+
+		rr := addressRecord(q, v.JailAddress, v.JailAddress6)
+		if rr != nil {
+			answers = append(answers, rr)
+		}
+		return
+	}
+
+	return
+}
+
+// Generates an A or AAAA record (depending on the question type) for a VM or a Jail.
+//
+// Returns nil if the resource doesn't have an address of the requested family,
+// which results in an empty NOERROR (NODATA) response.
+func addressRecord(q dns.Question, address string, address6 string) dns.RR {
+	recordType := "A"
+	if q.Qtype == dns.TypeAAAA {
+		recordType = "AAAA"
+		address = address6
+	}
+	if len(address) < 1 {
+		return nil
+	}
+
+	rr, err := dns.NewRR(q.Name + " IN " + recordType + " " + address)
+	if err != nil {
+		log.Errorf("Failed to create an %s record: %s", recordType, err.Error())
+		return nil
+	}
+	return rr
+}
+
+// Resolves the target of a static CNAME record: using the local VMs/Jails if possible, or the upstream servers otherwise.
+//
+// Static CNAMEs pointing to other static CNAMEs are not followed, which prevents any resolution loops.
+func (z *dnsZone) resolveCnameTarget(q dns.Question, view string) []dns.RR {
+	local := *z
+	local.staticRecords = nil
+	answers, _, found := local.resolveLocal(q, view)
+	if found {
+		return answers
+	}
+
+	upstreams, _ := z.upstreamsFor(q.Name, view)
+	response, _, err := queryExternalDNS(q, upstreams)
+	if err != nil {
+		log.Error("Failed to query external DNS:", err)
+		return nil
+	}
+
+	return response.Answer
+}
+
+// Returns the list of upstream servers for the query name, using the most specific conditional forwarding rule.
+//
+// Falls back to the default upstream servers if no rule matched, in which case the returned rule name is empty.
+func (z *dnsZone) upstreamsFor(qName string, view string) (upstreams []string, rule string) {
+	name := strings.ToLower(strings.TrimSuffix(qName, "."))
+
+	for _, v := range z.forwardingRules {
+		if !visibleInView(v.networks, view) {
+			continue
+		}
+		if name == v.suffix || strings.HasSuffix(name, "."+v.suffix) {
+			return v.upstreams, v.suffix
+		}
+	}
+
+	return z.upstreamServers, ""
+}
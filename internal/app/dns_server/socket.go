@@ -0,0 +1,89 @@
+package main
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+)
+
+// Control socket server, used by the deploy/destroy/clone code paths to push the zone updates,
+// and by the CLI to query the current zone state.
+func socketServer() {
+	// Remove the old socket if it exists
+	if _, err := os.Stat(DnsServerUtils.SOCKET_FILE); err == nil {
+		os.Remove(DnsServerUtils.SOCKET_FILE)
+	}
+
+	listener, err := net.Listen("unix", DnsServerUtils.SOCKET_FILE)
+	if err != nil {
+		log.Fatalf("Error creating Unix socket: %v", err)
+	}
+	defer os.Remove(DnsServerUtils.SOCKET_FILE)
+	defer listener.Close()
+
+	log.Infof("Control socket is listening on %s", DnsServerUtils.SOCKET_FILE)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Error("Error accepting connection:", err)
+			continue
+		}
+
+		go handleConnection(conn)
+	}
+}
+
+func handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	// Read the message until a newline character is encountered
+	reader := bufio.NewReader(conn)
+	messageBytes, err := reader.ReadBytes('\n')
+	if err != nil {
+		log.Error("Error reading message:", err)
+		return
+	}
+	// Remove the newline before processing
+	messageBytes = messageBytes[:len(messageBytes)-1]
+
+	var base DnsServerUtils.BasePayload
+	err = json.Unmarshal(messageBytes, &base)
+	if err != nil {
+		log.Error("Error unmarshalling JSON:", err)
+		return
+	}
+
+	switch base.Type {
+	case DnsServerUtils.MSG_ZONE_RELOAD:
+		log.Info("Received a zone reload request over the control socket")
+		err := reloadZone()
+		if err != nil {
+			writeResponse(conn, DnsServerUtils.SocketResponse{Success: false, Message: err.Error()})
+			return
+		}
+		writeResponse(conn, DnsServerUtils.SocketResponse{Success: true})
+
+	case DnsServerUtils.MSG_ZONE_STATUS:
+		writeResponse(conn, zoneStatus())
+
+	default:
+		log.Warn("Unknown payload type:", base.Type)
+		writeResponse(conn, DnsServerUtils.SocketResponse{Success: false, Message: "unknown payload type"})
+	}
+}
+
+func writeResponse(conn net.Conn, response any) {
+	respBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Error("Error marshalling the socket response:", err)
+		return
+	}
+
+	respBytes = append(respBytes, []byte("\n")...)
+	_, err = conn.Write(respBytes)
+	if err != nil {
+		log.Error("Error writing the socket response:", err)
+	}
+}
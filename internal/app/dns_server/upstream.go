@@ -0,0 +1,276 @@
+package main
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// upstream keeps the connection pool and the health state for a single upstream DNS server.
+//
+// Upstreams live in a global registry (not in the zone), so the open connections and the health
+// history survive zone reloads.
+type upstream struct {
+	address  string // address as it was configured, e.g. "tls://1.1.1.1:853"
+	protocol string // one of the DnsServerUtils.UPSTREAM_PROTO_* values
+	endpoint string // host:port for UDP and TLS, full URL for HTTPS
+
+	mutex               sync.Mutex
+	idleConns           []*dns.Conn // reusable DNS-over-TLS connections
+	queries             uint64
+	failures            uint64
+	consecutiveFailures int
+	downUntil           time.Time
+	lastError           string
+	lastLatency         time.Duration
+	latencyTotal        time.Duration
+}
+
+var (
+	upstreamRegistry      = make(map[string]*upstream)
+	upstreamRegistryMutex = &sync.Mutex{}
+
+	// Shared DNS-over-HTTPS client, keeps the connections alive between requests
+	dohClient = &http.Client{
+		Timeout: DnsServerUtils.UPSTREAM_TIMEOUT * time.Second,
+		Transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: DnsServerUtils.UPSTREAM_MAX_IDLE_CONNS,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: DnsServerUtils.UPSTREAM_TIMEOUT * time.Second,
+		},
+	}
+
+	// CAs trusted for the DNS-over-TLS upstreams, the system roots are used if not set
+	dotRootCAs *x509.CertPool
+)
+
+// Returns the upstream for a given (normalized) address, creating it on the first use.
+func getUpstream(address string) *upstream {
+	upstreamRegistryMutex.Lock()
+	defer upstreamRegistryMutex.Unlock()
+
+	u, ok := upstreamRegistry[address]
+	if ok {
+		return u
+	}
+
+	u = &upstream{address: address}
+	switch {
+	case strings.HasPrefix(address, "tls://"):
+		u.protocol = DnsServerUtils.UPSTREAM_PROTO_TLS
+		u.endpoint = strings.TrimPrefix(address, "tls://")
+	case strings.HasPrefix(address, "https://"):
+		u.protocol = DnsServerUtils.UPSTREAM_PROTO_HTTPS
+		u.endpoint = address
+	default:
+		u.protocol = DnsServerUtils.UPSTREAM_PROTO_UDP
+		u.endpoint = strings.TrimPrefix(address, "udp://")
+	}
+
+	upstreamRegistry[address] = u
+	return u
+}
+
+// Checks if the upstream is currently considered healthy (i.e. it's not in the failure back-off period)
+func (u *upstream) healthy() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return time.Now().After(u.downUntil)
+}
+
+// Sends the query to the upstream server, and records the result in the upstream health stats.
+func (u *upstream) exchange(m *dns.Msg) (r *dns.Msg, e error) {
+	start := time.Now()
+
+	switch u.protocol {
+	case DnsServerUtils.UPSTREAM_PROTO_TLS:
+		r, e = u.exchangeTls(m)
+	case DnsServerUtils.UPSTREAM_PROTO_HTTPS:
+		r, e = u.exchangeHttps(m)
+	default:
+		c := dns.Client{Timeout: DnsServerUtils.UPSTREAM_TIMEOUT * time.Second}
+		r, _, e = c.Exchange(m, u.endpoint)
+	}
+
+	if e == nil && r != nil && r.Rcode == dns.RcodeServerFailure {
+		e = errors.New("upstream returned SERVFAIL")
+	}
+
+	latency := time.Since(start)
+	u.record(latency, e)
+	observeUpstream(u.address, latency, e != nil)
+	return
+}
+
+// DNS-over-TLS exchange, which reuses the idle connections if any are available.
+//
+// A stale idle connection (closed by the remote side) is retried once on a freshly dialed one.
+func (u *upstream) exchangeTls(m *dns.Msg) (*dns.Msg, error) {
+	c := dns.Client{Net: "tcp-tls", Timeout: DnsServerUtils.UPSTREAM_TIMEOUT * time.Second}
+
+	conn, reused, err := u.takeConn()
+	if err != nil {
+		return nil, err
+	}
+
+	r, _, err := c.ExchangeWithConn(m, conn)
+	if err != nil && reused {
+		conn.Close()
+		conn, err = u.dialTls()
+		if err != nil {
+			return nil, err
+		}
+		r, _, err = c.ExchangeWithConn(m, conn)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	u.putConn(conn)
+	return r, nil
+}
+
+func (u *upstream) takeConn() (conn *dns.Conn, reused bool, e error) {
+	u.mutex.Lock()
+	if len(u.idleConns) > 0 {
+		conn = u.idleConns[len(u.idleConns)-1]
+		u.idleConns = u.idleConns[:len(u.idleConns)-1]
+		u.mutex.Unlock()
+		reused = true
+		return
+	}
+	u.mutex.Unlock()
+
+	conn, e = u.dialTls()
+	return
+}
+
+func (u *upstream) putConn(conn *dns.Conn) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if len(u.idleConns) >= DnsServerUtils.UPSTREAM_MAX_IDLE_CONNS {
+		conn.Close()
+		return
+	}
+	u.idleConns = append(u.idleConns, conn)
+}
+
+func (u *upstream) dialTls() (*dns.Conn, error) {
+	host, _, err := net.SplitHostPort(u.endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12, RootCAs: dotRootCAs}
+	return dns.DialTimeoutWithTLS("tcp-tls", u.endpoint, tlsConfig, DnsServerUtils.UPSTREAM_TIMEOUT*time.Second)
+}
+
+// DNS-over-HTTPS exchange (RFC 8484), using the POST method and the wire format.
+func (u *upstream) exchangeHttps(m *dns.Msg) (*dns.Msg, error) {
+	// The RFC recommends to use a zero ID, to make the responses cache friendly
+	query := m.Copy()
+	query.Id = 0
+
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.endpoint, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream responded with HTTP status %d", resp.StatusCode)
+	}
+
+	r := new(dns.Msg)
+	err = r.Unpack(body)
+	if err != nil {
+		return nil, err
+	}
+	r.Id = m.Id
+
+	return r, nil
+}
+
+// Records the query outcome, and puts the upstream into a back-off period after a number of consecutive failures
+func (u *upstream) record(latency time.Duration, err error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.queries += 1
+	u.lastLatency = latency
+	u.latencyTotal += latency
+
+	if err == nil {
+		u.consecutiveFailures = 0
+		u.downUntil = time.Time{}
+		return
+	}
+
+	u.failures += 1
+	u.consecutiveFailures += 1
+	u.lastError = err.Error()
+	if u.consecutiveFailures >= DnsServerUtils.UPSTREAM_MAX_FAILURES {
+		u.downUntil = time.Now().Add(DnsServerUtils.UPSTREAM_BACKOFF * time.Second)
+		log.Warnf("Upstream %s is marked as unhealthy after %d consecutive failures: %s", u.address, u.consecutiveFailures, u.lastError)
+	}
+}
+
+func (u *upstream) status() (r DnsServerUtils.UpstreamStatus) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	r.Address = u.address
+	r.Protocol = u.protocol
+	r.Healthy = time.Now().After(u.downUntil)
+	r.Queries = u.queries
+	r.Failures = u.failures
+	r.ConsecutiveFailures = u.consecutiveFailures
+	r.LastError = u.lastError
+	r.LastLatencyMs = float64(u.lastLatency.Microseconds()) / 1000
+	if u.queries > 0 {
+		r.AvgLatencyMs = float64(u.latencyTotal.Microseconds()) / float64(u.queries) / 1000
+	}
+	if !r.Healthy {
+		r.DownUntil = u.downUntil.Unix()
+	}
+
+	return
+}
+
+// Returns the health stats for the list of upstream addresses
+func upstreamsStatus(addresses []string) (r []DnsServerUtils.UpstreamStatus) {
+	for _, v := range addresses {
+		r = append(r, getUpstream(v).status())
+	}
+	return
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// Answers every A query with 192.0.2.1
+func stubAnswer(req *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	m.Answer = append(m.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+		A:   net.ParseIP("192.0.2.1"),
+	})
+	return m
+}
+
+func testQuery() *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion("test.hoster.example.", dns.TypeA)
+	return m
+}
+
+func checkAnswer(t *testing.T, query *dns.Msg, r *dns.Msg) {
+	t.Helper()
+	if r.Id != query.Id {
+		t.Errorf("reply ID = %d, want %d", r.Id, query.Id)
+	}
+	if len(r.Answer) != 1 {
+		t.Fatalf("reply has %d answers, want 1", len(r.Answer))
+	}
+	if a, ok := r.Answer[0].(*dns.A); !ok || !a.A.Equal(net.ParseIP("192.0.2.1")) {
+		t.Errorf("reply answer = %s, want 192.0.2.1", r.Answer[0])
+	}
+}
+
+func TestExchangeHttps(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			t.Errorf("unexpected DoH request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		req := new(dns.Msg)
+		err := req.Unpack(body)
+		if err != nil {
+			t.Errorf("could not unpack the DoH query: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Id != 0 {
+			t.Errorf("DoH query ID = %d, want 0", req.Id)
+		}
+
+		packed, _ := stubAnswer(req).Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	defer server.Close()
+
+	client := dohClient
+	dohClient = server.Client()
+	defer func() { dohClient = client }()
+
+	u := getUpstream(server.URL + "/dns-query")
+	if u.protocol != DnsServerUtils.UPSTREAM_PROTO_HTTPS {
+		t.Fatalf("upstream protocol = %s, want %s", u.protocol, DnsServerUtils.UPSTREAM_PROTO_HTTPS)
+	}
+
+	query := testQuery()
+	r, err := u.exchange(query)
+	if err != nil {
+		t.Fatalf("exchange() error = %s", err)
+	}
+	checkAnswer(t, query, r)
+
+	// The upstream is skipped after the consecutive failures
+	fail.Store(true)
+	for i := 0; i < DnsServerUtils.UPSTREAM_MAX_FAILURES; i++ {
+		_, err := u.exchange(testQuery())
+		if err == nil {
+			t.Fatalf("exchange() has succeeded on the HTTP 500")
+		}
+	}
+	status := u.status()
+	if u.healthy() || status.Healthy || status.Queries != 4 || status.Failures != 3 {
+		t.Errorf("upstream status = %+v, want unhealthy after 3 failures", status)
+	}
+}
+
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
+
+func TestExchangeTls(t *testing.T) {
+	// The httptest certificate is valid for 127.0.0.1
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	certServer.Close()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: certServer.TLS.Certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Counts the accepted connections, to check they are reused
+	counting := &countingListener{Listener: listener}
+	server := &dns.Server{
+		Listener: counting,
+		Net:      "tcp-tls",
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			w.WriteMsg(stubAnswer(req))
+		}),
+	}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	roots := dotRootCAs
+	dotRootCAs = x509.NewCertPool()
+	dotRootCAs.AddCert(certServer.Certificate())
+	defer func() { dotRootCAs = roots }()
+
+	u := getUpstream("tls://" + listener.Addr().String())
+	if u.protocol != DnsServerUtils.UPSTREAM_PROTO_TLS {
+		t.Fatalf("upstream protocol = %s, want %s", u.protocol, DnsServerUtils.UPSTREAM_PROTO_TLS)
+	}
+
+	for i := 0; i < 3; i++ {
+		query := testQuery()
+		r, err := u.exchange(query)
+		if err != nil {
+			t.Fatalf("exchange() error = %s", err)
+		}
+		checkAnswer(t, query, r)
+	}
+	if len(u.idleConns) != 1 || counting.accepted.Load() != 1 {
+		t.Errorf("upstream has %d idle connections (%d dialed), want the single reused one", len(u.idleConns), counting.accepted.Load())
+	}
+
+	// A stale idle connection is retried on a freshly dialed one
+	u.idleConns[0].Close()
+	query := testQuery()
+	r, err := u.exchange(query)
+	if err != nil {
+		t.Fatalf("exchange() on a stale connection error = %s", err)
+	}
+	checkAnswer(t, query, r)
+	if counting.accepted.Load() != 2 {
+		t.Errorf("%d connections dialed, want 2", counting.accepted.Load())
+	}
+	if status := u.status(); status.Failures != 0 || !status.Healthy {
+		t.Errorf("upstream status = %+v, want no failures", status)
+	}
+}
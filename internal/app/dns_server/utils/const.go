@@ -0,0 +1,49 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package DnsServerUtils
+
+// Socket File Constants
+const SOCKET_FILE = "/var/run/hoster_dns.sock"
+
+// Control socket message types
+const (
+	MSG_ZONE_STATUS = "zone_status" // returns the list of records currently served
+	MSG_ZONE_RELOAD = "zone_reload" // re-reads the VM/Jail/static records and swaps the zone in one go
+)
+
+// Record sources, used to mark where a particular zone record came from
+const (
+	SOURCE_STATIC   = "static"
+	SOURCE_VM       = "vm"
+	SOURCE_JAIL     = "jail"
+	SOURCE_UPSTREAM = "upstream"
+	SOURCE_BLOCKED  = "blocked" // the name is on the blocklist
+	SOURCE_REFUSED  = "refused" // the client is not on the allowed list
+	SOURCE_LIMITED  = "limited" // the client went over the rate limit
+)
+
+// Default per-client rate limit, queries per second
+const DEFAULT_RATE_LIMIT = 100
+
+// Prometheus metrics endpoint, scraped by the Hoster node exporter
+const METRICS_ADDRESS = "127.0.0.1:9153"
+
+// How often (in seconds) the DNS server checks the VM/Jail config files for changes
+const WATCH_INTERVAL = 10
+
+// Upstream protocols
+const (
+	UPSTREAM_PROTO_UDP   = "udp"   // plain DNS, e.g. "9.9.9.9" or "9.9.9.9:53"
+	UPSTREAM_PROTO_TLS   = "tls"   // DNS-over-TLS, e.g. "tls://9.9.9.9:853"
+	UPSTREAM_PROTO_HTTPS = "https" // DNS-over-HTTPS, e.g. "https://dns.quad9.net/dns-query"
+)
+
+// Upstream connection and health tracking settings
+const (
+	UPSTREAM_TIMEOUT        = 3  // seconds, single query timeout
+	UPSTREAM_MAX_IDLE_CONNS = 4  // max number of idle (reusable) connections per upstream
+	UPSTREAM_MAX_FAILURES   = 3  // consecutive failures before the upstream is marked as unhealthy
+	UPSTREAM_BACKOFF        = 30 // seconds, how long an unhealthy upstream is skipped for
+)
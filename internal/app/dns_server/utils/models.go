@@ -0,0 +1,62 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package DnsServerUtils
+
+type BasePayload struct {
+	Type string `json:"type,omitempty"`
+}
+
+type ZoneRecord struct {
+	Name   string `json:"name"`   // Record name, e.g. "test-vm-1"
+	Type   string `json:"type"`   // Record type, e.g. "A", "CNAME"
+	Data   string `json:"data"`   // Record data, e.g. "10.0.101.11"
+	Source string `json:"source"` // Where the record came from: static, vm, jail
+}
+
+type ZoneStatus struct {
+	BasePayload                          // type: zone_status
+	SearchDomain    string               `json:"search_domain"`    // DNS search domain from the host config
+	LastReload      int64                `json:"last_reload"`      // Unix timestamp of the last successful zone reload
+	ReloadCounter   int                  `json:"reload_counter"`   // Number of zone reloads since the service start-up
+	Upstreams       []string             `json:"upstreams"`        // Upstream DNS servers in use
+	Records         []ZoneRecord         `json:"records"`          // List of records served from the local zone
+	Views           []ZoneView           `json:"views"`            // Per-network views, built from the Hoster networks
+	ForwardingRules []ZoneForwardingRule `json:"forwarding_rules"` // Conditional forwarding rules, most specific first
+	UpstreamHealth  []UpstreamStatus     `json:"upstream_health"`  // Health and latency stats for every upstream in use
+	AllowedClients  []string             `json:"allowed_clients"`  // Networks allowed to query the DNS server
+	RateLimit       int                  `json:"rate_limit"`       // Per-client rate limit (queries per second), 0 if disabled
+	BlockedDomains  int                  `json:"blocked_domains"`  // Number of domains loaded from the blocklists
+	BlockSinkhole   string               `json:"block_sinkhole"`   // Address returned for the blocked A queries, NXDOMAIN if empty
+}
+
+type UpstreamStatus struct {
+	Address             string  `json:"address"`              // Upstream address, e.g. "tls://9.9.9.9:853"
+	Protocol            string  `json:"protocol"`             // udp, tls or https
+	Healthy             bool    `json:"healthy"`              // false if the upstream is in the failure back-off period
+	Queries             uint64  `json:"queries"`              // Total number of queries sent
+	Failures            uint64  `json:"failures"`             // Total number of failed queries
+	ConsecutiveFailures int     `json:"consecutive_failures"` // Failures since the last successful query
+	LastLatencyMs       float64 `json:"last_latency_ms"`      // Latency of the last query, milliseconds
+	AvgLatencyMs        float64 `json:"avg_latency_ms"`       // Average latency, milliseconds
+	DownUntil           int64   `json:"down_until,omitempty"` // Unix timestamp, when the upstream is going to be retried
+	LastError           string  `json:"last_error,omitempty"` // Last error received from this upstream
+}
+
+type ZoneView struct {
+	Name   string `json:"name"`   // Hoster network name
+	Subnet string `json:"subnet"` // Network subnet, e.g. "10.0.101.0/24"
+}
+
+type ZoneForwardingRule struct {
+	DomainSuffix string   `json:"domain_suffix"`
+	Upstreams    []string `json:"upstreams"`
+	Networks     []string `json:"networks,omitempty"`
+}
+
+type SocketResponse struct {
+	BasePayload
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
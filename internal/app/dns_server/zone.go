@@ -0,0 +1,269 @@
+package main
+
+import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
+	HosterHost "HosterCore/internal/pkg/hoster/host"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dnsZone is an immutable snapshot of everything the DNS server needs to answer a query.
+// It's never modified in place: a reload builds a brand new zone and swaps the pointer,
+// so a request handler that picked up a zone keeps a consistent view until it's done.
+type dnsZone struct {
+	hostConf        HosterHost.HostConfig
+	staticRecords   []HosterHost.DnsStaticRecord
+	upstreamServers []string
+	vmInfoList      []VmInfoStruct
+	jailInfoList    []JailInfoStruct
+	views           []dnsView
+	forwardingRules []forwardingRule
+	allowedClients  []*net.IPNet
+	rateLimit       int
+	blocklist       map[string]bool
+	blockSinkhole   string
+	loadedAt        int64
+}
+
+// dnsView maps a Hoster network to the client subnet, so that clients on different bridges can get different answers
+type dnsView struct {
+	name   string
+	subnet *net.IPNet
+}
+
+type forwardingRule struct {
+	suffix    string   // lower case, no leading or trailing dots
+	upstreams []string // host:port
+	networks  []string // views this rule applies to, all views if empty
+}
+
+var (
+	zone          = &dnsZone{}
+	zoneMutex     = &sync.RWMutex{}
+	zoneReloads   int
+	zoneReloadMux = &sync.Mutex{} // makes sure that only one reload runs at a time
+	zoneFootprint string
+)
+
+// Returns the zone snapshot that is currently being served
+func currentZone() *dnsZone {
+	zoneMutex.RLock()
+	defer zoneMutex.RUnlock()
+	return zone
+}
+
+// Re-reads the host config, static records, VMs and Jails, and atomically replaces the active zone.
+func reloadZone() error {
+	zoneReloadMux.Lock()
+	defer zoneReloadMux.Unlock()
+
+	hostConf, err := HosterHost.GetHostConfig()
+	if err != nil {
+		log.Errorf("Failed to read the host config: %s", err.Error())
+		return err
+	}
+
+	newZone := &dnsZone{}
+	newZone.hostConf = hostConf
+	newZone.staticRecords = append(newZone.staticRecords, hostConf.DnsStaticRecords...)
+	newZone.upstreamServers = parseUpstreamDnsServers(hostConf)
+	newZone.vmInfoList = getVmsInfo()
+	newZone.jailInfoList = getJailsInfo()
+	newZone.views = parseViews()
+	newZone.forwardingRules = parseForwardingRules(hostConf)
+	newZone.allowedClients = parseAllowedClients(hostConf, newZone.views)
+	newZone.rateLimit = parseRateLimit(hostConf)
+	newZone.blocklist = parseBlocklists(hostConf)
+	newZone.blockSinkhole = hostConf.DnsBlockSinkhole
+	newZone.loadedAt = time.Now().Unix()
+
+	zoneMutex.Lock()
+	zone = newZone
+	zoneReloads += 1
+	zoneMutex.Unlock()
+
+	log.Infof("DNS zone has been reloaded: %d static records, %d VMs, %d Jails, %d views, %d forwarding rules, %d blocked domains, upstreams: %s",
+		len(newZone.staticRecords), len(newZone.vmInfoList), len(newZone.jailInfoList), len(newZone.views), len(newZone.forwardingRules), len(newZone.blocklist), newZone.upstreamServers)
+	return nil
+}
+
+// Periodically checks the VM/Jail config files for changes, and reloads the zone if anything was modified.
+//
+// This is a safety net for the changes that didn't come through the control socket (e.g. a manual config edit).
+func watchZoneChanges() {
+	for {
+		time.Sleep(DnsServerUtils.WATCH_INTERVAL * time.Second)
+
+		footprint := resourcesFingerprint()
+		if footprint == zoneFootprint {
+			continue
+		}
+
+		if len(zoneFootprint) > 0 {
+			log.Info("VM/Jail config change detected, reloading the DNS zone")
+		}
+		zoneFootprint = footprint
+
+		err := reloadZone()
+		if err != nil {
+			log.Errorf("Failed to reload the DNS zone: %s", err.Error())
+		}
+	}
+}
+
+// Returns a list of records served from the local zone
+func zoneStatus() (r DnsServerUtils.ZoneStatus) {
+	zoneMutex.RLock()
+	z := zone
+	r.ReloadCounter = zoneReloads
+	zoneMutex.RUnlock()
+
+	r.Type = DnsServerUtils.MSG_ZONE_STATUS
+	r.SearchDomain = z.hostConf.DnsSearchDomain
+	r.LastReload = z.loadedAt
+	r.Upstreams = append(r.Upstreams, z.upstreamServers...)
+
+	for _, v := range z.staticRecords {
+		r.Records = append(r.Records, DnsServerUtils.ZoneRecord{Name: v.Domain, Type: strings.ToUpper(v.Type), Data: v.Data, Source: DnsServerUtils.SOURCE_STATIC})
+	}
+	for _, v := range z.vmInfoList {
+		r.Records = append(r.Records, DnsServerUtils.ZoneRecord{Name: v.vmName, Type: "A", Data: v.vmAddress, Source: DnsServerUtils.SOURCE_VM})
+		if len(v.vmAddress6) > 0 {
+			r.Records = append(r.Records, DnsServerUtils.ZoneRecord{Name: v.vmName, Type: "AAAA", Data: v.vmAddress6, Source: DnsServerUtils.SOURCE_VM})
+		}
+	}
+	for _, v := range z.jailInfoList {
+		r.Records = append(r.Records, DnsServerUtils.ZoneRecord{Name: v.JailName, Type: "A", Data: v.JailAddress, Source: DnsServerUtils.SOURCE_JAIL})
+		if len(v.JailAddress6) > 0 {
+			r.Records = append(r.Records, DnsServerUtils.ZoneRecord{Name: v.JailName, Type: "AAAA", Data: v.JailAddress6, Source: DnsServerUtils.SOURCE_JAIL})
+		}
+	}
+	for _, v := range z.views {
+		r.Views = append(r.Views, DnsServerUtils.ZoneView{Name: v.name, Subnet: v.subnet.String()})
+	}
+	upstreams := append([]string{}, z.upstreamServers...)
+	for _, v := range z.forwardingRules {
+		r.ForwardingRules = append(r.ForwardingRules, DnsServerUtils.ZoneForwardingRule{DomainSuffix: v.suffix, Upstreams: v.upstreams, Networks: v.networks})
+		for _, vv := range v.upstreams {
+			if !slices.Contains(upstreams, vv) {
+				upstreams = append(upstreams, vv)
+			}
+		}
+	}
+	r.UpstreamHealth = upstreamsStatus(upstreams)
+
+	for _, v := range z.allowedClients {
+		r.AllowedClients = append(r.AllowedClients, v.String())
+	}
+	r.RateLimit = z.rateLimit
+	r.BlockedDomains = len(z.blocklist)
+	r.BlockSinkhole = z.blockSinkhole
+
+	return
+}
+
+// Parses the list of upstream DNS servers from the host config file.
+func parseUpstreamDnsServers(hostConf HosterHost.HostConfig) (r []string) {
+	for _, v := range hostConf.DnsServers {
+		r = append(r, normalizeUpstream(v))
+	}
+
+	// If host config doesn't include any servers, use the public ones
+	if len(r) < 1 {
+		r = append(r, DNS_SRV4_QUAD_NINE)
+		r = append(r, DNS_SRV4_CLOUD_FLARE)
+	}
+
+	return
+}
+
+var reMatchPort = regexp.MustCompile(`.*:\d+$`)
+
+// Appends the default DNS port to the upstream server address, if the port wasn't set explicitly.
+//
+// Accepts plain addresses ("9.9.9.9"), DNS-over-TLS ("tls://9.9.9.9") and DNS-over-HTTPS ("https://dns.quad9.net/dns-query").
+func normalizeUpstream(server string) string {
+	server = strings.TrimSpace(server)
+
+	if strings.HasPrefix(server, "https://") {
+		return server
+	}
+	if strings.HasPrefix(server, "tls://") {
+		if reMatchPort.MatchString(strings.TrimPrefix(server, "tls://")) {
+			return server
+		}
+		return server + ":853"
+	}
+
+	server = strings.TrimPrefix(server, "udp://")
+	if reMatchPort.MatchString(server) {
+		return server
+	}
+	return server + ":53"
+}
+
+// Parses the conditional forwarding rules from the host config file.
+//
+// Rules are sorted by the suffix length, so that the most specific rule always wins.
+func parseForwardingRules(hostConf HosterHost.HostConfig) (r []forwardingRule) {
+	for _, v := range hostConf.DnsForwarding {
+		rule := forwardingRule{}
+		rule.suffix = strings.ToLower(strings.Trim(v.DomainSuffix, "."))
+		if len(rule.suffix) < 1 {
+			log.Warn("Skipping a forwarding rule with an empty domain suffix")
+			continue
+		}
+		if len(v.Upstreams) < 1 {
+			log.Warnf("Skipping a forwarding rule without upstreams: %s", rule.suffix)
+			continue
+		}
+
+		for _, vv := range v.Upstreams {
+			rule.upstreams = append(rule.upstreams, normalizeUpstream(vv))
+		}
+		rule.networks = append(rule.networks, v.Networks...)
+		r = append(r, rule)
+	}
+
+	sort.SliceStable(r, func(i, j int) bool {
+		return len(r[i].suffix) > len(r[j].suffix)
+	})
+
+	return
+}
+
+// Builds the list of views using the Hoster networks.
+func parseViews() (r []dnsView) {
+	networks, err := HosterNetwork.GetNetworkConfig()
+	if err != nil {
+		log.Warnf("Could not read the network config, per-network views are disabled: %s", err.Error())
+		return
+	}
+
+	for _, v := range networks {
+		_, subnet, err := net.ParseCIDR(v.Subnet)
+		if err != nil {
+			log.Warnf("Could not parse the subnet for network %s: %s", v.NetworkName, err.Error())
+			continue
+		}
+		r = append(r, dnsView{name: v.NetworkName, subnet: subnet})
+
+		// Dual-stack networks get a second view entry, so the IPv6 clients are matched too
+		if v.Ipv6Enabled() {
+			_, subnet6, err := net.ParseCIDR(v.Subnet6)
+			if err != nil {
+				log.Warnf("Could not parse the IPv6 subnet for network %s: %s", v.NetworkName, err.Error())
+				continue
+			}
+			r = append(r, dnsView{name: v.NetworkName, subnet: subnet6})
+		}
+	}
+
+	return
+}
@@ -2,50 +2,20 @@ package CarpClient
 
 import (
 	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
 	"bufio"
 	"encoding/json"
 	"fmt"
 	"net"
 )
 
-func ReceiveHostAdd(input CarpUtils.HostInfo) error {
-	conn, err := net.Dial("unix", CarpUtils.SOCKET_FILE)
-	if err != nil {
-		return fmt.Errorf("can't connect to Unix socket: " + err.Error())
-	}
-	defer conn.Close()
-
-	// Marshal the payload to JSON
+// Passes the ping received over the REST API to ha_carp, which verifies it against the sender's key
+func ReceiveHostAdd(msg HaMembership.SignedMessage, ipAddress string) error {
+	input := CarpUtils.SignedPayload{IpAddress: ipAddress, Message: msg}
 	input.Type = "host_add"
-	payloadBytes, err := json.Marshal(input)
-	if err != nil {
-		return fmt.Errorf("error marshaling JSON: %v", err)
-	}
 
-	// Send the JSON payload to the server
-	// _, err = conn.Write(payloadBytes)
-	// if err != nil {
-	// 	return fmt.Errorf("error sending data: %v", err)
-	// }
-	// Send the JSON payload to the server
-	jsonDataWithNewLine := append(payloadBytes, []byte("\n")...)
-	_, err = conn.Write(jsonDataWithNewLine)
-	if err != nil {
-		return fmt.Errorf("error sending data: %v", err)
-	}
-
-	// Read the server's response
-	reader := bufio.NewReader(conn)
-	// Read the message until a newline character is encountered
-	messageBytes, err := reader.ReadBytes('\n')
-	if err != nil {
-		return fmt.Errorf("error reading message: %v", err)
-	}
-	// Remove the newline before processing
-	messageBytes = messageBytes[:len(messageBytes)-1]
-	_ = messageBytes // discard the response
-
-	return nil
+	var response CarpUtils.SocketResponse
+	return socketCall(input, &response)
 }
 
 func GetHaStatus() (r CarpUtils.HaStatus, e error) {
@@ -102,7 +72,71 @@ func GetHaStatus() (r CarpUtils.HaStatus, e error) {
 	return
 }
 
-func ReceiveRemoteState(input CarpUtils.HaStatus) error {
+// Passes the master's state received over the REST API to ha_carp, which verifies it against the master's key.
+// The state is only accepted from the current CARP master, which must also be the one named in the REST route.
+func ReceiveRemoteState(msg HaMembership.SignedMessage, masterHostname string) error {
+	input := CarpUtils.SignedPayload{Message: msg, MasterHostname: masterHostname}
+	input.Type = "ha_receive_hosts"
+
+	var response CarpUtils.SocketResponse
+	return socketCall(input, &response)
+}
+
+// Records the join request of the remote node, and returns the identity of this one
+func MemberJoin(request HaMembership.JoinRequest, ipAddress string) (r CarpUtils.MemberResponse, e error) {
+	input := CarpUtils.MemberRequest{Join: request, IpAddress: ipAddress}
+	input.Type = "member_join"
+
+	e = socketCall(input, &r)
+	return
+}
+
+// Trusts the node this one has joined, so it's state syncs are accepted without the approval
+func MemberTrust(member HaMembership.Member) error {
+	input := CarpUtils.MemberRequest{Member: member}
+	input.Type = "member_trust"
+
+	var response CarpUtils.MemberResponse
+	return socketCall(input, &response)
+}
+
+// Approves the pending join request, the fingerprint must match the key the node has joined with
+func MemberApprove(hostname string, fingerprint string) (r HaMembership.Member, e error) {
+	input := CarpUtils.MemberRequest{HostName: hostname, Fingerprint: fingerprint}
+	input.Type = "member_approve"
+
+	var response CarpUtils.MemberResponse
+	e = socketCall(input, &response)
+	if e != nil {
+		return
+	}
+
+	for _, v := range response.Members {
+		if v.HostName == hostname {
+			r = v
+		}
+	}
+	return
+}
+
+func MemberRemove(hostname string) error {
+	input := CarpUtils.MemberRequest{HostName: hostname}
+	input.Type = "member_remove"
+
+	var response CarpUtils.MemberResponse
+	return socketCall(input, &response)
+}
+
+func ListMembers() (r CarpUtils.MemberResponse, e error) {
+	input := CarpUtils.MemberRequest{}
+	input.Type = "member_list"
+
+	e = socketCall(input, &r)
+	return
+}
+
+// Sends the payload to ha_carp and reads the response into the target. The target must embed the SocketResponse.
+func socketCall(input interface{}, target interface{}) error {
 	conn, err := net.Dial("unix", CarpUtils.SOCKET_FILE)
 	if err != nil {
 		return fmt.Errorf("can't connect to Unix socket: " + err.Error())
@@ -110,7 +144,6 @@ func ReceiveRemoteState(input CarpUtils.HaStatus) error {
 	defer conn.Close()
 
 	// Marshal the payload to JSON
-	input.Type = "ha_receive_hosts"
 	payloadBytes, err := json.Marshal(input)
 	if err != nil {
 		return fmt.Errorf("error marshaling JSON: %v", err)
@@ -132,7 +165,23 @@ func ReceiveRemoteState(input CarpUtils.HaStatus) error {
 	}
 	// Remove the newline before processing
 	messageBytes = messageBytes[:len(messageBytes)-1]
-	_ = messageBytes // discard the response
+
+	response := CarpUtils.SocketResponse{}
+	err = json.Unmarshal(messageBytes, &response)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling JSON: %v", err)
+	}
+	if !response.Success {
+		if len(response.Error) > 0 {
+			return fmt.Errorf("%s", response.Error)
+		}
+		return fmt.Errorf("request has been rejected by the HA module")
+	}
+
+	err = json.Unmarshal(messageBytes, target)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling JSON: %v", err)
+	}
 
 	return nil
 }
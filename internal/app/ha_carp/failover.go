@@ -0,0 +1,54 @@
+package main
+
+import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	ApiV2client "HosterCore/internal/pkg/api_v2_client"
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	"fmt"
+)
+
+const failoverMaxAttempts = 3
+const failoverHistorySize = 100
+
+// CARP leadership: the CARP master fails over the resources, as long as it has the quorum
+type carpBackend struct{}
+
+func (carpBackend) Name() string {
+	return "carp"
+}
+
+func (carpBackend) IsLeader() bool {
+	return isSelfMaster() && hasQuorum()
+}
+
+func (carpBackend) Nodes() ([]HaCore.Node, error) {
+	return CarpUtils.Nodes(listHosts()), nil
+}
+
+// Backups of the hosts which have gone offline, taken out of the offline backups list by the failover loop
+func (carpBackend) Copies() ([]HaCore.Copy, error) {
+	return CarpUtils.Copies(failoverBatch), nil
+}
+
+// Offline backups the current failover run works on, guarded by mutexFailoverRun
+var failoverBatch []CarpUtils.BackupInfo
+
+var failoverExecutor = HaCore.NewExecutor(carpBackend{}, executeFailover, failoverMaxAttempts, failoverHistorySize)
+
+// Instructs the chosen backup holder to take over the resource using the REST API
+func executeFailover(plan HaCore.Plan, request HaCore.Request) error {
+	for _, v := range listHosts() {
+		if v.HostName == plan.Target {
+			return ApiV2client.CarpFailover(v, request)
+		}
+	}
+	return fmt.Errorf("host %s is not a member of the cluster", plan.Target)
+}
+
+func replaceFailovers(records []CarpUtils.FailoverRecord) {
+	failoverExecutor.ReplaceHistory(records)
+}
+
+func listFailovers() []CarpUtils.FailoverRecord {
+	return failoverExecutor.History()
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	ApiV2client "HosterCore/internal/pkg/api_v2_client"
+	FileExists "HosterCore/internal/pkg/file_exists"
+	FreeBSDOsInfo "HosterCore/internal/pkg/freebsd/info"
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	HosterJail "HosterCore/internal/pkg/hoster/jail"
+	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterVm "HosterCore/internal/pkg/hoster/vm"
+	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Start with a full lease, so the node doesn't fence itself right after the (re)start
+var leaseRenewed = time.Now().Local().Unix()
+
+// Failover and rejoin must never run at the same time, otherwise the host could be rejoined half way through it's failover
+var mutexFailoverRun = &sync.Mutex{}
+
+func hasQuorum() bool {
+	return CarpUtils.HasQuorum(listHosts(), time.Now().Local().Unix(), activeHaConfig.Lease())
+}
+
+// Collects the local host state, which is reported to the master with every ping
+func localHostInfo() (r CarpUtils.HostInfo, e error) {
+	r.HostName, e = FreeBSDsysctls.SysctlKernHostname()
+	if e != nil {
+		return
+	}
+
+	ramInfo, err := FreeBSDOsInfo.GetRamInfo()
+	if err == nil {
+		r.RamFree = ramInfo.RamFreeBytes
+	}
+
+	vms, err := HosterVmUtils.ReadCache()
+	if err != nil {
+		e = err
+		return
+	}
+	for _, v := range vms {
+		if v.Running && !v.Backup {
+			r.Running = append(r.Running, HaCore.ResourceKey("vm", v.Name))
+			if v.Ha != nil && len(v.Ha.AntiAffinityGroup) > 0 {
+				r.Groups = append(r.Groups, v.Ha.AntiAffinityGroup)
+			}
+		}
+	}
+
+	jails, err := HosterJailUtils.ReadCache()
+	if err != nil {
+		e = err
+		return
+	}
+	for _, v := range jails {
+		if v.Running && !v.Backup {
+			r.Running = append(r.Running, HaCore.ResourceKey("jail", v.Name))
+			if v.Ha != nil && len(v.Ha.AntiAffinityGroup) > 0 {
+				r.Groups = append(r.Groups, v.Ha.AntiAffinityGroup)
+			}
+		}
+	}
+
+	r.Fenced = FileExists.CheckUsingOsStat(CarpUtils.FENCED_FILE)
+	r.MaxResources = activeHaConfig.MaxResources
+	r.Maintenance = FileExists.CheckUsingOsStat(CarpUtils.MAINTENANCE_FILE)
+	return
+}
+
+// Fences the local resources if the master (with a quorum) hasn't renewed our lease in time.
+// By the time the master fails this host over, it's resources have already been stopped and locked.
+func checkLease() {
+	if !activeHaConfig.ParticipateInFailover {
+		return
+	}
+	if FileExists.CheckUsingOsStat(CarpUtils.FENCED_FILE) {
+		return
+	}
+
+	expiredFor := time.Now().Local().Unix() - leaseRenewed
+	if expiredFor <= int64(activeHaConfig.Lease()) {
+		return
+	}
+
+	log.Errorf("Lease has not been renewed for %d seconds (lost the master or the quorum), fencing the local resources", expiredFor)
+	fenceLocalResources()
+}
+
+func fenceLocalResources() {
+	// The Jail running status is only reported for the parent, so collect it before the Jails are locked.
+	// Only the resources running right now are started again once the node rejoins.
+	running := []string{}
+	vms, err := HosterVmUtils.ListJsonApi()
+	if err != nil {
+		log.Error("Could not list the VMs: ", err)
+	}
+	for _, v := range vms {
+		if v.Running && !v.Backup {
+			running = append(running, HaCore.ResourceKey("vm", v.Name))
+		}
+	}
+
+	runningJails := []string{}
+	jails, err := HosterJailUtils.ListJsonApi()
+	if err != nil {
+		log.Error("Could not list the Jails: ", err)
+	}
+	for _, v := range jails {
+		if v.Running {
+			runningJails = append(runningJails, v.Name)
+			running = append(running, HaCore.ResourceKey("jail", v.Name))
+		}
+	}
+
+	// Mark the node as fenced before anything is locked, so it stays fenced even if the process dies half way
+	err = CarpUtils.WriteFencedState(running)
+	if err != nil {
+		log.Error("Could not create the fencing marker: ", err)
+	}
+
+	lockedVms, err := HosterVm.LockAllVms()
+	if err != nil {
+		log.Error("Could not lock the VMs: ", err)
+	}
+	for _, v := range lockedVms {
+		err := HosterVm.Stop(v, true, true)
+		if err != nil && !strings.Contains(err.Error(), HosterVmUtils.ERRTXT_VM_IS_STOPPED) {
+			log.Errorf("Could not stop the fenced VM %s: %s", v, err.Error())
+			continue
+		}
+		log.Warnf("VM %s has been fenced", v)
+	}
+
+	lockedJails, err := HosterJail.LockAllJails()
+	if err != nil {
+		log.Error("Could not lock the Jails: ", err)
+	}
+	for _, v := range lockedJails {
+		if slices.Contains(runningJails, v) {
+			err := HosterJail.Stop(v)
+			if err != nil {
+				log.Errorf("Could not stop the fenced Jail %s: %s", v, err.Error())
+				continue
+			}
+		}
+		log.Warnf("Jail %s has been fenced", v)
+	}
+}
+
+// Rejoins the hosts which came back: the resources failed over while they were away are demoted to backups,
+// and the rest of their fenced resources are unlocked (the ones that were running at the fencing time are started again).
+func rejoinHosts() {
+	if !isSelfMaster() {
+		return
+	}
+
+	mutexFailoverRun.Lock()
+	defer mutexFailoverRun.Unlock()
+
+	hostsCopy := listHosts()
+	for k, v := range CarpUtils.DuplicateParents(hostsCopy) {
+		log.Errorf("Split-brain detected: %s is running on %s", k, strings.Join(v, ", "))
+	}
+
+	if !hasQuorum() {
+		log.Warn("No quorum, the hosts will not be rejoined")
+		return
+	}
+
+	mutexOfflineBackups.RLock()
+	pending := []CarpUtils.BackupInfo{}
+	pending = append(pending, offlineBackups...)
+	mutexOfflineBackups.RUnlock()
+
+	now := time.Now().Local().Unix()
+	for _, v := range hostsCopy {
+		if v.Offline || now-v.LastSeen > int64(activeHaConfig.Lease()) {
+			continue
+		}
+
+		// The host came back before it's resources were failed over, wait for the failover to finish first
+		failoverPending := slices.ContainsFunc(pending, func(b CarpUtils.BackupInfo) bool { return b.ParentHost == v.HostName })
+		if failoverPending {
+			continue
+		}
+
+		stale := CarpUtils.StaleCopies(v, listFailovers())
+		if !v.Fenced && len(stale) < 1 {
+			continue
+		}
+
+		err := ApiV2client.CarpRejoin(v, CarpUtils.RejoinRequest{Demote: stale})
+		if err != nil {
+			log.Errorf("Could not rejoin the host %s: %s", v.HostName, err.Error())
+			continue
+		}
+
+		failoverExecutor.MarkDemoted(v.HostName)
+		log.Warnf("Host %s has rejoined the cluster, %d stale resource copies have been demoted", v.HostName, len(stale))
+	}
+}
@@ -67,6 +67,8 @@ func receivePing(host CarpUtils.HostInfo) {
 
 	defer mutexHosts.Unlock()
 	if !found {
+		// Never seen hosts must not be marked offline before they had a chance to ping again
+		host.LastSeen = time.Now().Local().Unix()
 		hosts = append(hosts, host)
 	}
 }
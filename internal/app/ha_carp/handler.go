@@ -4,6 +4,7 @@ import (
 	CarpUtils "HosterCore/internal/app/ha_carp/utils"
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"net"
 )
 
@@ -32,12 +33,36 @@ func handleConnection(conn net.Conn) {
 	// Switch on the type field to unmarshal into the correct struct
 	switch base.Type {
 	case "host_add":
+		var signed CarpUtils.SignedPayload
+		err = json.Unmarshal(messageBytes, &signed)
+		if err != nil {
+			log.Error("Error unmarshalling Signed Payload:", err)
+			closeWithFailure(conn)
+			return
+		}
+
 		var payload CarpUtils.HostInfo
-		err = json.Unmarshal(messageBytes, &payload)
+		err = verifyMember(signed.Message, &payload)
 		if err != nil {
-			log.Error("Error unmarshalling Hosts Payload:", err)
+			log.Warnf("Rejected ping from %s (%s): %s", signed.Message.From, signed.IpAddress, err.Error())
+			closeWithError(conn, err)
 			return
 		}
+		if payload.HostName != signed.Message.From {
+			log.Warnf("Rejected ping from %s, it was sent on behalf of %s", signed.Message.From, payload.HostName)
+			closeWithError(conn, fmt.Errorf("host name doesn't match the sender"))
+			return
+		}
+
+		// The address the ping came from is the one the master can reach the host on
+		if len(signed.IpAddress) > 0 {
+			payload.IpAddress = signed.IpAddress
+		}
+		err = members.SetAddress(payload.HostName, payload.IpAddress)
+		if err != nil {
+			log.Error("Could not save the member address:", err)
+		}
+
 		// addNewHost(payload)
 		receivePing(payload)
 		log.Debugf("Received Hosts Payload: %+v", payload)
@@ -59,6 +84,8 @@ func handleConnection(conn net.Conn) {
 		log.Debugf("Received Hosts Payload: %+v", payload)
 		return
 
+	// Not signed on purpose: the socket is 0600 (root only), and unlike host_add and ha_receive_hosts
+	// no REST route relays it, so the remote nodes can't reach it. Their copies only arrive with the signed master state.
 	case "backup_add":
 		var payload CarpUtils.BackupInfo
 		err = json.Unmarshal(messageBytes, &payload)
@@ -96,7 +123,10 @@ func handleConnection(conn net.Conn) {
 		}
 		ha.Hosts = listHosts()
 		ha.Resources = listBackups()
+		ha.Failovers = listFailovers()
+		ha.Quorum = iAmMaster && hasQuorum()
 		ha.CurrentMaster = currentMaster
+		ha.Members = members.List()
 		ha.ServiceHealth = "OK"
 
 		// Send a response back
@@ -120,11 +150,28 @@ func handleConnection(conn net.Conn) {
 			return
 		}
 
-		info := CarpUtils.HaStatus{}
-		err = json.Unmarshal(messageBytes, &info)
+		var signed CarpUtils.SignedPayload
+		err = json.Unmarshal(messageBytes, &signed)
 		if err != nil {
-			log.Warn("Unknown payload type:", base.Type)
+			log.Error("Error unmarshalling Signed Payload:", err)
 			closeWithFailure(conn)
+			return
+		}
+
+		// Any approved member can sign a message, but only the current master is allowed to replace the cluster state
+		if len(currentMaster) < 1 || signed.Message.From != currentMaster || signed.Message.From != signed.MasterHostname {
+			err = fmt.Errorf("state sync from %s (sent as %s) is not from the current master %s", signed.Message.From, signed.MasterHostname, currentMaster)
+			log.Warnf("Rejected state sync: %s", err.Error())
+			closeWithError(conn, err)
+			return
+		}
+
+		info := CarpUtils.HaStatus{}
+		err = verifyMember(signed.Message, &info)
+		if err != nil {
+			log.Warnf("Rejected state sync from %s: %s", signed.Message.From, err.Error())
+			closeWithError(conn, err)
+			return
 		}
 
 		mutexHosts.Lock()
@@ -137,10 +184,44 @@ func handleConnection(conn net.Conn) {
 		backups = append(backups, info.Resources...)
 		mutexBackups.Unlock()
 
+		// Keep the failover history, so the next master knows which copies are stale when the old parents come back
+		replaceFailovers(info.Failovers)
+
+		// Older masters don't send the membership, keep the local one in that case
+		if len(info.Members) > 0 {
+			err = members.Replace(info.Members)
+			if err != nil {
+				log.Error("Could not save the synced membership:", err)
+			}
+		}
+
 		closeWithSuccess(conn)
 		log.Debug("Received and synced state from master")
 		return
 
+	case "member_join", "member_trust", "member_approve", "member_remove", "member_list":
+		var payload CarpUtils.MemberRequest
+		err = json.Unmarshal(messageBytes, &payload)
+		if err != nil {
+			log.Error("Error unmarshalling Member Payload:", err)
+			closeWithFailure(conn)
+			return
+		}
+
+		err = handleMemberRequest(payload)
+		if err != nil {
+			log.Warnf("Membership request %s has failed: %s", base.Type, err.Error())
+			closeWithError(conn, err)
+			return
+		}
+
+		resp := CarpUtils.MemberResponse{Self: selfMember(), Members: members.List()}
+		resp.Success = true
+		respBytes, _ := json.Marshal(resp)
+		respBytes = append(respBytes, []byte("\n")...)
+		conn.Write(respBytes)
+		return
+
 	default:
 		log.Warn("Unknown payload type:", base.Type)
 		closeWithFailure(conn)
@@ -159,7 +240,17 @@ func closeWithSuccess(conn net.Conn) {
 
 func closeWithFailure(conn net.Conn) {
 	response := CarpUtils.SocketResponse{
-		Success: true,
+		Success: false,
+	}
+	respBytes, _ := json.Marshal(response)
+	respBytes = append(respBytes, []byte("\n")...)
+	conn.Write(respBytes)
+}
+
+func closeWithError(conn net.Conn, err error) {
+	response := CarpUtils.SocketResponse{
+		Success: false,
+		Error:   err.Error(),
 	}
 	respBytes, _ := json.Marshal(response)
 	respBytes = append(respBytes, []byte("\n")...)
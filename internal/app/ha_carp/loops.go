@@ -4,9 +4,6 @@ import (
 	CarpUtils "HosterCore/internal/app/ha_carp/utils"
 	ApiV2client "HosterCore/internal/pkg/api_v2_client"
 	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
-	"slices"
-	"sort"
-	"strings"
 	"sync"
 	"time"
 )
@@ -46,14 +43,26 @@ func checkIfMaster() {
 }
 
 func pingMaster() {
-	hostname, err := ApiV2client.PingMaster(activeHaConfig)
+	host, err := localHostInfo()
+	if err != nil {
+		log.Error("Error collecting the local state:", err)
+		return
+	}
+
+	res, err := ApiV2client.PingMaster(activeHaConfig, host, nodeKey)
 	if err != nil {
 		log.Error("Error pinging master:", err)
 		return
 	}
 
-	if hostname != currentMaster {
-		currentMaster = hostname
+	if res.Quorum {
+		leaseRenewed = time.Now().Local().Unix()
+	} else {
+		log.Warn("Master has no quorum, the lease has not been renewed")
+	}
+
+	if res.Hostname != currentMaster {
+		currentMaster = res.Hostname
 	}
 }
 
@@ -66,6 +75,9 @@ func syncState() {
 	ha := CarpUtils.HaStatus{}
 	ha.Resources = listBackups()
 	ha.Hosts = listHosts()
+	ha.Failovers = listFailovers()
+	ha.Members = members.List()
+	ha.CurrentMaster = currentMaster
 
 	wg := sync.WaitGroup{}
 	log.Debug("STATE SYNC: Begin syncing state using fan-out")
@@ -76,7 +88,7 @@ func syncState() {
 		go func(v CarpUtils.HostInfo, wg *sync.WaitGroup) {
 			defer wg.Done()
 
-			err := ApiV2client.SendLocalState(ha, v.IpAddress, currentMaster)
+			err := ApiV2client.SendLocalState(ha, v.IpAddress, currentMaster, nodeKey)
 			if err != nil {
 				log.Errorf("STATE SYNC: Error sending local state to %s: %s", v.IpAddress, err.Error())
 			}
@@ -119,30 +131,39 @@ func getRemoteBackups() {
 }
 
 func detectOfflineHosts() {
-	if isSelfMaster() {
+	if !isSelfMaster() {
 		return
 	}
 
-	mutexHosts.Lock()
 	config, err := CarpUtils.ParseCarpConfigFile()
 	if err != nil {
 		log.Error("Error getting config: ", err)
 		return
 	}
 
+	offlineHosts := []string{}
+	mutexHosts.Lock()
 	for i, v := range hosts {
 		if v.Offline {
 			continue
 		}
 
-		if time.Now().Local().Unix()-v.LastSeen > int64(config.FailoverAfter) { // Remove hosts that haven't been seen in a while
+		if time.Now().Local().Unix()-v.LastSeen > int64(config.FailoverDelay()) { // Remove hosts that haven't been seen in a while
 			hosts[i].Offline = true
-			addOfflineBackup(v.HostName)
+			if v.Maintenance {
+				log.Infof("Host %s has gone offline during the maintenance, skipping the failover", v.HostName)
+				continue
+			}
+			offlineHosts = append(offlineHosts, v.HostName)
 			log.Warnf("Host %s has gone offline", v.HostName)
 		}
 	}
-
 	mutexHosts.Unlock()
+
+	// The backup list is refreshed from the other hosts, which needs the hosts lock to be released
+	for _, v := range offlineHosts {
+		addOfflineBackup(v)
+	}
 }
 
 func failOverResource() {
@@ -153,50 +174,74 @@ func failOverResource() {
 	if !isSelfMaster() {
 		return
 	}
-	if len(offlineBackups) < 1 {
+
+	// A master without the quorum might be the isolated one, while the hosts it sees as offline keep running their resources
+	if !hasQuorum() {
+		log.Warn("No quorum, the failover is on hold")
 		return
 	}
 
-	failoverInProcess = true
+	mutexFailoverRun.Lock()
+	defer mutexFailoverRun.Unlock()
+
+	// Take the offline backups out of the shared list, so the (slow) REST calls below don't block the new offline hosts from being added
 	mutexOfflineBackups.Lock()
-	defer func() {
-		failoverInProcess = false
+	if len(offlineBackups) < 1 {
 		mutexOfflineBackups.Unlock()
-	}()
-
-	if len(offlineBackups) == 1 {
-		log.Warnf("Failing over resource %s to %s", offlineBackups[0].ResourceName, offlineBackups[0].ParentHost)
 		return
 	}
+	failoverInProcess = true
+	failoverBatch = []CarpUtils.BackupInfo{}
+	failoverBatch = append(failoverBatch, offlineBackups...)
+	offlineBackups = []CarpUtils.BackupInfo{}
+	mutexOfflineBackups.Unlock()
 
-	// Sort by ResourceName
-	sort.SliceStable(offlineBackups, func(i, j int) bool {
-		return strings.ToLower(backups[i].ResourceName) < strings.ToLower(backups[j].ResourceName)
-	})
+	defer func() {
+		failoverInProcess = false
+	}()
 
-	localList := []CarpUtils.BackupInfo{}
-	for _, v := range offlineBackups { // filter out the latest snapshot for each resource
-		for ii, vv := range localList {
-			if slices.Contains(localList, v) {
-				continue
-			}
+	result, err := failoverExecutor.Run()
+	if err != nil {
+		// The quorum has been lost in the meantime, keep the backups for the next round
+		log.Warn(err.Error())
+		result.Retry = CarpUtils.Copies(failoverBatch)
+	}
 
-			if v.ResourceName == vv.ResourceName {
-				if v.LastSnapshot > vv.LastSnapshot {
-					localList[ii] = v
-				}
-				continue
-			}
+	for k, v := range result.Reasons {
+		log.Debugf("Can't fail over %s yet (%s), will retry", k, v)
+	}
 
-			localList = append(localList, v)
+	for _, v := range result.Records {
+		if v.Success {
+			log.Infof("Failed over %s %s from %s to %s (%s, snapshot %s)", v.ResourceType, v.ResourceName, v.OldParent, v.NewParent, v.FailoverStrategy, v.LastSnapshot)
+			continue
+		}
+		if v.Attempt >= failoverMaxAttempts {
+			log.Errorf("Giving up on failing over %s %s after %d attempts: %s", v.ResourceType, v.ResourceName, v.Attempt, v.Error)
+			continue
 		}
+		log.Errorf("Could not fail over %s %s to %s (attempt %d), will retry: %s", v.ResourceType, v.ResourceName, v.NewParent, v.Attempt, v.Error)
 	}
 
-	failed := []CarpUtils.BackupInfo{}
-	for _, v := range localList {
-		log.Warnf("Failing over resource %s to %s", v.ResourceName, v.ParentHost)
+	// Resources still waiting for the failover are left to the rejoin, if their parent has come back in the meantime
+	parentsOnline := make(map[string]bool)
+	for _, v := range listHosts() {
+		if !v.Offline {
+			parentsOnline[v.HostName] = true
+		}
+	}
+	requeue := []CarpUtils.BackupInfo{}
+	for _, v := range result.Retry {
+		if parentsOnline[v.Parent] {
+			continue
+		}
+		backup, found := CarpUtils.BackupOf(failoverBatch, v)
+		if found {
+			requeue = append(requeue, backup)
+		}
 	}
 
-	offlineBackups = []CarpUtils.BackupInfo{}
-	offlineBackups = append(offlineBackups, failed...)
+	mutexOfflineBackups.Lock()
+	offlineBackups = append(offlineBackups, requeue...)
+	mutexOfflineBackups.Unlock()
 }
@@ -21,6 +21,12 @@ func main() {
 		}
 	}
 
+	// Load the membership before accepting any connections, the pings and state syncs are verified against it
+	err := initMembership()
+	if err != nil {
+		log.Fatalf("Error loading the cluster membership: %v", err)
+	}
+
 	// Remove the old socket if it exists
 	if _, err := os.Stat(CarpUtils.SOCKET_FILE); err == nil {
 		os.Remove(CarpUtils.SOCKET_FILE)
@@ -31,6 +37,11 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error creating Unix socket: %v", err)
 	}
+	// Only root may talk to the HA module directly
+	err = os.Chmod(CarpUtils.SOCKET_FILE, 0600)
+	if err != nil {
+		log.Fatalf("Error securing Unix socket: %v", err)
+	}
 
 	// Clean up the socket file and listener
 	defer log.Info("HA Module is shutting down")
@@ -60,6 +71,20 @@ func main() {
 		}
 	}()
 
+	go func() { // Fence the local resources if the lease has expired
+		for {
+			checkLease()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	go func() { // Demote the stale resource copies on the hosts that came back
+		for {
+			rejoinHosts()
+			time.Sleep(15 * time.Second)
+		}
+	}()
+
 	go func() { // Failover resources
 		for {
 			failOverResource()
@@ -0,0 +1,119 @@
+package main
+
+import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
+	"crypto/ed25519"
+	"fmt"
+	"time"
+)
+
+var nodeKey ed25519.PrivateKey
+var members *HaMembership.Members
+
+// Loads the node key and the persisted membership. The approved members are tracked as hosts from the start,
+// so the ones that don't come back after the restart are still failed over.
+func initMembership() (e error) {
+	nodeKey, e = HaMembership.LoadOrCreateKey()
+	if e != nil {
+		return
+	}
+	members, e = HaMembership.ReadMembers()
+	if e != nil {
+		return
+	}
+
+	hostname, err := FreeBSDsysctls.SysctlKernHostname()
+	if err != nil {
+		e = err
+		return
+	}
+
+	// This node is always a member of it's own cluster
+	self, found := members.Approved(hostname)
+	if !found || self.PublicKey != HaMembership.PublicKeyString(nodeKey) {
+		e = members.Trust(HaMembership.Member{HostName: hostname, PublicKey: HaMembership.PublicKeyString(nodeKey)})
+		if e != nil {
+			return
+		}
+	}
+
+	now := time.Now().Local().Unix()
+	for _, v := range members.List() {
+		if v.Status != HaMembership.STATUS_APPROVED || v.HostName == hostname || len(v.IpAddress) < 1 {
+			continue
+		}
+		addNewHost(CarpUtils.HostInfo{HostName: v.HostName, IpAddress: v.IpAddress, LastSeen: now})
+	}
+
+	log.Infof("Loaded %d cluster member(s) from %s", len(members.List()), HaMembership.MEMBERS_FILE)
+	return
+}
+
+// Verifies the message was signed by the approved member, and unmarshals it's payload into the target
+func verifyMember(msg HaMembership.SignedMessage, target interface{}) error {
+	member, found := members.Approved(msg.From)
+	if !found {
+		return fmt.Errorf("%s is not an approved member, use `hoster ha approve %s <fingerprint>` to let it in", msg.From, msg.From)
+	}
+
+	return HaMembership.Verify(msg, member.PublicKey, target)
+}
+
+func selfMember() HaMembership.Member {
+	hostname, _ := FreeBSDsysctls.SysctlKernHostname()
+	self, _ := members.Approved(hostname)
+	return self
+}
+
+func removeMember(hostname string) error {
+	if hostname == selfMember().HostName {
+		return fmt.Errorf("can't remove this node from it's own cluster")
+	}
+
+	err := members.Remove(hostname)
+	if err != nil {
+		return err
+	}
+
+	mutexHosts.Lock()
+	result := []CarpUtils.HostInfo{}
+	for _, v := range hosts {
+		if v.HostName != hostname {
+			result = append(result, v)
+		}
+	}
+	hosts = result
+	mutexHosts.Unlock()
+
+	log.Warnf("Host %s has been removed from the cluster", hostname)
+	return nil
+}
+
+func handleMemberRequest(request CarpUtils.MemberRequest) error {
+	switch request.Type {
+	case "member_join":
+		member, err := members.Request(request.Join, request.IpAddress)
+		if err != nil {
+			return err
+		}
+		if member.Status == HaMembership.STATUS_PENDING {
+			fingerprint := HaMembership.Fingerprint(member.PublicKey)
+			log.Warnf("Host %s (%s) has asked to join the cluster, use `hoster ha approve %s %s` to let it in", member.HostName, request.IpAddress, member.HostName, fingerprint)
+		}
+	case "member_trust":
+		log.Infof("Trusting %s as a cluster member", request.Member.HostName)
+		return members.Trust(request.Member)
+	case "member_approve":
+		member, err := members.Approve(request.HostName, request.Fingerprint)
+		if err != nil {
+			return err
+		}
+		log.Infof("Host %s has been approved as a cluster member", member.HostName)
+	case "member_remove":
+		return removeMember(request.HostName)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package CarpUtils
+
+import (
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Cluster overview, as shown by `hoster ha status` and returned by the REST API
+type ClusterStatus struct {
+	Status           string            `json:"status"`         // HA status of this node: MASTER, FOLLOWER
+	CurrentMaster    string            `json:"current_master"` // Current master hostname
+	Quorum           bool              `json:"quorum"`         // Majority of the cluster members is online, as seen by the master
+	Carp             []CarpInfo        `json:"carp"`           // CARP state of the local interfaces
+	Nodes            []NodeStatus      `json:"nodes"`
+	Resources        []ResourceStatus  `json:"resources"`
+	PendingFailovers []PendingFailover `json:"pending_failovers"` // Resources on the offline hosts, which have not been failed over yet
+}
+
+type NodeStatus struct {
+	HostName    string `json:"host_name"`
+	IpAddress   string `json:"ip_address"`
+	Role        string `json:"role"`      // master or follower
+	Member      string `json:"member"`    // Membership status, empty if the host is not a member
+	LastSeen    int64  `json:"last_seen"` // Unix timestamp of the last ping
+	Offline     bool   `json:"offline"`
+	Fenced      bool   `json:"fenced"`
+	Maintenance bool   `json:"maintenance"`
+	Running     int    `json:"running"` // Number of HA resources running on the host
+}
+
+type ResourceStatus struct {
+	ResourceName string   `json:"resource_name"`
+	ResourceType string   `json:"resource_type"`
+	ParentHost   string   `json:"parent_host"`
+	BackupHosts  []string `json:"backup_hosts"`  // Hosts holding a replicated copy
+	LastSnapshot string   `json:"last_snapshot"` // Newest snapshot found on the backup holders
+	SnapshotAge  int64    `json:"snapshot_age"`  // Seconds since the last snapshot was taken, -1 if unknown
+}
+
+type PendingFailover struct {
+	ResourceName string `json:"resource_name"`
+	ResourceType string `json:"resource_type"`
+	OldParent    string `json:"old_parent"`           // Offline parent host name
+	Attempts     int    `json:"attempts"`             // Failed attempts so far
+	LastAttempt  int64  `json:"last_attempt"`         // Unix timestamp of the last attempt, 0 if it has not been attempted yet
+	Error        string `json:"error,omitempty"`      // Error of the last attempt
+	NewParent    string `json:"new_parent,omitempty"` // Host the last attempt was made on
+}
+
+// Snapshot names end with the timestamp, e.g. replication_2023-08-14_16-49-08
+const snapshotTimeLayout = "2006-01-02_15-04-05"
+
+// Builds the cluster overview from the HA status, and the local CARP state
+func NewClusterStatus(ha HaStatus, carp []CarpInfo) (r ClusterStatus) {
+	now := time.Now()
+	r.Status = ha.Status
+	r.CurrentMaster = ha.CurrentMaster
+	r.Quorum = ha.Quorum
+	r.Carp = carp
+	if r.Carp == nil {
+		r.Carp = []CarpInfo{}
+	}
+
+	offline := []string{}
+	r.Nodes = []NodeStatus{}
+	for _, v := range ha.Hosts {
+		node := NodeStatus{HostName: v.HostName, IpAddress: v.IpAddress, LastSeen: v.LastSeen, Offline: v.Offline, Fenced: v.Fenced, Maintenance: v.Maintenance, Running: len(v.Running)}
+		node.Role = "follower"
+		if v.HostName == ha.CurrentMaster {
+			node.Role = "master"
+		}
+		for _, m := range ha.Members {
+			if m.HostName == v.HostName {
+				node.Member = m.Status
+			}
+		}
+
+		if v.Offline {
+			offline = append(offline, v.HostName)
+		}
+		r.Nodes = append(r.Nodes, node)
+	}
+	// Approved members which have never pinged the master
+	for _, m := range ha.Members {
+		if m.Status != HaMembership.STATUS_APPROVED || slices.ContainsFunc(r.Nodes, func(n NodeStatus) bool { return n.HostName == m.HostName }) {
+			continue
+		}
+		node := NodeStatus{HostName: m.HostName, IpAddress: m.IpAddress, Role: "follower", Member: m.Status, Offline: true}
+		if m.HostName == ha.CurrentMaster {
+			node.Role = "master"
+			node.Offline = false
+		}
+		r.Nodes = append(r.Nodes, node)
+	}
+
+	r.Resources = []ResourceStatus{}
+	for _, v := range ha.Resources {
+		index := slices.IndexFunc(r.Resources, func(s ResourceStatus) bool {
+			return s.ResourceName == v.ResourceName && s.ResourceType == v.ResourceType
+		})
+		if index < 0 {
+			r.Resources = append(r.Resources, ResourceStatus{ResourceName: v.ResourceName, ResourceType: v.ResourceType, ParentHost: v.ParentHost, BackupHosts: []string{}, SnapshotAge: -1})
+			index = len(r.Resources) - 1
+		}
+
+		resource := &r.Resources[index]
+		if !slices.Contains(resource.BackupHosts, v.CurrentHost) {
+			resource.BackupHosts = append(resource.BackupHosts, v.CurrentHost)
+		}
+
+		taken, found := snapshotTime(v.LastSnapshot)
+		if !found {
+			continue
+		}
+		age := int64(now.Sub(taken).Seconds())
+		if resource.SnapshotAge < 0 || age < resource.SnapshotAge {
+			resource.SnapshotAge = age
+			resource.LastSnapshot = v.LastSnapshot
+		}
+	}
+
+	r.PendingFailovers = []PendingFailover{}
+	for _, v := range r.Resources {
+		if !slices.Contains(offline, v.ParentHost) {
+			continue
+		}
+
+		pending := PendingFailover{ResourceName: v.ResourceName, ResourceType: v.ResourceType, OldParent: v.ParentHost}
+		done := false
+		for _, f := range ha.Failovers {
+			if f.ResourceName != v.ResourceName || f.ResourceType != v.ResourceType || f.OldParent != v.ParentHost {
+				continue
+			}
+			// The earlier outage has ended with the fail back, only the attempts made since then count
+			if f.Success && f.Demoted {
+				pending = PendingFailover{ResourceName: v.ResourceName, ResourceType: v.ResourceType, OldParent: v.ParentHost}
+				continue
+			}
+			if f.Success {
+				done = true
+				break
+			}
+			pending.Attempts = pending.Attempts + 1
+			pending.LastAttempt = f.Time
+			pending.Error = f.Error
+			pending.NewParent = f.NewParent
+		}
+
+		if !done {
+			r.PendingFailovers = append(r.PendingFailovers, pending)
+		}
+	}
+
+	return
+}
+
+func snapshotTime(snapshot string) (r time.Time, found bool) {
+	if len(snapshot) < len(snapshotTimeLayout) {
+		return
+	}
+
+	r, err := time.ParseInLocation(snapshotTimeLayout, snapshot[len(snapshot)-len(snapshotTimeLayout):], time.Local)
+	if err != nil {
+		return
+	}
+	return r, true
+}
+
+// Human readable age, e.g. 45s, 12m, 3h, 2d
+func FormatAge(seconds int64) string {
+	switch {
+	case seconds < 0:
+		return "-"
+	case seconds < 60:
+		return fmt.Sprintf("%ds", seconds)
+	case seconds < 3600:
+		return fmt.Sprintf("%dm", seconds/60)
+	case seconds < 86400:
+		return fmt.Sprintf("%dh", seconds/3600)
+	}
+	return fmt.Sprintf("%dd", seconds/86400)
+}
@@ -0,0 +1,146 @@
+package CarpUtils
+
+import (
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewClusterStatusPendingFailovers(t *testing.T) {
+	record := func(oldParent string, success bool, demoted bool, attemptError string, at int64) FailoverRecord {
+		return FailoverRecord{
+			Request:   HaCore.Request{ResourceType: "vm", ResourceName: "test-vm-1", OldParent: oldParent},
+			NewParent: "node2",
+			Time:      at,
+			Success:   success,
+			Demoted:   demoted,
+			Error:     attemptError,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		offline   bool
+		failovers []FailoverRecord
+		want      []PendingFailover
+	}{
+		{
+			name: "parent is online",
+			want: []PendingFailover{},
+		},
+		{
+			name:    "not attempted yet",
+			offline: true,
+			want:    []PendingFailover{{ResourceName: "test-vm-1", ResourceType: "vm", OldParent: "node1"}},
+		},
+		{
+			name:      "failed attempts",
+			offline:   true,
+			failovers: []FailoverRecord{record("node1", false, false, "no space", 100), record("node1", false, false, "start failed", 200), record("node3", false, false, "other parent", 300)},
+			want:      []PendingFailover{{ResourceName: "test-vm-1", ResourceType: "vm", OldParent: "node1", Attempts: 2, LastAttempt: 200, Error: "start failed", NewParent: "node2"}},
+		},
+		{
+			name:      "failed over",
+			offline:   true,
+			failovers: []FailoverRecord{record("node1", false, false, "start failed", 100), record("node1", true, false, "", 200)},
+			want:      []PendingFailover{},
+		},
+		{
+			name:      "offline again after the fail back",
+			offline:   true,
+			failovers: []FailoverRecord{record("node1", false, false, "start failed", 100), record("node1", true, true, "", 200), record("node1", false, false, "no space", 300)},
+			want:      []PendingFailover{{ResourceName: "test-vm-1", ResourceType: "vm", OldParent: "node1", Attempts: 1, LastAttempt: 300, Error: "no space", NewParent: "node2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha := HaStatus{
+				Status:        "MASTER",
+				CurrentMaster: "node2",
+				Hosts:         []HostInfo{{HostName: "node1", Offline: tt.offline}, {HostName: "node2"}},
+				Resources: []BackupInfo{
+					{ResourceName: "test-vm-1", ResourceType: "vm", CurrentHost: "node2", ParentHost: "node1"},
+					{ResourceName: "test-vm-1", ResourceType: "vm", CurrentHost: "node3", ParentHost: "node1"},
+				},
+				Failovers: tt.failovers,
+			}
+
+			got := NewClusterStatus(ha, nil).PendingFailovers
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewClusterStatus() pending failovers = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClusterStatusSnapshotAge(t *testing.T) {
+	snapshot := func(age time.Duration) string {
+		return "zroot/vm-encrypted/test-vm-1@replication_" + time.Now().Add(-age).Format(snapshotTimeLayout)
+	}
+	older := snapshot(2 * time.Hour)
+	newer := snapshot(10 * time.Minute)
+
+	tests := []struct {
+		name      string
+		snapshots []string // one per backup holder
+		want      string
+		wantAge   int64 // seconds, -1 if unknown
+	}{
+		{name: "newest copy wins", snapshots: []string{older, newer}, want: newer, wantAge: 600},
+		{name: "newest copy first", snapshots: []string{newer, older}, want: newer, wantAge: 600},
+		{name: "unknown snapshot time", snapshots: []string{"zroot/vm-encrypted/test-vm-1@manual", ""}, wantAge: -1},
+		{name: "unknown and known snapshot time", snapshots: []string{"", older}, want: older, wantAge: 7200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ha := HaStatus{Hosts: []HostInfo{{HostName: "node1"}}}
+			for i, v := range tt.snapshots {
+				holder := []string{"node2", "node3"}[i]
+				ha.Resources = append(ha.Resources, BackupInfo{ResourceName: "test-vm-1", ResourceType: "vm", CurrentHost: holder, ParentHost: "node1", LastSnapshot: v})
+			}
+
+			r := NewClusterStatus(ha, nil)
+			if len(r.Resources) != 1 {
+				t.Fatalf("NewClusterStatus() resources = %+v, want a single resource", r.Resources)
+			}
+			got := r.Resources[0]
+			if !reflect.DeepEqual(got.BackupHosts, []string{"node2", "node3"}) {
+				t.Errorf("NewClusterStatus() backup hosts = %v, want node2 and node3", got.BackupHosts)
+			}
+			if got.LastSnapshot != tt.want {
+				t.Errorf("NewClusterStatus() last snapshot = %s, want %s", got.LastSnapshot, tt.want)
+			}
+			// The test may cross a second boundary
+			if got.SnapshotAge < tt.wantAge || got.SnapshotAge > tt.wantAge+2 || (tt.wantAge < 0 && got.SnapshotAge != -1) {
+				t.Errorf("NewClusterStatus() snapshot age = %d, want %d", got.SnapshotAge, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestNewClusterStatusNodes(t *testing.T) {
+	ha := HaStatus{
+		CurrentMaster: "node1",
+		Hosts:         []HostInfo{{HostName: "node2", IpAddress: "10.0.0.2", Running: []string{"vm/test-vm-1", "jail/test-jail-1"}}},
+		Members: []HaMembership.Member{
+			{HostName: "node1", IpAddress: "10.0.0.1", Status: HaMembership.STATUS_APPROVED},
+			{HostName: "node2", IpAddress: "10.0.0.2", Status: HaMembership.STATUS_APPROVED},
+			{HostName: "node3", IpAddress: "10.0.0.3", Status: HaMembership.STATUS_APPROVED},
+			{HostName: "node4", IpAddress: "10.0.0.4", Status: HaMembership.STATUS_PENDING},
+		},
+	}
+
+	want := []NodeStatus{
+		{HostName: "node2", IpAddress: "10.0.0.2", Role: "follower", Member: HaMembership.STATUS_APPROVED, Running: 2},
+		{HostName: "node1", IpAddress: "10.0.0.1", Role: "master", Member: HaMembership.STATUS_APPROVED},
+		// Approved, but has never pinged the master
+		{HostName: "node3", IpAddress: "10.0.0.3", Role: "follower", Member: HaMembership.STATUS_APPROVED, Offline: true},
+	}
+	if got := NewClusterStatus(ha, nil).Nodes; !reflect.DeepEqual(got, want) {
+		t.Errorf("NewClusterStatus() nodes = %+v, want %+v", got, want)
+	}
+}
@@ -15,3 +15,19 @@ const (
 
 // Socket File Constants
 const SOCKET_FILE = "/var/run/ha_carp.sock"
+
+// Failover Strategy Constants
+const (
+	FAILOVER_CIRESET       = "cireset"
+	FAILOVER_CHANGE_PARENT = "change_parent"
+)
+
+// Fencing Constants
+const (
+	FENCED_FILE    = "/var/db/hoster_ha_fenced" // Exists while the local resources are fenced, kept across the reboots as the resources stay locked
+	LEASE_TIME_MIN = 20                         // Seconds
+	FENCE_GRACE    = 15                         // Seconds it takes to notice the expired lease and stop the local resources
+)
+
+// Maintenance mode marker, kept in /var/db so the drained node stays in maintenance across the reboots
+const MAINTENANCE_FILE = "/var/db/hoster_ha_maintenance"
@@ -0,0 +1,76 @@
+package CarpUtils
+
+import HaCore "HosterCore/internal/pkg/hoster/ha/core"
+
+type FailoverPlan struct {
+	Backup BackupInfo // Backup copy that will be promoted
+	Target HostInfo   // Backup holder that becomes the new parent
+}
+
+func (b BackupInfo) Copy() HaCore.Copy {
+	return HaCore.Copy{
+		ResourceType:     b.ResourceType,
+		ResourceName:     b.ResourceName,
+		Holder:           b.CurrentHost,
+		Parent:           b.ParentHost,
+		Snapshot:         b.LastSnapshot,
+		FailoverStrategy: b.FailoverStrategy,
+		Ha:               b.Ha,
+	}
+}
+
+func (h HostInfo) Node() HaCore.Node {
+	return HaCore.Node{
+		Name:         h.HostName,
+		Offline:      h.Offline,
+		Maintenance:  h.Maintenance,
+		RamFree:      h.RamFree,
+		MaxResources: h.MaxResources,
+		Resources:    len(h.Running),
+		Groups:       h.Groups,
+	}
+}
+
+func Copies(backups []BackupInfo) (r []HaCore.Copy) {
+	for _, v := range backups {
+		r = append(r, v.Copy())
+	}
+	return
+}
+
+func Nodes(hosts []HostInfo) (r []HaCore.Node) {
+	for _, v := range hosts {
+		r = append(r, v.Node())
+	}
+	return
+}
+
+// Returns the backup the copy was made from
+func BackupOf(backups []BackupInfo, c HaCore.Copy) (r BackupInfo, found bool) {
+	for _, v := range backups {
+		if v.ResourceType == c.ResourceType && v.ResourceName == c.ResourceName && v.CurrentHost == c.Holder {
+			return v, true
+		}
+	}
+	return
+}
+
+// Plans the failover of the offline backups with the HA core placement (see HaCore.PlanFailover)
+func PlanFailover(offline []BackupInfo, hosts []HostInfo) (plans []FailoverPlan, pending []BackupInfo, reasons map[string]string) {
+	corePlans, corePending, reasons := HaCore.PlanFailover(Copies(offline), Nodes(hosts))
+	for _, v := range corePlans {
+		backup, _ := BackupOf(offline, v.Copy)
+		for _, vv := range hosts {
+			if vv.HostName == v.Target {
+				plans = append(plans, FailoverPlan{Backup: backup, Target: vv})
+			}
+		}
+	}
+
+	for _, v := range corePending {
+		backup, _ := BackupOf(offline, v)
+		pending = append(pending, backup)
+	}
+
+	return
+}
@@ -0,0 +1,110 @@
+package CarpUtils
+
+import (
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
+	"testing"
+)
+
+func TestPlanFailover(t *testing.T) {
+	backup := func(holder string, snapshot string) BackupInfo {
+		return BackupInfo{
+			ResourceName:     "test-vm-1",
+			ResourceType:     "vm",
+			LastSnapshot:     "zroot/vm-encrypted/test-vm-1@replication_" + snapshot,
+			CurrentHost:      holder,
+			ParentHost:       "node1",
+			FailoverStrategy: "cireset",
+		}
+	}
+
+	tests := []struct {
+		name        string
+		offline     []BackupInfo
+		hosts       []HostInfo
+		wantTarget  string
+		wantPending int
+	}{
+		{
+			name:       "newest snapshot wins",
+			offline:    []BackupInfo{backup("node2", "2023-08-14_16-49-08"), backup("node3", "2023-08-14_16-50-08")},
+			hosts:      []HostInfo{{HostName: "node2"}, {HostName: "node3"}},
+			wantTarget: "node3",
+		},
+		{
+			name:       "offline holder is skipped",
+			offline:    []BackupInfo{backup("node2", "2023-08-14_16-49-08"), backup("node3", "2023-08-14_16-50-08")},
+			hosts:      []HostInfo{{HostName: "node2"}, {HostName: "node3", Offline: true}},
+			wantTarget: "node2",
+		},
+		{
+			name:       "holder in maintenance is skipped",
+			offline:    []BackupInfo{backup("node2", "2023-08-14_16-49-08"), backup("node3", "2023-08-14_16-50-08")},
+			hosts:      []HostInfo{{HostName: "node2"}, {HostName: "node3", Maintenance: true}},
+			wantTarget: "node2",
+		},
+		{
+			name:       "holder running less resources wins on the same snapshot",
+			offline:    []BackupInfo{backup("node2", "2023-08-14_16-49-08"), backup("node3", "2023-08-14_16-49-08")},
+			hosts:      []HostInfo{{HostName: "node2", Running: []string{"vm/test-vm-2"}}, {HostName: "node3"}},
+			wantTarget: "node3",
+		},
+		{
+			name:        "holder at the max resources is pending",
+			offline:     []BackupInfo{backup("node2", "2023-08-14_16-49-08")},
+			hosts:       []HostInfo{{HostName: "node2", MaxResources: 1, Running: []string{"vm/test-vm-2"}}},
+			wantPending: 1,
+		},
+		{
+			name:        "no online holder is pending",
+			offline:     []BackupInfo{backup("node2", "2023-08-14_16-49-08")},
+			hosts:       []HostInfo{{HostName: "node3"}},
+			wantPending: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plans, pending, reasons := PlanFailover(tt.offline, tt.hosts)
+			if len(pending) != tt.wantPending {
+				t.Fatalf("PlanFailover() pending = %d, want %d", len(pending), tt.wantPending)
+			}
+			if tt.wantPending > 0 {
+				if len(plans) != 0 {
+					t.Fatalf("PlanFailover() plans = %+v, want none", plans)
+				}
+				if len(reasons["vm/test-vm-1"]) < 1 {
+					t.Errorf("PlanFailover() has no reason for the pending resource")
+				}
+				return
+			}
+
+			if len(plans) != 1 {
+				t.Fatalf("PlanFailover() plans = %d, want 1", len(plans))
+			}
+			if plans[0].Target.HostName != tt.wantTarget || plans[0].Backup.CurrentHost != tt.wantTarget {
+				t.Errorf("PlanFailover() target = %s (backup on %s), want %s", plans[0].Target.HostName, plans[0].Backup.CurrentHost, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestPlanFailoverSettings(t *testing.T) {
+	offline := []BackupInfo{
+		{ResourceName: "db-1", ResourceType: "vm", CurrentHost: "node2", ParentHost: "node1", Ha: &HaPlacement.Settings{AntiAffinityGroup: "db", Priority: 10}},
+		{ResourceName: "db-1", ResourceType: "vm", CurrentHost: "node3", ParentHost: "node1", Ha: &HaPlacement.Settings{AntiAffinityGroup: "db", Priority: 10}},
+		{ResourceName: "db-2", ResourceType: "vm", CurrentHost: "node2", ParentHost: "node1", Ha: &HaPlacement.Settings{AntiAffinityGroup: "db", PreferredNodes: []string{"node2"}}},
+	}
+	hosts := []HostInfo{{HostName: "node2"}, {HostName: "node3", RamFree: 1024}}
+
+	// db-1 goes first (priority) to the node with more free RAM, db-2 can't share it's group with db-1 and stays on the preferred node2
+	plans, pending, _ := PlanFailover(offline, hosts)
+	if len(pending) != 0 || len(plans) != 2 {
+		t.Fatalf("PlanFailover() plans = %d, pending = %d, want 2 and 0", len(plans), len(pending))
+	}
+	if plans[0].Backup.ResourceName != "db-1" || plans[0].Target.HostName != "node3" {
+		t.Errorf("PlanFailover() first plan = %s on %s, want db-1 on node3", plans[0].Backup.ResourceName, plans[0].Target.HostName)
+	}
+	if plans[1].Backup.ResourceName != "db-2" || plans[1].Target.HostName != "node2" {
+		t.Errorf("PlanFailover() second plan = %s on %s, want db-2 on node2", plans[1].Backup.ResourceName, plans[1].Target.HostName)
+	}
+}
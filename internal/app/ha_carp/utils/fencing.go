@@ -0,0 +1,142 @@
+package CarpUtils
+
+import (
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	"encoding/json"
+	"os"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Fencing marker, which records the resources that were running when the node fenced itself
+type FencedState struct {
+	FencedAt string   `json:"fenced_at"`
+	Running  []string `json:"running"` // Resources that were running at the fencing time, e.g. "vm/test-vm-1", only these are started on the rejoin
+}
+
+// Returns the lease time in seconds: a node which hasn't heard from the master (with a quorum) for this long fences it's resources.
+// Defaults to half of the failover time.
+func (c CarpConfig) Lease() int {
+	r := c.LeaseTime
+	if r < 1 {
+		r = c.FailoverAfter / 2
+	}
+	if r < LEASE_TIME_MIN {
+		r = LEASE_TIME_MIN
+	}
+	return r
+}
+
+// Returns the number of seconds after which an offline host is failed over.
+// It's never shorter than the lease plus the fencing grace period, so the offline host had the time to fence itself.
+func (c CarpConfig) FailoverDelay() int {
+	if c.FailoverAfter < c.Lease()+FENCE_GRACE {
+		return c.Lease() + FENCE_GRACE
+	}
+	return c.FailoverAfter
+}
+
+// Checks if the majority of the known cluster members has been seen within the lease time.
+//
+// An even split (e.g. one node of a two-node cluster is down) goes to the half holding the tie-breaker, which is the member
+// with the lowest host name. So a two-node cluster survives the loss of the other node, but not the loss of the tie-breaker.
+func HasQuorum(hosts []HostInfo, now int64, lease int) bool {
+	if len(hosts) < 1 {
+		return false
+	}
+
+	online := 0
+	tieBreaker := hosts[0]
+	for _, v := range hosts {
+		if now-v.LastSeen <= int64(lease) {
+			online = online + 1
+		}
+		if v.HostName < tieBreaker.HostName {
+			tieBreaker = v
+		}
+	}
+
+	if online*2 == len(hosts) {
+		return now-tieBreaker.LastSeen <= int64(lease)
+	}
+	return online*2 > len(hosts)
+}
+
+// Creates the fencing marker, with the list of resources running at the fencing time
+func WriteFencedState(running []string) error {
+	state := FencedState{FencedAt: time.Now().Format(time.RFC3339), Running: running}
+	if state.Running == nil {
+		state.Running = []string{}
+	}
+
+	data, err := json.MarshalIndent(state, "", "   ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(FENCED_FILE, data, 0644)
+}
+
+// Reads the fencing marker. Markers written before the running list was recorded don't parse, and nothing is started for them.
+func ReadFencedState() (r FencedState, e error) {
+	data, err := os.ReadFile(FENCED_FILE)
+	if err != nil {
+		e = err
+		return
+	}
+
+	e = json.Unmarshal(data, &r)
+	return
+}
+
+// Returns the resources the (re)joining host must demote to backups: the ones failed over from it while it was away,
+// and the ones it runs after they have been failed over to a different host (split-brain).
+func StaleCopies(host HostInfo, history []FailoverRecord) (r []FailoverRecord) {
+	keys := []string{}
+	latest := make(map[string]FailoverRecord)
+	for _, v := range history {
+		if !v.Success {
+			continue
+		}
+
+		key := HaCore.ResourceKey(v.ResourceType, v.ResourceName)
+		if _, ok := latest[key]; !ok {
+			keys = append(keys, key)
+		}
+		latest[key] = v
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v := latest[key]
+		if v.NewParent == host.HostName {
+			continue
+		}
+
+		if (v.OldParent == host.HostName && !v.Demoted) || slices.Contains(host.Running, key) {
+			r = append(r, v)
+		}
+	}
+
+	return
+}
+
+// Returns the resources running on more than one host as a parent (resource -> hosts)
+func DuplicateParents(hosts []HostInfo) (r map[string][]string) {
+	r = make(map[string][]string)
+
+	parents := make(map[string][]string)
+	for _, v := range hosts {
+		for _, vv := range v.Running {
+			parents[vv] = append(parents[vv], v.HostName)
+		}
+	}
+
+	for k, v := range parents {
+		if len(v) > 1 {
+			r[k] = v
+		}
+	}
+
+	return
+}
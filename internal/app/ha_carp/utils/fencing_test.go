@@ -0,0 +1,34 @@
+package CarpUtils
+
+import "testing"
+
+func TestHasQuorum(t *testing.T) {
+	const now = 1700000000
+	const lease = 20
+	online := func(name string) HostInfo { return HostInfo{HostName: name, LastSeen: now - 5} }
+	offline := func(name string) HostInfo { return HostInfo{HostName: name, LastSeen: now - 60} }
+
+	tests := []struct {
+		name  string
+		hosts []HostInfo
+		want  bool
+	}{
+		{name: "no hosts", hosts: []HostInfo{}, want: false},
+		{name: "single node", hosts: []HostInfo{online("node1")}, want: true},
+		{name: "two nodes online", hosts: []HostInfo{online("node1"), online("node2")}, want: true},
+		{name: "two nodes, tie-breaker online", hosts: []HostInfo{online("node1"), offline("node2")}, want: true},
+		{name: "two nodes, tie-breaker offline", hosts: []HostInfo{offline("node1"), online("node2")}, want: false},
+		{name: "three nodes, one offline", hosts: []HostInfo{offline("node1"), online("node2"), online("node3")}, want: true},
+		{name: "three nodes, two offline", hosts: []HostInfo{online("node1"), offline("node2"), offline("node3")}, want: false},
+		{name: "four nodes, split with the tie-breaker", hosts: []HostInfo{online("node3"), offline("node2"), online("node1"), offline("node4")}, want: true},
+		{name: "four nodes, split without the tie-breaker", hosts: []HostInfo{online("node3"), offline("node1"), online("node2"), offline("node4")}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasQuorum(tt.hosts, now, lease); got != tt.want {
+				t.Errorf("HasQuorum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,21 @@
 package CarpUtils
 
+import (
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
+)
+
 type HaStatus struct {
-	BasePayload                // type: ha_status
-	ServiceHealth string       `json:"service_health"` // Health status: OK, WARN, CRIT
-	Status        string       `json:"status"`         // Current HA status: MASTER, BACKUP, INIT
-	CurrentMaster string       `json:"current_master"` // Current master hostname
-	Hosts         []HostInfo   `json:"hosts"`          // List of hosts
-	Resources     []BackupInfo `json:"resources"`      // List of resources
+	BasePayload                         // type: ha_status
+	ServiceHealth string                `json:"service_health"` // Health status: OK, WARN, CRIT
+	Status        string                `json:"status"`         // Current HA status: MASTER, BACKUP, INIT
+	CurrentMaster string                `json:"current_master"` // Current master hostname
+	Quorum        bool                  `json:"quorum"`         // Majority of the cluster members is online, as seen by the master
+	Hosts         []HostInfo            `json:"hosts"`          // List of hosts
+	Resources     []BackupInfo          `json:"resources"`      // List of resources
+	Failovers     []FailoverRecord      `json:"failovers"`      // Recent failover attempts, oldest first
+	Members       []HaMembership.Member `json:"members"`        // Cluster membership, synced from the master
 }
 
 type CarpInfo struct {
@@ -26,6 +35,8 @@ type CarpConfig struct {
 	Advbase               int    `json:"advbase"`                 // Advertisement base interval, seconds
 	Advskew               int    `json:"advskew"`                 // Advertisement skew, calculated as 1/256th of a second
 	FailoverAfter         int    `json:"failover_after"`          // Failover after x seconds
+	LeaseTime             int    `json:"lease_time,omitempty"`    // Fence the local resources if the lease hasn't been renewed by the master for x seconds
+	MaxResources          int    `json:"max_resources,omitempty"` // Max number of HA resources running on this host, the failover skips it once reached (0 means unlimited)
 	Interface             string `json:"interface"`               // Interface name
 	MasterIpAddress       string `json:"master_ip_address"`       // IP address
 	Netmask               string `json:"netmask"`                 // Netmask
@@ -34,25 +45,64 @@ type CarpConfig struct {
 
 type BackupInfo struct {
 	BasePayload
-	ResourceName     string `json:"resource_name"`     // Resource name
-	ResourceType     string `json:"resource_type"`     // Resource type, e.g. "vm", "jail"
-	LastSnapshot     string `json:"last_snapshot"`     // Last snapshot name
-	CurrentHost      string `json:"current_host"`      // Current host name
-	ParentHost       string `json:"parent_host"`       // Parent host name
-	FailoverStrategy string `json:"failover_strategy"` // Failover strategy, e.g. "cireset" or "change_parent"
+	ResourceName     string                `json:"resource_name"`     // Resource name
+	ResourceType     string                `json:"resource_type"`     // Resource type, e.g. "vm", "jail"
+	LastSnapshot     string                `json:"last_snapshot"`     // Last snapshot name
+	CurrentHost      string                `json:"current_host"`      // Current host name
+	ParentHost       string                `json:"parent_host"`       // Parent host name
+	FailoverStrategy string                `json:"failover_strategy"` // Failover strategy, e.g. "cireset" or "change_parent"
+	Ha               *HaPlacement.Settings `json:"ha,omitempty"`      // HA settings of the resource: priority, preferred nodes and anti-affinity group
 }
 
 type HostInfo struct {
 	BasePayload
-	Offline   bool   `json:"offline,omitempty"`    // Online status
-	LastSeen  int64  `json:"last_seen,omitempty"`  // Last seen timestamp
-	HostName  string `json:"host_name,omitempty"`  // Host name
-	IpAddress string `json:"ip_address,omitempty"` // IP address
+	Offline      bool     `json:"offline,omitempty"`       // Online status
+	LastSeen     int64    `json:"last_seen,omitempty"`     // Last seen timestamp
+	HostName     string   `json:"host_name,omitempty"`     // Host name
+	IpAddress    string   `json:"ip_address,omitempty"`    // IP address
+	RamFree      uint64   `json:"ram_free,omitempty"`      // Free RAM in bytes, reported with every ping
+	Running      []string `json:"running,omitempty"`       // Resources running on the host as a parent, e.g. "vm/test-vm-1"
+	Fenced       bool     `json:"fenced,omitempty"`        // Host has fenced it's resources, and waits for the master to rejoin it
+	MaxResources int      `json:"max_resources,omitempty"` // Max number of HA resources the host accepts, 0 means unlimited
+	Groups       []string `json:"groups,omitempty"`        // Anti-affinity groups of the resources running on the host
+	Maintenance  bool     `json:"maintenance,omitempty"`   // Host is being drained (or has been drained) for maintenance, it's not failed over
+}
+
+// Failover requests and records are shared by both HA modes, they are defined by the HA core
+type FailoverRequest = HaCore.Request
+type FailoverRecord = HaCore.Record
+
+type RejoinRequest struct {
+	Demote []FailoverRecord `json:"demote"` // Resources failed over while the host was away, to be demoted to backups
 }
 
 type SocketResponse struct {
 	BasePayload
-	Success bool `json:"success"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Message received over the REST API, which ha_carp verifies against the sender's key
+type SignedPayload struct {
+	BasePayload                               // type: host_add, ha_receive_hosts
+	IpAddress      string                     `json:"ip_address,omitempty"`      // Address the message came from
+	MasterHostname string                     `json:"master_hostname,omitempty"` // Master the state sync claims to come from (REST route parameter)
+	Message        HaMembership.SignedMessage `json:"message"`
+}
+
+type MemberRequest struct {
+	BasePayload                          // type: member_join, member_trust, member_approve, member_remove, member_list
+	HostName    string                   `json:"host_name,omitempty"`
+	IpAddress   string                   `json:"ip_address,omitempty"`
+	Fingerprint string                   `json:"fingerprint,omitempty"` // Key fingerprint confirmed by the admin, required for the approval
+	Join        HaMembership.JoinRequest `json:"join"`
+	Member      HaMembership.Member      `json:"member"`
+}
+
+type MemberResponse struct {
+	SocketResponse
+	Self    HaMembership.Member   `json:"self"`    // This node
+	Members []HaMembership.Member `json:"members"` // All members, including the pending ones
 }
 
 type BasePayload struct {
@@ -62,4 +112,5 @@ type BasePayload struct {
 type CarpPingResponse struct {
 	Message  string `json:"message"`  // success
 	Hostname string `json:"hostname"` // hostname
+	Quorum   bool   `json:"quorum"`   // Master has a quorum, so the lease is renewed
 }
@@ -0,0 +1,96 @@
+//go:build freebsd
+// +build freebsd
+
+package main
+
+import (
+	FreeBSDPgrep "HosterCore/internal/pkg/freebsd/pgrep"
+	HosterJail "HosterCore/internal/pkg/hoster/jail"
+	HosterLocations "HosterCore/internal/pkg/hoster/locations"
+	HosterVm "HosterCore/internal/pkg/hoster/vm"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Survives the reboot, so it's possible to find out why the node has fenced itself
+const FENCING_LOG_LOCATION = "/var/db/hoster_ha_fencing.log"
+
+type fencingRecord struct {
+	Time    string   `json:"time"`
+	Action  string   `json:"action"`
+	Reasons []string `json:"reasons"`
+	Debug   bool     `json:"debug,omitempty"` // Action was only logged, not executed
+}
+
+// Appends the record to the fencing log, and makes sure it has reached the disk before the action is taken
+func recordFencing(action string, reasons []string, debug bool) error {
+	record := fencingRecord{Time: time.Now().Format(time.RFC3339), Action: action, Reasons: reasons, Debug: debug}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(FENCING_LOG_LOCATION, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func executeAction(action string) error {
+	switch action {
+	case ACTION_RESTART_API:
+		return restartApi()
+	case ACTION_STOP_RESOURCES:
+		return stopResources()
+	case ACTION_REBOOT:
+		// Lock first, the resources must not start again after the reboot
+		_ = stopResources()
+		syscall.Sync()
+		return exec.Command("reboot").Run()
+	}
+
+	return errors.New("unknown action: " + action)
+}
+
+// The restarted API finds this watchdog running, and keeps sending the heartbeat to it
+func restartApi() error {
+	pid, err := FreeBSDPgrep.FindRestAPIv2()
+	if err == nil && pid > 0 {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+		time.Sleep(time.Second)
+	}
+
+	bin, err := HosterLocations.LocateBinary("rest_api_v2")
+	if err != nil {
+		return err
+	}
+
+	command := exec.Command(bin)
+	command.Env = append(os.Environ(), "LOG_FILE=/var/log/hoster_rest_api_v2.log")
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return command.Start()
+}
+
+// Fences the production resources with an HA lock, and kills everything that's running
+func stopResources() error {
+	var errs []error
+	_, err := HosterVm.LockAllVms()
+	errs = append(errs, err)
+	_, err = HosterJail.LockAllJails()
+	errs = append(errs, err)
+	errs = append(errs, HosterVm.StopAll(true, true))
+	errs = append(errs, HosterJail.StopAll())
+
+	return errors.Join(errs...)
+}
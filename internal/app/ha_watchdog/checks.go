@@ -0,0 +1,198 @@
+//go:build freebsd
+// +build freebsd
+
+package main
+
+import (
+	RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type healthCheck interface {
+	Name() string
+	// Returns the reason, if the check has failed
+	Check() error
+}
+
+// Unix time of the last SIGHUP received from the REST API
+var lastReachOut atomic.Int64
+
+const (
+	HEARTBEAT_PERIOD = 4 // Seconds between the SIGHUPs sent by the REST API
+	HEARTBEAT_SLACK  = 2 // Seconds the heartbeat can be late by, e.g. when the API is busy
+)
+
+// The heartbeat is missing if it hasn't arrived for the check interval, but never before the REST API had the chance to send it
+func heartbeatWindow(interval int) int64 {
+	return int64(max(interval, HEARTBEAT_PERIOD) + HEARTBEAT_SLACK)
+}
+
+type heartbeatCheck struct {
+	window int64
+}
+
+func (c heartbeatCheck) Name() string { return CHECK_HEARTBEAT }
+
+func (c heartbeatCheck) Check() error {
+	last := lastReachOut.Load()
+	if time.Now().Unix() > last+c.window {
+		return fmt.Errorf("REST API heartbeat is missing, previous alive timestamp: %d", last)
+	}
+	return nil
+}
+
+type apiHealthCheck struct {
+	client *http.Client
+}
+
+func (c apiHealthCheck) Name() string { return CHECK_API_HEALTH }
+
+func (c apiHealthCheck) Check() error {
+	// The config is read every time, the port could have changed with the API restart
+	conf, err := RestApiConfig.GetApiConfig()
+	if err != nil {
+		return err
+	}
+	protocol := conf.Protocol
+	if protocol != "https" {
+		protocol = "http"
+	}
+
+	url := protocol + "://127.0.0.1:" + strconv.Itoa(conf.Port) + "/api/v2/health"
+	res, err := c.client.Get(url)
+	if err != nil {
+		return fmt.Errorf("REST API health check has failed: %s", err.Error())
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("REST API health check has responded with %d", res.StatusCode)
+	}
+	return nil
+}
+
+type zfsPoolCheck struct {
+	pools []string // All imported pools, if empty
+}
+
+func (c zfsPoolCheck) Name() string { return CHECK_ZFS_POOL }
+
+func (c zfsPoolCheck) Check() error {
+	out, err := exec.Command("zpool", "list", "-H", "-o", "name,health").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not list the ZFS pools: %s; %s", strings.TrimSpace(string(out)), err.Error())
+	}
+
+	found := []string{}
+	failed := []string{}
+	for _, v := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(v)
+		if len(fields) < 2 {
+			continue
+		}
+		if len(c.pools) > 0 && !slices.Contains(c.pools, fields[0]) {
+			continue
+		}
+		found = append(found, fields[0])
+		if fields[1] != "ONLINE" {
+			failed = append(failed, fields[0]+" is "+fields[1])
+		}
+	}
+	for _, v := range c.pools {
+		if !slices.Contains(found, v) {
+			failed = append(failed, v+" is not imported")
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New("ZFS pool check has failed: " + strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+type uplinkCheck struct {
+	address string // Default gateway is used, if empty
+}
+
+func (c uplinkCheck) Name() string { return CHECK_UPLINK }
+
+func (c uplinkCheck) Check() error {
+	address := c.address
+	if len(address) < 1 {
+		gateway, err := defaultGateway()
+		if err != nil {
+			return err
+		}
+		address = gateway
+	}
+
+	out, err := exec.Command("ping", "-c", "1", "-t", "2", address).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("uplink %s is not reachable: %s", address, lastLine(string(out)))
+	}
+	return nil
+}
+
+func defaultGateway() (r string, e error) {
+	out, err := exec.Command("route", "-n", "get", "default").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("could not find the default gateway: %s", strings.TrimSpace(string(out)))
+		return
+	}
+
+	for _, v := range strings.Split(string(out), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(v), ":")
+		if found && key == "gateway" {
+			r = strings.TrimSpace(value)
+			return
+		}
+	}
+
+	e = errors.New("could not find the default gateway")
+	return
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
+
+func newChecks(conf RestApiConfig.HaWatchdogConfig) (r []healthCheck) {
+	for _, v := range conf.Checks {
+		switch v {
+		case CHECK_HEARTBEAT:
+			r = append(r, heartbeatCheck{window: heartbeatWindow(conf.Interval)})
+		case CHECK_API_HEALTH:
+			client := &http.Client{Timeout: time.Duration(conf.Interval) * time.Second}
+			// Local self-signed certificate can't be verified
+			client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+			r = append(r, apiHealthCheck{client: client})
+		case CHECK_ZFS_POOL:
+			r = append(r, zfsPoolCheck{pools: conf.ZfsPools})
+		case CHECK_UPLINK:
+			r = append(r, uplinkCheck{address: conf.UplinkAddress})
+		}
+	}
+
+	return
+}
+
+// Returns the reasons of all failed checks
+func runChecks(checks []healthCheck) (r []string) {
+	for _, v := range checks {
+		err := v.Check()
+		if err != nil {
+			r = append(r, v.Name()+": "+err.Error())
+		}
+	}
+	return
+}
@@ -0,0 +1,112 @@
+//go:build freebsd
+// +build freebsd
+
+package main
+
+import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	CHECK_HEARTBEAT  = "heartbeat"  // REST API keeps sending the SIGHUP
+	CHECK_API_HEALTH = "api_health" // REST API responds on /api/v2/health
+	CHECK_ZFS_POOL   = "zfs_pool"   // ZFS pools are ONLINE
+	CHECK_UPLINK     = "uplink"     // Uplink address is reachable
+
+	ACTION_RESTART_API    = "restart_api"
+	ACTION_STOP_RESOURCES = "stop_resources" // Locks (fences) and kills all VMs and Jails
+	ACTION_REBOOT         = "reboot"
+)
+
+const (
+	DEFAULT_INTERVAL          = 5
+	DEFAULT_FAILURE_THRESHOLD = 2
+	DEFAULT_ESCALATE_AFTER    = 2
+)
+
+var knownChecks = []string{CHECK_HEARTBEAT, CHECK_API_HEALTH, CHECK_ZFS_POOL, CHECK_UPLINK}
+var knownActions = []string{ACTION_RESTART_API, ACTION_STOP_RESOURCES, ACTION_REBOOT}
+
+// Reads the watchdog section of the ha_config.json, and fills in the defaults.
+// The watchdog must keep running without the config, so the defaults are returned together with the error.
+func loadConfig() (r RestApiConfig.HaWatchdogConfig, e error) {
+	haConf, haConfErr := RestApiConfig.GetHaConfig()
+	err := haConfErr
+	if err == nil {
+		r = haConf.Watchdog
+	}
+
+	if r.Interval < 1 {
+		r.Interval = DEFAULT_INTERVAL
+	}
+	if r.FailureThreshold < 1 {
+		r.FailureThreshold = DEFAULT_FAILURE_THRESHOLD
+	}
+	if r.EscalateAfter < 1 {
+		r.EscalateAfter = DEFAULT_ESCALATE_AFTER
+	}
+	if len(r.Checks) < 1 {
+		r.Checks = []string{CHECK_HEARTBEAT}
+	}
+	if len(r.Actions) < 1 {
+		r.Actions = slices.Clone(knownActions)
+	}
+
+	for _, v := range r.Checks {
+		if !slices.Contains(knownChecks, v) {
+			err = fmt.Errorf("unknown watchdog check: %s", v)
+		}
+	}
+	for _, v := range r.Actions {
+		if !slices.Contains(knownActions, v) {
+			err = fmt.Errorf("unknown watchdog action: %s", v)
+		}
+	}
+	if err != nil {
+		e = err
+		r.Checks = []string{CHECK_HEARTBEAT}
+		r.Actions = slices.Clone(knownActions)
+	}
+
+	// The other nodes must not start the resources before this one has stopped them
+	failover := failoverTime(haConf, haConfErr)
+	fencing := fencingTime(r)
+	if failover > 0 && fencing >= failover {
+		e = errors.Join(e, fmt.Errorf("the watchdog takes %ds to stop the resources, which is not shorter than the %ds failover time", fencing, failover))
+		r.Interval = DEFAULT_INTERVAL
+		r.FailureThreshold = DEFAULT_FAILURE_THRESHOLD
+		r.EscalateAfter = DEFAULT_ESCALATE_AFTER
+	}
+
+	return
+}
+
+// Returns the seconds it takes the failed checks to reach the stop_resources action (or the reboot, if it's not used),
+// or 0 if the resources are never stopped
+func fencingTime(c RestApiConfig.HaWatchdogConfig) int {
+	step := slices.Index(c.Actions, ACTION_STOP_RESOURCES)
+	if step < 0 {
+		step = slices.Index(c.Actions, ACTION_REBOOT)
+	}
+	if step < 0 {
+		return 0
+	}
+	return c.Interval * (c.FailureThreshold + step*c.EscalateAfter)
+}
+
+// Returns the shortest time after which this node is failed over by the others (REST API HA or CARP),
+// or 0 if neither of them is configured
+func failoverTime(haConf RestApiConfig.HaConfig, haConfErr error) (r int) {
+	if haConfErr == nil {
+		r = max(int(haConf.FailOverTime), RestApiConfig.HA_MIN_FAILOVER_TIME)
+	}
+	carpConf, err := CarpUtils.ParseCarpConfigFile()
+	if err == nil && (r < 1 || carpConf.FailoverDelay() < r) {
+		r = carpConf.FailoverDelay()
+	}
+	return
+}
@@ -4,11 +4,11 @@
 package main
 
 import (
-	"HosterCore/cmd"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -40,16 +40,28 @@ func main() {
 		log.Info("started in PROD mode")
 	}
 
-	timesFailed := 0
-	timesFailedMax := 2
-	lastReachOut := time.Now().Unix()
+	conf, err := loadConfig()
+	if err != nil {
+		log.Warnf("could not use the watchdog config, falling back to the defaults: %s", err.Error())
+	}
+	checks := newChecks(conf)
+	log.Infof("checks: %v, actions: %v, interval: %ds, failure threshold: %d, escalate after: %d",
+		conf.Checks, conf.Actions, conf.Interval, conf.FailureThreshold, conf.EscalateAfter)
+
+	lastReachOut.Store(time.Now().Unix())
+	// Set by the REST API, once it has lost the quorum: the resources must be fenced right away, and not by restarting the API
+	var fenceRequested atomic.Bool
 
 	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
 		for sig := range signals {
 			if sig == syscall.SIGHUP {
-				lastReachOut = time.Now().Unix()
+				lastReachOut.Store(time.Now().Unix())
+			}
+			if sig == syscall.SIGUSR1 {
+				log.Warn("REST API has requested the self fencing")
+				fenceRequested.Store(true)
 			}
 			if sig == syscall.SIGTERM || sig == syscall.SIGINT {
 				log.Info("received SIGTERM, executing a graceful exit")
@@ -58,26 +70,60 @@ func main() {
 		}
 	}()
 
+	timesFailed := 0
+	// Next action in the ladder
+	step := 0
 	for {
-		time.Sleep(time.Second * 5)
+		time.Sleep(time.Second * time.Duration(conf.Interval))
 
-		if timesFailed >= timesFailedMax {
-			if debugMode {
-				log.Debug("(DEBUG) RestAPI process has failed, rebooting the system now")
-				os.Exit(1)
-			} else {
-				log.Warn("RestAPI process has failed, rebooting the system now")
-				cmd.LockAllVms()
-				_ = exec.Command("reboot").Run()
+		reasons := runChecks(checks)
+		forced := fenceRequested.Swap(false)
+		if forced {
+			reasons = append(reasons, "REST API has lost the quorum, and requested the self fencing")
+			// Restarting the API doesn't stop the resources, which are about to be failed over
+			for step < len(conf.Actions) && conf.Actions[step] == ACTION_RESTART_API {
+				step += 1
 			}
 		}
 
-		if time.Now().Unix() > lastReachOut+5 {
-			log.Debugf("ping failed, previous alive timestamp: %d", lastReachOut)
-			timesFailed += 1
-			log.Debugf("pings missed so far: %d, may need to fence the system after this many failed pings: %d", timesFailed, timesFailedMax)
-		} else {
+		if len(reasons) < 1 {
+			if timesFailed > 0 {
+				log.Infof("all checks have passed again, after %d failed round(s)", timesFailed)
+			}
 			timesFailed = 0
+			step = 0
+			continue
+		}
+
+		timesFailed += 1
+		log.Debugf("failed round %d: %s", timesFailed, strings.Join(reasons, "; "))
+
+		if step >= len(conf.Actions) {
+			continue
+		}
+		if !forced && timesFailed < conf.FailureThreshold+step*conf.EscalateAfter {
+			continue
+		}
+
+		action := conf.Actions[step]
+		step += 1
+		err := recordFencing(action, reasons, debugMode)
+		if err != nil {
+			log.Errorf("could not write the fencing log %s: %s", FENCING_LOG_LOCATION, err.Error())
+		}
+
+		if debugMode {
+			log.Debugf("(DEBUG) %s would be executed now, because: %s", action, strings.Join(reasons, "; "))
+			if step >= len(conf.Actions) {
+				os.Exit(1)
+			}
+			continue
+		}
+
+		log.Warnf("executing %s, because: %s", action, strings.Join(reasons, "; "))
+		err = executeAction(action)
+		if err != nil {
+			log.Errorf("%s has failed: %s", action, err.Error())
 		}
 	}
 }
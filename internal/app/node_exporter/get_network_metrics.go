@@ -0,0 +1,42 @@
+package main
+
+import (
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// Returns the VM TAP and Jail epair interface counters (from the VM/Jail point of view)
+func getNetworkMetrics() string {
+	stats, err := HosterNetwork.GetIfaceStats()
+	if err != nil {
+		log.Println("Failed to collect the network interface stats: " + err.Error())
+		return ""
+	}
+
+	metrics := []struct {
+		name  string
+		help  string
+		value func(HosterNetwork.IfaceStats) uint64
+	}{
+		{"hoster_network_receive_bytes_total", "Bytes received by the VM or Jail network interface.", func(v HosterNetwork.IfaceStats) uint64 { return v.RxBytes }},
+		{"hoster_network_transmit_bytes_total", "Bytes sent by the VM or Jail network interface.", func(v HosterNetwork.IfaceStats) uint64 { return v.TxBytes }},
+		{"hoster_network_receive_packets_total", "Packets received by the VM or Jail network interface.", func(v HosterNetwork.IfaceStats) uint64 { return v.RxPackets }},
+		{"hoster_network_transmit_packets_total", "Packets sent by the VM or Jail network interface.", func(v HosterNetwork.IfaceStats) uint64 { return v.TxPackets }},
+		{"hoster_network_receive_errors_total", "Receive errors on the VM or Jail network interface.", func(v HosterNetwork.IfaceStats) uint64 { return v.RxErrors }},
+		{"hoster_network_transmit_errors_total", "Transmit errors on the VM or Jail network interface.", func(v HosterNetwork.IfaceStats) uint64 { return v.TxErrors }},
+	}
+
+	result := ""
+	for _, m := range metrics {
+		result = result + "# HELP " + m.name + " " + m.help + "\n"
+		result = result + "# TYPE " + m.name + " counter\n"
+		for _, v := range stats {
+			labels := fmt.Sprintf("{resource_type=%q,resource_name=%q,interface=%q,network=%q}", v.ResourceType, v.ResourceName, v.Interface, v.Network)
+			result = result + m.name + labels + " " + strconv.FormatUint(m.value(v), 10) + "\n"
+		}
+	}
+
+	return result
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	DnsServerUtils "HosterCore/internal/app/dns_server/utils"
 	"fmt"
 	"io"
 	"log"
@@ -73,6 +74,20 @@ func concatMetrics() string {
 		wg.Done()
 	}()
 
+	wg.Add(1)
+	go func() {
+		metricsText := getNetworkMetrics()
+		addMetricsToList(metricsText)
+		wg.Done()
+	}()
+
+	wg.Add(1)
+	go func() {
+		metricsText := getDnsServerMetrics()
+		addMetricsToList(metricsText)
+		wg.Done()
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -283,6 +298,25 @@ func getNodeExporterMetrics() string {
 	return metricsString
 }
 
+// Scrapes the metrics exposed by the Hoster DNS server (query counters, latency histograms, upstream health)
+func getDnsServerMetrics() string {
+	url := "http://" + DnsServerUtils.METRICS_ADDRESS + "/metrics"
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Println("Failed to scrape the DNS server metrics: " + err.Error())
+		return ""
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Println("Failed to read the response body: " + err.Error())
+		return ""
+	}
+
+	return string(body)
+}
+
 var metricsList []string
 var metricsListMutex sync.Mutex
 
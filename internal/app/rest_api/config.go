@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+func init() {
+	execPath, err := os.Executable()
+	if err != nil {
+		os.Exit(1)
+	}
+	execDir := filepath.Dir(execPath)
+	restApiConfigFile, err := os.ReadFile(execDir + "/config_files/restapi_config.json")
+	if err != nil {
+		os.Exit(1)
+	}
+	err = json.Unmarshal(restApiConfigFile, &restApiConfig)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	// The HA mode (node registration, manager election and VM failover) is served by the v2 REST API, which runs it through the HA core
+	if restApiConfig.HaMode {
+		_ = exec.Command("logger", "-t", "HOSTER_REST", "WARNING: HA MODE IS NOT SUPPORTED BY THIS REST API SERVER ANYMORE, USE THE V2 REST API").Run()
+	}
+	_ = exec.Command("logger", "-t", "HOSTER_REST", "INFO: STARING REST API SERVER IN REGULAR (NON-HA) MODE").Run()
+}
@@ -11,7 +11,6 @@ import (
 	"os/signal"
 	"path"
 	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
@@ -432,65 +431,7 @@ func main() {
 	v1.Post("/vm/change-parent", handleVmChangeParent)
 	v1.Post("/vm/cireset", handleVmCiReset)
 
-	if restApiConfig.HaMode {
-		ha := v1.Group("/ha")
-		ha.Post("/register", handleHaRegistration)
-		ha.Get("/all-members", handleHaShareAllMembers)
-		ha.Post("/terminate", handleHaTerminate)
-		ha.Post("/ping", handleHaPing)
-		ha.Get("/monitor", handleHaMonitor)
-		ha.Get("/vms", handleHaVmsList)
-	}
-
-	timesFailed := 0
-	timesFailedMax := 3
 	hosterRestLabel := "HOSTER_REST"
-	if restApiConfig.HaMode {
-		if restApiConfig.HaDebug {
-			_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "DEBUG: hoster_rest_api started in DEBUG mode").Run()
-		} else {
-			_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "PROD: hoster_rest_api started in PRODUCTION mode").Run()
-		}
-
-		hosterRestLabel = "HOSTER_HA_REST"
-		_ = exec.Command("logger", "-t", hosterRestLabel, "DEBUG: hoster_rest_api service start-up").Run()
-
-		// Execute ha_watchdog using nohup and disown it
-		if restApiConfig.HaDebug {
-			os.Setenv("REST_API_HA_DEBUG", "true")
-		}
-		haWatchdogCmd := exec.Command("nohup", "/opt/hoster-core/ha_watchdog", "&")
-		haWatchdogCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-		err = haWatchdogCmd.Start()
-
-		go func() {
-			for {
-				time.Sleep(time.Second * 4)
-
-				out, err := exec.Command("pgrep", "ha_watchdog").CombinedOutput()
-				if err != nil {
-					_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "ERROR: ha_watchdog process is not running").Run()
-					timesFailed += 1
-				} else {
-					_ = exec.Command("kill", "-SIGHUP", strings.TrimSpace(string(out))).Run()
-					timesFailed = 0
-				}
-
-				if timesFailed >= timesFailedMax {
-					if restApiConfig.HaDebug {
-						_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "DEBUG: process will exit due to HA_WATCHDOG failure").Run()
-						_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "DEBUG: the host system shall reboot soon").Run()
-						os.Exit(1)
-					} else {
-						_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "PROD: process will exit due to HA_WATCHDOG failure").Run()
-						cmd.LockAllVms()
-						_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "PROD: the host system shall reboot soon").Run()
-						_ = exec.Command("reboot").Run()
-					}
-				}
-			}
-		}()
-	}
 
 	signals := make(chan os.Signal, 1)
 	// SIGTERM -> kill -s SIGTERM  ||  SIGINT -> CTRL+C
@@ -503,11 +444,7 @@ func main() {
 				}
 
 				if sig == syscall.SIGINT {
-					_ = exec.Command("logger", "-t", hosterRestLabel, "INFO: received SIGINT (CTRL+C // graceful HA stop), exiting").Start()
-
-					if restApiConfig.HaMode {
-						terminateOtherMembers()
-					}
+					_ = exec.Command("logger", "-t", hosterRestLabel, "INFO: received SIGINT (CTRL+C), exiting").Start()
 				}
 
 				err := app.Shutdown()
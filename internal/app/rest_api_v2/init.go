@@ -4,9 +4,10 @@
 package main
 
 import (
-	"HosterCore/cmd"
 	RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
 	FreeBSDLogger "HosterCore/internal/pkg/freebsd/logger"
+	HosterJail "HosterCore/internal/pkg/hoster/jail"
+	HosterVm "HosterCore/internal/pkg/hoster/vm"
 	"os"
 	"os/exec"
 	"strings"
@@ -103,9 +104,13 @@ func init() {
 	if restConf.HaDebug {
 		os.Setenv("REST_API_HA_DEBUG", "true")
 	}
-	haWatchdogCmd := exec.Command("/opt/hoster-core/ha_watchdog")
-	haWatchdogCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	_ = haWatchdogCmd.Start()
+	// The API could have been restarted by the watchdog, which keeps running
+	_, err := exec.Command("pgrep", "ha_watchdog").CombinedOutput()
+	if err != nil {
+		haWatchdogCmd := exec.Command("/opt/hoster-core/ha_watchdog")
+		haWatchdogCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		_ = haWatchdogCmd.Start()
+	}
 
 	go func() {
 		pingWatchdog()
@@ -132,7 +137,8 @@ func pingWatchdog() {
 				os.Exit(1)
 			} else {
 				_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "PROD: process will exit due to HA_WATCHDOG failure").Run()
-				cmd.LockAllVms()
+				HosterVm.LockAllVms()
+				HosterJail.LockAllJails()
 				_ = exec.Command("logger", "-t", "HOSTER_HA_REST", "PROD: the host system shall reboot soon").Run()
 				_ = exec.Command("reboot").Run()
 			}
@@ -46,6 +46,7 @@ func main() {
 	// Middleware -> Logging
 	log = MiddlewareLogging.Configure(logrus.DebugLevel)
 	handlers.SetLogConfig(log)
+	handlers.StartIfaceStatsCollector()
 	r.Use(log.LogResponses)
 
 	// Health checks
@@ -77,6 +78,9 @@ func main() {
 	// Networks
 	r.HandleFunc("/api/v2/network/all", handlers.NetworkList).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/network/add-new-network", handlers.PostNewNetwork).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/network/forward/all", handlers.NetworkForwardList).Methods(http.MethodGet)
+	r.HandleFunc("/api/v2/network/forward/add", handlers.PostNetworkForward).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/network/forward/delete", handlers.DeleteNetworkForward).Methods(http.MethodDelete, http.MethodPost) // additional POST method for the clients that do not support DELETE
 	// VMs
 	r.HandleFunc("/api/v2/vm/all", handlers.VmList).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/vm/all/cache", handlers.VmListCache).Methods(http.MethodGet)
@@ -110,6 +114,7 @@ func main() {
 	r.HandleFunc("/api/v2/vm/clone", handlers.VmClone).Methods(http.MethodPost)
 	r.HandleFunc("/api/v2/vm/deploy", handlers.VmPostDeploy).Methods(http.MethodPost)
 	r.HandleFunc("/api/v2/vm/destroy/{vm_name}", handlers.VmDestroy).Methods(http.MethodDelete, http.MethodPost)
+	r.HandleFunc("/api/v2/vm/migrate/{vm_name}", handlers.VmPostMigrate).Methods(http.MethodPost)
 	// Jails
 	r.HandleFunc("/api/v2/jail/all", handlers.JailList).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/jail/all/cache", handlers.JailListCache).Methods(http.MethodGet)
@@ -122,6 +127,8 @@ func main() {
 	r.HandleFunc("/api/v2/jail/clone", handlers.JailClone).Methods(http.MethodPost)
 	r.HandleFunc("/api/v2/jail/destroy/{jail_name}", handlers.JailDestroy).Methods(http.MethodDelete)
 	r.HandleFunc("/api/v2/jail/deploy", handlers.JailDeploy).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/jail/migrate/{jail_name}", handlers.JailPostMigrate).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/migration/unfinished", handlers.MigrationListUnfinished).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/jail/readme/{jail_name}", handlers.JailGetReadme).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/jail/get/shells/{jail_name}", handlers.JailGetShells).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/jail/settings/{jail_name}", handlers.JailGetSettings).Methods(http.MethodGet)
@@ -147,14 +154,24 @@ func main() {
 	r.HandleFunc("/api/v2/prometheus/autodiscovery/vms", handlers.PrometheusAutoDiscoveryVms).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/prometheus/autodiscovery/vms/use-ips", handlers.PrometheusAutoDiscoveryVmsIps).Methods(http.MethodGet)
 	// WireGuard
-	r.HandleFunc("/api/v2/wireguard/script", handlers.WireGuardScript).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/wireguard/info", handlers.WireGuardInfo).Methods(http.MethodGet)
+	r.HandleFunc("/api/v2/wireguard/peer/all", handlers.WireGuardPeerList).Methods(http.MethodGet)
+	r.HandleFunc("/api/v2/wireguard/peer/sync", handlers.WireGuardPeerSync).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/wireguard/peer/add", handlers.PostWireGuardPeer).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/wireguard/peer/delete", handlers.DeleteWireGuardPeer).Methods(http.MethodDelete)
+	r.HandleFunc("/api/v2/wireguard/peer/delete", handlers.DeleteWireGuardPeer).Methods(http.MethodPost) // additional POST method for the clients that do not support DELETE
 
 	// HA
 	r.HandleFunc("/api/v2/carp-ha/ping", handlers.CarpPing).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/carp-ha/join", handlers.CarpJoin).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/carp-ha/status", handlers.CarpStatus).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/carp-ha/backups", handlers.CarpReturnListOfBackups).Methods(http.MethodGet)
 	r.HandleFunc("/api/v2/carp-ha/receive-state/{master_hostname}", handlers.CarpReceiveHostState).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/carp-ha/failover", handlers.CarpFailover).Methods(http.MethodPost)
+	r.HandleFunc("/api/v2/carp-ha/rejoin", handlers.CarpRejoin).Methods(http.MethodPost)
 	if restConf.HaMode {
 		r.HandleFunc("/api/v2/ha/ping", HandlersHA.HandlePing).Methods(http.MethodPost)
+		r.HandleFunc("/api/v2/ha/status", HandlersHA.HandleStatus).Methods(http.MethodGet)
 		r.HandleFunc("/api/v2/ha/register", HandlersHA.HandleRegistration).Methods(http.MethodPost)
 		r.HandleFunc("/api/v2/ha/terminate", HandlersHA.HandleTerminate).Methods(http.MethodPost)
 		r.HandleFunc("/api/v2/ha/jail-list", HandlersHA.HandleJailList).Methods(http.MethodGet)
@@ -2,12 +2,18 @@ package RestApiConfig
 
 import (
 	FileExists "HosterCore/internal/pkg/file_exists"
+	HaElection "HosterCore/internal/pkg/hoster/ha/election"
 	HosterLocations "HosterCore/internal/pkg/hoster/locations"
 	"encoding/json"
 	"errors"
 	"os"
 )
 
+// An isolated node self-fences once it has lost the other candidates: 3 failed pings (2s apart), the next 10s candidate check,
+// and then the ha_watchdog stops the resources in the next round (5s by default), before it reboots the host.
+// The offline node must not be failed over before that, otherwise both copies could be running at the same time.
+const HA_MIN_FAILOVER_TIME = 60
+
 type HaConfig struct {
 	NodeType         string   `json:"node_type"`
 	FailOverStrategy string   `json:"failover_strategy"`
@@ -15,6 +21,23 @@ type HaConfig struct {
 	BackupNode       bool     `json:"backup_node"`
 	Candidates       []HaNode `json:"candidates"`
 	StartupTime      int64    `json:"startup_time"`
+	// Max number of HA resources running on this node, the failover skips it once reached (0 means unlimited)
+	MaxResources int `json:"max_resources,omitempty"`
+	// ha_watchdog health checks and fencing actions
+	Watchdog HaWatchdogConfig `json:"watchdog"`
+}
+
+// All ha_watchdog settings are optional. The time it takes to reach the stop_resources action
+// (interval * (failure_threshold + escalate_after), by default) must stay below the failover time, so the resources
+// are stopped before the other nodes start them. The ha_watchdog falls back to the default timing otherwise.
+type HaWatchdogConfig struct {
+	Interval         int      `json:"interval,omitempty"`          // Seconds between the health check rounds, 5 by default
+	FailureThreshold int      `json:"failure_threshold,omitempty"` // Failed rounds in a row before the first action is taken, 2 by default
+	EscalateAfter    int      `json:"escalate_after,omitempty"`    // Failed rounds after each action, before the next one is taken, 2 by default
+	Checks           []string `json:"checks,omitempty"`            // heartbeat, api_health, zfs_pool, uplink (heartbeat only by default)
+	Actions          []string `json:"actions,omitempty"`           // Action ladder: restart_api, stop_resources, reboot (all 3 by default)
+	UplinkAddress    string   `json:"uplink_address,omitempty"`    // Address pinged by the uplink check, the default gateway is used if empty
+	ZfsPools         []string `json:"zfs_pools,omitempty"`         // Pools watched by the zfs_pool check, all imported pools by default
 }
 
 type HaNode struct {
@@ -30,6 +53,11 @@ type HaNode struct {
 	StartupTime      int64  `json:"startup_time"`
 	Registered       bool   `json:"registered"`
 	TimesFailed      int    `json:"times_failed"`
+	MaxResources     int    `json:"max_resources,omitempty"`
+	// The node is being drained for maintenance, it's not failed over when it goes offline
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Set by the candidates, which use the ping to run the manager election
+	Election *HaElection.Message `json:"election,omitempty"`
 }
 
 const haConfFileName = "ha_config.json"
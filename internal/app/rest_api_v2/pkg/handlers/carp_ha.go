@@ -6,12 +6,15 @@ import (
 	ApiAuth "HosterCore/internal/app/rest_api_v2/pkg/auth"
 	JSONResponse "HosterCore/internal/app/rest_api_v2/pkg/json_response"
 	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
 	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
 	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
 	zfsutils "HosterCore/internal/pkg/zfs_utils"
 	"encoding/json"
 	"net/http"
 	"strings"
+
+	"github.com/gorilla/mux"
 )
 
 // @Tags High Availability
@@ -21,7 +24,7 @@ import (
 // @Security BasicAuth
 // @Success 200 {object} CarpUtils.CarpPingResponse{}
 // @Failure 500 {object} SwaggerError{}
-// @Param Input body CarpUtils.HostInfo{} true "Request Payload"
+// @Param Input body HaMembership.SignedMessage{} true "HostInfo signed by the sender's node key"
 // @Router /carp-ha/ping [post]
 func CarpPing(w http.ResponseWriter, r *http.Request) {
 	if !ApiAuth.CheckHaUser(r) {
@@ -30,10 +33,7 @@ func CarpPing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	input := CarpUtils.HostInfo{}
-	input.IpAddress = r.RemoteAddr
-	input.IpAddress = strings.Split(r.RemoteAddr, ":")[0]
-
+	input := HaMembership.SignedMessage{}
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&input)
 	if err != nil {
@@ -41,7 +41,7 @@ func CarpPing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = CarpClient.ReceiveHostAdd(input)
+	err = CarpClient.ReceiveHostAdd(input, strings.Split(r.RemoteAddr, ":")[0])
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -58,6 +58,12 @@ func CarpPing(w http.ResponseWriter, r *http.Request) {
 		Hostname: hostname,
 	}
 
+	// The follower's lease is only renewed if the master has a quorum
+	status, err := CarpClient.GetHaStatus()
+	if err == nil {
+		res.Quorum = status.Quorum
+	}
+
 	payload, err := json.Marshal(res)
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
@@ -70,12 +76,12 @@ func CarpPing(w http.ResponseWriter, r *http.Request) {
 
 // @Tags High Availability
 // @Summary Receive the cluster state from the master.
-// @Description Receive the cluster state from the master.<br>`AUTH`: Only HA user is allowed.
+// @Description Receive the cluster state from the master. The state is rejected unless it's signed by the current CARP master, named in the path.<br>`AUTH`: Only HA user is allowed.
 // @Produce json
 // @Security BasicAuth
 // @Success 200 {object} SwaggerSuccess{}
 // @Failure 500 {object} SwaggerError{}
-// @Param Input body CarpUtils.HaStatus{} true "Request Payload"
+// @Param Input body HaMembership.SignedMessage{} true "HaStatus signed by the master's node key"
 // @Param master_hostname path string true "Hostname of the master server"
 // @Router /carp-ha/receive-state/{master_hostname} [post]
 func CarpReceiveHostState(w http.ResponseWriter, r *http.Request) {
@@ -85,7 +91,7 @@ func CarpReceiveHostState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	input := CarpUtils.HaStatus{}
+	input := HaMembership.SignedMessage{}
 	decoder := json.NewDecoder(r.Body)
 	err := decoder.Decode(&input)
 	if err != nil {
@@ -93,7 +99,7 @@ func CarpReceiveHostState(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = CarpClient.ReceiveRemoteState(input)
+	err = CarpClient.ReceiveRemoteState(input, mux.Vars(r)["master_hostname"])
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -104,6 +110,86 @@ func CarpReceiveHostState(w http.ResponseWriter, r *http.Request) {
 	w.Write(payload)
 }
 
+// @Tags High Availability
+// @Summary CARP cluster status.
+// @Description Get the overview of the CARP cluster: nodes, resources with their backup holders, and pending failovers.<br>`AUTH`: Both users are allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} CarpUtils.ClusterStatus{}
+// @Failure 500 {object} SwaggerError{}
+// @Router /carp-ha/status [get]
+func CarpStatus(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckAnyUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	ha, err := CarpClient.GetHaStatus()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// CARP state is informational, the status is still useful without it
+	carp, _ := CarpUtils.ParseIfconfig()
+
+	payload, err := json.Marshal(CarpUtils.NewClusterStatus(ha, carp))
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// @Tags High Availability
+// @Summary Ask to join the CARP cluster.
+// @Description Record the join request of the remote node, it stays pending until approved with `hoster ha approve`.<br>`AUTH`: Only HA user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} HaMembership.JoinResponse{}
+// @Failure 500 {object} SwaggerError{}
+// @Param Input body HaMembership.JoinRequest{} true "Request Payload"
+// @Router /carp-ha/join [post]
+func CarpJoin(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckHaUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := HaMembership.JoinRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	res, err := CarpClient.MemberJoin(input, strings.Split(r.RemoteAddr, ":")[0])
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := HaMembership.STATUS_PENDING
+	for _, v := range res.Members {
+		if v.HostName == input.HostName {
+			status = v.Status
+		}
+	}
+
+	payload, err := json.Marshal(HaMembership.JoinResponse{HostName: res.Self.HostName, PublicKey: res.Self.PublicKey, Status: status})
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
 // @Tags High Availability
 // @Summary Receive the cluster state from the master.
 // @Description Receive the cluster state from the master.<br>`AUTH`: Only HA user is allowed.
@@ -153,6 +239,7 @@ func CarpReturnListOfBackups(w http.ResponseWriter, r *http.Request) {
 				temp.ResourceName = v.Name
 				temp.ParentHost = v.ParentHost
 				temp.FailoverStrategy = v.FailoverStrategy
+				temp.Ha = v.Ha
 
 				for _, vv := range snaps {
 					if vv.Dataset == v.Simple.DsName+"/"+v.Name {
@@ -172,6 +259,7 @@ func CarpReturnListOfBackups(w http.ResponseWriter, r *http.Request) {
 				temp.ResourceName = v.Name
 				temp.ParentHost = v.Parent
 				temp.FailoverStrategy = v.FailoverStrategy
+				temp.Ha = v.Ha
 
 				for _, vv := range snaps {
 					if vv.Dataset == v.Simple.DsName+"/"+v.Name {
@@ -0,0 +1,266 @@
+//go:build freebsd
+// +build freebsd
+
+package handlers
+
+import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	ApiAuth "HosterCore/internal/app/rest_api_v2/pkg/auth"
+	JSONResponse "HosterCore/internal/app/rest_api_v2/pkg/json_response"
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	HosterJail "HosterCore/internal/pkg/hoster/jail"
+	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterLocations "HosterCore/internal/pkg/hoster/locations"
+	HosterVm "HosterCore/internal/pkg/hoster/vm"
+	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// @Tags High Availability
+// @Summary Take over a resource from an offline parent.
+// @Description Take over a resource from an offline parent: apply the failover strategy (`change_parent` or `cireset`) to the local backup copy, and start it.<br>Calling it for a resource that is already running on this host is a no-op.<br>`AUTH`: Only HA user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess{}
+// @Failure 400 {object} SwaggerError{}
+// @Failure 500 {object} SwaggerError{}
+// @Param Input body CarpUtils.FailoverRequest{} true "Request Payload"
+// @Router /carp-ha/failover [post]
+func CarpFailover(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckHaUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := CarpUtils.FailoverRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch input.ResourceType {
+	case "vm":
+		err = carpFailoverVm(input)
+	case "jail":
+		err = carpFailoverJail(input)
+	default:
+		ReportError(w, http.StatusBadRequest, fmt.Sprintf("unknown resource type: %s", input.ResourceType))
+		return
+	}
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+func carpFailoverVm(input CarpUtils.FailoverRequest) error {
+	info, err := HosterVmUtils.InfoJsonApi(input.ResourceName)
+	if err != nil {
+		return err
+	}
+	if !info.Backup && info.Running {
+		return nil
+	}
+	if info.Running {
+		return fmt.Errorf("backup copy of the VM %s is running, refusing to fail it over", input.ResourceName)
+	}
+
+	strategy := input.FailoverStrategy
+	if len(strategy) < 1 {
+		strategy = info.FailoverStrategy
+	}
+
+	if info.Backup {
+		if strategy == CarpUtils.FAILOVER_CIRESET {
+			// VM cireset is a part of the hoster CLI
+			binary, err := HosterLocations.LocateBinary("hoster")
+			if err != nil {
+				return err
+			}
+			out, err := exec.Command(binary, "vm", "cireset", input.ResourceName).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("%s; %s", strings.TrimSpace(string(out)), err.Error())
+			}
+		} else {
+			err = HosterVm.ChangeParent(input.ResourceName, "", false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return HosterVm.Start(input.ResourceName, false, false)
+}
+
+func carpFailoverJail(input CarpUtils.FailoverRequest) error {
+	info, err := HosterJailUtils.InfoJsonApi(input.ResourceName)
+	if err != nil {
+		return err
+	}
+	if !info.Backup && info.Running {
+		return nil
+	}
+	if info.Running {
+		return fmt.Errorf("backup copy of the Jail %s is running, refusing to fail it over", input.ResourceName)
+	}
+
+	strategy := input.FailoverStrategy
+	if len(strategy) < 1 {
+		strategy = info.FailoverStrategy
+	}
+
+	if info.Backup {
+		if strategy == CarpUtils.FAILOVER_CIRESET {
+			ciReset := HosterJail.CiResetInput{}
+			ciReset.OldJailName = input.ResourceName
+			ciReset.DsParent = info.Simple.Mountpoint
+			ciReset.CpuLimit = info.CPULimitPercent
+			ciReset.RamLimit = info.RAMLimit
+			ciReset.Network = info.Network
+			ciReset.Production = info.Production
+			err = HosterJail.CiReset(ciReset)
+		} else {
+			err = HosterJail.ChangeParent(input.ResourceName, "", false)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return HosterJail.Start(input.ResourceName)
+}
+
+// @Tags High Availability
+// @Summary Rejoin this host to the cluster.
+// @Description Rejoin this host to the cluster after it was away: the resources failed over to the other hosts are stopped and demoted to backups (new parent is set),
+// @Description and the rest of the HA locked (fenced) resources are unlocked. Only the resources which were running at the fencing time are started again.<br>`AUTH`: Only HA user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess{}
+// @Failure 400 {object} SwaggerError{}
+// @Failure 500 {object} SwaggerError{}
+// @Param Input body CarpUtils.RejoinRequest{} true "Request Payload"
+// @Router /carp-ha/rejoin [post]
+func CarpRejoin(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckHaUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := CarpUtils.RejoinRequest{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Demote the stale copies first, so they are not unlocked below
+	errs := []error{}
+	for _, v := range input.Demote {
+		err := carpDemote(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not demote %s %s: %s", v.ResourceType, v.ResourceName, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		ReportError(w, http.StatusInternalServerError, errors.Join(errs...).Error())
+		return
+	}
+
+	// Only the resources which were running when the host fenced itself are started again
+	running := []string{}
+	fenced, err := CarpUtils.ReadFencedState()
+	if err == nil {
+		running = fenced.Running
+	} else if !os.IsNotExist(err) {
+		log.Error("could not read the fencing marker, the unlocked resources will not be started: " + err.Error())
+	}
+
+	vms, err := HosterVm.UnlockAllVms()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jails, err := HosterJail.UnlockAllJails()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = os.Remove(CarpUtils.FENCED_FILE)
+	if err != nil && !os.IsNotExist(err) {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for _, v := range vms {
+		if !slices.Contains(running, HaCore.ResourceKey("vm", v)) {
+			continue
+		}
+		err := HosterVm.Start(v, false, false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not start the VM %s: %s", v, err.Error()))
+		}
+	}
+	for _, v := range jails {
+		if !slices.Contains(running, HaCore.ResourceKey("jail", v)) {
+			continue
+		}
+		err := HosterJail.Start(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not start the Jail %s: %s", v, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		ReportError(w, http.StatusInternalServerError, errors.Join(errs...).Error())
+		return
+	}
+
+	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// Stops the stale copy of the resource (if it's running), and hands it over to the new parent
+func carpDemote(record CarpUtils.FailoverRecord) error {
+	switch record.ResourceType {
+	case "vm":
+		info, err := HosterVmUtils.InfoJsonApi(record.ResourceName)
+		if err != nil {
+			return err
+		}
+		if info.Running {
+			err = HosterVm.Stop(record.ResourceName, true, true)
+			if err != nil {
+				return err
+			}
+		}
+		return HosterVm.ChangeParent(record.ResourceName, record.NewParent, true)
+
+	case "jail":
+		// Jail running status is only reported for the parent (and the stale copy might be HA locked), so always try to stop it
+		err := HosterJail.Stop(record.ResourceName)
+		if err != nil && !strings.Contains(err.Error(), "already offline") {
+			return err
+		}
+		return HosterJail.ChangeParent(record.ResourceName, record.NewParent, true)
+	}
+
+	return fmt.Errorf("unknown resource type: %s", record.ResourceType)
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+//go:build freebsd
+// +build freebsd
+
+package handlers
+
+import (
+	ApiAuth "HosterCore/internal/app/rest_api_v2/pkg/auth"
+	JSONResponse "HosterCore/internal/app/rest_api_v2/pkg/json_response"
+	HosterMigration "HosterCore/internal/pkg/hoster/migration"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type MigrationInput struct {
+	Target       HosterMigration.Target `json:"target"`
+	RemoveSource bool                   `json:"remove_source"`
+}
+
+// @Tags VMs
+// @Summary Migrate the VM to another Hoster node.
+// @Description Migrate the VM to another Hoster node, reached over SSH. The migration runs in the background, use `/migration/unfinished` to follow it.<br>Call it again with the same target to resume the interrupted migration, `409` is returned while the migration is still running.<br>`AUTH`: Only `rest` user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess
+// @Failure 409 {object} SwaggerError
+// @Failure 500 {object} SwaggerError
+// @Param vm_name path string true "VM Name"
+// @Param Input body MigrationInput true "Request payload"
+// @Router /vm/migrate/{vm_name} [post]
+func VmPostMigrate(w http.ResponseWriter, r *http.Request) {
+	postMigrate(w, r, HosterMigration.RESOURCE_VM, mux.Vars(r)["vm_name"])
+}
+
+// @Tags Jails
+// @Summary Migrate the Jail to another Hoster node.
+// @Description Migrate the Jail to another Hoster node, reached over SSH. The migration runs in the background, use `/migration/unfinished` to follow it.<br>Call it again with the same target to resume the interrupted migration, `409` is returned while the migration is still running.<br>`AUTH`: Only `rest` user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess
+// @Failure 409 {object} SwaggerError
+// @Failure 500 {object} SwaggerError
+// @Param jail_name path string true "Jail Name"
+// @Param Input body MigrationInput true "Request payload"
+// @Router /jail/migrate/{jail_name} [post]
+func JailPostMigrate(w http.ResponseWriter, r *http.Request) {
+	postMigrate(w, r, HosterMigration.RESOURCE_JAIL, mux.Vars(r)["jail_name"])
+}
+
+func postMigrate(w http.ResponseWriter, r *http.Request, resType string, resName string) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := MigrationInput{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(input.Target.Endpoint) < 1 {
+		ReportError(w, http.StatusBadRequest, "target endpoint must be set")
+		return
+	}
+
+	// The lock is taken here, so the conflict is reported before the migration goes to the background
+	lock, err := HosterMigration.LockState(resType, resName)
+	if err != nil {
+		if errors.Is(err, HosterMigration.ErrMigrationInProgress) {
+			ReportError(w, http.StatusConflict, err.Error())
+			return
+		}
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	go func(input MigrationInput) {
+		_, err := HosterMigration.Migrate(resType, resName, input.Target, HosterMigration.Options{RemoveSource: input.RemoveSource, Lock: lock})
+		if err != nil {
+			log.Error("could not migrate " + resType + " " + resName + ": " + err.Error())
+		}
+	}(input)
+
+	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// @Tags VMs, Jails
+// @Summary List the unfinished migrations.
+// @Description List the migrations which are still running, or have been interrupted (including the completed phases and their timing).<br>`AUTH`: Both users are allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} []HosterMigration.State
+// @Failure 500 {object} SwaggerError
+// @Router /migration/unfinished [get]
+func MigrationListUnfinished(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckAnyUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	states, err := HosterMigration.ListUnfinished()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	payload, err := json.Marshal(states)
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
@@ -79,7 +79,3 @@ type JailNetworkInput struct {
 type ZfsDatasetInput struct {
 	Dataset string `json:"dataset"`
 }
-
-type WireGuardScriptInput struct {
-	Script string `json:"script"`
-}
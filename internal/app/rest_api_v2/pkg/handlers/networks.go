@@ -3,6 +3,7 @@ package handlers
 import (
 	ApiAuth "HosterCore/internal/app/rest_api_v2/pkg/auth"
 	JSONResponse "HosterCore/internal/app/rest_api_v2/pkg/json_response"
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
 	HosterNetwork "HosterCore/internal/pkg/hoster/network"
 	"encoding/json"
 	"net/http"
@@ -45,6 +46,7 @@ func NetworkList(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security BasicAuth
 // @Success 200 {object} SwaggerSuccess
+// @Failure 400 {object} SwaggerError
 // @Failure 500 {object} SwaggerError
 // @Param Input body []HosterNetwork.NetworkConfig true "Request Payload"
 // @Router /network/add-new-network [post]
@@ -79,6 +81,12 @@ func PostNewNetwork(w http.ResponseWriter, r *http.Request) {
 	}
 
 	netConf = append(netConf, input)
+	err = HosterNetwork.ValidateNetworkConfig(netConf)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	err = HosterNetwork.SaveNetworkConfig(netConf)
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
@@ -94,3 +102,113 @@ func PostNewNetwork(w http.ResponseWriter, r *http.Request) {
 	SetStatusCode(w, http.StatusOK)
 	w.Write(payload)
 }
+
+// @Tags Networks
+// @Summary Get the port forwards list.
+// @Description Get the port forwards list.<br>`AUTH`: Only REST user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} []HosterNetwork.PortForward
+// @Failure 500 {object} SwaggerError
+// @Router /network/forward/all [get]
+func NetworkForwardList(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	forwards, err := HosterNetwork.GetPortForwards()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if forwards == nil {
+		forwards = []HosterNetwork.PortForward{}
+	}
+
+	payload, err := json.Marshal(forwards)
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// @Tags Networks
+// @Summary Add a new port forward.
+// @Description Add a new port forward to a VM or Jail. The forward follows the VM or Jail if it's IP address changes.<br>`AUTH`: Only REST user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess
+// @Failure 500 {object} SwaggerError
+// @Param Input body HosterNetwork.PortForward true "Request Payload"
+// @Router /network/forward/add [post]
+func PostNetworkForward(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := HosterNetwork.PortForward{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = HosterHostUtils.AddPortForward(input)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+type PortForwardDeleteInput struct {
+	Protocol   string `json:"protocol"` // tcp (default) or udp
+	PublicPort int    `json:"public_port"`
+	Interface  string `json:"interface,omitempty"` // interface the forward was created with, empty for the forwards without one
+}
+
+// @Tags Networks
+// @Summary Delete a port forward.
+// @Description Delete a port forward using it's public port.<br>`AUTH`: Only REST user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess
+// @Failure 500 {object} SwaggerError
+// @Param Input body PortForwardDeleteInput true "Request Payload"
+// @Router /network/forward/delete [delete]
+func DeleteNetworkForward(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := PortForwardDeleteInput{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = HosterHostUtils.RemovePortForward(input.Protocol, input.PublicPort, input.Interface)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
@@ -10,19 +10,57 @@ import (
 	FreeBSDPgrep "HosterCore/internal/pkg/freebsd/pgrep"
 	"HosterCore/internal/pkg/freebsd/rctl"
 	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
 	"encoding/json"
 	"net/http"
 	"regexp"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+const IFACE_STATS_INTERVAL = 10 * time.Second
+
+var ifaceStatsCollector = HosterNetwork.NewIfaceStatsCollector(IFACE_STATS_INTERVAL)
+
+// Starts the periodic VM/Jail network interface counters collection (used to calculate the transfer rates)
+func StartIfaceStatsCollector() {
+	go ifaceStatsCollector.Run()
+}
+
+type ResourceMetrics struct {
+	rctl.RctMetrics
+	Network []HosterNetwork.IfaceStats `json:"network"`
+}
+
+// Returns the network interface counters for the resource, falls back to a direct collection if the
+// periodic collector has no data for it yet (e.g. it was just started).
+func resourceIfaceStats(resourceType string, resourceName string) (r []HosterNetwork.IfaceStats) {
+	r = ifaceStatsCollector.Get(resourceType, resourceName)
+	if len(r) > 0 {
+		return
+	}
+
+	stats, err := HosterNetwork.GetIfaceStats()
+	if err != nil {
+		log.Error("could not collect the network interface stats: " + err.Error())
+		return []HosterNetwork.IfaceStats{}
+	}
+	r = []HosterNetwork.IfaceStats{}
+	for _, v := range stats {
+		if v.ResourceType == resourceType && v.ResourceName == resourceName {
+			r = append(r, v)
+		}
+	}
+	return
+}
+
 // @Tags Metrics, VMs
-// @Summary Get the RCTL metrics for a specific VM.
-// @Description Get the RCTL metrics for a specific VM.<br>`AUTH`: Only `rest` user is allowed.
+// @Summary Get the RCTL and network metrics for a specific VM.
+// @Description Get the RCTL and network interface metrics for a specific VM.<br>`AUTH`: Only `rest` user is allowed.
 // @Produce json
 // @Security BasicAuth
-// @Success 200 {object} rctl.RctMetrics
+// @Success 200 {object} ResourceMetrics
 // @Failure 500 {object} SwaggerError
 // @Param vm_name path string true "VM Name"
 // @Router /metrics/vm/{vm_name} [get]
@@ -59,8 +97,9 @@ func VmMetrics(w http.ResponseWriter, r *http.Request) {
 		ReportError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	metrics := ResourceMetrics{RctMetrics: rctl, Network: resourceIfaceStats("vm", vmName)}
 
-	payload, err := json.Marshal(rctl)
+	payload, err := json.Marshal(metrics)
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -71,11 +110,11 @@ func VmMetrics(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Tags Metrics, Jails
-// @Summary Get the RCTL metrics for a specific Jail.
-// @Description Get the RCTL metrics for a specific Jail.<br>`AUTH`: Only `rest` user is allowed.
+// @Summary Get the RCTL and network metrics for a specific Jail.
+// @Description Get the RCTL and network interface metrics for a specific Jail.<br>`AUTH`: Only `rest` user is allowed.
 // @Produce json
 // @Security BasicAuth
-// @Success 200 {object} rctl.RctMetrics
+// @Success 200 {object} ResourceMetrics
 // @Failure 500 {object} SwaggerError
 // @Param jail_name path string true "Jail Name"
 // @Router /metrics/jail/{jail_name} [get]
@@ -111,8 +150,9 @@ func JailMetrics(w http.ResponseWriter, r *http.Request) {
 		ReportError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	metrics := ResourceMetrics{RctMetrics: rctl, Network: resourceIfaceStats("jail", jailName)}
 
-	payload, err := json.Marshal(rctl)
+	payload, err := json.Marshal(metrics)
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -1027,6 +1027,7 @@ func VmPostExpandDisk(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security BasicAuth
 // @Success 200 {object} SwaggerSuccess
+// @Failure 400 {object} SwaggerError
 // @Failure 500 {object} SwaggerError
 // @Param vm_name path string true "Name of the VM"
 // @Param Input body HosterVmUtils.VmNetwork{} true "Request payload"
@@ -1049,6 +1050,15 @@ func VmPostAddNewNetwork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An empty MAC address is allocated automatically
+	if len(input.NetworkMac) > 0 {
+		err = HosterVmUtils.CheckMacAddress(input.NetworkMac, "")
+		if err != nil {
+			ReportError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	err = HosterVm.AddNewVmNetwork(vmName, input)
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
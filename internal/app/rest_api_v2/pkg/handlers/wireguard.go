@@ -3,61 +3,208 @@ package handlers
 import (
 	ApiAuth "HosterCore/internal/app/rest_api_v2/pkg/auth"
 	JSONResponse "HosterCore/internal/app/rest_api_v2/pkg/json_response"
-	"bytes"
+	HosterWireGuard "HosterCore/internal/pkg/hoster/wireguard"
+	"encoding/json"
 	"net/http"
-	"os"
-	"os/exec"
-
-	"github.com/google/uuid"
 )
 
 // @Tags WireGuard
-// @Summary Accept any arbitrary bash script that brings up the WG interfaces.
-// @Description Accept any arbitrary bash script that brings up the WG interfaces.<br>`AUTH`: Only REST user is allowed.
+// @Summary Get the public WireGuard info of this node.
+// @Description Get the public WireGuard info of this node, which can be added as a peer on the other nodes.<br>`AUTH`: Only REST user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} HosterWireGuard.Peer
+// @Failure 500 {object} SwaggerError
+// @Router /wireguard/info [get]
+func WireGuardInfo(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	conf, err := HosterWireGuard.GetConfig()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	self, err := conf.Self()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	payload, err := json.Marshal(self)
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// @Tags WireGuard
+// @Summary Get the WireGuard peer inventory.
+// @Description Get the WireGuard peer inventory.<br>`AUTH`: Only REST user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} []HosterWireGuard.Peer
+// @Failure 500 {object} SwaggerError
+// @Router /wireguard/peer/all [get]
+func WireGuardPeerList(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	conf, err := HosterWireGuard.GetConfig()
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if conf.Peers == nil {
+		conf.Peers = []HosterWireGuard.Peer{}
+	}
+
+	payload, err := json.Marshal(conf.Peers)
+	if err != nil {
+		ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// @Tags WireGuard
+// @Summary Replace the WireGuard peer inventory.
+// @Description Replace the whole WireGuard peer inventory, and apply the updated mesh config.<br>The same inventory can be pushed to every node: each node only connects to the peers in it's own cluster group, and skips itself.<br>`AUTH`: Only REST user is allowed.
 // @Produce json
 // @Security BasicAuth
 // @Success 200 {object} SwaggerSuccess
+// @Failure 400 {object} SwaggerError
 // @Failure 500 {object} SwaggerError
-// @Accept plain/text
-// @Param Input body string true "Request payload"
-// @Router /wireguard/script [post]
-func WireGuardScript(w http.ResponseWriter, r *http.Request) {
+// @Param Input body []HosterWireGuard.Peer true "Request Payload"
+// @Router /wireguard/peer/sync [post]
+func WireGuardPeerSync(w http.ResponseWriter, r *http.Request) {
 	if !ApiAuth.CheckRestUser(r) {
 		user, pass, _ := r.BasicAuth()
 		UnauthenticatedResponse(w, user, pass)
 		return
 	}
 
-	// Limit the size of the script to avoid potential abuse
-	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // 10MB limit
-	// Read the script from the request body
-	scriptBuf := new(bytes.Buffer)
-	_, err := scriptBuf.ReadFrom(r.Body)
+	input := []HosterWireGuard.Peer{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
 	if err != nil {
-		ReportError(w, http.StatusBadRequest, "Failed to read script: "+err.Error())
+		ReportError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	script := scriptBuf.String()
 
-	uniqueId := uuid.New().String()
-	scriptLoc := "/tmp/hoster_wg_" + uniqueId + ".sh"
+	err = HosterWireGuard.ValidatePeers(input)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	err = os.WriteFile(scriptLoc, []byte(script), 0644)
+	err = HosterWireGuard.UpdatePeers(func(c *HosterWireGuard.Config) error {
+		c.Peers = input
+		return nil
+	})
 	if err != nil {
 		ReportError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	go func(script string, scriptLoc string) {
-		defer os.Remove(scriptLoc)
+	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// @Tags WireGuard
+// @Summary Add or update a WireGuard peer.
+// @Description Add a WireGuard peer to the inventory (or update the existing one with the same name and group), and apply the updated mesh config.<br>`AUTH`: Only REST user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess
+// @Failure 400 {object} SwaggerError
+// @Failure 500 {object} SwaggerError
+// @Param Input body HosterWireGuard.Peer true "Request Payload"
+// @Router /wireguard/peer/add [post]
+func PostWireGuardPeer(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := HosterWireGuard.Peer{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = HosterWireGuard.ValidatePeers([]HosterWireGuard.Peer{input})
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = HosterWireGuard.UpdatePeers(func(c *HosterWireGuard.Config) error {
+		c.SetPeer(input)
+		return HosterWireGuard.ValidatePeers(c.Peers)
+	})
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
+	SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+type WireGuardPeerDeleteInput struct {
+	Name  string `json:"name"`
+	Group string `json:"group,omitempty"`
+}
 
-		// out, err := exec.Command("bash", scriptLoc).CombinedOutput()
-		_, err := exec.Command("bash", scriptLoc).CombinedOutput()
-		if err != nil {
-			// errStr := strings.TrimSpace(string(out)) + "; " + err.Error()
-			return
-		}
-	}(script, scriptLoc)
+// @Tags WireGuard
+// @Summary Delete a WireGuard peer.
+// @Description Delete a WireGuard peer from the inventory, and apply the updated mesh config.<br>`AUTH`: Only REST user is allowed.
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} SwaggerSuccess
+// @Failure 400 {object} SwaggerError
+// @Param Input body WireGuardPeerDeleteInput true "Request Payload"
+// @Router /wireguard/peer/delete [delete]
+func DeleteWireGuardPeer(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckRestUser(r) {
+		user, pass, _ := r.BasicAuth()
+		UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	input := WireGuardPeerDeleteInput{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&input)
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = HosterWireGuard.UpdatePeers(func(c *HosterWireGuard.Config) error {
+		return c.RemovePeer(input.Name, input.Group)
+	})
+	if err != nil {
+		ReportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	payload, _ := JSONResponse.GenerateJson(w, "message", "success")
 	SetStatusCode(w, http.StatusOK)
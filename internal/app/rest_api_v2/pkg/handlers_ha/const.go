@@ -1,3 +1,16 @@
 package HandlersHA
 
+import RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
+
 const HA_LOG_LOCATION = "/var/log/hoster_ha.log"
+
+const HA_MIN_FAILOVER_TIME = RestApiConfig.HA_MIN_FAILOVER_TIME
+
+// Manager election: the leader lease must outlive a few missed heartbeats (sent every tick),
+// and the election timeout is longer than the lease, so the followers never campaign against a live leader.
+const (
+	HA_ELECTION_TICK         = 2
+	HA_ELECTION_LEASE        = 10
+	HA_ELECTION_TIMEOUT      = 12
+	HA_ELECTION_CALL_TIMEOUT = 3
+)
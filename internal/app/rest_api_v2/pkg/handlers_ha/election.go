@@ -0,0 +1,143 @@
+//go:build freebsd
+// +build freebsd
+
+package HandlersHA
+
+import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
+	FileExists "HosterCore/internal/pkg/file_exists"
+	HaElection "HosterCore/internal/pkg/hoster/ha/election"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Only set on the candidate nodes
+var electionNode *HaElection.Node
+
+var electionClient = &http.Client{Timeout: HA_ELECTION_CALL_TIMEOUT * time.Second}
+
+// Delivers the election messages to the other candidates over the regular /api/v2/ha/ping
+type pingTransport struct{}
+
+func (pingTransport) Send(member string, msg HaElection.Message) (r HaElection.Reply, e error) {
+	candidate := RestApiConfig.HaNode{}
+	candidateFound := false
+	for _, v := range haConf.Candidates {
+		if v.Hostname == member {
+			candidate = v
+			candidateFound = true
+			break
+		}
+	}
+	if !candidateFound {
+		e = errors.New("unknown candidate " + member)
+		return
+	}
+
+	host := RestApiConfig.HaNode{}
+	host.Hostname = myHostname
+	host.StartupTime = haConf.StartupTime
+	host.Maintenance = FileExists.CheckUsingOsStat(CarpUtils.MAINTENANCE_FILE)
+	host.Election = &msg
+
+	jsonPayload, err := json.Marshal(host)
+	if err != nil {
+		e = err
+		return
+	}
+
+	url := candidate.Protocol + "://" + candidate.Address + ":" + candidate.Port + "/api/v2/ha/ping"
+	req, err := http.NewRequest("POST", url, strings.NewReader(string(jsonPayload)))
+	if err != nil {
+		e = err
+		return
+	}
+
+	auth := candidate.User + ":" + candidate.Password
+	authEncoded := base64.StdEncoding.EncodeToString([]byte(auth))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Basic "+authEncoded)
+
+	res, err := electionClient.Do(req)
+	if err != nil {
+		e = err
+		return
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		e = err
+		return
+	}
+	if res.StatusCode != http.StatusOK {
+		e = fmt.Errorf("candidate %s has responded with %d: %s", member, res.StatusCode, string(body))
+		return
+	}
+
+	pong := PingResponse{}
+	err = json.Unmarshal(body, &pong)
+	if err != nil {
+		e = err
+		return
+	}
+	if pong.Election == nil {
+		e = errors.New("candidate " + member + " did not take part in the election")
+		return
+	}
+
+	r = *pong.Election
+	return
+}
+
+func initElection() (e error) {
+	members := []string{}
+	for _, v := range haConf.Candidates {
+		members = append(members, v.Hostname)
+	}
+
+	electionNode, e = HaElection.NewNode(HaElection.Config{
+		NodeName:        myHostname,
+		Members:         members,
+		LeaseDuration:   HA_ELECTION_LEASE * time.Second,
+		ElectionTimeout: HA_ELECTION_TIMEOUT * time.Second,
+	}, pingTransport{})
+
+	return
+}
+
+// Runs the manager election: only the leader holding a valid lease (acknowledged by the majority of the candidates) acts as the cluster manager
+func trackManager() {
+	defer func() {
+		if r := recover(); r != nil {
+			errorValue := fmt.Sprintf("%s", r)
+			internalLog.Warnf("PANIC AVOIDED: trackManager() %s", errorValue)
+		}
+	}()
+
+	for {
+		electionNode.Tick()
+		status := electionNode.Status()
+
+		if electionNode.IsLeader() {
+			if !iAmManager {
+				internalLog.Infof("becoming a new cluster manager, term %d", status.Term)
+				iAmManager = true
+			}
+		} else {
+			if iAmManager {
+				internalLog.Warnf("stepping down as a cluster manager, term %d, current state %s", status.Term, status.State)
+				iAmManager = false
+			}
+		}
+
+		time.Sleep(time.Second * HA_ELECTION_TICK)
+	}
+}
@@ -0,0 +1,186 @@
+//go:build freebsd
+// +build freebsd
+
+package HandlersHA
+
+import (
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Static candidates: the manager elected among the candidates from ha_config.json fails over the VMs, as long as it holds the lease
+type staticBackend struct{}
+
+func (staticBackend) Name() string {
+	return "static"
+}
+
+func (staticBackend) IsLeader() bool {
+	return iAmManager && electionNode != nil && electionNode.IsLeader()
+}
+
+func (staticBackend) Nodes() ([]HaCore.Node, error) {
+	nodes, _ := collectMembership()
+	return nodes, nil
+}
+
+func (staticBackend) Copies() ([]HaCore.Copy, error) {
+	_, copies := collectMembership()
+	return copies, nil
+}
+
+// Checks if the node has missed the pings for longer than it's failover time
+func nodeOffline(node HosterHaNode) bool {
+	// Nodes first seen through a ping don't report their failover time
+	failOverTime := node.NodeInfo.FailOverTime
+	if failOverTime < HA_MIN_FAILOVER_TIME {
+		failOverTime = HA_MIN_FAILOVER_TIME
+	}
+	return time.Now().Unix() > node.LastPing+failOverTime
+}
+
+// Builds the membership from the hosts db: the offline nodes are flagged, and the VM lists of the online ones
+// provide both their load and the VM copies they hold
+func collectMembership() (nodes []HaCore.Node, copies []HaCore.Copy) {
+	for _, v := range readHostsDb(&hostsDbLock) {
+		// Backup hosts participate purely for quorum purposes, and the nodes in maintenance are not taking any new VMs
+		node := HaCore.Node{
+			Name:         v.NodeInfo.Hostname,
+			Offline:      nodeOffline(v),
+			MaxResources: v.NodeInfo.MaxResources,
+			Backup:       v.NodeInfo.BackupNode,
+			Maintenance:  v.NodeInfo.Maintenance,
+		}
+		if node.Offline || node.Backup || node.Maintenance {
+			nodes = append(nodes, node)
+			continue
+		}
+
+		haVms, err := haNodeVms(v)
+		if err != nil {
+			internalLog.Error("could not get the VM list from ::" + v.NodeInfo.Hostname + ":: -> " + err.Error())
+			continue
+		}
+
+		for _, vv := range haVms {
+			if vv.Live && vv.ParentHost == vv.CurrentHost {
+				node.Resources += 1
+				if vv.Ha != nil && len(vv.Ha.AntiAffinityGroup) > 0 {
+					node.Groups = append(node.Groups, vv.Ha.AntiAffinityGroup)
+				}
+			}
+
+			copies = append(copies, HaCore.Copy{
+				ResourceType:     "vm",
+				ResourceName:     vv.VmName,
+				Holder:           vv.CurrentHost,
+				Parent:           vv.ParentHost,
+				Snapshot:         vv.LatestSnapshot,
+				FailoverStrategy: v.NodeInfo.FailOverStrategy,
+				Ha:               vv.Ha,
+			})
+		}
+		nodes = append(nodes, node)
+	}
+
+	return
+}
+
+func haNodeVms(node HosterHaNode) (r []HaVm, e error) {
+	url := node.NodeInfo.Protocol + "://" + node.NodeInfo.Address + ":" + node.NodeInfo.Port + "/api/v2/ha/vm-list"
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		e = err
+		return
+	}
+
+	auth := node.NodeInfo.User + ":" + node.NodeInfo.Password
+	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		e = err
+		return
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		e = err
+		return
+	}
+
+	e = json.Unmarshal(body, &r)
+	return
+}
+
+// The offline node is removed from the hosts db right after the failover, so there is nothing to retry
+var failoverExecutor = HaCore.NewExecutor(staticBackend{}, executeFailover, 1, 100)
+
+// Promotes the VM copy on the target node (using the node's failover strategy), and starts it there
+func executeFailover(plan HaCore.Plan, request HaCore.Request) error {
+	if restConf.HaDebug {
+		internalLog.Warnf("failing over a VM ::%s:: from an offline host ::%s:: to ::%s::", request.ResourceName, request.OldParent, plan.Target)
+		return errors.New("HA debug mode is on, the failover has only been logged")
+	}
+
+	for _, v := range readHostsDb(&hostsDbLock) {
+		if v.NodeInfo.Hostname != plan.Target {
+			continue
+		}
+
+		time.Sleep(1500 * time.Millisecond)
+		internalLog.Warnf("failing over a VM ::%s:: from an offline host ::%s:: to ::%s::", request.ResourceName, request.OldParent, plan.Target)
+
+		switch request.FailoverStrategy {
+		case "cireset", "ci-reset":
+			err := postToHaNode(v, "/api/v2/vm/cireset", request.ResourceName)
+			if err != nil {
+				return fmt.Errorf("cireset call failed: %s", err.Error())
+			}
+		case "changeparent", "change-parent":
+			err := postToHaNode(v, "/api/v2/vm/change-parent", request.ResourceName)
+			if err != nil {
+				return fmt.Errorf("change parent call failed: %s", err.Error())
+			}
+		}
+
+		err := postToHaNode(v, "/api/v1/vm/start", request.ResourceName)
+		if err != nil {
+			return fmt.Errorf("start call failed: %s", err.Error())
+		}
+		return nil
+	}
+
+	return fmt.Errorf("host %s is not a member of the cluster", plan.Target)
+}
+
+func postToHaNode(node HosterHaNode, path string, vmName string) error {
+	url := node.NodeInfo.Protocol + "://" + node.NodeInfo.Address + ":" + node.NodeInfo.Port + path
+	payload := strings.NewReader(`{ "name": "` + vmName + `" }`)
+	req, err := http.NewRequest("POST", url, payload)
+	if err != nil {
+		return err
+	}
+
+	auth := node.NodeInfo.User + ":" + node.NodeInfo.Password
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("status code %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	ErrorMappings "HosterCore/internal/app/rest_api_v2/pkg/error_mappings"
 	"HosterCore/internal/app/rest_api_v2/pkg/handlers"
 	JSONResponse "HosterCore/internal/app/rest_api_v2/pkg/json_response"
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
 	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
 	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
 	zfsutils "HosterCore/internal/pkg/zfs_utils"
@@ -69,9 +70,9 @@ func HandleRegistration(w http.ResponseWriter, r *http.Request) {
 
 // @Tags HA
 // @Summary Handle the HA node ping.
-// @Description Handle the HA node ping.
+// @Description Handle the HA node ping. Manager candidates also use it to run the manager election.
 // @Produce json
-// @Success 200 {object} handlers.SwaggerSuccess
+// @Success 200 {object} PingResponse
 // @Failure 500 {object} handlers.SwaggerError
 // @Security BasicAuth
 // @Param Input body RestApiConfig.HaNode true "Request payload"
@@ -99,14 +100,106 @@ func HandlePing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The election message is sent on behalf of the pinging node, it can't ask for the votes (or lead) for someone else
+	if input.Election != nil && input.Election.From != input.Hostname {
+		internalLog.Warnf("rejecting the election message from ::%s:: sent on behalf of ::%s::", input.Hostname, input.Election.From)
+		handlers.ReportError(w, http.StatusBadRequest, "election message sender doesn't match the hostname")
+		return
+	}
+
 	hosterHaNode := HosterHaNode{}
 	hosterHaNode.NodeInfo = input
 	hosterHaNode.NodeInfo.Address = r.RemoteAddr
 	hosterHaNode.LastPing = time.Now().Unix()
 
+	// The election message is not a part of the node info
+	hosterHaNode.NodeInfo.Election = nil
 	modifyHostsDb(ModifyHostsDb{AddOrUpdate: true, Data: hosterHaNode}, &hostsDbLock)
 
-	payload, _ := JSONResponse.GenerateJson(w, "message", "pong")
+	pong := PingResponse{Message: "pong"}
+	if electionNode != nil {
+		electionNode.Seen(input.Hostname)
+		if input.Election != nil {
+			reply := electionNode.Handle(*input.Election)
+			pong.Election = &reply
+		}
+	} else if input.Election != nil {
+		handlers.ReportError(w, http.StatusBadRequest, "this node is not a manager candidate")
+		return
+	}
+
+	payload, err := json.Marshal(pong)
+	if err != nil {
+		handlers.ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	handlers.SetStatusCode(w, http.StatusOK)
+	w.Write(payload)
+}
+
+// @Tags HA
+// @Summary Report the HA cluster status.
+// @Description Report the HA cluster status: manager election term, current leader, and the health of the cluster members.
+// @Produce json
+// @Success 200 {object} HaStatus
+// @Failure 500 {object} handlers.SwaggerError
+// @Security BasicAuth
+// @Router /ha/status [get]
+func HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if !ApiAuth.CheckAnyUser(r) {
+		user, pass, _ := r.BasicAuth()
+		handlers.UnauthenticatedResponse(w, user, pass)
+		return
+	}
+
+	status := HaStatus{}
+	status.Hostname = myHostname
+	status.Manager = iAmManager
+	status.ClusterInitialized = clusterInitialized
+	status.CandidatesRegistered = candidatesRegistered
+	status.Backend = failoverExecutor.Backend().Name()
+	status.Failovers = failoverExecutor.History()
+	if electionNode != nil {
+		status.Candidate = true
+		election := electionNode.Status()
+		status.Election = &election
+	}
+
+	now := time.Now().Unix()
+	status.Nodes = []HaNodeStatus{}
+	for _, v := range readHostsDb(&hostsDbLock) {
+		node := HaNodeStatus{}
+		node.Hostname = v.NodeInfo.Hostname
+		node.Address = v.NodeInfo.Address
+		node.BackupNode = v.NodeInfo.BackupNode
+		node.FailOverTime = v.NodeInfo.FailOverTime
+		node.LastPing = v.LastPing
+		node.Maintenance = v.NodeInfo.Maintenance
+		for _, vv := range haConf.Candidates {
+			if vv.Hostname == v.NodeInfo.Hostname {
+				node.Candidate = true
+				break
+			}
+		}
+
+		failOverTime := node.FailOverTime
+		if failOverTime < HA_MIN_FAILOVER_TIME {
+			failOverTime = HA_MIN_FAILOVER_TIME
+		}
+		node.Online = now <= v.LastPing+failOverTime
+
+		status.Nodes = append(status.Nodes, node)
+	}
+
+	payload, err := json.Marshal(status)
+	if err != nil {
+		handlers.ReportError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	handlers.SetStatusCode(w, http.StatusOK)
 	w.Write(payload)
 }
@@ -146,11 +239,12 @@ func HandleTerminate(w http.ResponseWriter, r *http.Request) {
 }
 
 type HaVm struct {
-	VmName         string `json:"vm_name"`
-	Live           bool   `json:"live"`
-	LatestSnapshot string `json:"latest_snapshot"`
-	ParentHost     string `json:"parent_host"`
-	CurrentHost    string `json:"current_host"`
+	VmName         string                `json:"vm_name"`
+	Live           bool                  `json:"live"`
+	LatestSnapshot string                `json:"latest_snapshot"`
+	ParentHost     string                `json:"parent_host"`
+	CurrentHost    string                `json:"current_host"`
+	Ha             *HaPlacement.Settings `json:"ha,omitempty"`
 }
 
 // @Tags HA
@@ -199,6 +293,7 @@ func HandleVmList(w http.ResponseWriter, r *http.Request) {
 		temp.Live = v.Running
 		temp.ParentHost = v.ParentHost
 		temp.CurrentHost = v.CurrentHost
+		temp.Ha = v.Ha
 
 		tempSnaps := []zfsutils.SnapshotInfo{}
 		for _, vv := range snaps {
@@ -222,11 +317,12 @@ func HandleVmList(w http.ResponseWriter, r *http.Request) {
 }
 
 type HaJail struct {
-	VmName         string `json:"jail_name"`
-	Live           bool   `json:"live"`
-	LatestSnapshot string `json:"latest_snapshot"`
-	ParentHost     string `json:"parent_host"`
-	CurrentHost    string `json:"current_host"`
+	VmName         string                `json:"jail_name"`
+	Live           bool                  `json:"live"`
+	LatestSnapshot string                `json:"latest_snapshot"`
+	ParentHost     string                `json:"parent_host"`
+	CurrentHost    string                `json:"current_host"`
+	Ha             *HaPlacement.Settings `json:"ha,omitempty"`
 }
 
 // @Tags HA
@@ -274,6 +370,7 @@ func HandleJailList(w http.ResponseWriter, r *http.Request) {
 		temp.Live = v.Running
 		temp.ParentHost = v.Parent
 		temp.CurrentHost = v.CurrentHost
+		temp.Ha = v.Ha
 
 		tempSnaps := []zfsutils.SnapshotInfo{}
 		for _, vv := range snaps {
@@ -101,8 +101,9 @@ func init() {
 		internalLog.Panic(err)
 	}
 
-	if haConf.FailOverTime < 10 {
-		haConf.FailOverTime = 60
+	if haConf.FailOverTime < HA_MIN_FAILOVER_TIME {
+		internalLog.Warnf("node failover time of %d seconds is shorter than the self-fencing time, using %d seconds instead", haConf.FailOverTime, HA_MIN_FAILOVER_TIME)
+		haConf.FailOverTime = HA_MIN_FAILOVER_TIME
 	}
 	// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "INFO: node failover time is: "+strconv.Itoa(int(haConf.FailOverTime))+" seconds").Run()
 	internalLog.Infof("node failover time is %d seconds", haConf.FailOverTime)
@@ -118,6 +119,10 @@ func init() {
 	for _, v := range haConf.Candidates {
 		hostname, _ := FreeBSDsysctls.SysctlKernHostname()
 		if v.Hostname == hostname {
+			err := initElection()
+			if err != nil {
+				internalLog.Panicf("could not initialize the manager election: %s", err.Error())
+			}
 			go trackManager()
 			go removeOfflineNodes()
 		}
@@ -4,18 +4,20 @@
 package HandlersHA
 
 import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
 	RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
+	FileExists "HosterCore/internal/pkg/file_exists"
+	FreeBSDPgrep "HosterCore/internal/pkg/freebsd/pgrep"
 	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -46,9 +48,12 @@ func trackCandidatesOnline() {
 			clusterInitialized = true
 		}
 
-		if clusterInitialized && candidatesRegistered < 2 {
+		// Same majority the manager election needs, so the isolated node is fenced, and the rest can keep the manager
+		if clusterInitialized && candidatesRegistered*2 <= len(haConf.Candidates) {
 			// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "EMERG: candidatesRegistered has gone below 2, initiating self fencing").Run()
-			internalLog.Warn("number of manager nodes (candidatesRegistered) has gone below 2, initiating self fencing")
+			internalLog.Warnf("number of manager nodes (candidatesRegistered) has gone down to %d out of %d, initiating self fencing", candidatesRegistered, len(haConf.Candidates))
+			// Otherwise the watchdog would try to restart the API first
+			requestWatchdogFencing()
 			os.Exit(0)
 		}
 
@@ -56,58 +61,6 @@ func trackCandidatesOnline() {
 	}
 }
 
-func trackManager() {
-	defer func() {
-		if r := recover(); r != nil {
-			errorValue := fmt.Sprintf("%s", r)
-			// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "PANIC AVOIDED: trackManager(): "+errorValue).Run()
-			internalLog.Warnf("PANIC AVOIDED: trackManager() %s", errorValue)
-		}
-	}()
-
-	for {
-		if clusterInitialized {
-			var copyHostsDb = readHostsDb(&hostsDbLock)
-			if len(copyHostsDb) < 2 {
-				continue
-			}
-
-			var filteredCandidates []HosterHaNode
-
-			sort.Slice(copyHostsDb, func(i int, j int) bool {
-				return copyHostsDb[i].NodeInfo.StartupTime < copyHostsDb[j].NodeInfo.StartupTime
-			})
-
-			for _, host := range copyHostsDb {
-				for _, candidate := range haConf.Candidates {
-					if host.NodeInfo.Hostname == candidate.Hostname {
-						if candidate.Registered {
-							filteredCandidates = append(filteredCandidates, host)
-						}
-						break
-					}
-				}
-			}
-
-			if filteredCandidates[0].NodeInfo.Hostname == myHostname {
-				if !iAmManager {
-					// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "INFO: becoming a new cluster manager").Run()
-					internalLog.Info("becoming a new cluster manager")
-					iAmManager = true
-				}
-			} else {
-				if iAmManager {
-					// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "WARN: stepping down as a cluster manager").Run()
-					internalLog.Warn("stepping down as a cluster manager")
-					iAmManager = false
-				}
-			}
-
-			time.Sleep(time.Second * 4)
-		}
-	}
-}
-
 func registerNode() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -153,6 +106,8 @@ func registerNode() {
 			host.FailOverTime = haConf.FailOverTime
 			host.StartupTime = haConf.StartupTime
 			host.BackupNode = haConf.BackupNode
+			host.MaxResources = haConf.MaxResources
+			host.Maintenance = FileExists.CheckUsingOsStat(CarpUtils.MAINTENANCE_FILE)
 
 			jsonPayload, _ := json.Marshal(host)
 			payload := strings.NewReader(string(jsonPayload))
@@ -226,6 +181,7 @@ func sendPing() {
 				host := RestApiConfig.HaNode{}
 				host.Hostname, _ = FreeBSDsysctls.SysctlKernHostname()
 				host.StartupTime = haConf.StartupTime
+				host.Maintenance = FileExists.CheckUsingOsStat(CarpUtils.MAINTENANCE_FILE)
 
 				jsonPayload, err := json.Marshal(host)
 				if err != nil {
@@ -283,9 +239,13 @@ func removeOfflineNodes() {
 	for {
 		hostsDbCopy := readHostsDb(&hostsDbLock)
 		for _, v := range hostsDbCopy {
-			if time.Now().Unix() > v.LastPing+v.NodeInfo.FailOverTime {
+			if nodeOffline(v) {
 				if len(v.NodeInfo.Hostname) > 0 {
-					failoverHostVms(v)
+					if v.NodeInfo.Maintenance {
+						internalLog.Infof("host has gone offline during the maintenance, skipping the failover %s", v.NodeInfo.Hostname)
+					} else {
+						failoverHostVms(v)
+					}
 					modifyHostsDb(ModifyHostsDb{Data: v, Remove: true}, &hostsDbLock)
 					// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "WARN: host has gone offline: "+v.NodeInfo.Hostname).Run()
 					internalLog.Warnf("host has gone offline %s", v.NodeInfo.Hostname)
@@ -310,132 +270,20 @@ func failoverHostVms(haNode HosterHaNode) {
 		return
 	}
 
-	haVms := []HaVm{}
-	hostsDbCopy := readHostsDb(&hostsDbLock)
-	for _, v := range hostsDbCopy {
-		haVmsTemp := []HaVm{}
-
-		// Skip the failed node (passed via function parameters, and not offline-d yet)
-		if v.NodeInfo.Hostname == haNode.NodeInfo.Hostname {
-			continue
-		}
-		// Skip if the node in question is a backup host, participating purely for quorum purposes
-		if v.NodeInfo.BackupNode {
-			continue
-		}
-
-		url := v.NodeInfo.Protocol + "://" + v.NodeInfo.Address + ":" + v.NodeInfo.Port + "/api/v2/ha/vms"
-		req, _ := http.NewRequest("GET", url, nil)
-		auth := v.NodeInfo.User + ":" + v.NodeInfo.Password
-		authEncoded := base64.StdEncoding.EncodeToString([]byte(auth))
-		req.Header.Add("Authorization", "Basic "+authEncoded)
-		res, err := http.DefaultClient.Do(req)
-		if err != nil {
-			// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "ERROR: line 345: "+err.Error()).Run()
-			internalLog.Error("line 333: " + err.Error())
-			continue
-		}
-
-		defer res.Body.Close()
-		body, _ := io.ReadAll(res.Body)
-
-		err = json.Unmarshal(body, &haVmsTemp)
-		if err != nil {
-			// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "ERROR: line 354: "+err.Error()).Run()
-			internalLog.Error("line 343: " + err.Error())
-			continue
-		}
-
-		for _, vv := range haVmsTemp {
-			if vv.ParentHost == haNode.NodeInfo.Hostname {
-				haVms = append(haVms, vv)
-			}
-		}
+	// The lease could have run out since the last election tick, and a manager in the minority partition
+	// can't tell the offline node apart from it's own isolation
+	result, err := failoverExecutor.Run()
+	if err != nil {
+		internalLog.Warnf("not failing over the node ::%s:: -> %s", haNode.NodeInfo.Hostname, err.Error())
+		return
 	}
 
-	sort.Slice(haVms, func(i int, j int) bool {
-		return haVms[i].LatestSnapshot < haVms[j].LatestSnapshot
-	})
-
-	uniqueHaVms := []HaVm{}
-	for _, v := range haVms {
-		vmExists := false
-		for _, vv := range uniqueHaVms {
-			if v.VmName == vv.VmName {
-				vmExists = true
-			}
-		}
-		if !vmExists {
-			uniqueHaVms = append(uniqueHaVms, v)
-		}
+	for k, v := range result.Reasons {
+		internalLog.Errorf("could not fail over the VM ::%s:: from an offline host ::%s:: (%s)", k, haNode.NodeInfo.Hostname, v)
 	}
-
-	for _, v := range uniqueHaVms {
-		if restConf.HaDebug {
-			// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "WARN: MOVING VM: "+v.VmName+" FROM offline parent: "+v.ParentHost+" TO: "+v.CurrentHost).Run()
-			internalLog.Warnf("failing over a VM ::%s:: from an offline host ::%s:: to ::%s::", v.VmName, v.ParentHost, v.CurrentHost)
-			continue
-		}
-
-		for _, vv := range hostsDbCopy {
-			if vv.NodeInfo.Hostname == v.CurrentHost {
-				time.Sleep(1500 * time.Millisecond)
-				// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "WARN: MOVING VM: "+v.VmName+" FROM offline parent: "+v.ParentHost+" TO: "+v.CurrentHost).Run()
-				internalLog.Warnf("failing over a VM ::%s:: from an offline host ::%s:: to ::%s::", v.VmName, v.ParentHost, v.CurrentHost)
-
-				auth := vv.NodeInfo.User + ":" + vv.NodeInfo.Password
-				authEncoded := base64.StdEncoding.EncodeToString([]byte(auth))
-
-				// Use failover strategy to failover the VM
-				if vv.NodeInfo.FailOverStrategy == "cireset" || vv.NodeInfo.FailOverStrategy == "ci-reset" {
-					url := vv.NodeInfo.Protocol + "://" + vv.NodeInfo.Address + ":" + vv.NodeInfo.Port + "/api/v2/vm/cireset"
-					payload := strings.NewReader(`{ "name": "` + v.VmName + `" }`)
-					req, _ := http.NewRequest("POST", url, payload)
-
-					req.Header.Add("Content-Type", "application/json")
-					req.Header.Add("Authorization", "Basic "+authEncoded)
-					res, err := http.DefaultClient.Do(req)
-					if res.StatusCode != 200 {
-						_ = err
-						// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "ERROR: CIRESET FAILED FOR THE VM: "+v.VmName+" ON: "+v.CurrentHost).Run()
-						internalLog.Errorf("cireset call failed for the VM ::%s:: on host ::%s::", v.VmName, v.CurrentHost)
-						continue
-					}
-					req.Body.Close()
-					res.Body.Close()
-				} else if vv.NodeInfo.FailOverStrategy == "changeparent" || vv.NodeInfo.FailOverStrategy == "change-parent" {
-					url := vv.NodeInfo.Protocol + "://" + vv.NodeInfo.Address + ":" + vv.NodeInfo.Port + "/api/v2/vm/change-parent"
-					payload := strings.NewReader(`{ "name": "` + v.VmName + `" }`)
-					req, _ := http.NewRequest("POST", url, payload)
-
-					req.Header.Add("Content-Type", "application/json")
-					req.Header.Add("Authorization", "Basic "+authEncoded)
-					res, err := http.DefaultClient.Do(req)
-					if res.StatusCode != 200 {
-						_ = err
-						// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "ERROR: CHANGE PARENT FAILED FOR THE VM: "+v.VmName+" ON: "+v.CurrentHost).Run()
-						internalLog.Errorf("change parent call failed for the VM ::%s:: on host ::%s::", v.VmName, v.CurrentHost)
-						continue
-					}
-					req.Body.Close()
-					res.Body.Close()
-				}
-
-				// Start VM on a new host
-				url := vv.NodeInfo.Protocol + "://" + vv.NodeInfo.Address + ":" + vv.NodeInfo.Port + "/api/v1/vm/start"
-				payload := strings.NewReader(`{ "name": "` + v.VmName + `" }`)
-				req, _ := http.NewRequest("POST", url, payload)
-
-				req.Header.Add("Content-Type", "application/json")
-				req.Header.Add("Authorization", "Basic "+authEncoded)
-				res, err := http.DefaultClient.Do(req)
-				if res.StatusCode != 200 {
-					_ = err
-					// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "ERROR: VM START FAILED FOR THE VM: "+v.VmName+" ON: "+v.CurrentHost).Run()
-					internalLog.Errorf("start call failed for the VM ::%s:: on host ::%s::", v.VmName, v.CurrentHost)
-					continue
-				}
-			}
+	for _, v := range result.Records {
+		if !v.Success {
+			internalLog.Errorf("could not fail over the VM ::%s:: to host ::%s:: -> %s", v.ResourceName, v.NewParent, v.Error)
 		}
 	}
 }
@@ -467,10 +315,13 @@ func modifyHostsDb(input ModifyHostsDb, dbLock *sync.RWMutex) {
 			// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "INFO: registered a new node: "+input.data.NodeInfo.Hostname).Run()
 			internalLog.Infof("registered a new node: %s", input.Data.NodeInfo.Hostname)
 			haHostsDb = append(haHostsDb, input.Data)
+			// The node could be back after it's VMs have been failed over, they are failed over again if it goes offline once more
+			failoverExecutor.MarkDemoted(input.Data.NodeInfo.Hostname)
 		} else {
 			// _ = exec.Command("logger", "-t", "HOSTER_HA_REST", "DEBUG: Updated last ping time and network address for "+msg.NodeInfo.Hostname).Run()
 			internalLog.Debugf("updated last ping time for: %s", input.Data.NodeInfo.Hostname)
 			haHostsDb[hostIndex].NodeInfo.Address = input.Data.NodeInfo.Address
+			haHostsDb[hostIndex].NodeInfo.Maintenance = input.Data.NodeInfo.Maintenance
 			if input.Data.NodeInfo.StartupTime > 0 {
 				haHostsDb[hostIndex].NodeInfo.StartupTime = input.Data.NodeInfo.StartupTime
 			}
@@ -572,3 +423,17 @@ func TerminateOtherMembers() {
 	}
 	wg.Wait()
 }
+
+// Asks the ha_watchdog to stop the resources right away
+func requestWatchdogFencing() {
+	pid, err := FreeBSDPgrep.FindWatchdog()
+	if err != nil {
+		internalLog.Error("ha_watchdog process is not running, can't request the self fencing")
+		return
+	}
+
+	err = syscall.Kill(pid, syscall.SIGUSR1)
+	if err != nil {
+		internalLog.Errorf("could not request the self fencing from ha_watchdog: %s", err.Error())
+	}
+}
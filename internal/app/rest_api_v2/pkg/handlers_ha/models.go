@@ -1,6 +1,10 @@
 package HandlersHA
 
-import RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
+import (
+	RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	HaElection "HosterCore/internal/pkg/hoster/ha/election"
+)
 
 type HosterHaNode struct {
 	LastPing int64                `json:"last_ping"`
@@ -12,3 +16,31 @@ type ModifyHostsDb struct {
 	Remove      bool
 	Data        HosterHaNode
 }
+
+type PingResponse struct {
+	Message  string            `json:"message"`
+	Election *HaElection.Reply `json:"election,omitempty"`
+}
+
+type HaNodeStatus struct {
+	Hostname     string `json:"hostname"`
+	Address      string `json:"address"`
+	Candidate    bool   `json:"candidate"`
+	BackupNode   bool   `json:"backup_node"`
+	FailOverTime int64  `json:"failover_time"`
+	LastPing     int64  `json:"last_ping"`
+	Online       bool   `json:"online"`
+	Maintenance  bool   `json:"maintenance"`
+}
+
+type HaStatus struct {
+	Hostname             string             `json:"hostname"`
+	Candidate            bool               `json:"candidate"`
+	Manager              bool               `json:"manager"`
+	ClusterInitialized   bool               `json:"cluster_initialized"`
+	CandidatesRegistered int                `json:"candidates_registered"`
+	Election             *HaElection.Status `json:"election,omitempty"`
+	Nodes                []HaNodeStatus     `json:"nodes"`
+	Backend              string             `json:"backend"`   // Membership and leadership backend of the HA core
+	Failovers            []HaCore.Record    `json:"failovers"` // Recent failover attempts, oldest first
+}
@@ -97,6 +97,8 @@ func main() {
 
 				log.WithFields(logrus.Fields{"type": LOG_SUPERVISOR}).Info("Shutting down -> Performing network cleanup")
 				_, _ = HosterNetwork.VmNetworkCleanup(vmName)
+				log.WithFields(logrus.Fields{"type": LOG_SUPERVISOR}).Info("Shutting down -> Performing firewall cleanup")
+				_ = HosterVmUtils.FlushFirewall(vmName)
 				log.WithFields(logrus.Fields{"type": LOG_SUPERVISOR}).Info("Shutting down -> Performing Bhyve cleanup")
 				_ = HosterVmUtils.BhyveCtlDestroy(vmName)
 
@@ -105,6 +107,7 @@ func main() {
 			} else {
 				log.WithFields(logrus.Fields{"type": LOG_SUPERVISOR}).Errorf("Bhyve returned a panic exit code: %d. Shutting down all VM related processes and performing system clean up.", exitCode)
 				_, _ = HosterNetwork.VmNetworkCleanup(vmName)
+				_ = HosterVmUtils.FlushFirewall(vmName)
 				_ = HosterVmUtils.BhyveCtlDestroy(vmName)
 				log.WithFields(logrus.Fields{"type": LOG_SUPERVISOR}).Error("SUPERVISED SESSION ENDED. Unexpected exit code.")
 				os.Exit(101)
@@ -124,6 +127,7 @@ func main() {
 
 		log.WithFields(logrus.Fields{"type": LOG_SUPERVISOR}).Error("SUPERVISED SESSION ENDED. SOMETHING UNPREDICTED HAPPENED! THE PROCESS HAD TO EXIT!")
 		_, _ = HosterNetwork.VmNetworkCleanup(vmName)
+		_ = HosterVmUtils.FlushFirewall(vmName)
 		_ = HosterVmUtils.BhyveCtlDestroy(vmName)
 		os.Exit(1000)
 	}
@@ -3,18 +3,15 @@ package ApiV2client
 import (
 	CarpUtils "HosterCore/internal/app/ha_carp/utils"
 	RestApiConfig "HosterCore/internal/app/rest_api_v2/pkg/config"
-	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HaMembership "HosterCore/internal/pkg/hoster/ha/membership"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
-func PingMaster(carpConfig CarpUtils.CarpConfig) (r string, e error) {
-	hostname, err := FreeBSDsysctls.SysctlKernHostname()
-	if err != nil {
-		e = err
-		return
-	}
-
+// Pings the CARP master with the local host state (signed with the node key), and returns the master's hostname and quorum status
+func PingMaster(carpConfig CarpUtils.CarpConfig, host CarpUtils.HostInfo, key ed25519.PrivateKey) (r CarpUtils.CarpPingResponse, e error) {
 	apiConfig, err := RestApiConfig.GetApiConfig()
 	if err != nil {
 		e = err
@@ -33,9 +30,12 @@ func PingMaster(carpConfig CarpUtils.CarpConfig) (r string, e error) {
 		return
 	}
 
-	host := CarpUtils.HostInfo{}
-	host.HostName = hostname
-	jp, err := json.Marshal(host)
+	msg, err := HaMembership.Sign(key, host.HostName, host)
+	if err != nil {
+		e = err
+		return
+	}
+	jp, err := json.Marshal(msg)
 	if err != nil {
 		e = err
 		return
@@ -49,18 +49,17 @@ func PingMaster(carpConfig CarpUtils.CarpConfig) (r string, e error) {
 		return
 	}
 
-	res := CarpUtils.CarpPingResponse{}
-	err = json.Unmarshal(body, &res)
+	err = json.Unmarshal(body, &r)
 	if err != nil {
 		e = err
 		return
 	}
 
-	r = res.Hostname
 	return
 }
 
-func SendLocalState(haState CarpUtils.HaStatus, remoteIp string, masterHostname string) error {
+// Sends the master's state to the follower, signed with the master's node key
+func SendLocalState(haState CarpUtils.HaStatus, remoteIp string, masterHostname string, key ed25519.PrivateKey) error {
 	apiConfig, err := RestApiConfig.GetApiConfig()
 	if err != nil {
 		return err
@@ -77,7 +76,11 @@ func SendLocalState(haState CarpUtils.HaStatus, remoteIp string, masterHostname
 		return fmt.Errorf("no HA user found in the config")
 	}
 
-	jp, err := json.Marshal(haState)
+	msg, err := HaMembership.Sign(key, masterHostname, haState)
+	if err != nil {
+		return err
+	}
+	jp, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
@@ -125,3 +128,116 @@ func ReturnBackups(host CarpUtils.HostInfo) (r []CarpUtils.BackupInfo, e error)
 
 	return
 }
+
+// Instructs the backup holder to take over the resource (using it's failover strategy) and start it
+func CarpFailover(host CarpUtils.HostInfo, request CarpUtils.FailoverRequest) error {
+	apiConfig, err := RestApiConfig.GetApiConfig()
+	if err != nil {
+		return err
+	}
+
+	url := apiConfig.Protocol + "://" + host.IpAddress + ":" + fmt.Sprintf("%d", apiConfig.Port) + "/api/v2/carp-ha/failover"
+	auth := ""
+	for _, v := range apiConfig.HTTPAuth {
+		if v.HaUser {
+			auth = v.User + ":" + v.Password
+		}
+	}
+	if len(auth) < 1 {
+		return fmt.Errorf("no HA user found in the config")
+	}
+
+	return postCarpFailover(url, auth, request)
+}
+
+func postCarpFailover(url string, auth string, request CarpUtils.FailoverRequest) error {
+	jp, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	mp := make(map[string]interface{})
+	json.Unmarshal(jp, &mp)
+
+	_, err = PostFuncWithTimeout(url, auth, FAILOVER_CALL_TIMEOUT*time.Second, mp)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Rejoins the host which has been away: demotes the resources failed over from it, unlocks the rest of it's fenced resources, and starts the ones that were running at the fencing time
+func CarpRejoin(host CarpUtils.HostInfo, request CarpUtils.RejoinRequest) error {
+	apiConfig, err := RestApiConfig.GetApiConfig()
+	if err != nil {
+		return err
+	}
+
+	url := apiConfig.Protocol + "://" + host.IpAddress + ":" + fmt.Sprintf("%d", apiConfig.Port) + "/api/v2/carp-ha/rejoin"
+	auth := ""
+	for _, v := range apiConfig.HTTPAuth {
+		if v.HaUser {
+			auth = v.User + ":" + v.Password
+		}
+	}
+	if len(auth) < 1 {
+		return fmt.Errorf("no HA user found in the config")
+	}
+
+	jp, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	mp := make(map[string]interface{})
+	json.Unmarshal(jp, &mp)
+
+	_, err = PostFuncWithTimeout(url, auth, FAILOVER_CALL_TIMEOUT*time.Second, mp)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Asks the remote node to let this one join it's cluster. The request stays pending until the admin approves it over there.
+func CarpJoin(remoteIp string, request HaMembership.JoinRequest) (r HaMembership.JoinResponse, e error) {
+	apiConfig, err := RestApiConfig.GetApiConfig()
+	if err != nil {
+		e = err
+		return
+	}
+
+	url := apiConfig.Protocol + "://" + remoteIp + ":" + fmt.Sprintf("%d", apiConfig.Port) + "/api/v2/carp-ha/join"
+	auth := ""
+	for _, v := range apiConfig.HTTPAuth {
+		if v.HaUser {
+			auth = v.User + ":" + v.Password
+		}
+	}
+	if len(auth) < 1 {
+		e = fmt.Errorf("no HA user found in the config")
+		return
+	}
+
+	jp, err := json.Marshal(request)
+	if err != nil {
+		e = err
+		return
+	}
+	mp := make(map[string]interface{})
+	json.Unmarshal(jp, &mp)
+
+	body, err := PostFunc(url, auth, mp)
+	if err != nil {
+		e = err
+		return
+	}
+
+	err = json.Unmarshal(body, &r)
+	if err != nil {
+		e = err
+		return
+	}
+
+	return
+}
@@ -0,0 +1,132 @@
+package ApiV2client
+
+import (
+	CarpUtils "HosterCore/internal/app/ha_carp/utils"
+	HaCore "HosterCore/internal/pkg/hoster/ha/core"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type testBackend struct{}
+
+func (testBackend) Name() string {
+	return "test"
+}
+
+func (testBackend) IsLeader() bool {
+	return true
+}
+
+func (testBackend) Nodes() ([]HaCore.Node, error) {
+	return nil, nil
+}
+
+func (testBackend) Copies() ([]HaCore.Copy, error) {
+	return nil, nil
+}
+
+// Stub HA peer, which records the failover requests it receives and answers with the given status code
+type stubPeer struct {
+	mu       sync.Mutex
+	status   int
+	requests []CarpUtils.FailoverRequest
+	server   *httptest.Server
+}
+
+func newStubPeer(t *testing.T, status int) *stubPeer {
+	peer := &stubPeer{status: status}
+	peer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "ha_user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/carp-ha/failover" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		request := CarpUtils.FailoverRequest{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		peer.mu.Lock()
+		peer.requests = append(peer.requests, request)
+		peer.mu.Unlock()
+
+		w.WriteHeader(peer.status)
+		w.Write([]byte(`{"message":"done"}`))
+	}))
+	t.Cleanup(peer.server.Close)
+
+	return peer
+}
+
+func TestCarpFailoverStubPeer(t *testing.T) {
+	peers := map[string]*stubPeer{
+		"node2": newStubPeer(t, http.StatusOK),
+		"node3": newStubPeer(t, http.StatusInternalServerError),
+	}
+
+	execute := func(plan HaCore.Plan, request HaCore.Request) error {
+		return postCarpFailover(peers[plan.Target].server.URL+"/api/v2/carp-ha/failover", "ha_user:secret", request)
+	}
+	executor := HaCore.NewExecutor(testBackend{}, execute, 2, 10)
+
+	copies := []HaCore.Copy{
+		{ResourceType: "vm", ResourceName: "test-vm-1", Holder: "node2", Parent: "node1", FailoverStrategy: "cireset"},
+		{ResourceType: "jail", ResourceName: "test-jail-1", Holder: "node3", Parent: "node1", FailoverStrategy: "change_parent"},
+	}
+	nodes := []HaCore.Node{{Name: "node1", Offline: true}, {Name: "node2"}, {Name: "node3"}}
+
+	result, err := executor.Failover(copies, nodes)
+	if err != nil {
+		t.Fatalf("Failover() error = %s", err)
+	}
+	if len(result.Records) != 2 {
+		t.Fatalf("Failover() records = %d, want 2", len(result.Records))
+	}
+
+	for _, v := range result.Records {
+		switch v.ResourceName {
+		case "test-vm-1":
+			if !v.Success || v.NewParent != "node2" {
+				t.Errorf("test-vm-1 record = %+v, want a success on node2", v)
+			}
+		case "test-jail-1":
+			if v.Success || len(v.Error) < 1 {
+				t.Errorf("test-jail-1 record = %+v, want a failure", v)
+			}
+		}
+	}
+
+	// The peer has received the request as it was sent
+	if len(peers["node2"].requests) != 1 {
+		t.Fatalf("node2 received %d requests, want 1", len(peers["node2"].requests))
+	}
+	want := HaCore.Request{ResourceName: "test-vm-1", ResourceType: "vm", FailoverStrategy: "cireset", OldParent: "node1"}
+	if peers["node2"].requests[0] != want {
+		t.Errorf("node2 received %+v, want %+v", peers["node2"].requests[0], want)
+	}
+
+	// The failed resource is retried, and the successful one is not failed over again
+	if len(result.Retry) != 1 || result.Retry[0].ResourceName != "test-jail-1" {
+		t.Fatalf("Failover() retry = %+v, want test-jail-1", result.Retry)
+	}
+	result, err = executor.Failover(copies, nodes)
+	if err != nil {
+		t.Fatalf("Failover() error = %s", err)
+	}
+	if len(result.Records) != 1 || result.Records[0].Attempt != 2 || len(result.GaveUp) != 1 {
+		t.Fatalf("second Failover() = %+v, want a single last attempt which gives up", result)
+	}
+	if len(peers["node2"].requests) != 1 || len(peers["node3"].requests) != 2 {
+		t.Errorf("peers received %d and %d requests, want 1 and 2", len(peers["node2"].requests), len(peers["node3"].requests))
+	}
+}
@@ -1,3 +1,6 @@
 package ApiV2client
 
 const HTTP_CALL_TIMEOUT = 5
+
+// Failover changes the parent (or resets the config) and starts the resource, so it can take a while
+const FAILOVER_CALL_TIMEOUT = 120
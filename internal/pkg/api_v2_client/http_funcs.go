@@ -19,7 +19,12 @@ import (
 //
 // - `inputPayload` (optional) should be a map of strings, aka `map[string]interface{}{"ssh_auth_key": "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQD..."}`
 func PostFunc(url string, auth string, inputPayload ...map[string]interface{}) (r []byte, e error) {
-	ctx, cancel := context.WithTimeout(context.Background(), HTTP_CALL_TIMEOUT*time.Second)
+	return PostFuncWithTimeout(url, auth, HTTP_CALL_TIMEOUT*time.Second, inputPayload...)
+}
+
+// Same as PostFunc, but for the long running calls which need a custom timeout
+func PostFuncWithTimeout(url string, auth string, timeout time.Duration, inputPayload ...map[string]interface{}) (r []byte, e error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	var req *http.Request
@@ -0,0 +1,161 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterTables
+
+import (
+	DnsServerClient "HosterCore/internal/app/dns_server/client"
+	"fmt"
+	"os"
+
+	"github.com/aquasecurity/table"
+)
+
+func GenerateDnsZoneTable(unix bool) error {
+	zone, err := DnsServerClient.GetZoneStatus()
+	if err != nil {
+		return err
+	}
+
+	var t = table.New(os.Stdout)
+	t.SetAlignment(
+		table.AlignRight,  // ID number
+		table.AlignLeft,   // Record Name
+		table.AlignCenter, // Record Type
+		table.AlignLeft,   // Record Data
+		table.AlignCenter, // Record Source
+	)
+
+	if unix {
+		t.SetDividers(table.Dividers{
+			ALL: " ",
+			NES: " ",
+			NSW: " ",
+			NEW: " ",
+			ESW: " ",
+			NE:  " ",
+			NW:  " ",
+			SW:  " ",
+			ES:  " ",
+			EW:  " ",
+			NS:  " ",
+		})
+		t.SetRowLines(false)
+		t.SetBorderTop(false)
+		t.SetBorderBottom(false)
+	} else {
+		t.SetHeaders("DNS Zone: " + zone.SearchDomain)
+		t.SetHeaderColSpans(0, 5)
+
+		t.AddHeaders(
+			"#",
+			"Record\nName",
+			"Record\nType",
+			"Record\nData",
+			"Record\nSource",
+		)
+
+		t.SetLineStyle(table.StyleBrightCyan)
+		t.SetDividers(table.UnicodeRoundedDividers)
+		t.SetHeaderStyle(table.StyleBold)
+	}
+
+	for i, v := range zone.Records {
+		t.AddRow(
+			fmt.Sprintf("%d", i+1),
+			v.Name,
+			v.Type,
+			v.Data,
+			v.Source,
+		)
+	}
+
+	t.Render()
+	return nil
+}
+
+func GenerateDnsUpstreamsTable(unix bool) error {
+	zone, err := DnsServerClient.GetZoneStatus()
+	if err != nil {
+		return err
+	}
+
+	var t = table.New(os.Stdout)
+	t.SetAlignment(
+		table.AlignRight,  // ID number
+		table.AlignLeft,   // Address
+		table.AlignCenter, // Protocol
+		table.AlignCenter, // Healthy
+		table.AlignRight,  // Queries
+		table.AlignRight,  // Failures
+		table.AlignRight,  // Last Latency
+		table.AlignRight,  // Avg Latency
+		table.AlignLeft,   // Last Error
+	)
+
+	if unix {
+		t.SetDividers(table.Dividers{
+			ALL: " ",
+			NES: " ",
+			NSW: " ",
+			NEW: " ",
+			ESW: " ",
+			NE:  " ",
+			NW:  " ",
+			SW:  " ",
+			ES:  " ",
+			EW:  " ",
+			NS:  " ",
+		})
+		t.SetRowLines(false)
+		t.SetBorderTop(false)
+		t.SetBorderBottom(false)
+	} else {
+		t.SetHeaders("DNS Upstreams")
+		t.SetHeaderColSpans(0, 9)
+
+		t.AddHeaders(
+			"#",
+			"Address",
+			"Protocol",
+			"Healthy",
+			"Queries",
+			"Failures",
+			"Last\nLatency",
+			"Avg\nLatency",
+			"Last\nError",
+		)
+
+		t.SetLineStyle(table.StyleBrightCyan)
+		t.SetDividers(table.UnicodeRoundedDividers)
+		t.SetHeaderStyle(table.StyleBold)
+	}
+
+	for i, v := range zone.UpstreamHealth {
+		healthy := "🟢"
+		if !v.Healthy {
+			healthy = "🔴"
+		}
+
+		lastError := "-"
+		if len(v.LastError) > 0 {
+			lastError = v.LastError
+		}
+
+		t.AddRow(
+			fmt.Sprintf("%d", i+1),
+			v.Address,
+			v.Protocol,
+			healthy,
+			fmt.Sprintf("%d", v.Queries),
+			fmt.Sprintf("%d", v.Failures),
+			fmt.Sprintf("%.2fms", v.LastLatencyMs),
+			fmt.Sprintf("%.2fms", v.AvgLatencyMs),
+			lastError,
+		)
+	}
+
+	t.Render()
+	return nil
+}
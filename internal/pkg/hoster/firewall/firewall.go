@@ -0,0 +1,267 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterFirewall
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	POLICY_ALLOW = "allow"
+	POLICY_DENY  = "deny"
+
+	RESOURCE_VM   = "vm"
+	RESOURCE_JAIL = "jail"
+
+	// All per-resource anchors live under this one, which is referenced from /etc/pf.conf as `anchor "hoster/*"`
+	ANCHOR_ROOT = "hoster"
+	// Anchor-scoped table which holds all IP addresses of the VM or Jail
+	RESOURCE_TABLE = "self"
+)
+
+// Declarative firewall policy, which is stored in the VM or Jail config
+type Config struct {
+	Enabled        bool   `json:"enabled"`
+	DefaultInbound string `json:"default_inbound,omitempty"` // "deny" (default) or "allow"
+	DefaultEgress  string `json:"default_egress,omitempty"`  // "allow" (default) or "deny"
+	Inbound        []Rule `json:"inbound,omitempty"`
+	Egress         []Rule `json:"egress,omitempty"`
+}
+
+type Rule struct {
+	Protocol string   `json:"protocol"`        // tcp, udp, icmp, icmp6 or any
+	Ports    []string `json:"ports,omitempty"` // single ports or ranges, e.g. "22" or "8000:8100" (tcp and udp only)
+	Cidrs    []string `json:"cidrs,omitempty"` // source CIDRs for the inbound rules, destination CIDRs for the egress rules (any if empty)
+	Comment  string   `json:"comment,omitempty"`
+}
+
+var reAnchorName = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// Returns the PF anchor name for the resource, e.g. "hoster/vm-test-vm-1"
+func AnchorName(resourceType string, resourceName string) string {
+	return ANCHOR_ROOT + "/" + resourceType + "-" + reAnchorName.ReplaceAllString(resourceName, "_")
+}
+
+// Returns the default inbound policy
+func (c Config) InboundPolicy() string {
+	if len(c.DefaultInbound) < 1 {
+		return POLICY_DENY
+	}
+	return c.DefaultInbound
+}
+
+// Returns the default egress policy
+func (c Config) EgressPolicy() string {
+	if len(c.DefaultEgress) < 1 {
+		return POLICY_ALLOW
+	}
+	return c.DefaultEgress
+}
+
+// Validates the firewall policy
+func (c Config) Validate() error {
+	if c.InboundPolicy() != POLICY_ALLOW && c.InboundPolicy() != POLICY_DENY {
+		return fmt.Errorf("default_inbound must be set to %s or %s", POLICY_ALLOW, POLICY_DENY)
+	}
+	if c.EgressPolicy() != POLICY_ALLOW && c.EgressPolicy() != POLICY_DENY {
+		return fmt.Errorf("default_egress must be set to %s or %s", POLICY_ALLOW, POLICY_DENY)
+	}
+
+	for i, v := range c.Inbound {
+		err := v.validate()
+		if err != nil {
+			return fmt.Errorf("inbound rule %d: %s", i+1, err.Error())
+		}
+	}
+	for i, v := range c.Egress {
+		err := v.validate()
+		if err != nil {
+			return fmt.Errorf("egress rule %d: %s", i+1, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (r Rule) validate() error {
+	switch r.protocol() {
+	case "tcp", "udp":
+	case "icmp", "icmp6", "any":
+		if len(r.Ports) > 0 {
+			return fmt.Errorf("ports can only be used with the tcp and udp protocols")
+		}
+	default:
+		return fmt.Errorf("unsupported protocol: %s", r.Protocol)
+	}
+
+	for _, v := range r.Ports {
+		err := validatePort(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, v := range r.Cidrs {
+		if v == "any" {
+			continue
+		}
+		if _, err := parseCidr(v); err != nil {
+			return fmt.Errorf("invalid CIDR %s", v)
+		}
+	}
+
+	if strings.ContainsAny(r.Comment, "\n\r") {
+		return fmt.Errorf("comment must be a single line")
+	}
+
+	return nil
+}
+
+func (r Rule) protocol() string {
+	if len(r.Protocol) < 1 {
+		return "any"
+	}
+	return strings.ToLower(r.Protocol)
+}
+
+// Validates a single port ("22") or a port range ("8000:8100")
+func validatePort(port string) error {
+	parts := strings.Split(port, ":")
+	if len(parts) > 2 {
+		return fmt.Errorf("invalid port range %s", port)
+	}
+
+	values := []int{}
+	for _, v := range parts {
+		value, err := strconv.Atoi(v)
+		if err != nil || value < 1 || value > 65535 {
+			return fmt.Errorf("invalid port %s", port)
+		}
+		values = append(values, value)
+	}
+	if len(values) == 2 && values[0] > values[1] {
+		return fmt.Errorf("invalid port range %s", port)
+	}
+
+	return nil
+}
+
+// Parses an IP address or a CIDR, and returns it in a normalized form
+func parseCidr(cidr string) (r string, e error) {
+	if strings.Contains(cidr, "/") {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			e = err
+			return
+		}
+		r = prefix.Masked().String()
+		return
+	}
+
+	addr, err := netip.ParseAddr(cidr)
+	if err != nil {
+		e = err
+		return
+	}
+	r = addr.String()
+	return
+}
+
+// Renders the PF ruleset for the resource anchor. Doesn't touch the system, so the output can be
+// compared against the known good (golden) files.
+//
+// Addresses are the VM or Jail IPv4/IPv6 addresses the policy applies to. The rules are direction agnostic
+// (matching on the resource addresses instead), because the traffic is filtered on the bridge member interfaces.
+func Render(resourceType string, resourceName string, addresses []string, c Config) (r string, e error) {
+	e = c.Validate()
+	if e != nil {
+		return
+	}
+
+	tableAddresses := []string{}
+	for _, v := range addresses {
+		if len(v) < 1 {
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			e = fmt.Errorf("invalid %s IP address %s", resourceType, v)
+			return
+		}
+		tableAddresses = append(tableAddresses, addr.String())
+	}
+	if len(tableAddresses) < 1 {
+		e = fmt.Errorf("%s %s has no IP addresses to apply the firewall rules to", resourceType, resourceName)
+		return
+	}
+
+	self := "<" + RESOURCE_TABLE + ">"
+	lines := []string{}
+	lines = append(lines, fmt.Sprintf("# Hoster firewall: %s %s (do not edit, generated automatically)", resourceType, resourceName))
+	lines = append(lines, fmt.Sprintf("table %s const { %s }", self, strings.Join(tableAddresses, " ")))
+
+	lines = append(lines, "", "# Inbound")
+	for _, v := range c.Inbound {
+		lines = append(lines, renderRule(v, renderCidrs(v.Cidrs), self))
+	}
+	if c.InboundPolicy() == POLICY_DENY {
+		lines = append(lines, fmt.Sprintf("block quick from any to %s", self))
+	} else {
+		lines = append(lines, fmt.Sprintf("pass quick from any to %s keep state", self))
+	}
+
+	lines = append(lines, "", "# Egress")
+	for _, v := range c.Egress {
+		lines = append(lines, renderRule(v, self, renderCidrs(v.Cidrs)))
+	}
+	if c.EgressPolicy() == POLICY_DENY {
+		lines = append(lines, fmt.Sprintf("block quick from %s to any", self))
+	} else {
+		lines = append(lines, fmt.Sprintf("pass quick from %s to any keep state", self))
+	}
+
+	r = strings.Join(lines, "\n") + "\n"
+	return
+}
+
+func renderRule(rule Rule, from string, to string) string {
+	line := "pass quick"
+	if rule.protocol() != "any" {
+		line = line + " proto " + rule.protocol()
+	}
+	line = line + " from " + from + " to " + to
+	if len(rule.Ports) > 0 {
+		line = line + " port { " + strings.Join(rule.Ports, " ") + " }"
+	}
+	line = line + " keep state"
+	if len(rule.Comment) > 0 {
+		line = line + "  # " + rule.Comment
+	}
+
+	return line
+}
+
+func renderCidrs(cidrs []string) string {
+	result := []string{}
+	for _, v := range cidrs {
+		if v == "any" {
+			return "any"
+		}
+		cidr, err := parseCidr(v)
+		if err != nil {
+			continue
+		}
+		result = append(result, cidr)
+	}
+	if len(result) < 1 {
+		return "any"
+	}
+
+	return "{ " + strings.Join(result, " ") + " }"
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterFirewall
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Regenerates the golden files: go test ./internal/pkg/hoster/firewall/ -update
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestRenderGolden(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType string
+		resourceName string
+		addresses    []string
+		config       Config
+	}{
+		{
+			name:         "vm_default_deny",
+			resourceType: RESOURCE_VM,
+			resourceName: "test-vm-1",
+			addresses:    []string{"10.0.101.10"},
+			config: Config{
+				Enabled: true,
+				Inbound: []Rule{
+					{Protocol: "tcp", Ports: []string{"22"}, Cidrs: []string{"192.168.1.0/24", "203.0.113.7"}, Comment: "SSH from the office"},
+					{Protocol: "TCP", Ports: []string{"80", "443", "8000:8100"}},
+					{Protocol: "icmp"},
+				},
+			},
+		},
+		{
+			name:         "jail_egress_deny",
+			resourceType: RESOURCE_JAIL,
+			resourceName: "test jail",
+			addresses:    []string{"10.0.102.20", "", "fd00:102::20"},
+			config: Config{
+				Enabled:        true,
+				DefaultInbound: POLICY_ALLOW,
+				DefaultEgress:  POLICY_DENY,
+				Egress: []Rule{
+					{Protocol: "udp", Ports: []string{"53"}, Cidrs: []string{"10.0.102.1"}, Comment: "DNS"},
+					{Protocol: "tcp", Ports: []string{"443"}, Cidrs: []string{"198.51.100.1/24", "any"}},
+					{Protocol: "icmp6", Cidrs: []string{"fd00:102::/64"}},
+				},
+			},
+		},
+		{
+			name:         "vm_no_rules",
+			resourceType: RESOURCE_VM,
+			resourceName: "test-vm-2",
+			addresses:    []string{"10.0.101.11", "fd00:101::11"},
+			config:       Config{Enabled: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.resourceType, tt.resourceName, tt.addresses, tt.config)
+			if err != nil {
+				t.Fatalf("Render() error = %s", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				err := os.WriteFile(golden, []byte(got), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("Render() =\n%s\nwant (%s)\n%s", got, golden, want)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		config    Config
+		wantErr   string
+	}{
+		{name: "no addresses", addresses: []string{""}, wantErr: "has no IP addresses"},
+		{name: "invalid address", addresses: []string{"10.0.101.300"}, wantErr: "invalid vm IP address"},
+		{name: "invalid policy", addresses: []string{"10.0.101.10"}, config: Config{DefaultInbound: "drop"}, wantErr: "default_inbound must be set"},
+		{name: "ports with icmp", addresses: []string{"10.0.101.10"}, config: Config{Inbound: []Rule{{Protocol: "icmp", Ports: []string{"22"}}}}, wantErr: "inbound rule 1: ports can only be used"},
+		{name: "unsupported protocol", addresses: []string{"10.0.101.10"}, config: Config{Egress: []Rule{{Protocol: "sctp"}}}, wantErr: "egress rule 1: unsupported protocol"},
+		{name: "invalid port range", addresses: []string{"10.0.101.10"}, config: Config{Inbound: []Rule{{Protocol: "tcp", Ports: []string{"8100:8000"}}}}, wantErr: "invalid port range"},
+		{name: "invalid port", addresses: []string{"10.0.101.10"}, config: Config{Inbound: []Rule{{Protocol: "tcp", Ports: []string{"65536"}}}}, wantErr: "invalid port 65536"},
+		{name: "invalid CIDR", addresses: []string{"10.0.101.10"}, config: Config{Inbound: []Rule{{Protocol: "tcp", Cidrs: []string{"10.0.0.0/33"}}}}, wantErr: "invalid CIDR"},
+		{name: "multi-line comment", addresses: []string{"10.0.101.10"}, config: Config{Inbound: []Rule{{Protocol: "tcp", Comment: "ssh\npass quick all"}}}, wantErr: "comment must be a single line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(RESOURCE_VM, "test-vm-1", tt.addresses, tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Render() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnchorName(t *testing.T) {
+	if got, want := AnchorName(RESOURCE_JAIL, "test jail/1"), "hoster/jail-test_jail_1"; got != want {
+		t.Errorf("AnchorName() = %s, want %s", got, want)
+	}
+}
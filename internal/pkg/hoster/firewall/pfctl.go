@@ -0,0 +1,53 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterFirewall
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Loads the rendered ruleset into the PF anchor, replacing any rules and tables that were loaded previously
+func Load(anchor string, ruleset string) error {
+	cmd := exec.Command("pfctl", "-a", anchor, "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not load the PF anchor %s: %s; %s", anchor, strings.TrimSpace(string(out)), err.Error())
+	}
+
+	return nil
+}
+
+// Flushes all rules, tables and states from the PF anchor
+func Flush(anchor string) error {
+	out, err := exec.Command("pfctl", "-a", anchor, "-F", "all").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not flush the PF anchor %s: %s; %s", anchor, strings.TrimSpace(string(out)), err.Error())
+	}
+
+	return nil
+}
+
+// Renders and loads the firewall policy for the VM or Jail.
+//
+// If the firewall is not enabled, the anchor is flushed (on the best effort basis, PF may not be running at all),
+// so the rules left behind by the previous config version are removed.
+func Apply(resourceType string, resourceName string, addresses []string, c *Config) error {
+	anchor := AnchorName(resourceType, resourceName)
+	if c == nil || !c.Enabled {
+		_ = Flush(anchor)
+		return nil
+	}
+
+	ruleset, err := Render(resourceType, resourceName, addresses, *c)
+	if err != nil {
+		return err
+	}
+
+	return Load(anchor, ruleset)
+}
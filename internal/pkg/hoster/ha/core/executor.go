@@ -0,0 +1,184 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaCore
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Runs the failovers for both HA modes: plans the placement, promotes the copies, counts the attempts per resource,
+// and keeps the history of the recent attempts. Safe for the concurrent use, only one failover runs at a time.
+type Executor struct {
+	backend     Backend
+	execute     func(Plan, Request) error
+	maxAttempts int
+	historySize int
+
+	run      sync.Mutex
+	mu       sync.RWMutex
+	history  []Record
+	attempts map[string]int
+}
+
+// The execute func promotes the copy on the target node, and starts the resource there
+func NewExecutor(backend Backend, execute func(Plan, Request) error, maxAttempts int, historySize int) *Executor {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Executor{
+		backend:     backend,
+		execute:     execute,
+		maxAttempts: maxAttempts,
+		historySize: historySize,
+		history:     []Record{},
+		attempts:    make(map[string]int),
+	}
+}
+
+func (e *Executor) Backend() Backend {
+	return e.backend
+}
+
+// Fails over the resources of the offline nodes, using the membership of the backend (see OfflineCopies)
+func (e *Executor) Run() (r Result, err error) {
+	nodes, err := e.backend.Nodes()
+	if err != nil {
+		return
+	}
+	copies, err := e.backend.Copies()
+	if err != nil {
+		return
+	}
+
+	return e.Failover(OfflineCopies(copies, nodes), nodes)
+}
+
+// Fails over the resources of the offline parent(s) to the surviving nodes. Resources which have already been failed over
+// from the same parent are skipped, and the ones which could not be failed over are returned for the retry,
+// unless they have run out of attempts.
+func (e *Executor) Failover(copies []Copy, nodes []Node) (r Result, err error) {
+	e.run.Lock()
+	defer e.run.Unlock()
+
+	// The leadership is checked right before the failover, a leader which has lost the quorum might be the isolated one
+	if !e.backend.IsLeader() {
+		err = ErrNotLeader
+		return
+	}
+
+	todo := []Copy{}
+	for _, v := range copies {
+		if !e.FailedOver(v) {
+			todo = append(todo, v)
+		}
+	}
+
+	plans, pending, reasons := PlanFailover(todo, nodes)
+	r.Reasons = reasons
+	r.Retry = append(r.Retry, pending...)
+
+	for _, v := range plans {
+		key := ResourceKey(v.Copy.ResourceType, v.Copy.ResourceName)
+
+		e.mu.Lock()
+		e.attempts[key] = e.attempts[key] + 1
+		attempt := e.attempts[key]
+		e.mu.Unlock()
+
+		record := Record{}
+		record.ResourceName = v.Copy.ResourceName
+		record.ResourceType = v.Copy.ResourceType
+		record.FailoverStrategy = v.Copy.FailoverStrategy
+		record.OldParent = v.Copy.Parent
+		record.NewParent = v.Target
+		record.LastSnapshot = v.Copy.Snapshot
+		record.Attempt = attempt
+		record.Time = time.Now().Local().Unix()
+
+		execErr := e.execute(v, record.Request)
+		if execErr != nil {
+			record.Error = execErr.Error()
+		} else {
+			record.Success = true
+		}
+
+		if record.Success || record.Attempt >= e.maxAttempts {
+			e.mu.Lock()
+			delete(e.attempts, key)
+			e.mu.Unlock()
+		}
+		if !record.Success {
+			if record.Attempt >= e.maxAttempts {
+				r.GaveUp = append(r.GaveUp, record)
+			} else {
+				for _, vv := range todo {
+					if vv.ResourceType == record.ResourceType && vv.ResourceName == record.ResourceName {
+						r.Retry = append(r.Retry, vv)
+					}
+				}
+			}
+		}
+
+		r.Records = append(r.Records, record)
+	}
+
+	e.record(r.Records...)
+	return
+}
+
+func (e *Executor) record(records ...Record) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.history = append(e.history, records...)
+	if e.historySize > 0 && len(e.history) > e.historySize {
+		e.history = e.history[len(e.history)-e.historySize:]
+	}
+}
+
+// Checks if the resource has already been failed over from this (offline) parent.
+//
+// Once the old parent has rejoined (and the record is demoted), the resource could have been moved back to it,
+// so the demoted records don't count: if that parent goes offline again, the resource is failed over again.
+func (e *Executor) FailedOver(c Copy) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return slices.ContainsFunc(e.history, func(v Record) bool {
+		return v.Success && !v.Demoted && v.ResourceType == c.ResourceType && v.ResourceName == c.ResourceName && v.OldParent == c.Parent
+	})
+}
+
+// Returns the recent failover attempts, oldest first
+func (e *Executor) History() []Record {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return slices.Clone(e.history)
+}
+
+// Replaces the history with the one synced from the leader, so the next leader knows which copies are stale
+func (e *Executor) ReplaceHistory(records []Record) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.history = []Record{}
+	e.history = append(e.history, records...)
+}
+
+// Marks the successful failovers away from the (rejoined) host as demoted
+func (e *Executor) MarkDemoted(hostname string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, v := range e.history {
+		if v.Success && v.OldParent == hostname {
+			e.history[i].Demoted = true
+		}
+	}
+}
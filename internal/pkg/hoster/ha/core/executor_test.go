@@ -0,0 +1,214 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaCore
+
+import (
+	"errors"
+	"testing"
+)
+
+// In-memory backend, the leadership and the membership are set by the test
+type fakeBackend struct {
+	leader bool
+	nodes  []Node
+	copies []Copy
+}
+
+func (b *fakeBackend) Name() string {
+	return "fake"
+}
+
+func (b *fakeBackend) IsLeader() bool {
+	return b.leader
+}
+
+func (b *fakeBackend) Nodes() ([]Node, error) {
+	return b.nodes, nil
+}
+
+func (b *fakeBackend) Copies() ([]Copy, error) {
+	return b.copies, nil
+}
+
+func testMembership() *fakeBackend {
+	return &fakeBackend{
+		leader: true,
+		nodes:  []Node{{Name: "node1", Offline: true}, {Name: "node2"}},
+		copies: []Copy{
+			{ResourceType: "vm", ResourceName: "vm1", Holder: "node2", Parent: "node1", Snapshot: "replication_2023-08-14_16-49-08"},
+			{ResourceType: "vm", ResourceName: "vm2", Holder: "node1", Parent: "node2", Snapshot: "replication_2023-08-14_16-49-08"},
+		},
+	}
+}
+
+func TestExecutorNotLeader(t *testing.T) {
+	backend := testMembership()
+	backend.leader = false
+	calls := 0
+	executor := NewExecutor(backend, func(Plan, Request) error { calls++; return nil }, 3, 10)
+
+	_, err := executor.Run()
+	if !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("Run() error = %v, want %v", err, ErrNotLeader)
+	}
+	if calls > 0 {
+		t.Errorf("the follower has executed %d failovers", calls)
+	}
+}
+
+func TestExecutorRun(t *testing.T) {
+	backend := testMembership()
+	requests := []Request{}
+	executor := NewExecutor(backend, func(p Plan, r Request) error {
+		if p.Target != "node2" {
+			t.Errorf("failover target = %s, want node2", p.Target)
+		}
+		requests = append(requests, r)
+		return nil
+	}, 3, 10)
+
+	r, err := executor.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %s", err)
+	}
+	// vm2's parent is online, only vm1 is failed over
+	if len(requests) != 1 || requests[0].ResourceName != "vm1" || requests[0].OldParent != "node1" {
+		t.Fatalf("Run() requests = %+v, want vm1 from node1", requests)
+	}
+	if len(r.Records) != 1 || !r.Records[0].Success || r.Records[0].Attempt != 1 || r.Records[0].NewParent != "node2" {
+		t.Errorf("Run() records = %+v", r.Records)
+	}
+	if !executor.FailedOver(backend.copies[0]) {
+		t.Errorf("FailedOver() = false after the successful failover")
+	}
+
+	// Resources which have already been failed over from the same parent are skipped
+	r, err = executor.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %s", err)
+	}
+	if len(requests) != 1 || len(r.Records) != 0 {
+		t.Errorf("Run() has failed over vm1 again: %+v", r.Records)
+	}
+}
+
+func TestExecutorAttempts(t *testing.T) {
+	backend := testMembership()
+	executor := NewExecutor(backend, func(Plan, Request) error { return errors.New("start failed") }, 3, 10)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		r, err := executor.Run()
+		if err != nil {
+			t.Fatalf("Run() error = %s", err)
+		}
+		if len(r.Records) != 1 || r.Records[0].Success || r.Records[0].Attempt != attempt || r.Records[0].Error != "start failed" {
+			t.Fatalf("attempt %d: records = %+v", attempt, r.Records)
+		}
+
+		if attempt < 3 {
+			if len(r.Retry) != 1 || len(r.GaveUp) != 0 {
+				t.Errorf("attempt %d: retry = %+v, gave up = %+v, want a retry", attempt, r.Retry, r.GaveUp)
+			}
+			continue
+		}
+		if len(r.Retry) != 0 || len(r.GaveUp) != 1 {
+			t.Errorf("attempt %d: retry = %+v, gave up = %+v, want to give up", attempt, r.Retry, r.GaveUp)
+		}
+	}
+
+	if executor.FailedOver(backend.copies[0]) {
+		t.Errorf("FailedOver() = true without a successful failover")
+	}
+
+	// The attempts are reset after giving up
+	r, _ := executor.Run()
+	if len(r.Records) != 1 || r.Records[0].Attempt != 1 {
+		t.Errorf("records after giving up = %+v, want attempt 1", r.Records)
+	}
+}
+
+func TestExecutorPending(t *testing.T) {
+	backend := testMembership()
+	backend.nodes[1].MaxResources = 1
+	backend.nodes[1].Resources = 1
+	executor := NewExecutor(backend, func(Plan, Request) error { return nil }, 3, 10)
+
+	r, err := executor.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %s", err)
+	}
+	if len(r.Records) != 0 || len(r.Retry) != 1 || len(r.Reasons["vm/vm1"]) < 1 {
+		t.Errorf("Run() = %+v, want vm1 pending", r)
+	}
+}
+
+func TestExecutorHistory(t *testing.T) {
+	backend := testMembership()
+	executor := NewExecutor(backend, func(Plan, Request) error { return errors.New("start failed") }, 10, 3)
+
+	for i := 0; i < 5; i++ {
+		executor.Run()
+	}
+
+	history := executor.History()
+	if len(history) != 3 {
+		t.Fatalf("History() = %d records, want 3", len(history))
+	}
+	// The oldest records are dropped
+	for i, v := range history {
+		if v.Attempt != i+3 {
+			t.Errorf("History()[%d].Attempt = %d, want %d", i, v.Attempt, i+3)
+		}
+	}
+
+	executor.ReplaceHistory([]Record{{Request: Request{ResourceType: "vm", ResourceName: "vm1", OldParent: "node1"}, Success: true}})
+	if !executor.FailedOver(backend.copies[0]) {
+		t.Errorf("FailedOver() = false with the synced history")
+	}
+	executor.MarkDemoted("node1")
+	history = executor.History()
+	if len(history) != 1 || !history[0].Demoted {
+		t.Errorf("History() = %+v, want the demoted vm1", history)
+	}
+	if executor.FailedOver(backend.copies[0]) {
+		t.Errorf("FailedOver() = true for the demoted record")
+	}
+}
+
+func TestExecutorFailBack(t *testing.T) {
+	backend := testMembership()
+	requests := []Request{}
+	executor := NewExecutor(backend, func(p Plan, r Request) error {
+		requests = append(requests, r)
+		return nil
+	}, 3, 10)
+
+	// vm1 is failed over from node1 to node2
+	executor.Run()
+	if len(requests) != 1 {
+		t.Fatalf("Run() requests = %+v, want the vm1 failover", requests)
+	}
+
+	// node1 rejoins, and vm1 is migrated back to it
+	executor.MarkDemoted("node1")
+	backend.nodes[0].Offline = false
+	r, _ := executor.Run()
+	if len(r.Records) != 0 {
+		t.Fatalf("Run() with node1 online = %+v, want no failovers", r.Records)
+	}
+
+	// node1 goes offline again, and vm1 is failed over once more
+	backend.nodes[0].Offline = true
+	r, err := executor.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %s", err)
+	}
+	if len(requests) != 2 || len(r.Records) != 1 || !r.Records[0].Success || r.Records[0].OldParent != "node1" {
+		t.Errorf("Run() after the fail-back = %+v, want vm1 failed over again", r.Records)
+	}
+	if !executor.FailedOver(backend.copies[0]) {
+		t.Errorf("FailedOver() = false after the second failover")
+	}
+}
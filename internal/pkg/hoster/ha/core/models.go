@@ -0,0 +1,81 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaCore
+
+import (
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
+	"errors"
+)
+
+// Membership and leadership backend of the HA cluster: static candidates from ha_config.json, or CARP.
+// The failover executor only runs on the leader, so the backend must not report the leadership it can't back with the quorum.
+type Backend interface {
+	Name() string
+	IsLeader() bool
+	Membership
+}
+
+// Membership side of the backend: which nodes form the cluster (and which of them have gone offline),
+// and which copies of the resources they hold
+type Membership interface {
+	// All known nodes, the offline ones included (and flagged)
+	Nodes() ([]Node, error)
+	// Copies of the resources which might need the failover, only the ones whose parent is offline are failed over
+	Copies() ([]Copy, error)
+}
+
+var ErrNotLeader = errors.New("this node is not the HA leader (or has lost the quorum), the failover is on hold")
+
+type Node struct {
+	Name         string
+	Offline      bool
+	Maintenance  bool // Node is being drained for maintenance, it's not taking any new resources
+	Backup       bool // Node participates purely for the quorum purposes, it's not taking any resources
+	RamFree      uint64
+	MaxResources int      // Max number of HA resources running on the node, 0 means unlimited
+	Resources    int      // Number of HA resources already running on the node as a parent
+	Groups       []string // Anti-affinity groups of the resources already running on the node
+}
+
+// Replicated copy of the resource, held by one of the nodes
+type Copy struct {
+	ResourceType     string // "vm" or "jail"
+	ResourceName     string
+	Holder           string // Node holding the copy
+	Parent           string // Node the resource belongs to
+	Snapshot         string // Latest snapshot of the copy, e.g. "replication_2023-08-14_16-49-08"
+	FailoverStrategy string // "cireset" or "change_parent"
+	Ha               *HaPlacement.Settings
+}
+
+type Plan struct {
+	Copy   Copy   // Copy that will be promoted
+	Target string // Holder of the copy, which becomes the new parent
+}
+
+type Request struct {
+	ResourceName     string `json:"resource_name"`     // Resource name
+	ResourceType     string `json:"resource_type"`     // Resource type, e.g. "vm", "jail"
+	FailoverStrategy string `json:"failover_strategy"` // Failover strategy, e.g. "cireset" or "change_parent"
+	OldParent        string `json:"old_parent"`        // Offline parent host name
+}
+
+type Record struct {
+	Request
+	NewParent    string `json:"new_parent"`    // Host name the resource was failed over to
+	LastSnapshot string `json:"last_snapshot"` // Last snapshot available on the new parent
+	Attempt      int    `json:"attempt"`       // Attempt number, starting from 1
+	Time         int64  `json:"time"`          // Unix timestamp of the attempt
+	Success      bool   `json:"success"`       // Resource has been started on the new parent
+	Error        string `json:"error,omitempty"`
+	Demoted      bool   `json:"demoted,omitempty"` // Stale copy on the old parent has been demoted to a backup
+}
+
+type Result struct {
+	Records []Record          // Outcome of every attempt made in this run
+	Retry   []Copy            // Copies of the resources which are still waiting for the failover
+	Reasons map[string]string // Why the resources could not be placed, by the resource key
+	GaveUp  []Record          // Last attempts of the resources which have run out of attempts
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaCore
+
+import (
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
+	"strings"
+)
+
+// Returns the key which identifies the resource across all copy holders, e.g. "vm/test-vm-1"
+func ResourceKey(resourceType string, resourceName string) string {
+	return resourceType + "/" + resourceName
+}
+
+// Returns the sortable timestamp part of the snapshot, e.g. "zroot/vm-encrypted/test-vm-1@replication_2023-08-14_16-49-08" -> "20230814164908000000"
+func SnapshotTimestamp(snapshot string) string {
+	_, snapshotName, found := strings.Cut(snapshot, "@")
+	if !found {
+		snapshotName = snapshot
+	}
+
+	_, timestamp, found := strings.Cut(snapshotName, "_")
+	if !found {
+		return ""
+	}
+
+	digits := strings.Builder{}
+	for _, v := range timestamp {
+		if v >= '0' && v <= '9' {
+			digits.WriteRune(v)
+		}
+	}
+
+	// Older snapshot names don't include the microseconds
+	r := digits.String()
+	for len(r) < 20 {
+		r = r + "0"
+	}
+	return r
+}
+
+// Returns the copies of the resources whose parent has gone offline. Parents which have gone offline during
+// the maintenance are not failed over, and the ones unknown to the membership are treated as offline.
+func OfflineCopies(copies []Copy, nodes []Node) (r []Copy) {
+	parents := make(map[string]Node)
+	for _, v := range nodes {
+		parents[v.Name] = v
+	}
+
+	for _, v := range copies {
+		parent, found := parents[v.Parent]
+		if found && (!parent.Offline || parent.Maintenance) {
+			continue
+		}
+		r = append(r, v)
+	}
+	return
+}
+
+// Picks the best surviving holder for every resource of the offline parent(s), using the HA placement engine:
+// resources are placed by priority, and the preferred nodes, max resources and anti-affinity groups are respected.
+// Among the allowed holders the newest snapshot wins, then the node running less resources, and then the one with more free RAM.
+//
+// Copies which can't be placed yet (no online holder, or all of them are ruled out) are returned as pending, with the reason
+// for every resource key, so they can be retried later.
+func PlanFailover(copies []Copy, nodes []Node) (plans []Plan, pending []Copy, reasons map[string]string) {
+	reasons = make(map[string]string)
+	targets := []HaPlacement.Node{}
+	for _, v := range nodes {
+		if v.Offline || v.Maintenance || v.Backup {
+			continue
+		}
+		targets = append(targets, HaPlacement.Node{
+			Name:         v.Name,
+			RamFree:      v.RamFree,
+			MaxResources: v.MaxResources,
+			Resources:    v.Resources,
+			Groups:       v.Groups,
+		})
+	}
+
+	groups := make(map[string][]Copy)
+	resources := []HaPlacement.Resource{}
+	for _, v := range copies {
+		key := ResourceKey(v.ResourceType, v.ResourceName)
+		if _, ok := groups[key]; !ok {
+			resources = append(resources, HaPlacement.Resource{Key: key})
+		}
+		groups[key] = append(groups[key], v)
+	}
+
+	for i, resource := range resources {
+		for _, v := range groups[resource.Key] {
+			// Settings travel with the replicated config, any copy will do
+			if v.Ha != nil {
+				resources[i].Settings = *v.Ha
+			}
+			if v.Holder == v.Parent {
+				continue
+			}
+			resources[i].Copies = append(resources[i].Copies, HaPlacement.Copy{Node: v.Holder, Snapshot: SnapshotTimestamp(v.Snapshot)})
+		}
+	}
+
+	placements, rejected := HaPlacement.Plan(resources, targets)
+	for _, v := range placements {
+		for _, vv := range groups[v.Resource.Key] {
+			if vv.Holder == v.Copy.Node {
+				plans = append(plans, Plan{Copy: vv, Target: vv.Holder})
+				break
+			}
+		}
+	}
+
+	for _, v := range rejected {
+		pending = append(pending, groups[v.Resource.Key]...)
+		reasons[v.Resource.Key] = v.Reason
+	}
+
+	return
+}
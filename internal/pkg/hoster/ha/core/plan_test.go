@@ -0,0 +1,155 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaCore
+
+import (
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
+	"slices"
+	"testing"
+)
+
+func TestSnapshotTimestamp(t *testing.T) {
+	tests := []struct {
+		snapshot string
+		want     string
+	}{
+		{snapshot: "zroot/vm-encrypted/test-vm-1@replication_2023-08-14_16-49-08", want: "20230814164908000000"},
+		{snapshot: "replication_2023-08-14_16-49-08.123456", want: "20230814164908123456"},
+		{snapshot: "zroot/vm-encrypted/test-vm-1@manual", want: ""},
+		{snapshot: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.snapshot, func(t *testing.T) {
+			if got := SnapshotTimestamp(tt.snapshot); got != tt.want {
+				t.Errorf("SnapshotTimestamp() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfflineCopies(t *testing.T) {
+	nodes := []Node{
+		{Name: "node1", Offline: true},
+		{Name: "node2"},
+		{Name: "node3", Offline: true, Maintenance: true},
+	}
+	copies := []Copy{
+		{ResourceType: "vm", ResourceName: "vm1", Holder: "node2", Parent: "node1"},
+		{ResourceType: "vm", ResourceName: "vm2", Holder: "node1", Parent: "node2"},
+		{ResourceType: "vm", ResourceName: "vm3", Holder: "node2", Parent: "node3"},
+		{ResourceType: "jail", ResourceName: "jail1", Holder: "node2", Parent: "node4"},
+	}
+
+	got := []string{}
+	for _, v := range OfflineCopies(copies, nodes) {
+		got = append(got, ResourceKey(v.ResourceType, v.ResourceName))
+	}
+	if want := []string{"vm/vm1", "jail/jail1"}; !slices.Equal(got, want) {
+		t.Errorf("OfflineCopies() = %v, want %v", got, want)
+	}
+}
+
+func TestPlanFailover(t *testing.T) {
+	const older = "replication_2023-08-14_16-49-08"
+	const newer = "replication_2023-08-15_10-00-00"
+
+	tests := []struct {
+		name        string
+		copies      []Copy
+		nodes       []Node
+		wantPlans   map[string]string // resource key -> target
+		wantPending []string
+	}{
+		{
+			name: "newest snapshot wins",
+			copies: []Copy{
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node2", Parent: "node1", Snapshot: older},
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node3", Parent: "node1", Snapshot: newer},
+			},
+			nodes:     []Node{{Name: "node1", Offline: true}, {Name: "node2"}, {Name: "node3"}},
+			wantPlans: map[string]string{"vm/vm1": "node3"},
+		},
+		{
+			name: "offline, maintenance and backup nodes are skipped",
+			copies: []Copy{
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node2", Parent: "node1", Snapshot: newer},
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node3", Parent: "node1", Snapshot: newer},
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node4", Parent: "node1", Snapshot: newer},
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node5", Parent: "node1", Snapshot: older},
+			},
+			nodes: []Node{
+				{Name: "node1", Offline: true},
+				{Name: "node2", Offline: true},
+				{Name: "node3", Maintenance: true},
+				{Name: "node4", Backup: true},
+				{Name: "node5"},
+			},
+			wantPlans: map[string]string{"vm/vm1": "node5"},
+		},
+		{
+			name: "parent's own copy is never promoted",
+			copies: []Copy{
+				{ResourceType: "jail", ResourceName: "jail1", Holder: "node1", Parent: "node1", Snapshot: newer},
+			},
+			nodes:       []Node{{Name: "node1"}, {Name: "node2"}},
+			wantPending: []string{"jail/jail1"},
+		},
+		{
+			name: "no online holder",
+			copies: []Copy{
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node2", Parent: "node1", Snapshot: newer},
+			},
+			nodes:       []Node{{Name: "node1", Offline: true}, {Name: "node2", Offline: true}},
+			wantPending: []string{"vm/vm1"},
+		},
+		{
+			name: "settings are respected",
+			copies: []Copy{
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node2", Parent: "node1", Snapshot: newer, Ha: &HaPlacement.Settings{AntiAffinityGroup: "db"}},
+				{ResourceType: "vm", ResourceName: "vm1", Holder: "node3", Parent: "node1", Snapshot: older, Ha: &HaPlacement.Settings{AntiAffinityGroup: "db"}},
+				{ResourceType: "vm", ResourceName: "vm2", Holder: "node2", Parent: "node1", Snapshot: newer, Ha: &HaPlacement.Settings{PreferredNodes: []string{"node2"}}},
+				{ResourceType: "vm", ResourceName: "vm2", Holder: "node3", Parent: "node1", Snapshot: newer, Ha: &HaPlacement.Settings{PreferredNodes: []string{"node2"}}},
+			},
+			nodes:     []Node{{Name: "node1", Offline: true}, {Name: "node2", Groups: []string{"db"}}, {Name: "node3"}},
+			wantPlans: map[string]string{"vm/vm1": "node3", "vm/vm2": "node2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plans, pending, reasons := PlanFailover(tt.copies, tt.nodes)
+
+			got := make(map[string]string)
+			for _, v := range plans {
+				if v.Target != v.Copy.Holder {
+					t.Errorf("plan target %s is not the holder %s", v.Target, v.Copy.Holder)
+				}
+				got[ResourceKey(v.Copy.ResourceType, v.Copy.ResourceName)] = v.Target
+			}
+			if len(got) != len(tt.wantPlans) {
+				t.Errorf("PlanFailover() plans = %v, want %v", got, tt.wantPlans)
+			}
+			for k, v := range tt.wantPlans {
+				if got[k] != v {
+					t.Errorf("PlanFailover() %s -> %s, want %s", k, got[k], v)
+				}
+			}
+
+			for _, v := range tt.wantPending {
+				found := slices.ContainsFunc(pending, func(c Copy) bool { return ResourceKey(c.ResourceType, c.ResourceName) == v })
+				if !found {
+					t.Errorf("PlanFailover() pending = %v, want %s", pending, v)
+				}
+				if len(reasons[v]) < 1 {
+					t.Errorf("PlanFailover() has no reason for the pending %s", v)
+				}
+			}
+			if len(tt.wantPending) < 1 && len(pending) > 0 {
+				t.Errorf("PlanFailover() pending = %v, want none", pending)
+			}
+		})
+	}
+}
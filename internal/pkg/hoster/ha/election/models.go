@@ -0,0 +1,66 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaElection
+
+import "time"
+
+type State string
+
+const (
+	STATE_FOLLOWER  State = "follower"
+	STATE_CANDIDATE State = "candidate"
+	STATE_LEADER    State = "leader"
+)
+
+type Config struct {
+	// Name of this node, must be one of the Members
+	NodeName string
+	// All voting members, including this node
+	Members []string
+	// For how long a follower promises not to vote for anyone else after acknowledging the leader
+	LeaseDuration time.Duration
+	// How long a follower waits for the leader before starting an election (randomized between 1x and 2x)
+	ElectionTimeout time.Duration
+	// Clock used by the node, time.Now if not set (override it to simulate the cluster in-process)
+	Now func() time.Time
+}
+
+// Election message, sent over the /api/v2/ha/ping as a vote request or as a leader heartbeat
+type Message struct {
+	Term        int64  `json:"term"`
+	From        string `json:"from"`
+	VoteRequest bool   `json:"vote_request"`
+	PreVote     bool   `json:"pre_vote"`
+	Leader      bool   `json:"leader"`
+}
+
+type Reply struct {
+	Term int64  `json:"term"`
+	From string `json:"from"`
+	// Vote granted, or the leader heartbeat acknowledged
+	Granted bool   `json:"granted"`
+	Leader  string `json:"leader"`
+}
+
+type MemberStatus struct {
+	Name     string `json:"name"`
+	LastSeen int64  `json:"last_seen"`
+	Online   bool   `json:"online"`
+}
+
+type Status struct {
+	Node        string         `json:"node"`
+	State       State          `json:"state"`
+	Term        int64          `json:"term"`
+	Leader      string         `json:"leader"`
+	LeaseExpiry int64          `json:"lease_expiry"`
+	Quorum      bool           `json:"quorum"`
+	Members     []MemberStatus `json:"members"`
+}
+
+// Delivers the election message to the other member. The in-process simulations can call the other Node.Handle() directly.
+type Transport interface {
+	Send(member string, msg Message) (Reply, error)
+}
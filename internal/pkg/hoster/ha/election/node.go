@@ -0,0 +1,326 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaElection
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The leader gives up it's lease a bit earlier than the followers release their promise, to allow for the clock drift
+const (
+	leaseSafetyNumerator   = 9
+	leaseSafetyDenominator = 10
+)
+
+// Lease based leader election: a leader is elected by the majority of the members in a new term, and it stays the leader
+// only as long as the majority keeps acknowledging it's heartbeats.
+// A member which has acknowledged the leader (or granted it's vote) refuses to vote for anyone else until the lease runs out,
+// and the leader steps down before that, so there is never more than one leader holding a valid lease.
+type Node struct {
+	mu        sync.Mutex
+	config    Config
+	transport Transport
+	rng       *rand.Rand
+
+	state    State
+	term     int64
+	votedFor string
+	leader   string
+	// Leader only: the lease is valid until the majority acknowledgement runs out
+	leaseExpiry time.Time
+	// Member which we promised not to vote against, and until when
+	promisedTo       string
+	promiseExpiry    time.Time
+	electionDeadline time.Time
+	lastSeen         map[string]time.Time
+}
+
+func NewNode(config Config, transport Transport) (r *Node, e error) {
+	if len(config.NodeName) < 1 {
+		e = errors.New("node name must be set")
+		return
+	}
+	if !slices.Contains(config.Members, config.NodeName) {
+		e = errors.New("node " + config.NodeName + " is not one of the members")
+		return
+	}
+	if config.LeaseDuration <= 0 || config.ElectionTimeout <= 0 {
+		e = errors.New("lease duration and election timeout must be set")
+		return
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	// Seeded by the node name, so the simulated clusters are reproducible, and the members still time out at a different pace
+	hash := fnv.New64a()
+	hash.Write([]byte(config.NodeName))
+
+	r = &Node{
+		config:    config,
+		transport: transport,
+		rng:       rand.New(rand.NewSource(int64(hash.Sum64()))),
+		state:     STATE_FOLLOWER,
+		lastSeen:  make(map[string]time.Time),
+	}
+	r.electionDeadline = config.Now().Add(r.randomTimeout())
+	return
+}
+
+func (n *Node) randomTimeout() time.Duration {
+	return n.config.ElectionTimeout + time.Duration(n.rng.Int63n(int64(n.config.ElectionTimeout)))
+}
+
+func (n *Node) majority(count int) bool {
+	return count*2 > len(n.config.Members)
+}
+
+func (n *Node) becomeFollower(term int64) {
+	// The vote is only reset in the new term, a member can't vote twice in the same one
+	if term > n.term {
+		n.votedFor = ""
+	}
+	n.state = STATE_FOLLOWER
+	n.term = term
+	n.leader = ""
+}
+
+// Drives the state machine, must be called periodically (more often than the lease duration):
+// the leader renews it's lease, followers and candidates start a new election once the leader is gone.
+func (n *Node) Tick() {
+	n.mu.Lock()
+	now := n.config.Now()
+
+	if n.state == STATE_LEADER {
+		if now.After(n.leaseExpiry) {
+			n.becomeFollower(n.term)
+			n.electionDeadline = now.Add(n.randomTimeout())
+			n.mu.Unlock()
+			return
+		}
+
+		msg := Message{Term: n.term, From: n.config.NodeName, Leader: true}
+		n.mu.Unlock()
+		replies := n.broadcast(msg)
+
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		granted := n.processReplies(msg, replies)
+		if n.term == msg.Term && n.state == STATE_LEADER && n.majority(granted) {
+			n.leaseExpiry = now.Add(n.config.LeaseDuration * leaseSafetyNumerator / leaseSafetyDenominator)
+		}
+		return
+	}
+
+	// Don't disrupt the leader we've promised to follow, and wait for the election timeout to run out
+	if now.Before(n.promiseExpiry) || now.Before(n.electionDeadline) {
+		n.mu.Unlock()
+		return
+	}
+
+	// Pre-vote first: a member coming back from the minority partition must not bump the term (and depose the healthy leader),
+	// unless the majority is willing to elect it
+	term := n.term
+	n.electionDeadline = now.Add(n.randomTimeout())
+	preVote := Message{Term: term + 1, From: n.config.NodeName, VoteRequest: true, PreVote: true}
+	n.mu.Unlock()
+	replies := n.broadcast(preVote)
+
+	n.mu.Lock()
+	granted := n.processReplies(preVote, replies)
+	if n.term != term || n.state == STATE_LEADER || !n.majority(granted) {
+		n.mu.Unlock()
+		return
+	}
+
+	n.state = STATE_CANDIDATE
+	n.term += 1
+	n.votedFor = n.config.NodeName
+	n.leader = ""
+	msg := Message{Term: n.term, From: n.config.NodeName, VoteRequest: true}
+	n.mu.Unlock()
+	replies = n.broadcast(msg)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	granted = n.processReplies(msg, replies)
+	if n.term == msg.Term && n.state == STATE_CANDIDATE && n.majority(granted) {
+		n.state = STATE_LEADER
+		n.leader = n.config.NodeName
+		n.leaseExpiry = now.Add(n.config.LeaseDuration * leaseSafetyNumerator / leaseSafetyDenominator)
+	}
+}
+
+// Returns the number of members which have granted the request (including this node), and steps down if any of them is in a higher term
+func (n *Node) processReplies(msg Message, replies []Reply) (r int) {
+	now := n.config.Now()
+	r = 1
+	for _, v := range replies {
+		n.lastSeen[v.From] = now
+		if v.Term > n.term {
+			n.becomeFollower(v.Term)
+			n.electionDeadline = now.Add(n.randomTimeout())
+		}
+		if v.Granted {
+			r += 1
+		}
+	}
+
+	return
+}
+
+func (n *Node) broadcast(msg Message) (r []Reply) {
+	if n.transport == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	var mutexReplies sync.Mutex
+	for _, v := range n.config.Members {
+		if v == n.config.NodeName {
+			continue
+		}
+
+		wg.Add(1)
+		go func(member string) {
+			defer wg.Done()
+			reply, err := n.transport.Send(member, msg)
+			if err != nil {
+				return
+			}
+			// Don't trust the reply to name the right member
+			reply.From = member
+
+			mutexReplies.Lock()
+			r = append(r, reply)
+			mutexReplies.Unlock()
+		}(v)
+	}
+	wg.Wait()
+
+	return
+}
+
+// Handles the election message received from the other member
+func (n *Node) Handle(msg Message) (r Reply) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := n.config.Now()
+	n.lastSeen[msg.From] = now
+	r.From = n.config.NodeName
+
+	if msg.PreVote {
+		// Nothing changes here, only tells if the vote would be granted in the next term
+		leaseHeld := n.state == STATE_LEADER && now.Before(n.leaseExpiry)
+		promised := n.promisedTo != msg.From && now.Before(n.promiseExpiry)
+		r.Term = n.term
+		r.Leader = n.leader
+		r.Granted = !leaseHeld && !promised && msg.Term > n.term
+		return
+	}
+
+	if msg.VoteRequest {
+		// Refuse the vote without adopting the new term while our own, or the followed leader's lease is still valid
+		leaseHeld := n.state == STATE_LEADER && now.Before(n.leaseExpiry)
+		promised := n.promisedTo != msg.From && now.Before(n.promiseExpiry)
+		if leaseHeld || promised {
+			r.Term = n.term
+			r.Leader = n.leader
+			return
+		}
+	}
+
+	if msg.Term > n.term {
+		n.becomeFollower(msg.Term)
+	}
+
+	r.Term = n.term
+	if msg.Term < n.term {
+		r.Leader = n.leader
+		return
+	}
+
+	if msg.VoteRequest {
+		if len(n.votedFor) < 1 || n.votedFor == msg.From {
+			n.votedFor = msg.From
+			n.promisedTo = msg.From
+			n.promiseExpiry = now.Add(n.config.LeaseDuration)
+			n.electionDeadline = now.Add(n.randomTimeout())
+			r.Granted = true
+		}
+		r.Leader = n.leader
+		return
+	}
+
+	if msg.Leader {
+		// Only one leader can be elected in a term, so the candidates of the same term give up
+		n.state = STATE_FOLLOWER
+		n.leader = msg.From
+		n.promisedTo = msg.From
+		n.promiseExpiry = now.Add(n.config.LeaseDuration)
+		n.electionDeadline = now.Add(n.randomTimeout())
+		r.Granted = true
+	}
+
+	r.Leader = n.leader
+	return
+}
+
+// Marks the member as online, used for the member health when it's seen outside of the election (e.g. the regular pings)
+func (n *Node) Seen(member string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if slices.Contains(n.config.Members, member) {
+		n.lastSeen[member] = n.config.Now()
+	}
+}
+
+// Returns true only if this node is the leader, and it's lease is still valid
+func (n *Node) IsLeader() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.state == STATE_LEADER && n.config.Now().Before(n.leaseExpiry)
+}
+
+func (n *Node) Status() (r Status) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := n.config.Now()
+	r.Node = n.config.NodeName
+	r.State = n.state
+	r.Term = n.term
+	r.Leader = n.leader
+	if n.state == STATE_LEADER {
+		r.LeaseExpiry = n.leaseExpiry.Unix()
+	}
+
+	online := 0
+	for _, v := range n.config.Members {
+		member := MemberStatus{Name: v}
+		if v == n.config.NodeName {
+			member.LastSeen = now.Unix()
+			member.Online = true
+		} else if lastSeen, found := n.lastSeen[v]; found {
+			member.LastSeen = lastSeen.Unix()
+			member.Online = now.Sub(lastSeen) <= n.config.LeaseDuration
+		}
+		if member.Online {
+			online += 1
+		}
+		r.Members = append(r.Members, member)
+	}
+	r.Quorum = n.majority(online)
+
+	return
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaElection
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testLease   = 10 * time.Second
+	testTimeout = 3 * time.Second
+	testStep    = 500 * time.Millisecond
+)
+
+// Clock shared by all simulated members
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// In-process cluster: the messages are delivered by calling Handle() of the other member,
+// unless the two members are in different partitions
+type simCluster struct {
+	t     *testing.T
+	clock *fakeClock
+	names []string
+	nodes map[string]*Node
+
+	mu        sync.Mutex
+	partition map[string]int
+}
+
+type simTransport struct {
+	cluster *simCluster
+	from    string
+}
+
+func (s simTransport) Send(member string, msg Message) (Reply, error) {
+	if !s.cluster.reachable(s.from, member) {
+		return Reply{}, errors.New("unreachable")
+	}
+	return s.cluster.nodes[member].Handle(msg), nil
+}
+
+func newSimCluster(t *testing.T, size int) *simCluster {
+	c := &simCluster{
+		t:         t,
+		clock:     &fakeClock{now: time.Unix(1700000000, 0)},
+		nodes:     make(map[string]*Node),
+		partition: make(map[string]int),
+	}
+	for i := 1; i <= size; i++ {
+		c.names = append(c.names, fmt.Sprintf("node%d", i))
+	}
+
+	for _, v := range c.names {
+		config := Config{NodeName: v, Members: c.names, LeaseDuration: testLease, ElectionTimeout: testTimeout, Now: c.clock.Now}
+		node, err := NewNode(config, simTransport{cluster: c, from: v})
+		if err != nil {
+			t.Fatalf("NewNode() error = %s", err)
+		}
+		c.nodes[v] = node
+	}
+	return c
+}
+
+func (c *simCluster) reachable(from string, to string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.partition[from] == c.partition[to]
+}
+
+// Moves the members to the given partition, the rest of the cluster stays in the partition 0
+func (c *simCluster) split(partition int, members ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, v := range members {
+		c.partition[v] = partition
+	}
+}
+
+func (c *simCluster) heal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.partition = make(map[string]int)
+}
+
+// Returns the members which hold a valid lease, there must never be more than one
+func (c *simCluster) leaders() (r []string) {
+	for _, v := range c.names {
+		if c.nodes[v].IsLeader() {
+			r = append(r, v)
+		}
+	}
+	return
+}
+
+// Advances the clock and ticks every member, for the given duration, checking there is never more than one leader
+func (c *simCluster) run(d time.Duration) {
+	for elapsed := time.Duration(0); elapsed < d; elapsed += testStep {
+		c.clock.Advance(testStep)
+		for _, v := range c.names {
+			c.nodes[v].Tick()
+		}
+		if leaders := c.leaders(); len(leaders) > 1 {
+			c.t.Fatalf("%s: more than one leader holds a lease: %v", c.clock.Now().Format(time.TimeOnly), leaders)
+		}
+	}
+}
+
+func (c *simCluster) leader() string {
+	leaders := c.leaders()
+	if len(leaders) != 1 {
+		c.t.Fatalf("leaders = %v, want exactly one", leaders)
+	}
+	return leaders[0]
+}
+
+func TestElection(t *testing.T) {
+	c := newSimCluster(t, 3)
+	c.run(2 * testTimeout)
+	leader := c.leader()
+
+	for _, v := range c.names {
+		status := c.nodes[v].Status()
+		if status.Leader != leader {
+			t.Errorf("%s follows %q, want %s", v, status.Leader, leader)
+		}
+		if !status.Quorum {
+			t.Errorf("%s has no quorum", v)
+		}
+	}
+
+	// The lease keeps being renewed by the heartbeats
+	c.run(3 * testLease)
+	if got := c.leader(); got != leader {
+		t.Errorf("leader = %s after the renewals, want %s", got, leader)
+	}
+}
+
+func TestElectionPartition(t *testing.T) {
+	c := newSimCluster(t, 3)
+	c.run(2 * testTimeout)
+	oldLeader := c.leader()
+	oldTerm := c.nodes[oldLeader].Status().Term
+
+	// The isolated leader loses it's lease before the followers release their promise, and the majority elects a new leader
+	c.split(1, oldLeader)
+	c.run(testLease * leaseSafetyNumerator / leaseSafetyDenominator)
+	if c.nodes[oldLeader].IsLeader() {
+		t.Fatalf("isolated %s still holds the lease", oldLeader)
+	}
+	c.run(testLease + 2*testTimeout)
+	newLeader := c.leader()
+	if newLeader == oldLeader {
+		t.Fatalf("isolated %s has been re-elected", oldLeader)
+	}
+
+	// The pre-vote keeps the isolated member from bumping the term
+	isolated := c.nodes[oldLeader].Status()
+	if isolated.Term != oldTerm || isolated.State == STATE_LEADER {
+		t.Errorf("isolated member: term %d, state %s, want term %d as a follower", isolated.Term, isolated.State, oldTerm)
+	}
+
+	// Once the partition heals, the old leader follows the new one, without deposing it
+	c.heal()
+	c.run(testLease)
+	if got := c.leader(); got != newLeader {
+		t.Errorf("leader = %s after the partition has healed, want %s", got, newLeader)
+	}
+	if got := c.nodes[oldLeader].Status().Leader; got != newLeader {
+		t.Errorf("%s follows %q, want %s", oldLeader, got, newLeader)
+	}
+}
+
+func TestElectionMinority(t *testing.T) {
+	c := newSimCluster(t, 5)
+	c.split(1, "node1", "node2")
+	c.run(3 * testLease)
+
+	leader := c.leader()
+	if leader == "node1" || leader == "node2" {
+		t.Errorf("leader %s has been elected in the minority partition", leader)
+	}
+	for _, v := range []string{"node1", "node2"} {
+		if c.nodes[v].Status().Quorum {
+			t.Errorf("%s in the minority partition reports the quorum", v)
+		}
+	}
+}
+
+func TestElectionLeaseExpiry(t *testing.T) {
+	c := newSimCluster(t, 3)
+	c.run(2 * testTimeout)
+	c.leader()
+
+	// Without the majority acknowledging the heartbeats, the lease is not renewed, and runs out
+	for i, v := range c.names {
+		c.split(i+1, v)
+	}
+
+	c.run(testLease)
+	if leaders := c.leaders(); len(leaders) > 0 {
+		t.Errorf("leaders = %v with every member isolated, want none", leaders)
+	}
+
+	c.heal()
+	c.run(testLease + 2*testTimeout)
+	c.leader()
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaMembership
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reads the key of this node, a new one is generated on the first use
+func LoadOrCreateKey() (r ed25519.PrivateKey, e error) {
+	data, err := os.ReadFile(KEY_FILE)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			e = fmt.Errorf("node key %s is corrupted", KEY_FILE)
+			return
+		}
+		r = ed25519.NewKeyFromSeed(seed)
+		return
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		e = err
+		return
+	}
+
+	_, r, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		e = err
+		return
+	}
+
+	err = os.MkdirAll(STATE_FOLDER, 0700)
+	if err != nil {
+		e = err
+		return
+	}
+	e = os.WriteFile(KEY_FILE, []byte(base64.StdEncoding.EncodeToString(r.Seed())+"\n"), 0600)
+	return
+}
+
+func PublicKeyString(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// Returns the short fingerprint of the public key, which the admins compare out of band, e.g. "SHA256:3q2+7w..."
+func Fingerprint(publicKey string) string {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return "invalid"
+	}
+
+	sum := sha256.Sum256(key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+func signedBytes(from string, timestamp int64, payload string) []byte {
+	return []byte(from + "\n" + strconv.FormatInt(timestamp, 10) + "\n" + payload)
+}
+
+// Wraps the payload into a message signed by this node
+func Sign(key ed25519.PrivateKey, from string, payload interface{}) (r SignedMessage, e error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		e = err
+		return
+	}
+
+	r.From = from
+	r.Time = time.Now().Unix()
+	r.Payload = string(data)
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, signedBytes(r.From, r.Time, r.Payload)))
+	return
+}
+
+// Checks the message signature against the sender's public key, and unmarshals the payload into the target
+func Verify(msg SignedMessage, publicKey string, target interface{}) error {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key of %s is invalid", msg.From)
+	}
+	signature, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return fmt.Errorf("signature of the message from %s is invalid", msg.From)
+	}
+
+	skew := time.Now().Unix() - msg.Time
+	if skew > MAX_CLOCK_SKEW || skew < -MAX_CLOCK_SKEW {
+		return fmt.Errorf("message from %s is %d seconds off, check the clock sync", msg.From, skew)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), signedBytes(msg.From, msg.Time, msg.Payload), signature) {
+		return fmt.Errorf("message from %s has a bad signature", msg.From)
+	}
+
+	return json.Unmarshal([]byte(msg.Payload), target)
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaMembership
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSignVerify(t *testing.T) {
+	key := testKey(t)
+	otherKey := testKey(t)
+	request := JoinRequest{HostName: "node2", PublicKey: PublicKeyString(key)}
+	// Signs the message again, after it's time has been shifted
+	resign := func(msg *SignedMessage) {
+		msg.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, signedBytes(msg.From, msg.Time, msg.Payload)))
+	}
+
+	tests := []struct {
+		name      string
+		modify    func(msg *SignedMessage)
+		publicKey string
+		wantErr   string // empty means the message is accepted
+	}{
+		{name: "valid message", publicKey: PublicKeyString(key)},
+		{name: "clock skew within the limit", modify: func(msg *SignedMessage) { msg.Time -= MAX_CLOCK_SKEW - 5; resign(msg) }, publicKey: PublicKeyString(key)},
+		{name: "tampered time", modify: func(msg *SignedMessage) { msg.Time -= 5 }, publicKey: PublicKeyString(key), wantErr: "bad signature"},
+		{name: "signed by another key", publicKey: PublicKeyString(otherKey), wantErr: "message from node2 has a bad signature"},
+		{name: "tampered payload", modify: func(msg *SignedMessage) { msg.Payload = strings.Replace(msg.Payload, "node2", "node3", 1) }, publicKey: PublicKeyString(key), wantErr: "bad signature"},
+		{name: "tampered sender", modify: func(msg *SignedMessage) { msg.From = "node3" }, publicKey: PublicKeyString(key), wantErr: "message from node3 has a bad signature"},
+		{name: "message from the past", modify: func(msg *SignedMessage) { msg.Time -= MAX_CLOCK_SKEW + 10; resign(msg) }, publicKey: PublicKeyString(key), wantErr: "seconds off, check the clock sync"},
+		{name: "message from the future", modify: func(msg *SignedMessage) { msg.Time += MAX_CLOCK_SKEW + 10; resign(msg) }, publicKey: PublicKeyString(key), wantErr: "seconds off, check the clock sync"},
+		{name: "invalid signature encoding", modify: func(msg *SignedMessage) { msg.Signature = "not base64!" }, publicKey: PublicKeyString(key), wantErr: "signature of the message from node2 is invalid"},
+		{name: "invalid public key", publicKey: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: "public key of node2 is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Sign(key, "node2", request)
+			if err != nil {
+				t.Fatalf("Sign() error = %s", err)
+			}
+			if tt.modify != nil {
+				tt.modify(&msg)
+			}
+
+			got := JoinRequest{}
+			err = Verify(msg, tt.publicKey, &got)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %s", err)
+			}
+			if got != request {
+				t.Errorf("Verify() payload = %+v, want %+v", got, request)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	key := testKey(t)
+	fingerprint := Fingerprint(PublicKeyString(key))
+	if !strings.HasPrefix(fingerprint, "SHA256:") || fingerprint != Fingerprint(PublicKeyString(key)) {
+		t.Errorf("Fingerprint() = %s, want a stable SHA256 fingerprint", fingerprint)
+	}
+	if Fingerprint(PublicKeyString(testKey(t))) == fingerprint {
+		t.Errorf("Fingerprint() is the same for the different keys")
+	}
+	if got := Fingerprint("not base64!"); got != "invalid" {
+		t.Errorf("Fingerprint() = %s, want invalid", got)
+	}
+}
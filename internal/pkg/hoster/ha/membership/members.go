@@ -0,0 +1,209 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaMembership
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Membership list, persisted on every change. Safe for the concurrent use.
+type Members struct {
+	mu   sync.RWMutex
+	list []Member
+	file string // MEMBERS_FILE, unless set by the tests
+}
+
+// Reads the persisted membership, the list is empty if the node has never been a part of the cluster
+func ReadMembers() (r *Members, e error) {
+	r = &Members{list: []Member{}, file: MEMBERS_FILE}
+
+	data, err := os.ReadFile(MEMBERS_FILE)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			e = err
+		}
+		return
+	}
+
+	e = json.Unmarshal(data, &r.list)
+	return
+}
+
+// Write and rename, so the membership is never left half-written
+func (m *Members) save() error {
+	data, err := json.MarshalIndent(m.list, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(m.file), 0700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(m.file+".tmp", data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(m.file+".tmp", m.file)
+}
+
+func (m *Members) List() []Member {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.list)
+}
+
+// Returns the approved member
+func (m *Members) Approved(hostname string) (r Member, found bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, v := range m.list {
+		if v.HostName == hostname && v.Status == STATUS_APPROVED {
+			return v, true
+		}
+	}
+	return
+}
+
+// Records the join request. The key of the approved member (or of the pending request) can't be replaced this way,
+// it has to be removed first. Otherwise anyone knowing the HA password could swap the key right before the approval.
+func (m *Members) Request(request JoinRequest, ipAddress string) (r Member, e error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(request.HostName) < 1 || len(request.PublicKey) < 1 {
+		e = errors.New("host name and public key must be set")
+		return
+	}
+
+	index := slices.IndexFunc(m.list, func(v Member) bool { return v.HostName == request.HostName })
+	if index >= 0 {
+		existing := m.list[index]
+		if existing.PublicKey != request.PublicKey {
+			if existing.Status == STATUS_APPROVED {
+				e = fmt.Errorf("%s is already a member with a different key, remove it first", request.HostName)
+			} else {
+				e = fmt.Errorf("%s has already requested to join with a different key, remove the pending request first", request.HostName)
+			}
+			return
+		}
+		if existing.Status == STATUS_PENDING && existing.IpAddress != ipAddress {
+			m.list[index].IpAddress = ipAddress
+			e = m.save()
+		}
+		r = m.list[index]
+		return
+	}
+
+	r = Member{HostName: request.HostName, PublicKey: request.PublicKey, IpAddress: ipAddress, Status: STATUS_PENDING, Requested: time.Now().Unix()}
+	m.list = append(m.list, r)
+	e = m.save()
+	return
+}
+
+// Adds the member trusted without the approval: this node itself, or the node it has asked to join
+func (m *Members) Trust(member Member) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	member.Status = STATUS_APPROVED
+	if member.Approved == 0 {
+		member.Approved = time.Now().Unix()
+	}
+
+	index := slices.IndexFunc(m.list, func(v Member) bool { return v.HostName == member.HostName })
+	if index >= 0 {
+		if m.list[index] == member {
+			return nil
+		}
+		m.list[index] = member
+	} else {
+		m.list = append(m.list, member)
+	}
+	return m.save()
+}
+
+// Approves the join request. The fingerprint (as displayed on the joining node) must match the key of the request.
+func (m *Members) Approve(hostname string, fingerprint string) (r Member, e error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := slices.IndexFunc(m.list, func(v Member) bool { return v.HostName == hostname })
+	if index < 0 {
+		e = fmt.Errorf("%s has not requested to join", hostname)
+		return
+	}
+	if Fingerprint(m.list[index].PublicKey) != fingerprint {
+		e = fmt.Errorf("fingerprint %s doesn't match the key of %s, make sure the request comes from the right node", fingerprint, hostname)
+		return
+	}
+
+	if m.list[index].Status != STATUS_APPROVED {
+		m.list[index].Status = STATUS_APPROVED
+		m.list[index].Approved = time.Now().Unix()
+		e = m.save()
+	}
+	r = m.list[index]
+	return
+}
+
+func (m *Members) Remove(hostname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := slices.IndexFunc(m.list, func(v Member) bool { return v.HostName == hostname })
+	if index < 0 {
+		return fmt.Errorf("%s is not a member", hostname)
+	}
+
+	m.list = slices.Delete(m.list, index, index+1)
+	return m.save()
+}
+
+// Updates the last known address of the member
+func (m *Members) SetAddress(hostname string, ipAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := slices.IndexFunc(m.list, func(v Member) bool { return v.HostName == hostname })
+	if index < 0 || m.list[index].IpAddress == ipAddress {
+		return nil
+	}
+
+	m.list[index].IpAddress = ipAddress
+	return m.save()
+}
+
+// Replaces the approved members with the list synced from the master, the local pending requests are kept
+func (m *Members) Replace(list []Member) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := []Member{}
+	for _, v := range list {
+		if v.Status == STATUS_APPROVED {
+			result = append(result, v)
+		}
+	}
+	for _, v := range m.list {
+		if v.Status == STATUS_PENDING && !slices.ContainsFunc(result, func(r Member) bool { return r.HostName == v.HostName }) {
+			result = append(result, v)
+		}
+	}
+
+	if slices.Equal(result, m.list) {
+		return nil
+	}
+	m.list = result
+	return m.save()
+}
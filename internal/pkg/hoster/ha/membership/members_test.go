@@ -0,0 +1,194 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaMembership
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Membership with the given members, persisted into the temporary folder of the test
+func testMembers(t *testing.T, list ...Member) *Members {
+	t.Helper()
+	if list == nil {
+		list = []Member{}
+	}
+	return &Members{list: list, file: filepath.Join(t.TempDir(), "hoster_ha", "members.json")}
+}
+
+func TestMembersRequest(t *testing.T) {
+	key1 := PublicKeyString(testKey(t))
+	key2 := PublicKeyString(testKey(t))
+
+	tests := []struct {
+		name      string
+		existing  []Member
+		request   JoinRequest
+		ipAddress string
+		want      Member
+		wantErr   string
+	}{
+		{
+			name:      "new request is pending",
+			request:   JoinRequest{HostName: "node2", PublicKey: key1},
+			ipAddress: "10.0.0.2",
+			want:      Member{HostName: "node2", PublicKey: key1, IpAddress: "10.0.0.2", Status: STATUS_PENDING},
+		},
+		{
+			name:      "repeated request updates the address",
+			existing:  []Member{{HostName: "node2", PublicKey: key1, IpAddress: "10.0.0.2", Status: STATUS_PENDING}},
+			request:   JoinRequest{HostName: "node2", PublicKey: key1},
+			ipAddress: "10.0.0.3",
+			want:      Member{HostName: "node2", PublicKey: key1, IpAddress: "10.0.0.3", Status: STATUS_PENDING},
+		},
+		{
+			name:      "approved member keeps it's status and address",
+			existing:  []Member{{HostName: "node2", PublicKey: key1, IpAddress: "10.0.0.2", Status: STATUS_APPROVED}},
+			request:   JoinRequest{HostName: "node2", PublicKey: key1},
+			ipAddress: "10.0.0.3",
+			want:      Member{HostName: "node2", PublicKey: key1, IpAddress: "10.0.0.2", Status: STATUS_APPROVED},
+		},
+		{
+			name:     "pending key is pinned",
+			existing: []Member{{HostName: "node2", PublicKey: key1, Status: STATUS_PENDING}},
+			request:  JoinRequest{HostName: "node2", PublicKey: key2},
+			wantErr:  "node2 has already requested to join with a different key",
+		},
+		{
+			name:     "approved key is pinned",
+			existing: []Member{{HostName: "node2", PublicKey: key1, Status: STATUS_APPROVED}},
+			request:  JoinRequest{HostName: "node2", PublicKey: key2},
+			wantErr:  "node2 is already a member with a different key",
+		},
+		{
+			name:    "empty request",
+			request: JoinRequest{HostName: "node2"},
+			wantErr: "host name and public key must be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := testMembers(t, tt.existing...)
+			got, err := members.Request(tt.request, tt.ipAddress)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Request() error = %v, want %q", err, tt.wantErr)
+				}
+				// The pinned key is left untouched
+				if len(tt.existing) > 0 && members.List()[0].PublicKey != tt.existing[0].PublicKey {
+					t.Errorf("Request() has replaced the key: %+v", members.List())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Request() error = %s", err)
+			}
+
+			got.Requested = 0
+			got.Approved = 0
+			if got != tt.want {
+				t.Errorf("Request() = %+v, want %+v", got, tt.want)
+			}
+			if len(members.List()) != 1 {
+				t.Errorf("List() = %+v, want a single member", members.List())
+			}
+		})
+	}
+}
+
+func TestMembersApprove(t *testing.T) {
+	key := PublicKeyString(testKey(t))
+	members := testMembers(t)
+
+	_, err := members.Request(JoinRequest{HostName: "node2", PublicKey: key}, "10.0.0.2")
+	if err != nil {
+		t.Fatalf("Request() error = %s", err)
+	}
+	if _, found := members.Approved("node2"); found {
+		t.Errorf("Approved() has found the pending request")
+	}
+
+	_, err = members.Approve("node3", Fingerprint(key))
+	if err == nil || !strings.Contains(err.Error(), "node3 has not requested to join") {
+		t.Errorf("Approve() error = %v, want the unknown node", err)
+	}
+	_, err = members.Approve("node2", Fingerprint(PublicKeyString(testKey(t))))
+	if err == nil || !strings.Contains(err.Error(), "doesn't match the key of node2") {
+		t.Errorf("Approve() error = %v, want the fingerprint mismatch", err)
+	}
+	if _, found := members.Approved("node2"); found {
+		t.Fatalf("node2 was approved with the wrong fingerprint")
+	}
+
+	member, err := members.Approve("node2", Fingerprint(key))
+	if err != nil {
+		t.Fatalf("Approve() error = %s", err)
+	}
+	if member.Status != STATUS_APPROVED || member.Approved == 0 {
+		t.Errorf("Approve() = %+v, want the approved member", member)
+	}
+	if _, found := members.Approved("node2"); !found {
+		t.Errorf("Approved() hasn't found node2")
+	}
+
+	// The membership is persisted
+	data, err := os.ReadFile(members.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := []Member{}
+	err = json.Unmarshal(data, &saved)
+	if err != nil || !slices.Equal(saved, members.List()) {
+		t.Errorf("saved membership = %+v, want %+v", saved, members.List())
+	}
+}
+
+func TestMembersReplace(t *testing.T) {
+	local := []Member{
+		{HostName: "node1", PublicKey: "key1", Status: STATUS_APPROVED},
+		{HostName: "node4", PublicKey: "key4", Status: STATUS_PENDING},
+		{HostName: "node5", PublicKey: "key5", Status: STATUS_PENDING},
+	}
+	synced := []Member{
+		{HostName: "node1", PublicKey: "key1", Status: STATUS_APPROVED},
+		{HostName: "node2", PublicKey: "key2", Status: STATUS_APPROVED},
+		{HostName: "node3", PublicKey: "key3", Status: STATUS_PENDING},  // Pending on the master only
+		{HostName: "node5", PublicKey: "key5", Status: STATUS_APPROVED}, // Approved on the master
+	}
+	members := testMembers(t, slices.Clone(local)...)
+
+	err := members.Replace(synced)
+	if err != nil {
+		t.Fatalf("Replace() error = %s", err)
+	}
+
+	want := []Member{
+		{HostName: "node1", PublicKey: "key1", Status: STATUS_APPROVED},
+		{HostName: "node2", PublicKey: "key2", Status: STATUS_APPROVED},
+		{HostName: "node5", PublicKey: "key5", Status: STATUS_APPROVED},
+		{HostName: "node4", PublicKey: "key4", Status: STATUS_PENDING},
+	}
+	if got := members.List(); !slices.Equal(got, want) {
+		t.Errorf("Replace() = %+v, want %+v", got, want)
+	}
+
+	// The unchanged list is not written again
+	err = os.Remove(members.file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = members.Replace(synced)
+	if err != nil {
+		t.Fatalf("Replace() error = %s", err)
+	}
+	if _, err := os.Stat(members.file); err == nil {
+		t.Errorf("Replace() has saved the unchanged membership")
+	}
+}
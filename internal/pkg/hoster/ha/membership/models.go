@@ -0,0 +1,49 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaMembership
+
+const (
+	STATE_FOLDER = "/var/db/hoster_ha"
+	KEY_FILE     = STATE_FOLDER + "/node.key"     // Private key of this node, never leaves it
+	MEMBERS_FILE = STATE_FOLDER + "/members.json" // Cluster membership, survives the restarts
+
+	// Signed messages older (or newer) than this are rejected, so they can't be replayed later on
+	MAX_CLOCK_SKEW = 60 // Seconds
+)
+
+const (
+	STATUS_PENDING  = "pending"  // Join has been requested, waiting for the admin's approval
+	STATUS_APPROVED = "approved" // Member of the cluster
+)
+
+type Member struct {
+	HostName  string `json:"host_name"`
+	PublicKey string `json:"public_key"` // Base64 encoded ed25519 public key
+	IpAddress string `json:"ip_address,omitempty"`
+	Status    string `json:"status"`
+	Requested int64  `json:"requested,omitempty"` // Unix time of the join request
+	Approved  int64  `json:"approved,omitempty"`  // Unix time of the approval
+}
+
+// Message signed by the sender's node key. The payload is kept as a JSON string, so it reaches the receiver byte for byte.
+type SignedMessage struct {
+	From      string `json:"from"`
+	Time      int64  `json:"time"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"` // Base64 encoded ed25519 signature
+}
+
+// Sent by the node asking to join the cluster
+type JoinRequest struct {
+	HostName  string `json:"host_name"`
+	PublicKey string `json:"public_key"`
+}
+
+// Identity of the node which has received the join request, the joining node trusts it from now on
+type JoinResponse struct {
+	HostName  string `json:"host_name"`
+	PublicKey string `json:"public_key"`
+	Status    string `json:"status"` // Status of the join request
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaPlacement
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Per-resource HA settings, which are stored in the VM or Jail config
+type Settings struct {
+	Priority          int      `json:"priority,omitempty"`            // Higher priority resources are failed over first, default is 0
+	PreferredNodes    []string `json:"preferred_nodes,omitempty"`     // Failover targets to try first (among the copies with the newest snapshot), in the order of preference
+	AntiAffinityGroup string   `json:"anti_affinity_group,omitempty"` // Resources of the same group are never placed on the same node
+}
+
+// Copy of the resource available on the node, which can be promoted
+type Copy struct {
+	Node     string
+	Snapshot string // Sortable timestamp of the latest snapshot, the newest copy wins
+}
+
+type Resource struct {
+	Key      string // Unique resource key, e.g. "vm/test-vm-1"
+	Settings Settings
+	Copies   []Copy
+}
+
+type Node struct {
+	Name         string
+	RamFree      uint64
+	MaxResources int      // Max number of resources running on the node, 0 means unlimited
+	Resources    int      // Number of resources already running on the node
+	Groups       []string // Anti-affinity groups of the resources already running on the node
+}
+
+type Placement struct {
+	Resource Resource
+	Copy     Copy // Copy that will be promoted, Copy.Node is the new parent
+}
+
+type Rejection struct {
+	Resource Resource
+	Reason   string
+}
+
+func (s *Settings) preference(node string) int {
+	i := slices.Index(s.PreferredNodes, node)
+	if i < 0 {
+		return len(s.PreferredNodes)
+	}
+	return i
+}
+
+// Places the resources (which have lost their parent) on the nodes holding their copies.
+//
+// Resources are placed by priority (then by the key), so the critical ones get the free slots first. Among the allowed copies
+// the newest snapshot wins, then the preferred nodes, then the node with less resources running, and then the one with more free RAM.
+// A copy is not allowed on the node which has reached it's max resources, or which already runs a resource of the same anti-affinity group.
+//
+// Placements are returned in the order they should be executed in. Rejected resources can be retried later, once the nodes change.
+func Plan(resources []Resource, nodes []Node) (r []Placement, rejected []Rejection) {
+	state := make(map[string]*Node)
+	for _, v := range nodes {
+		node := v
+		node.Groups = slices.Clone(v.Groups)
+		state[v.Name] = &node
+	}
+
+	ordered := slices.Clone(resources)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Settings.Priority != ordered[j].Settings.Priority {
+			return ordered[i].Settings.Priority > ordered[j].Settings.Priority
+		}
+		return ordered[i].Key < ordered[j].Key
+	})
+
+	for _, resource := range ordered {
+		group := resource.Settings.AntiAffinityGroup
+		allowed := []Copy{}
+		reasons := []string{}
+		for _, v := range resource.Copies {
+			node, online := state[v.Node]
+			if !online {
+				reasons = append(reasons, v.Node+": offline")
+				continue
+			}
+			if node.MaxResources > 0 && node.Resources >= node.MaxResources {
+				reasons = append(reasons, fmt.Sprintf("%s: reached the max of %d resources", v.Node, node.MaxResources))
+				continue
+			}
+			if len(group) > 0 && slices.Contains(node.Groups, group) {
+				reasons = append(reasons, fmt.Sprintf("%s: already runs the anti-affinity group %s", v.Node, group))
+				continue
+			}
+			allowed = append(allowed, v)
+		}
+
+		if len(allowed) < 1 {
+			reason := "no copies available"
+			if len(reasons) > 0 {
+				reason = strings.Join(reasons, ", ")
+			}
+			rejected = append(rejected, Rejection{Resource: resource, Reason: reason})
+			continue
+		}
+
+		sort.SliceStable(allowed, func(i, j int) bool {
+			a, b := allowed[i], allowed[j]
+			// Promoting an older copy loses the data, so the preferred nodes only break the tie between the equally fresh ones
+			if a.Snapshot != b.Snapshot {
+				return a.Snapshot > b.Snapshot
+			}
+			if prefA, prefB := resource.Settings.preference(a.Node), resource.Settings.preference(b.Node); prefA != prefB {
+				return prefA < prefB
+			}
+			nodeA, nodeB := state[a.Node], state[b.Node]
+			if nodeA.Resources != nodeB.Resources {
+				return nodeA.Resources < nodeB.Resources
+			}
+			if nodeA.RamFree != nodeB.RamFree {
+				return nodeA.RamFree > nodeB.RamFree
+			}
+			return a.Node < b.Node
+		})
+
+		best := allowed[0]
+		node := state[best.Node]
+		node.Resources += 1
+		if len(group) > 0 {
+			node.Groups = append(node.Groups, group)
+		}
+		r = append(r, Placement{Resource: resource, Copy: best})
+	}
+
+	return
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HaPlacement
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	const older = "20230814164908000000"
+	const newer = "20230815100000000000"
+
+	tests := []struct {
+		name      string
+		resources []Resource
+		nodes     []Node
+		want      []string          // "<resource key> -> <node>", in the execution order
+		rejected  map[string]string // resource key -> part of the reason
+	}{
+		{
+			name: "newest snapshot wins",
+			resources: []Resource{
+				{Key: "vm/vm1", Copies: []Copy{{Node: "node2", Snapshot: older}, {Node: "node3", Snapshot: newer}}},
+			},
+			nodes: []Node{{Name: "node2"}, {Name: "node3"}},
+			want:  []string{"vm/vm1 -> node3"},
+		},
+		{
+			name: "less loaded node, then more free RAM",
+			resources: []Resource{
+				{Key: "vm/vm1", Copies: []Copy{{Node: "node2", Snapshot: newer}, {Node: "node3", Snapshot: newer}}},
+				{Key: "vm/vm2", Copies: []Copy{{Node: "node3", Snapshot: newer}, {Node: "node4", Snapshot: newer}}},
+				{Key: "vm/vm3", Copies: []Copy{{Node: "node5", Snapshot: newer}, {Node: "node6", Snapshot: newer}}},
+			},
+			nodes: []Node{
+				{Name: "node2", Resources: 2},
+				{Name: "node3", Resources: 1},
+				{Name: "node4", Resources: 1, RamFree: 2048},
+				{Name: "node5", RamFree: 1024},
+				{Name: "node6", RamFree: 4096},
+			},
+			want: []string{"vm/vm1 -> node3", "vm/vm2 -> node4", "vm/vm3 -> node6"},
+		},
+		{
+			name: "higher priority is placed first",
+			resources: []Resource{
+				{Key: "vm/a-low", Copies: []Copy{{Node: "node2", Snapshot: newer}}},
+				{Key: "vm/b-high", Settings: Settings{Priority: 10}, Copies: []Copy{{Node: "node2", Snapshot: newer}}},
+				{Key: "vm/c-default", Copies: []Copy{{Node: "node3", Snapshot: newer}}},
+			},
+			nodes:    []Node{{Name: "node2", MaxResources: 1}, {Name: "node3"}},
+			want:     []string{"vm/b-high -> node2", "vm/c-default -> node3"},
+			rejected: map[string]string{"vm/a-low": "node2: reached the max of 1 resources"},
+		},
+		{
+			name: "preferred node with an older copy loses to the newer snapshot",
+			resources: []Resource{
+				{Key: "vm/vm1", Settings: Settings{PreferredNodes: []string{"node4", "node2"}}, Copies: []Copy{{Node: "node2", Snapshot: older}, {Node: "node3", Snapshot: newer}}},
+			},
+			nodes: []Node{{Name: "node2"}, {Name: "node3"}, {Name: "node4"}},
+			want:  []string{"vm/vm1 -> node3"},
+		},
+		{
+			name: "preferred nodes break the tie between the equally fresh copies",
+			resources: []Resource{
+				{Key: "vm/vm1", Settings: Settings{PreferredNodes: []string{"node4", "node3"}}, Copies: []Copy{{Node: "node2", Snapshot: newer}, {Node: "node3", Snapshot: newer}, {Node: "node4", Snapshot: older}}},
+				{Key: "vm/vm2", Settings: Settings{PreferredNodes: []string{"node4", "node2"}}, Copies: []Copy{{Node: "node2", Snapshot: older}, {Node: "node4", Snapshot: older}}},
+			},
+			nodes: []Node{{Name: "node2"}, {Name: "node3", Resources: 5}, {Name: "node4"}},
+			want:  []string{"vm/vm1 -> node3", "vm/vm2 -> node4"},
+		},
+		{
+			name: "anti-affinity with the running and the placed resources",
+			resources: []Resource{
+				{Key: "vm/db1", Settings: Settings{AntiAffinityGroup: "db"}, Copies: []Copy{{Node: "node2", Snapshot: newer}, {Node: "node3", Snapshot: older}}},
+				{Key: "vm/db2", Settings: Settings{AntiAffinityGroup: "db"}, Copies: []Copy{{Node: "node3", Snapshot: newer}, {Node: "node4", Snapshot: older}}},
+				{Key: "vm/db3", Settings: Settings{AntiAffinityGroup: "db"}, Copies: []Copy{{Node: "node3", Snapshot: newer}}},
+				{Key: "vm/web1", Copies: []Copy{{Node: "node2", Snapshot: newer}}},
+			},
+			nodes:    []Node{{Name: "node2", Groups: []string{"db"}}, {Name: "node3"}, {Name: "node4"}},
+			want:     []string{"vm/db1 -> node3", "vm/db2 -> node4", "vm/web1 -> node2"},
+			rejected: map[string]string{"vm/db3": "node3: already runs the anti-affinity group db"},
+		},
+		{
+			name: "max resources counts the placed resources",
+			resources: []Resource{
+				{Key: "vm/vm1", Copies: []Copy{{Node: "node2", Snapshot: newer}, {Node: "node3", Snapshot: older}}},
+				{Key: "vm/vm2", Copies: []Copy{{Node: "node2", Snapshot: newer}, {Node: "node3", Snapshot: older}}},
+				{Key: "vm/vm3", Copies: []Copy{{Node: "node2", Snapshot: newer}, {Node: "node3", Snapshot: older}}},
+			},
+			nodes:    []Node{{Name: "node2", MaxResources: 2, Resources: 1}, {Name: "node3", MaxResources: 2, Resources: 1}},
+			want:     []string{"vm/vm1 -> node2", "vm/vm2 -> node3"},
+			rejected: map[string]string{"vm/vm3": "node2: reached the max of 2 resources, node3: reached the max of 2 resources"},
+		},
+		{
+			name: "offline holders and no copies",
+			resources: []Resource{
+				{Key: "vm/vm1", Copies: []Copy{{Node: "node9", Snapshot: newer}}},
+				{Key: "vm/vm2"},
+			},
+			nodes:    []Node{{Name: "node2"}},
+			rejected: map[string]string{"vm/vm1": "node9: offline", "vm/vm2": "no copies available"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placements, rejected := Plan(tt.resources, tt.nodes)
+
+			got := []string{}
+			for _, v := range placements {
+				got = append(got, v.Resource.Key+" -> "+v.Copy.Node)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Plan() = %v, want %v", got, tt.want)
+			}
+
+			if len(rejected) != len(tt.rejected) {
+				t.Errorf("Plan() rejected = %+v, want %v", rejected, tt.rejected)
+			}
+			for _, v := range rejected {
+				want, found := tt.rejected[v.Resource.Key]
+				if !found || !strings.Contains(v.Reason, want) {
+					t.Errorf("Plan() rejected %s (%s), want %q", v.Resource.Key, v.Reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanDoesNotModifyInput(t *testing.T) {
+	nodes := []Node{{Name: "node2", Groups: []string{"web"}}}
+	resources := []Resource{
+		{Key: "vm/b", Settings: Settings{AntiAffinityGroup: "db"}, Copies: []Copy{{Node: "node2"}}},
+		{Key: "vm/a", Copies: []Copy{{Node: "node2"}}},
+	}
+
+	Plan(resources, nodes)
+	if nodes[0].Resources != 0 || !slices.Equal(nodes[0].Groups, []string{"web"}) {
+		t.Errorf("Plan() has modified the nodes: %+v", nodes)
+	}
+	if resources[0].Key != "vm/b" {
+		t.Errorf("Plan() has reordered the resources: %+v", resources)
+	}
+}
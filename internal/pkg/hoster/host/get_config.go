@@ -18,19 +18,32 @@ type HostConfigKey struct {
 }
 
 type DnsStaticRecord struct {
-	Domain string `json:"domain"` // The domain name, e.g. "example.com" or simply "example"
-	Type   string `json:"type"`   // The record type, e.g. "A", "AAAA", "CNAME", "TXT", "MX", "NS", "SRV", "SOA", "PTR"
-	Data   string `json:"data"`   // The record data, e.g. "192.168.120.1" for A record, "mail.example.com" for CNAME, etc.
+	Domain   string   `json:"domain"`             // The domain name, e.g. "example.com" or simply "example"
+	Type     string   `json:"type"`               // The record type, e.g. "A", "AAAA", "CNAME", "TXT", "MX", "NS", "SRV", "SOA", "PTR"
+	Data     string   `json:"data"`               // The record data, e.g. "192.168.120.1" for A record, "mail.example.com" for CNAME, etc.
+	Networks []string `json:"networks,omitempty"` // Hoster network names (views) this record is visible to, e.g. ["internal"]. Visible to everyone if empty.
+}
+
+type DnsForwardingRule struct {
+	DomainSuffix string   `json:"domain_suffix"`      // The domain suffix to match, e.g. "corp.example.com" (also matches "db.corp.example.com")
+	Upstreams    []string `json:"upstreams"`          // Upstream DNS servers for this suffix, e.g. ["10.10.0.53", "10.10.0.54:5353"]
+	Networks     []string `json:"networks,omitempty"` // Hoster network names (views) this rule applies to, e.g. ["internal"]. Applies to everyone if empty.
 }
 
 type HostConfig struct {
-	ImageServer       string            `json:"public_vm_image_server"`
-	DnsSearchDomain   string            `json:"dns_search_domain,omitempty"`
-	Tags              []string          `json:"tags"`
-	ActiveZfsDatasets []string          `json:"active_datasets"`
-	DnsServers        []string          `json:"dns_servers,omitempty"`
-	DnsStaticRecords  []DnsStaticRecord `json:"dns_static_records,omitempty"`
-	HostSSHKeys       []HostConfigKey   `json:"host_ssh_keys"`
+	ImageServer       string              `json:"public_vm_image_server"`
+	DnsSearchDomain   string              `json:"dns_search_domain,omitempty"`
+	Tags              []string            `json:"tags"`
+	ActiveZfsDatasets []string            `json:"active_datasets"`
+	DnsServers        []string            `json:"dns_servers,omitempty"`
+	DnsStaticRecords  []DnsStaticRecord   `json:"dns_static_records,omitempty"`
+	DnsForwarding     []DnsForwardingRule `json:"dns_forwarding_rules,omitempty"`
+	DnsAllowedClients []string            `json:"dns_allowed_clients,omitempty"` // CIDRs allowed to query the DNS server, e.g. ["10.0.0.0/8"]. Defaults to the Hoster networks + localhost.
+	DnsRateLimit      int                 `json:"dns_rate_limit,omitempty"`      // Queries per second, per client. 0 uses the default limit, -1 disables rate limiting.
+	DnsBlocklists     []string            `json:"dns_blocklists,omitempty"`      // Hosts-format blocklist files, e.g. ["/opt/hoster-core/config_files/blocklist.txt"]
+	DnsBlockSinkhole  string              `json:"dns_block_sinkhole,omitempty"`  // Address returned for the blocked A queries, e.g. "0.0.0.0". NXDOMAIN is returned if empty.
+	MacOui            string              `json:"mac_oui,omitempty"`             // VM MAC address prefix, e.g. "58:9c:fc" (default)
+	HostSSHKeys       []HostConfigKey     `json:"host_ssh_keys"`
 }
 
 const confFileName = "host_config.json"
@@ -5,13 +5,22 @@
 package HosterHostUtils
 
 import (
+	DnsServerClient "HosterCore/internal/app/dns_server/client"
 	FreeBSDKill "HosterCore/internal/pkg/freebsd/kill"
 	FreeBSDPgrep "HosterCore/internal/pkg/freebsd/pgrep"
 	"errors"
 	"regexp"
 )
 
+// Asks the DNS server to reload it's zone over the control socket.
+//
+// Falls back to sending a SIGHUP, in case the control socket is not available (e.g. an older DNS server version is still running).
 func ReloadDns() error {
+	err := DnsServerClient.ReloadZone()
+	if err == nil {
+		return nil
+	}
+
 	svcInfo, err := dnsServiceInfo()
 	if err != nil {
 		reMatchExit1 := regexp.MustCompile(`exit status 1`)
@@ -0,0 +1,360 @@
+package HosterHostUtils
+
+import (
+	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
+	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Returns the list of IP addresses used by all VMs and Jails on this host.
+//
+// Resources without a network set are assigned to the first (default) network.
+func IpamInventory(networks []HosterNetwork.NetworkConfig) (r []HosterNetwork.IpLease, e error) {
+	defaultNetwork := ""
+	if len(networks) > 0 {
+		defaultNetwork = networks[0].NetworkName
+	}
+
+	vms, err := HosterVmUtils.ListAllSimple()
+	if err != nil {
+		e = err
+		return
+	}
+	for _, v := range vms {
+		conf, err := HosterVmUtils.GetVmConfig(v.Mountpoint + "/" + v.VmName)
+		if err != nil {
+			continue
+		}
+
+		for _, vv := range conf.Networks {
+			network := vv.NetworkBridge
+			if len(network) < 1 {
+				network = defaultNetwork
+			}
+			if len(vv.IPAddress) > 0 {
+				r = append(r, HosterNetwork.IpLease{Network: network, IpAddress: vv.IPAddress, Owner: v.VmName, OwnerType: "vm"})
+			}
+			if len(vv.IPv6Address) > 0 {
+				r = append(r, HosterNetwork.IpLease{Network: network, IpAddress: vv.IPv6Address, Owner: v.VmName, OwnerType: "vm"})
+			}
+		}
+	}
+
+	jails, err := HosterJailUtils.ListAllSimple()
+	if err != nil {
+		e = err
+		return
+	}
+	for _, v := range jails {
+		conf, err := HosterJailUtils.GetJailConfig(v.Mountpoint + "/" + v.JailName)
+		if err != nil {
+			continue
+		}
+		network := conf.Network
+		if len(network) < 1 {
+			network = defaultNetwork
+		}
+		if len(conf.IPAddress) > 0 {
+			r = append(r, HosterNetwork.IpLease{Network: network, IpAddress: conf.IPAddress, Owner: v.JailName, OwnerType: "jail"})
+		}
+		if len(conf.IPv6Address) > 0 {
+			r = append(r, HosterNetwork.IpLease{Network: network, IpAddress: conf.IPv6Address, Owner: v.JailName, OwnerType: "jail"})
+		}
+	}
+
+	return
+}
+
+// Loads the network config, the resource inventory and the IPAM state, and syncs the state with the inventory.
+//
+// If the network name is empty or could not be found, the first (default) network is used.
+// Returns the IPv6 IPAM if ipv6 is set (the network must be dual-stack).
+func loadIpam(networkName string, ipv6 bool) (ipam HosterNetwork.Ipam, network HosterNetwork.NetworkConfig, state HosterNetwork.IpamState, e error) {
+	networks, err := HosterNetwork.GetNetworkConfig()
+	if err != nil {
+		e = fmt.Errorf("could not read the network config: %s", err.Error())
+		return
+	}
+	if len(networks) < 1 {
+		e = fmt.Errorf("no networks are configured")
+		return
+	}
+
+	network = networks[0]
+	for _, v := range networks {
+		if v.NetworkName == networkName {
+			network = v
+			break
+		}
+	}
+
+	inventory, err := IpamInventory(networks)
+	if err != nil {
+		e = err
+		return
+	}
+
+	state, err = HosterNetwork.GetIpamState()
+	if err != nil {
+		e = fmt.Errorf("could not read the IPAM state: %s", err.Error())
+		return
+	}
+	state.Sync(networks, inventory, time.Now())
+
+	if ipv6 {
+		ipam, e = HosterNetwork.NewIpam6(network, inventory, state)
+		return
+	}
+	ipam, e = HosterNetwork.NewIpam(network, inventory, state)
+	return
+}
+
+// Checks if the IP address is an IPv6 one
+func isIpv6(ipAddress string) bool {
+	addr, err := netip.ParseAddr(ipAddress)
+	return err == nil && addr.Is6() && !addr.Is4In6()
+}
+
+// Allocates a new IP address on the network for the owner (VM or Jail name, can be empty).
+func AllocateIp(networkName string, owner string) (r string, e error) {
+	unlock, err := HosterNetwork.LockIpamState()
+	if err != nil {
+		e = err
+		return
+	}
+	defer unlock()
+
+	ipam, network, state, err := loadIpam(networkName, false)
+	if err != nil {
+		e = err
+		return
+	}
+
+	r, e = ipam.Allocate(owner, time.Now())
+	if e != nil {
+		return
+	}
+
+	// Persist the hold (and the quarantine changes found during the sync)
+	state.Hold(network, r, owner, time.Now())
+	e = HosterNetwork.SaveIpamState(state)
+	return
+}
+
+// Allocates a new IPv6 address on the network for the owner (VM or Jail name, can be empty).
+//
+// Returns an empty string if the network is IPv4 only. On the SLAAC networks the address is derived from
+// the MAC address (the same way the guest OS does it), and it's only used to publish the AAAA records.
+func AllocateIp6(networkName string, owner string, macAddress string) (r string, e error) {
+	networks, err := HosterNetwork.GetNetworkConfig()
+	if err != nil {
+		e = fmt.Errorf("could not read the network config: %s", err.Error())
+		return
+	}
+	if len(networks) < 1 {
+		e = fmt.Errorf("no networks are configured")
+		return
+	}
+
+	network := networks[0]
+	for _, v := range networks {
+		if v.NetworkName == networkName {
+			network = v
+			break
+		}
+	}
+	if !network.Ipv6Enabled() {
+		return
+	}
+	if network.Ipv6Slaac() {
+		if len(macAddress) < 1 {
+			return
+		}
+		return HosterNetwork.Eui64Address(network.Subnet6, macAddress)
+	}
+
+	unlock, err := HosterNetwork.LockIpamState()
+	if err != nil {
+		e = err
+		return
+	}
+	defer unlock()
+
+	ipam, _, state, err := loadIpam(network.NetworkName, true)
+	if err != nil {
+		e = err
+		return
+	}
+
+	r, e = ipam.Allocate(owner, time.Now())
+	if e != nil {
+		return
+	}
+
+	state.Hold(network, r, owner, time.Now())
+	e = HosterNetwork.SaveIpamState(state)
+	return
+}
+
+// Checks if the IP address (IPv4 or IPv6) can be explicitly assigned to the owner (VM or Jail name, can be empty).
+func CheckIpAvailable(networkName string, ipAddress string, owner string) error {
+	ipam, _, _, err := loadIpam(networkName, isIpv6(ipAddress))
+	if err != nil {
+		return err
+	}
+
+	return ipam.Check(ipAddress, owner)
+}
+
+// Reserves an IP address on the network. A new address is allocated if the IP address is empty.
+//
+// Returns the reserved IP address.
+func ReserveIp(networkName string, ipAddress string, owner string, comment string) (r string, e error) {
+	unlock, err := HosterNetwork.LockIpamState()
+	if err != nil {
+		e = err
+		return
+	}
+	defer unlock()
+
+	ipam, network, state, err := loadIpam(networkName, isIpv6(ipAddress))
+	if err != nil {
+		e = err
+		return
+	}
+	if addr, err := netip.ParseAddr(ipAddress); err == nil {
+		ipAddress = addr.String()
+	}
+
+	if len(ipAddress) < 1 {
+		ipAddress, err = ipam.Allocate(owner, time.Now())
+		if err != nil {
+			e = err
+			return
+		}
+	} else {
+		err = ipam.Check(ipAddress, owner)
+		if err != nil {
+			e = err
+			return
+		}
+	}
+
+	for _, v := range state.Reservations {
+		if v.Network == network.NetworkName && v.IpAddress == ipAddress {
+			e = fmt.Errorf("IP address %s is already reserved", ipAddress)
+			return
+		}
+	}
+
+	state.Reservations = append(state.Reservations, HosterNetwork.IpReservation{
+		Network:   network.NetworkName,
+		IpAddress: ipAddress,
+		Owner:     owner,
+		Comment:   comment,
+		CreatedAt: time.Now().Unix(),
+	})
+
+	e = HosterNetwork.SaveIpamState(state)
+	if e != nil {
+		return
+	}
+
+	r = ipAddress
+	return
+}
+
+// Removes the IP address reservation, and puts the address into quarantine.
+func ReleaseIp(networkName string, ipAddress string) error {
+	unlock, err := HosterNetwork.LockIpamState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, network, state, err := loadIpam(networkName, false)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return fmt.Errorf("invalid IP address %s", ipAddress)
+	}
+	ipAddress = addr.String()
+
+	for _, v := range state.Leases {
+		if v.Network == network.NetworkName && v.IpAddress == ipAddress {
+			return fmt.Errorf("IP address %s is still in use by %s", ipAddress, v.Owner)
+		}
+	}
+
+	state.Release(network, ipAddress, time.Now())
+	return HosterNetwork.SaveIpamState(state)
+}
+
+// Lists all used, reserved, quarantined and excluded addresses, including the conflicts between VMs and Jails.
+//
+// Lists all networks if the network name is empty.
+func ListIps(networkName string) (r []HosterNetwork.IpamAddress, e error) {
+	unlock, err := HosterNetwork.LockIpamState()
+	if err != nil {
+		e = err
+		return
+	}
+	defer unlock()
+
+	networks, err := HosterNetwork.GetNetworkConfig()
+	if err != nil {
+		e = fmt.Errorf("could not read the network config: %s", err.Error())
+		return
+	}
+
+	inventory, err := IpamInventory(networks)
+	if err != nil {
+		e = err
+		return
+	}
+
+	state, err := HosterNetwork.GetIpamState()
+	if err != nil {
+		e = fmt.Errorf("could not read the IPAM state: %s", err.Error())
+		return
+	}
+	state.Sync(networks, inventory, time.Now())
+
+	for _, v := range networks {
+		if len(networkName) > 0 && v.NetworkName != networkName {
+			continue
+		}
+
+		ipam, err := HosterNetwork.NewIpam(v, inventory, state)
+		if err != nil {
+			e = err
+			return
+		}
+		r = append(r, ipam.List(time.Now())...)
+
+		if v.Ipv6Enabled() {
+			ipam6, err := HosterNetwork.NewIpam6(v, inventory, state)
+			if err != nil {
+				e = err
+				return
+			}
+			r = append(r, ipam6.List(time.Now())...)
+		}
+	}
+
+	for _, v := range HosterNetwork.FindIpConflicts(inventory) {
+		if len(networkName) > 0 && v.Network != networkName {
+			continue
+		}
+		r = append(r, v)
+	}
+
+	e = HosterNetwork.SaveIpamState(state)
+	return
+}
@@ -0,0 +1,186 @@
+package HosterHostUtils
+
+import (
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Returns the current IPv4 addresses of all VMs and Jails (name -> address), which are used as the port forward targets
+func PortForwardTargets() (r map[string]string, e error) {
+	networks, err := HosterNetwork.GetNetworkConfig()
+	if err != nil {
+		e = fmt.Errorf("could not read the network config: %s", err.Error())
+		return
+	}
+
+	inventory, err := IpamInventory(networks)
+	if err != nil {
+		e = err
+		return
+	}
+
+	r = make(map[string]string)
+	for _, v := range inventory {
+		if isIpv6(v.IpAddress) {
+			continue
+		}
+		if _, found := r[v.Owner]; !found {
+			r[v.Owner] = v.IpAddress
+		}
+	}
+	return
+}
+
+// Renders the port forwards using the current VM and Jail IP addresses, and loads them into PF.
+//
+// Returns the forwards that were skipped because their target doesn't exist (anymore).
+func ApplyPortForwards() (skipped []HosterNetwork.PortForward, e error) {
+	forwards, err := HosterNetwork.GetPortForwards()
+	if err != nil {
+		e = err
+		return
+	}
+
+	targets, err := PortForwardTargets()
+	if err != nil {
+		e = err
+		return
+	}
+
+	// Not being able to find the default route is only an issue for the forwards without an interface set
+	defaultInterface, _ := HosterNetwork.DefaultRouteInterface()
+
+	ruleset, skipped := HosterNetwork.RenderPortForwards(forwards, targets, defaultInterface)
+	err = HosterNetwork.LoadPortForwards(ruleset)
+	// Clearing out an empty list of forwards is not worth failing on (PF may not be running at all)
+	if err != nil && len(forwards) > 0 {
+		e = err
+		return
+	}
+
+	return
+}
+
+// Re-applies the port forwards if there are any pointing to the VM or Jail, so they follow it's current IP address.
+func RefreshPortForwards(target string) error {
+	forwards, err := HosterNetwork.PortForwardsFor(target)
+	if err != nil {
+		return err
+	}
+	if len(forwards) < 1 {
+		return nil
+	}
+
+	_, err = ApplyPortForwards()
+	return err
+}
+
+// Validates and saves a new port forward, and applies the updated port forwards list.
+func AddPortForward(forward HosterNetwork.PortForward) error {
+	if len(forward.Protocol) < 1 {
+		forward.Protocol = "tcp"
+	}
+	forward.Protocol = strings.ToLower(forward.Protocol)
+
+	forwards, err := HosterNetwork.GetPortForwards()
+	if err != nil {
+		return err
+	}
+
+	err = HosterNetwork.ValidatePortForward(forward, forwards)
+	if err != nil {
+		return err
+	}
+
+	targets, err := PortForwardTargets()
+	if err != nil {
+		return err
+	}
+	if _, found := targets[forward.Target]; !found {
+		return fmt.Errorf("could not find a VM or Jail with an IPv4 address: %s", forward.Target)
+	}
+
+	inUse, err := hostPortInUse(forward.Protocol, forward.PublicPort)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return fmt.Errorf("public port %s/%d is used by a service running on this host", forward.Protocol, forward.PublicPort)
+	}
+
+	forwards = append(forwards, forward)
+	err = HosterNetwork.SavePortForwards(forwards)
+	if err != nil {
+		return err
+	}
+
+	_, err = ApplyPortForwards()
+	return err
+}
+
+// Removes the port forward, and applies the updated port forwards list.
+//
+// Interface must match the one the forward was created with: an empty interface only removes the forward without one,
+// never the forwards of the same port on the other interfaces.
+func RemovePortForward(protocol string, publicPort int, iface string) error {
+	if len(protocol) < 1 {
+		protocol = "tcp"
+	}
+
+	forwards, err := HosterNetwork.GetPortForwards()
+	if err != nil {
+		return err
+	}
+
+	result := []HosterNetwork.PortForward{}
+	found := false
+	otherInterfaces := []string{}
+	for _, v := range forwards {
+		if !strings.EqualFold(v.Protocol, protocol) || v.PublicPort != publicPort {
+			result = append(result, v)
+			continue
+		}
+		if v.Interface == iface {
+			found = true
+			continue
+		}
+		otherInterfaces = append(otherInterfaces, v.Interface)
+		result = append(result, v)
+	}
+	if !found {
+		if len(iface) < 1 && len(otherInterfaces) > 0 {
+			return fmt.Errorf("port forward %s/%d is set on the interface(s) %s, set the interface of the one to remove", protocol, publicPort, strings.Join(otherInterfaces, ", "))
+		}
+		return fmt.Errorf("port forward %s/%d doesn't exist", protocol, publicPort)
+	}
+
+	err = HosterNetwork.SavePortForwards(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = ApplyPortForwards()
+	return err
+}
+
+// Checks if any process on this host listens on the port
+func hostPortInUse(protocol string, port int) (r bool, e error) {
+	out, err := exec.Command("sockstat", "-4", "-6", "-l", "-P", protocol, "-p", strconv.Itoa(port)).CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("%s; %s", strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	for _, v := range strings.Split(string(out), "\n") {
+		v = strings.TrimSpace(v)
+		if len(v) < 1 || strings.HasPrefix(v, "USER") {
+			continue
+		}
+		r = true
+		return
+	}
+	return
+}
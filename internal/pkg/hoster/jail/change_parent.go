@@ -0,0 +1,59 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterJail
+
+import (
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	"errors"
+	"fmt"
+)
+
+// Replaces the parent on a Jail specified, if the newParent is passed as an empty string GetHostName() will be automatically used.
+func ChangeParent(jailName string, newParent string, ignoreLiveCheck bool) error {
+	// If the logger was already set, ignore this
+	if !log.ConfigSet {
+		log.SetFileLocation(HosterJailUtils.JAIL_AUDIT_LOG_LOCATION)
+	}
+
+	if len(jailName) < 1 {
+		return errors.New("you must provide a Jail name")
+	}
+
+	jailInfo, err := HosterJailUtils.InfoJsonApi(jailName)
+	if err != nil {
+		return err
+	}
+
+	if !ignoreLiveCheck {
+		if jailInfo.Running {
+			return errors.New("jail must be offline to perform this operation")
+		}
+	}
+
+	if len(newParent) < 1 {
+		newParent, _ = FreeBSDsysctls.SysctlKernHostname()
+	}
+
+	jailConf := jailInfo.JailConfig
+	if jailConf.Parent == newParent {
+		log.Debug("No changes applied, because the old parent value is the same as a new parent value")
+		return nil
+	}
+	jailConf.Parent = newParent
+
+	err = HosterJailUtils.ConfigFileWriter(jailConf, jailInfo.Simple.Mountpoint+"/"+jailName+"/"+HosterJailUtils.JAIL_CONFIG_NAME)
+	if err != nil {
+		return err
+	}
+
+	_, err = HosterJailUtils.WriteCache()
+	if err != nil {
+		return err
+	}
+
+	log.Info(fmt.Sprintf("Parent host has been changed for %s to %s", jailName, newParent))
+	return nil
+}
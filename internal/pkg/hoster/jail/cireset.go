@@ -56,7 +56,11 @@ func CiReset(input CiResetInput) error {
 		jailFolder = input.DsParent + "/" + input.OldJailName
 	}
 
-	jailConfig, err := generateJailDeployConfig(input.CpuLimit, input.RamLimit, input.IpAddress, input.Network, input.DnsServer, prod)
+	jailName := input.JailName
+	if len(jailName) < 1 {
+		jailName = input.OldJailName
+	}
+	jailConfig, err := generateJailDeployConfig(jailName, input.CpuLimit, input.RamLimit, input.IpAddress, input.Network, input.DnsServer, prod)
 	if err != nil {
 		return err
 	}
@@ -5,6 +5,7 @@
 package HosterJail
 
 import (
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
 	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
 	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
 	zfsutils "HosterCore/internal/pkg/zfs_utils"
@@ -73,5 +74,12 @@ func Clone(jailName string, newJailName string, snapshotName string) error {
 	}
 
 	log.Warn("jail has been cloned: " + jailName + "; cloned jail name: " + newJailName)
+
+	// The clone is already there, a failed DNS reload must not report it as failed
+	err = HosterHostUtils.ReloadDns()
+	if err != nil {
+		log.Warn("could not reload the DNS server after cloning the jail: " + newJailName + "; error: " + err.Error())
+	}
+
 	return nil
 }
@@ -69,7 +69,7 @@ func Deploy(input DeployInput) error {
 		}
 	}
 
-	jailConfig, err := generateJailDeployConfig(input.CpuLimit, input.RamLimit, input.IpAddress, input.Network, input.DnsServer, prod)
+	jailConfig, err := generateJailDeployConfig(input.JailName, input.CpuLimit, input.RamLimit, input.IpAddress, input.Network, input.DnsServer, prod)
 	if err != nil {
 		return err
 	}
@@ -177,7 +177,8 @@ jailNameLoop:
 	}
 }
 
-func generateJailDeployConfig(cpuLimit int, ramLimit string, ipAddress string, network string, dnsServer string, prod bool) (r HosterJailUtils.JailConfig, e error) {
+// The Jail name is the IPAM owner, so the addresses reserved for it are picked up
+func generateJailDeployConfig(jailName string, cpuLimit int, ramLimit string, ipAddress string, network string, dnsServer string, prod bool) (r HosterJailUtils.JailConfig, e error) {
 	r.CPULimitPercent = cpuLimit
 	r.RAMLimit = ramLimit
 
@@ -209,7 +210,7 @@ func generateJailDeployConfig(cpuLimit int, ramLimit string, ipAddress string, n
 	}
 
 	if len(ipAddress) < 1 {
-		r.IPAddress, err = HosterHostUtils.GenerateNewRandomIp(r.Network)
+		r.IPAddress, err = HosterHostUtils.AllocateIp(r.Network, jailName)
 		if err != nil {
 			e = err
 			return
@@ -218,6 +219,15 @@ func generateJailDeployConfig(cpuLimit int, ramLimit string, ipAddress string, n
 		r.IPAddress = ipAddress
 	}
 
+	// Dual-stack networks (SLAAC Jails configure their IPv6 address at the start time)
+	if networks[networkIndex].Ipv6Enabled() && !networks[networkIndex].Ipv6Slaac() {
+		r.IPv6Address, err = HosterHostUtils.AllocateIp6(r.Network, jailName, "")
+		if err != nil {
+			e = err
+			return
+		}
+	}
+
 	if len(dnsServer) > 0 {
 		r.DnsServer = dnsServer
 	}
@@ -0,0 +1,59 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterJail
+
+import (
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+)
+
+// Fences the production Jails this host is a parent of, by replacing the parent with an HA lock.
+//
+// Returns the list of Jails locked, which can't be started again until unlocked.
+func LockAllJails() (r []string, e error) {
+	jails, err := HosterJailUtils.ListJsonApi()
+	if err != nil {
+		e = err
+		return
+	}
+
+	haLockedString := HosterVmUtils.HaLockString()
+	for _, v := range jails {
+		if v.Production && !v.Backup {
+			err := ChangeParent(v.Name, haLockedString, true)
+			if err != nil {
+				log.Error("could not lock the Jail " + v.Name + ": " + err.Error())
+				continue
+			}
+			r = append(r, v.Name)
+		}
+	}
+
+	return
+}
+
+// Returns the HA locked Jails back to this host. Returns the list of Jails unlocked.
+func UnlockAllJails() (r []string, e error) {
+	jails, err := HosterJailUtils.ListJsonApi()
+	if err != nil {
+		e = err
+		return
+	}
+
+	hostname, _ := FreeBSDsysctls.SysctlKernHostname()
+	for _, v := range jails {
+		if v.Production && HosterVmUtils.IsHaLocked(v.Parent) {
+			err := ChangeParent(v.Name, hostname, true)
+			if err != nil {
+				log.Error("could not unlock the Jail " + v.Name + ": " + err.Error())
+				continue
+			}
+			r = append(r, v.Name)
+		}
+	}
+
+	return
+}
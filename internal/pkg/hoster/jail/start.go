@@ -8,6 +8,7 @@ import (
 	FileExists "HosterCore/internal/pkg/file_exists"
 	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
 	HosterHost "HosterCore/internal/pkg/hoster/host"
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
 	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
 	HosterNetwork "HosterCore/internal/pkg/hoster/network"
 	"bytes"
@@ -20,12 +21,15 @@ import (
 )
 
 type JailStart struct {
-	JailName      string
-	JailHostname  string
-	JailRootPath  string
-	CpuLimitReal  int
-	DefaultRouter string
-	Netmask       string
+	JailName       string
+	JailHostname   string
+	JailRootPath   string
+	CpuLimitReal   int
+	DefaultRouter  string
+	Netmask        string
+	DefaultRouter6 string
+	Netmask6       string
+	Ipv6Slaac      bool
 	HosterJailUtils.JailConfig
 	HosterNetwork.EpairInterface
 }
@@ -85,6 +89,11 @@ func Start(jailName string) error {
 		log.ErrorToFile(err.Error())
 		return err
 	}
+	err = HosterNetwork.ApplyShaping(ifaces.IFaceA, jailConfig.BandwidthIngress, jailConfig.BandwidthEgress)
+	if err != nil {
+		log.ErrorToFile(err.Error())
+		return err
+	}
 
 	err = createMissingConfigFiles(jailConfig, jailDsFolder+"/"+HosterJailUtils.JAIL_ROOT_FOLDER)
 	if err != nil {
@@ -104,6 +113,16 @@ func Start(jailName string) error {
 		return err
 	}
 
+	err = HosterJailUtils.ApplyFirewall(jailName, jailConfig)
+	if err != nil {
+		log.ErrorToFile(err.Error())
+		return err
+	}
+	err = HosterHostUtils.RefreshPortForwards(jailName)
+	if err != nil {
+		log.ErrorToFile("could not apply the port forwards: " + err.Error())
+	}
+
 	out, err := exec.Command("jail", "-f", jailTempRuntimeLocation, "-c").CombinedOutput()
 	if err != nil {
 		errorValue := fmt.Sprintf("%s; %s", strings.TrimSpace(string(out)), err.Error())
@@ -223,6 +242,13 @@ func setJailStartValues(jailName string, jailDsFolder string, jailConfig HosterJ
 			r.DefaultRouter = v.Gateway
 			Netmask := strings.Split(v.Subnet, "/")[1]
 			r.Netmask = Netmask
+
+			if v.Ipv6Slaac() {
+				r.Ipv6Slaac = true
+			} else if v.Ipv6Enabled() {
+				r.DefaultRouter6 = v.Gateway6
+				r.Netmask6 = HosterNetwork.PrefixLength(v.Subnet6)
+			}
 		}
 	}
 
@@ -6,6 +6,7 @@ package HosterJail
 
 import (
 	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -51,6 +52,9 @@ func Stop(jailName string) error {
 	jailTempRuntimeLocation := jailDsInfo.Mountpoint + "/" + jailName + "/" + HosterJailUtils.JAIL_TEMP_RUNTIME
 	// EOF Check if Jail exists and get it's dataset configuration
 
+	// The epair interface is destroyed together with the Jail, so the traffic shaping has to be removed first
+	_ = HosterNetwork.RemoveResourceShaping("jail", jailName)
+
 	out, err := exec.Command("jail", "-f", jailTempRuntimeLocation, "-r", jailName).CombinedOutput()
 	if err != nil {
 		errorValue := fmt.Sprintf("%s; %s", strings.TrimSpace(string(out)), err.Error())
@@ -58,6 +62,9 @@ func Stop(jailName string) error {
 		return errors.New(errorValue)
 	}
 
+	// Best effort, the anchor doesn't exist if the Jail firewall is not enabled
+	_ = HosterJailUtils.FlushFirewall(jailName)
+
 	err = HosterJailUtils.RemoveUptimeStateFile(jailName)
 	if err != nil {
 		log.ErrorToFile(err.Error())
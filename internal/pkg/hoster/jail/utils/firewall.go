@@ -0,0 +1,30 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterJailUtils
+
+import (
+	HosterFirewall "HosterCore/internal/pkg/hoster/firewall"
+)
+
+// Returns all IPv4 and IPv6 addresses assigned to the Jail
+func (c JailConfig) IpAddresses() (r []string) {
+	if len(c.IPAddress) > 0 {
+		r = append(r, c.IPAddress)
+	}
+	if len(c.IPv6Address) > 0 {
+		r = append(r, c.IPv6Address)
+	}
+	return
+}
+
+// Loads the Jail firewall rules into it's own PF anchor (or flushes the anchor if the firewall is disabled)
+func ApplyFirewall(jailName string, conf JailConfig) error {
+	return HosterFirewall.Apply(HosterFirewall.RESOURCE_JAIL, jailName, conf.IpAddresses(), conf.Firewall)
+}
+
+// Removes the Jail firewall rules from PF
+func FlushFirewall(jailName string) error {
+	return HosterFirewall.Flush(HosterFirewall.AnchorName(HosterFirewall.RESOURCE_JAIL, jailName))
+}
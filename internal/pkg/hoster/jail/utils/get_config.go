@@ -6,6 +6,8 @@ package HosterJailUtils
 
 import (
 	FileExists "HosterCore/internal/pkg/file_exists"
+	HosterFirewall "HosterCore/internal/pkg/hoster/firewall"
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
 	HosterHost "HosterCore/internal/pkg/hoster/host"
 	"encoding/json"
 	"errors"
@@ -14,22 +16,27 @@ import (
 )
 
 type JailConfig struct {
-	Production       bool     `json:"production"`
-	CPULimitPercent  int      `json:"cpu_limit_percent"`
-	RAMLimit         string   `json:"ram_limit"`
-	FailoverStrategy string   `json:"failover_strategy,omitempty"` // Can only be set to one of the two values: "cireset" or "change_parent"
-	StartupScript    string   `json:"startup_script"`
-	ShutdownScript   string   `json:"shutdown_script"`
-	ConfigFileAppend string   `json:"config_file_append"`
-	IPAddress        string   `json:"ip_address"`
-	Network          string   `json:"network"`
-	DnsSearchDomain  string   `json:"dns_search_domain,omitempty"`
-	DnsServer        string   `json:"dns_server"`
-	Timezone         string   `json:"timezone"`
-	Parent           string   `json:"parent"`
-	UUID             string   `json:"uuid,omitempty"`
-	Description      string   `json:"description"`
-	Tags             []string `json:"tags"`
+	Production       bool                   `json:"production"`
+	CPULimitPercent  int                    `json:"cpu_limit_percent"`
+	RAMLimit         string                 `json:"ram_limit"`
+	FailoverStrategy string                 `json:"failover_strategy,omitempty"` // Can only be set to one of the two values: "cireset" or "change_parent"
+	StartupScript    string                 `json:"startup_script"`
+	ShutdownScript   string                 `json:"shutdown_script"`
+	ConfigFileAppend string                 `json:"config_file_append"`
+	IPAddress        string                 `json:"ip_address"`
+	IPv6Address      string                 `json:"ipv6_address,omitempty"`
+	Network          string                 `json:"network"`
+	BandwidthIngress string                 `json:"bandwidth_ingress,omitempty"` // dummynet cap for the traffic towards the Jail, e.g. 100Mbit/s
+	BandwidthEgress  string                 `json:"bandwidth_egress,omitempty"`  // dummynet cap for the traffic from the Jail, e.g. 100Mbit/s
+	DnsSearchDomain  string                 `json:"dns_search_domain,omitempty"`
+	DnsServer        string                 `json:"dns_server"`
+	Timezone         string                 `json:"timezone"`
+	Parent           string                 `json:"parent"`
+	UUID             string                 `json:"uuid,omitempty"`
+	Description      string                 `json:"description"`
+	Tags             []string               `json:"tags"`
+	Firewall         *HosterFirewall.Config `json:"firewall,omitempty"`
+	Ha               *HaPlacement.Settings  `json:"ha,omitempty"`
 }
 
 const jailConfFilename = "jail_config.json"
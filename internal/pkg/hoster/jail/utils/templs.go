@@ -30,6 +30,16 @@ const TemplateJailRunningConfigPartial = `# Running Jail config generated by Hos
     exec.poststart += "jexec {{ .JailName }} ifconfig {{ .IFaceB }} inet {{ .IPAddress }}/{{ .Netmask }}";
     exec.poststart += "jexec {{ .JailName }} ifconfig {{ .IFaceB }} up";
     exec.poststart += "jexec {{ .JailName }} route add default {{ .DefaultRouter }}";
+    {{- if .IPv6Address }}
+    exec.poststart += "jexec {{ .JailName }} ifconfig {{ .IFaceB }} inet6 -ifdisabled {{ .IPv6Address }}/{{ .Netmask6 }}";
+    {{- end }}
+    {{- if .DefaultRouter6 }}
+    exec.poststart += "jexec {{ .JailName }} route -6 add default {{ .DefaultRouter6 }}";
+    {{- end }}
+    {{- if .Ipv6Slaac }}
+    exec.poststart += "jexec {{ .JailName }} ifconfig {{ .IFaceB }} inet6 -ifdisabled accept_rtadv auto_linklocal";
+    exec.poststart += "jexec {{ .JailName }} rtsol {{ .IFaceB }}";
+    {{- end }}
 
     # Cleanup the created resources
     exec.poststop += "rctl -r jail:{{ .JailName }}";
@@ -72,6 +82,7 @@ const TemplateJailConfigJson = `{
     "config_file_append": "jail_custom_parameters.conf",
 
     "ip_address": "{{ .IPAddress }}",
+    "ipv6_address": "{{ .IPv6Address }}",
     "network": "{{ .Network }}",
     "dns_server": "{{ .DnsServer }}",
 
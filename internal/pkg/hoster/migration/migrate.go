@@ -0,0 +1,297 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+//go:build freebsd
+// +build freebsd
+
+package HosterMigration
+
+import (
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HosterJail "HosterCore/internal/pkg/hoster/jail"
+	HosterJailUtils "HosterCore/internal/pkg/hoster/jail/utils"
+	HosterVm "HosterCore/internal/pkg/hoster/vm"
+	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	RESOURCE_VM   = "vm"
+	RESOURCE_JAIL = "jail"
+
+	// VMs get this long to shut down gracefully, before they are killed
+	vmStopTimeout = 300
+)
+
+type Options struct {
+	RemoveSource bool              // Destroy the local copy, once the resource is running on the target
+	Progress     func(PhaseTiming) // Called after every completed phase, e.g. to print the timing
+	Lock         *StateLock        // Lock taken by the caller (e.g. to report the conflict before going to the background), released at the end
+}
+
+// Moves the resource to the target node, keeping the downtime down to the last replication delta:
+// the resource is replicated while it's still running, then it's stopped, it's parent is changed to the target,
+// and the final changes (including the new parent in the config) are replicated. At the end, the parent is also changed
+// on the target side, and the resource is started there (only if it was running before).
+//
+// If anything fails before the final replication is done, the old parent is restored and the resource is started again locally.
+// Past that point the target has everything it needs, so the state is kept and the next call resumes the migration
+// from the phase that has failed (the same goes for the interrupted migrations).
+//
+// Only one migration of the resource can run at a time, ErrMigrationInProgress is returned while the other one holds the lock.
+func Migrate(resType string, resName string, target Target, opts Options) (r State, e error) {
+	lock := opts.Lock
+	if lock == nil {
+		lock, e = LockState(resType, resName)
+		if e != nil {
+			return
+		}
+	}
+	defer lock.Unlock()
+
+	// If the logger was already set, ignore this
+	if !log.ConfigSet {
+		if resType == RESOURCE_JAIL {
+			log.SetFileLocation(HosterJailUtils.JAIL_AUDIT_LOG_LOCATION)
+		} else {
+			log.SetFileLocation(HosterVmUtils.VM_AUDIT_LOG_LOCATION)
+		}
+	}
+	target.setDefaults()
+
+	r, found, err := readState(resType, resName)
+	if err != nil {
+		e = err
+		return
+	}
+	if found {
+		if r.Target.Endpoint != target.Endpoint {
+			e = fmt.Errorf("%s %s has an unfinished migration to %s, resume it using the same target", resType, resName, r.Target.Endpoint)
+			return
+		}
+		// The speed limit (or the key) could be changed on resume
+		r.Target = target
+		r.RemoveSource = r.RemoveSource || opts.RemoveSource
+		log.Info(fmt.Sprintf("Resuming the migration of %s %s to %s", resType, resName, r.NewParent))
+	} else {
+		r, e = newState(resType, resName, target, opts)
+		if e != nil {
+			return
+		}
+		log.Info(fmt.Sprintf("Migrating %s %s to %s, replicating while it's still running", resType, resName, r.NewParent))
+	}
+
+	phases := []struct {
+		name string
+		run  func() error
+		skip bool
+	}{
+		{name: PHASE_PRE_SYNC, run: func() error { return replicate(resName, r.Target) }},
+		{name: PHASE_STOP, run: func() error { return stopResource(resType, resName) }, skip: !r.Running},
+		{name: PHASE_CHANGE_PARENT, run: func() error { return changeParent(resType, resName, r.NewParent) }},
+		{name: PHASE_FINAL_SYNC, run: func() error { return replicate(resName, r.Target) }},
+		{name: PHASE_REMOTE_PARENT, run: func() error { return remoteChangeParent(resType, resName, r.Target) }},
+		{name: PHASE_REMOTE_START, run: func() error {
+			_, err := runRemote(r.Target, REMOTE_HOSTER_BINARY, resType, "start", resName)
+			return err
+		}, skip: !r.Running},
+		{name: PHASE_REMOVE_SOURCE, run: func() error { return destroyResource(resType, resName) }, skip: !r.RemoveSource},
+	}
+
+	for _, phase := range phases {
+		if phase.skip || r.Done(phase.name) {
+			continue
+		}
+
+		started := time.Now()
+		err := phase.run()
+		if err != nil {
+			e = fmt.Errorf("%s has failed: %w", phase.name, err)
+			if !r.Done(PHASE_FINAL_SYNC) {
+				e = errors.Join(e, restore(r), removeState(resType, resName))
+			} else {
+				log.Error(fmt.Sprintf("Migration of %s %s has failed at %s, run it again to resume: %s", resType, resName, phase.name, err.Error()))
+			}
+			return
+		}
+
+		timing := PhaseTiming{Phase: phase.name, Seconds: time.Since(started).Seconds()}
+		r.Completed = append(r.Completed, timing)
+		err = writeState(r)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Could not save the migration state of %s %s: %s", resType, resName, err.Error()))
+		}
+		if opts.Progress != nil {
+			opts.Progress(timing)
+		}
+	}
+
+	e = removeState(resType, resName)
+	log.Info(fmt.Sprintf("%s %s has been migrated to %s, downtime %s", resType, resName, r.NewParent, r.Downtime().Round(time.Millisecond)))
+	return
+}
+
+// Checks the resource and the target, before anything is changed
+func newState(resType string, resName string, target Target, opts Options) (r State, e error) {
+	hostname, err := FreeBSDsysctls.SysctlKernHostname()
+	if err != nil {
+		e = err
+		return
+	}
+
+	running, parent, err := resourceState(resType, resName)
+	if err != nil {
+		e = err
+		return
+	}
+	if parent != hostname {
+		e = fmt.Errorf("%s %s is a backup, it can only be migrated by it's parent %s", resType, resName, parent)
+		return
+	}
+
+	newParent, err := RemoteHostname(target)
+	if err != nil {
+		e = err
+		return
+	}
+	if newParent == hostname {
+		e = fmt.Errorf("%s is this node, pick a different target", target.Endpoint)
+		return
+	}
+
+	r.ResType = resType
+	r.ResName = resName
+	r.Target = target
+	r.OldParent = hostname
+	r.NewParent = newParent
+	r.Running = running
+	r.RemoveSource = opts.RemoveSource
+	r.StartedAt = time.Now().Unix()
+	r.Completed = []PhaseTiming{}
+
+	e = writeState(r)
+	return
+}
+
+// Returns the running state and the parent of the resource
+func resourceState(resType string, resName string) (running bool, parent string, e error) {
+	switch resType {
+	case RESOURCE_VM:
+		info, err := HosterVmUtils.InfoJsonApi(resName)
+		if err != nil {
+			e = err
+			return
+		}
+		running = info.Running
+		parent = info.ParentHost
+	case RESOURCE_JAIL:
+		info, err := HosterJailUtils.InfoJsonApi(resName)
+		if err != nil {
+			e = err
+			return
+		}
+		running = info.Running
+		parent = info.Parent
+	default:
+		e = fmt.Errorf("unknown resource type %s, use %s or %s", resType, RESOURCE_VM, RESOURCE_JAIL)
+	}
+
+	return
+}
+
+func changeParent(resType string, resName string, newParent string) error {
+	if resType == RESOURCE_JAIL {
+		return HosterJail.ChangeParent(resName, newParent, false)
+	}
+	return HosterVm.ChangeParent(resName, newParent, false)
+}
+
+// VM shutdown is async, so wait for the bhyve process to exit before the final snapshot is taken
+func stopResource(resType string, resName string) error {
+	// Already stopped by the interrupted run
+	running, _, err := resourceState(resType, resName)
+	if err != nil {
+		return err
+	}
+	if !running {
+		return nil
+	}
+
+	if resType == RESOURCE_JAIL {
+		return HosterJail.Stop(resName)
+	}
+
+	err = HosterVm.Stop(resName, false, false)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < vmStopTimeout; i++ {
+		running, _ := HosterVmUtils.GetRunningVms()
+		if !slices.Contains(running, resName) {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+
+	log.Warn(fmt.Sprintf("VM %s did not shut down in %d seconds, killing it", resName, vmStopTimeout))
+	err = HosterVm.Stop(resName, true, true)
+	if err != nil {
+		return err
+	}
+	time.Sleep(2 * time.Second)
+
+	return nil
+}
+
+// The replicated config already points to the new parent, this only makes sure the target agrees
+func remoteChangeParent(resType string, resName string, target Target) (e error) {
+	if resType == RESOURCE_JAIL {
+		_, e = runRemote(target, REMOTE_HOSTER_BINARY, "jail", "parent", resName)
+	} else {
+		_, e = runRemote(target, REMOTE_HOSTER_BINARY, "set", "parent", resName)
+	}
+	return
+}
+
+func destroyResource(resType string, resName string) error {
+	if resType == RESOURCE_JAIL {
+		return HosterJail.Destroy(resName)
+	}
+	return HosterVm.Destroy(resName)
+}
+
+// Puts the resource back on this node, after the migration has failed half way
+func restore(state State) error {
+	if state.Done(PHASE_CHANGE_PARENT) {
+		err := changeParent(state.ResType, state.ResName, state.OldParent)
+		if err != nil {
+			return fmt.Errorf("could not restore the parent: %w", err)
+		}
+	}
+	if !state.Running {
+		return nil
+	}
+
+	running, _, err := resourceState(state.ResType, state.ResName)
+	if err != nil {
+		return err
+	}
+	if !running {
+		if state.ResType == RESOURCE_JAIL {
+			err = HosterJail.Start(state.ResName)
+		} else {
+			err = HosterVm.Start(state.ResName, false, false)
+		}
+		if err != nil {
+			return fmt.Errorf("could not start it again: %w", err)
+		}
+	}
+
+	log.Warn(fmt.Sprintf("Migration of %s %s has failed, it's running on this node again", state.ResType, state.ResName))
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterMigration
+
+import (
+	SpeedLimitVar "HosterCore/internal/app/mbuffer/speed_limit_var"
+	SchedulerClient "HosterCore/internal/app/scheduler/client"
+	SchedulerUtils "HosterCore/internal/app/scheduler/utils"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	DEFAULT_SSH_PORT    = 22
+	DEFAULT_SSH_KEY     = "/root/.ssh/id_rsa"
+	DEFAULT_SPEED_LIMIT = 50 // MB/s
+
+	// Hoster CLI location on the target node
+	REMOTE_HOSTER_BINARY = "/opt/hoster-core/hoster"
+)
+
+// Node the resource is migrated to, reached over SSH (the same way the replication does it)
+type Target struct {
+	Endpoint   string `json:"endpoint"` // SSH endpoint, e.g. "10.0.0.2" or "root@10.0.0.2"
+	Port       int    `json:"port"`
+	Key        string `json:"key"`
+	SpeedLimit int    `json:"speed_limit"` // Replication speed limit, MB/s
+}
+
+func (t *Target) setDefaults() {
+	if t.Port < 1 {
+		t.Port = DEFAULT_SSH_PORT
+	}
+	if len(t.Key) < 1 {
+		t.Key = DEFAULT_SSH_KEY
+	}
+	if t.SpeedLimit < 1 {
+		t.SpeedLimit = DEFAULT_SPEED_LIMIT
+	}
+}
+
+// Runs the command on the target node, and returns it's output
+func runRemote(target Target, args ...string) (r string, e error) {
+	sshArgs := []string{"-oStrictHostKeyChecking=accept-new", "-oBatchMode=yes", "-i", target.Key, fmt.Sprintf("-p%d", target.Port), target.Endpoint}
+	sshArgs = append(sshArgs, args...)
+
+	out, err := exec.Command("ssh", sshArgs...).CombinedOutput()
+	r = strings.TrimSpace(string(out))
+	if err != nil {
+		e = fmt.Errorf("%s failed on %s: %s; %s", strings.Join(args, " "), target.Endpoint, r, err.Error())
+		return
+	}
+
+	return
+}
+
+// Returns the hostname of the target node, which becomes the new parent
+func RemoteHostname(target Target) (r string, e error) {
+	target.setDefaults()
+	r, e = runRemote(target, "sysctl", "-n", "kern.hostname")
+	return
+}
+
+// Sends the resource to the target node using the scheduler's replication scripts, but runs them right away.
+// Every call takes a new replication snapshot, so the second run only sends the changes made since the first one.
+func replicate(resName string, target Target) error {
+	job := SchedulerUtils.ReplicationJob{}
+	job.ResName = resName
+	job.SshEndpoint = target.Endpoint
+	job.SshPort = target.Port
+	job.SshKey = target.Key
+	job.SpeedLimit = target.SpeedLimit
+
+	scripts, _, err := SchedulerClient.Replicate(job)
+	if err != nil {
+		return err
+	}
+
+	env := append(os.Environ(), fmt.Sprintf("%s=%d", SpeedLimitVar.SPEED_LIMIT_OS_ENV, target.SpeedLimit))
+	for _, v := range append(scripts.ScriptsRemove, scripts.ScriptsReplicate...) {
+		cmd := exec.Command("sh", "-c", v)
+		cmd.Env = env
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("replication failed: %s; %s", lastLine(string(out)), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// zfs send reports the progress on every line, only the last one is useful in the error
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
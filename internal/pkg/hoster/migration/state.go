@@ -0,0 +1,188 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterMigration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Unfinished migrations are kept here, so they can be resumed after the interruption (or even the reboot)
+const MIGRATION_STATE_DIR = "/var/db/hoster_migrations"
+
+const (
+	PHASE_PRE_SYNC      = "pre-sync"      // Replication while the resource is still running
+	PHASE_STOP          = "stop"          // Downtime starts here
+	PHASE_CHANGE_PARENT = "change-parent" // Local copy is handed over to the target
+	PHASE_FINAL_SYNC    = "final-sync"    // Changes made since the pre-sync, including the new parent in the config
+	PHASE_REMOTE_PARENT = "remote-parent"
+	PHASE_REMOTE_START  = "remote-start" // Downtime ends here
+	PHASE_REMOVE_SOURCE = "remove-source"
+)
+
+type PhaseTiming struct {
+	Phase   string  `json:"phase"`
+	Seconds float64 `json:"seconds"`
+}
+
+type State struct {
+	ResType      string        `json:"res_type"`
+	ResName      string        `json:"res_name"`
+	Target       Target        `json:"target"`
+	OldParent    string        `json:"old_parent"`
+	NewParent    string        `json:"new_parent"`
+	Running      bool          `json:"running"` // Resource was running before the migration, and will be started on the target
+	RemoveSource bool          `json:"remove_source"`
+	StartedAt    int64         `json:"started_at"`
+	Completed    []PhaseTiming `json:"completed"`
+}
+
+// Returns true if the phase has been completed by the previous (interrupted) run
+func (s *State) Done(phase string) bool {
+	return slices.ContainsFunc(s.Completed, func(p PhaseTiming) bool { return p.Phase == phase })
+}
+
+// Time the resource has been down for, from the stop to the remote start
+func (s *State) Downtime() (r time.Duration) {
+	if !s.Running {
+		return
+	}
+	for _, v := range s.Completed {
+		if v.Phase == PHASE_PRE_SYNC || v.Phase == PHASE_REMOVE_SOURCE {
+			continue
+		}
+		r += time.Duration(v.Seconds * float64(time.Second))
+	}
+	return
+}
+
+func stateFileLocation(resType string, resName string) string {
+	return MIGRATION_STATE_DIR + "/" + resType + "_" + resName + ".json"
+}
+
+var ErrMigrationInProgress = errors.New("migration is already in progress")
+
+// Exclusive lock on the migration of a single resource, held for the whole migration
+type StateLock struct {
+	file *os.File
+}
+
+// Takes the migration lock, or returns ErrMigrationInProgress if another migration of the resource holds it.
+// The state file itself is replaced on every write, so the lock is taken on the file next to it.
+func LockState(resType string, resName string) (r *StateLock, e error) {
+	err := os.MkdirAll(MIGRATION_STATE_DIR, 0750)
+	if err != nil {
+		e = err
+		return
+	}
+
+	file, err := os.OpenFile(stateFileLocation(resType, resName)+".lock", os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		e = err
+		return
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			e = fmt.Errorf("%w: %s %s", ErrMigrationInProgress, resType, resName)
+			return
+		}
+		e = err
+		return
+	}
+
+	r = &StateLock{file: file}
+	return
+}
+
+func (l *StateLock) Unlock() {
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+}
+
+func readState(resType string, resName string) (r State, found bool, e error) {
+	data, err := os.ReadFile(stateFileLocation(resType, resName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		e = err
+		return
+	}
+
+	e = json.Unmarshal(data, &r)
+	found = e == nil
+	return
+}
+
+func writeState(state State) error {
+	err := os.MkdirAll(MIGRATION_STATE_DIR, 0750)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	// Write and rename, the half-written state can't be resumed
+	location := stateFileLocation(state.ResType, state.ResName)
+	err = os.WriteFile(location+".tmp", data, 0640)
+	if err != nil {
+		return err
+	}
+	return os.Rename(location+".tmp", location)
+}
+
+func removeState(resType string, resName string) error {
+	err := os.Remove(stateFileLocation(resType, resName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Returns all unfinished migrations
+func ListUnfinished() (r []State, e error) {
+	r = []State{}
+	files, err := os.ReadDir(MIGRATION_STATE_DIR)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		e = err
+		return
+	}
+
+	for _, v := range files {
+		if v.IsDir() || !strings.HasSuffix(v.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(MIGRATION_STATE_DIR + "/" + v.Name())
+		if err != nil {
+			e = err
+			return
+		}
+		state := State{}
+		err = json.Unmarshal(data, &state)
+		if err != nil {
+			e = err
+			return
+		}
+		r = append(r, state)
+	}
+
+	return
+}
@@ -21,6 +21,25 @@ type NetworkConfig struct {
 	BridgeInterface string `json:"bridge_interface"`
 	ApplyBridgeAddr bool   `json:"apply_bridge_address"`
 	Comment         string `json:"comment"`
+	// IPAM settings
+	IpAllocation string   `json:"ip_allocation,omitempty"` // "random" (default) or "sequential"
+	ExcludedIps  []string `json:"excluded_ips,omitempty"`  // single addresses or ranges, e.g. "10.0.101.50-10.0.101.60"
+	IpQuarantine int      `json:"ip_quarantine,omitempty"` // seconds, 0 means default (1 hour), -1 disables the quarantine
+	// IPv6 settings, the network is IPv4 only if the IPv6 subnet is not set
+	Subnet6     string `json:"network_subnet6,omitempty"`      // e.g. "fd00:100::/64"
+	Gateway6    string `json:"network_gateway6,omitempty"`     // e.g. "fd00:100::1"
+	RangeStart6 string `json:"network_range_start6,omitempty"` // defaults to the "<prefix>::10" address
+	RangeEnd6   string `json:"network_range_end6,omitempty"`   // defaults to the "<prefix>::ffff" address
+	Ipv6Mode    string `json:"ipv6_mode,omitempty"`            // "static" (default, addresses are allocated by Hoster) or "slaac"
+	// VLAN and VXLAN settings, the uplink interface is created by "hoster network init" and added to the bridge
+	VlanId      int    `json:"vlan_id,omitempty"`      // 802.1Q tag, "bridge_interface" is used as the parent interface
+	VxlanId     int    `json:"vxlan_id,omitempty"`     // VXLAN Network Identifier (VNI)
+	VxlanLocal  string `json:"vxlan_local,omitempty"`  // local tunnel endpoint address
+	VxlanRemote string `json:"vxlan_remote,omitempty"` // remote tunnel endpoint address (unicast tunnels)
+	VxlanGroup  string `json:"vxlan_group,omitempty"`  // multicast group address (multicast tunnels, used instead of the vxlan_remote)
+	VxlanDev    string `json:"vxlan_dev,omitempty"`    // interface used to send the multicast traffic
+	VxlanPort   int    `json:"vxlan_port,omitempty"`   // defaults to 4789
+	Mtu         int    `json:"mtu,omitempty"`          // uplink interface MTU, e.g. 1450 for VXLAN over the 1500 MTU links
 }
 
 const confFileName = "network_config.json"
@@ -66,27 +85,28 @@ func GetNetworkConfig() (r []NetworkConfig, e error) {
 }
 
 // Saves the network config to the network_config.json file by taking in the NetworkConfig struct.
+//
+// The config is validated first, and then written atomically, so a broken config never reaches the disk.
 func SaveNetworkConfig(config []NetworkConfig) error {
-	confFile, err := getNetworkConfigLocation()
+	err := ValidateNetworkConfig(config)
 	if err != nil {
 		return err
 	}
 
-	jsonData, err := json.MarshalIndent(config, "", "   ")
+	confFile, err := getNetworkConfigLocation()
 	if err != nil {
 		return err
 	}
 
-	file, err := os.OpenFile(confFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	jsonData, err := json.MarshalIndent(config, "", "   ")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	_, err = file.Write(jsonData)
+	err = os.WriteFile(confFile+".tmp", jsonData, 0644)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.Rename(confFile+".tmp", confFile)
 }
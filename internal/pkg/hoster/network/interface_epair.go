@@ -7,6 +7,7 @@ package HosterNetwork
 import (
 	"fmt"
 	"os/exec"
+	"regexp"
 	"strings"
 )
 
@@ -79,3 +80,49 @@ func CreateEpairInterface(jailName string, networkName string) (r EpairInterface
 func JailNetworkCleanup(jailName string, networkName string) (r EpairInterface, e error) {
 	return
 }
+
+var reIfconfigEther = regexp.MustCompile(`^\s+ether\s+(\S+)`)
+
+// Parses the `ifconfig` output, and returns the MAC addresses of the Jail epair interfaces (MAC -> Jail name).
+//
+// Only the host side (epairNa) is visible on the host, the Jail side (epairNb) gets the same MAC with the last octet set to 0b.
+func parseEpairMacs(ifconfigOutput string) map[string]string {
+	r := make(map[string]string)
+	owners := parseIfaceOwners(ifconfigOutput)
+
+	iface := ""
+	for _, v := range strings.Split(ifconfigOutput, "\n") {
+		if match := reIfconfigHeader.FindStringSubmatch(v); len(match) > 1 {
+			iface = match[1]
+			continue
+		}
+		match := reIfconfigEther.FindStringSubmatch(v)
+		if len(match) < 2 || !reEpairNumber.MatchString(iface) {
+			continue
+		}
+
+		owner := iface
+		if o, found := owners[iface]; found && o.resourceType == "jail" {
+			owner = o.resourceName
+		}
+		mac := strings.ToLower(match[1])
+		r[mac] = owner
+		if strings.HasSuffix(mac, ":0a") {
+			r[strings.TrimSuffix(mac, "0a")+"0b"] = owner
+		}
+	}
+
+	return r
+}
+
+// Returns the MAC addresses of the Jail epair interfaces on this host (MAC -> Jail name)
+func EpairMacAddresses() (r map[string]string, e error) {
+	out, err := exec.Command("ifconfig").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("%s; %s", strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	r = parseEpairMacs(string(out))
+	return
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEpairMacs(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifconfig string
+		want     map[string]string
+	}{
+		{
+			name: "Jail epairs",
+			ifconfig: `em0: flags=1008843<UP,BROADCAST,RUNNING,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 1500
+	ether 58:9c:fc:00:00:01
+epair0a: flags=1008943<UP,BROADCAST,RUNNING,PROMISC,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 1500
+	description: "jail::test-jail-1 iface::epair0a network::internal"
+	ether 02:7F:11:22:33:0A
+	groups: epair
+epair1a: flags=1008943<UP,BROADCAST,RUNNING,PROMISC,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 1500
+	description: "jail::test-jail-2 iface::epair1a network::external"
+	ether 58:9c:fc:10:20:30
+tap0: flags=1008943<UP,BROADCAST,RUNNING,PROMISC,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 1500
+	description: "vm::test-vm-1 iface::tap0 network::internal"
+	ether 58:9c:fc:52:bb:be
+`,
+			want: map[string]string{
+				"02:7f:11:22:33:0a": "test-jail-1",
+				"02:7f:11:22:33:0b": "test-jail-1", // Jail side of the epair
+				"58:9c:fc:10:20:30": "test-jail-2",
+			},
+		},
+		{
+			name: "epair without the Hoster description",
+			ifconfig: `epair5a: flags=1008943<UP,BROADCAST,RUNNING,PROMISC,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 1500
+	ether 02:aa:bb:cc:dd:0a
+epair6b: flags=1008943<UP,BROADCAST,RUNNING,PROMISC,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 1500
+	ether 02:aa:bb:cc:ee:0b
+`,
+			want: map[string]string{"02:aa:bb:cc:dd:0a": "epair5a", "02:aa:bb:cc:dd:0b": "epair5a"},
+		},
+		{
+			name:     "no epairs",
+			ifconfig: "lo0: flags=1008049<UP,LOOPBACK,RUNNING,MULTICAST,LOWER_UP> metric 0 mtu 16384\n",
+			want:     map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseEpairMacs(tt.ifconfig)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEpairMacs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
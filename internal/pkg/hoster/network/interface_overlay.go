@@ -0,0 +1,197 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const VXLAN_DEFAULT_PORT = 4789
+
+// Returns the name of the bridge uplink: VXLAN or VLAN interface if one is configured,
+// the physical (bridged) interface otherwise. Returns an empty string for the NAT (not bridged) networks.
+func (n NetworkConfig) UplinkInterface() string {
+	if n.VxlanId > 0 {
+		return "vxlan" + strconv.Itoa(n.VxlanId)
+	}
+	if n.VlanId > 0 {
+		return n.BridgeInterface + "." + strconv.Itoa(n.VlanId)
+	}
+	if len(n.BridgeInterface) > 0 && n.BridgeInterface != "None" {
+		return n.BridgeInterface
+	}
+	return ""
+}
+
+// Validates the VLAN and VXLAN settings
+func (n NetworkConfig) ValidateOverlay() error {
+	if n.VlanId != 0 && n.VxlanId != 0 {
+		return fmt.Errorf("network %s: vlan_id and vxlan_id can't be used together", n.NetworkName)
+	}
+
+	if n.VlanId != 0 {
+		if n.VlanId < 1 || n.VlanId > 4094 {
+			return fmt.Errorf("network %s: vlan_id must be within 1-4094", n.NetworkName)
+		}
+		if len(n.BridgeInterface) < 1 || n.BridgeInterface == "None" {
+			return fmt.Errorf("network %s: VLAN networks require the parent interface to be set in bridge_interface", n.NetworkName)
+		}
+	}
+
+	if n.VxlanId != 0 {
+		if n.VxlanId < 1 || n.VxlanId > 16777215 {
+			return fmt.Errorf("network %s: vxlan_id must be within 1-16777215", n.NetworkName)
+		}
+		if _, err := netip.ParseAddr(n.VxlanLocal); err != nil {
+			return fmt.Errorf("network %s: vxlan_local must be a valid IP address", n.NetworkName)
+		}
+		if (len(n.VxlanRemote) > 0) == (len(n.VxlanGroup) > 0) {
+			return fmt.Errorf("network %s: either vxlan_remote or vxlan_group must be set", n.NetworkName)
+		}
+		if len(n.VxlanRemote) > 0 {
+			if _, err := netip.ParseAddr(n.VxlanRemote); err != nil {
+				return fmt.Errorf("network %s: vxlan_remote must be a valid IP address", n.NetworkName)
+			}
+		}
+		if len(n.VxlanGroup) > 0 {
+			group, err := netip.ParseAddr(n.VxlanGroup)
+			if err != nil || !group.IsMulticast() {
+				return fmt.Errorf("network %s: vxlan_group must be a multicast address", n.NetworkName)
+			}
+			if len(n.VxlanDev) < 1 {
+				return fmt.Errorf("network %s: vxlan_dev is required for the multicast VXLAN networks", n.NetworkName)
+			}
+		}
+		if n.VxlanPort < 0 || n.VxlanPort > 65535 {
+			return fmt.Errorf("network %s: invalid vxlan_port", n.NetworkName)
+		}
+	}
+
+	if n.Mtu != 0 && (n.Mtu < 576 || n.Mtu > 9216) {
+		return fmt.Errorf("network %s: mtu must be within 576-9216", n.NetworkName)
+	}
+
+	return nil
+}
+
+// Checks if the network interface exists
+func InterfaceExists(iface string) bool {
+	return exec.Command("ifconfig", iface).Run() == nil
+}
+
+// Creates the VLAN or VXLAN uplink interface for the network (if it doesn't exist yet), and returns it's name.
+//
+// Plain bridged networks return the physical interface name, and NAT networks return an empty string.
+func CreateUplinkInterface(n NetworkConfig) (r string, e error) {
+	e = n.ValidateOverlay()
+	if e != nil {
+		return
+	}
+
+	r = n.UplinkInterface()
+	if n.VlanId < 1 && n.VxlanId < 1 {
+		return
+	}
+	if InterfaceExists(r) {
+		return
+	}
+
+	args := []string{r, "create"}
+	if n.VlanId > 0 {
+		args = append(args, "vlan", strconv.Itoa(n.VlanId), "vlandev", n.BridgeInterface)
+	} else {
+		port := n.VxlanPort
+		if port == 0 {
+			port = VXLAN_DEFAULT_PORT
+		}
+
+		args = append(args, "vxlanid", strconv.Itoa(n.VxlanId), "vxlanlocal", n.VxlanLocal, "vxlanport", strconv.Itoa(port))
+		if len(n.VxlanRemote) > 0 {
+			args = append(args, "vxlanremote", n.VxlanRemote)
+		} else {
+			args = append(args, "vxlangroup", n.VxlanGroup)
+		}
+		if len(n.VxlanDev) > 0 {
+			args = append(args, "vxlandev", n.VxlanDev)
+		}
+	}
+	if n.Mtu > 0 {
+		args = append(args, "mtu", strconv.Itoa(n.Mtu))
+	}
+
+	out, err := exec.Command("ifconfig", args...).CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("could not create %s: %s; %s", r, strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	out, err = exec.Command("ifconfig", r, "description", "hoster::network::"+n.NetworkName, "up").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("could not bring up %s: %s; %s", r, strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	return
+}
+
+var reBridgeMember = regexp.MustCompile(`^\s+member:\s+(\S+)`)
+
+// Returns the list of the bridge member interfaces
+func BridgeMembers(bridge string) (r []string, e error) {
+	out, err := exec.Command("ifconfig", bridge).CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("%s; %s", strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	for _, v := range strings.Split(string(out), "\n") {
+		match := reBridgeMember.FindStringSubmatch(v)
+		if len(match) > 1 {
+			r = append(r, match[1])
+		}
+	}
+
+	return
+}
+
+// Removes the network bridge, and the VLAN or VXLAN uplink interface created for it.
+//
+// Refuses to do so if any VMs or Jails are still connected to the bridge, unless force is set.
+func DestroyNetworkInterfaces(n NetworkConfig, force bool) error {
+	bridge := "vm-" + n.NetworkName
+	uplink := n.UplinkInterface()
+
+	if InterfaceExists(bridge) {
+		members, err := BridgeMembers(bridge)
+		if err != nil {
+			return err
+		}
+		for _, v := range members {
+			if v != uplink && !force {
+				return fmt.Errorf("network %s is still in use by the interface %s", n.NetworkName, v)
+			}
+		}
+
+		out, err := exec.Command("ifconfig", bridge, "destroy").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("could not destroy %s: %s; %s", bridge, strings.TrimSpace(string(out)), err.Error())
+		}
+	}
+
+	// Only the interfaces created by Hoster are removed, physical interfaces are left untouched
+	if (n.VlanId > 0 || n.VxlanId > 0) && InterfaceExists(uplink) {
+		out, err := exec.Command("ifconfig", uplink, "destroy").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("could not destroy %s: %s; %s", uplink, strings.TrimSpace(string(out)), err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Network interface counters of a VM TAP or a Jail epair interface.
+//
+// All counters are shown from the VM/Jail point of view: the bytes sent by the host side
+// of the interface are the bytes received by the VM/Jail, and vice versa.
+type IfaceStats struct {
+	Interface     string  `json:"interface"`
+	Network       string  `json:"network"`
+	ResourceType  string  `json:"resource_type"` // vm or jail
+	ResourceName  string  `json:"resource_name"`
+	RxBytes       uint64  `json:"rx_bytes"`
+	TxBytes       uint64  `json:"tx_bytes"`
+	RxPackets     uint64  `json:"rx_packets"`
+	TxPackets     uint64  `json:"tx_packets"`
+	RxErrors      uint64  `json:"rx_errors"`
+	TxErrors      uint64  `json:"tx_errors"`
+	RxBytesPerSec float64 `json:"rx_bytes_per_sec"` // only set by the IfaceStatsCollector
+	TxBytesPerSec float64 `json:"tx_bytes_per_sec"` // only set by the IfaceStatsCollector
+}
+
+type ifaceOwner struct {
+	resourceType string
+	resourceName string
+	network      string
+}
+
+var reIfconfigHeader = regexp.MustCompile(`^(\S+):\s+flags=`)
+var reIfconfigDescription = regexp.MustCompile(`^\s+description:\s+(.*)$`)
+var reOwnerVm = regexp.MustCompile(`vm::([^\s"]+)`)
+var reOwnerJail = regexp.MustCompile(`jail::([^\s"]+)`)
+var reOwnerNetwork = regexp.MustCompile(`network::([^\s"]+)`)
+
+// Maps the interfaces to their VMs or Jails, using the descriptions Hoster sets on the TAP and epair interfaces,
+// e.g. "vm::test-vm-1 iface::tap0 network::internal". Takes in the `ifconfig` output.
+func parseIfaceOwners(ifconfigOutput string) map[string]ifaceOwner {
+	r := make(map[string]ifaceOwner)
+
+	iface := ""
+	for _, v := range strings.Split(ifconfigOutput, "\n") {
+		match := reIfconfigHeader.FindStringSubmatch(v)
+		if len(match) > 1 {
+			iface = match[1]
+			continue
+		}
+
+		match = reIfconfigDescription.FindStringSubmatch(v)
+		if len(match) < 2 || len(iface) < 1 {
+			continue
+		}
+
+		owner := ifaceOwner{}
+		if vm := reOwnerVm.FindStringSubmatch(match[1]); len(vm) > 1 {
+			owner.resourceType = "vm"
+			owner.resourceName = vm[1]
+		} else if jail := reOwnerJail.FindStringSubmatch(match[1]); len(jail) > 1 {
+			owner.resourceType = "jail"
+			owner.resourceName = jail[1]
+		} else {
+			continue
+		}
+		if network := reOwnerNetwork.FindStringSubmatch(match[1]); len(network) > 1 {
+			owner.network = network[1]
+		}
+
+		r[iface] = owner
+	}
+
+	return r
+}
+
+type netstatOutput struct {
+	Statistics struct {
+		Interface []struct {
+			Name            string `json:"name"`
+			Network         string `json:"network"`
+			ReceivedPackets uint64 `json:"received-packets"`
+			ReceivedErrors  uint64 `json:"received-errors"`
+			ReceivedBytes   uint64 `json:"received-bytes"`
+			SentPackets     uint64 `json:"sent-packets"`
+			SendErrors      uint64 `json:"send-errors"`
+			SentBytes       uint64 `json:"sent-bytes"`
+		} `json:"interface"`
+	} `json:"statistics"`
+}
+
+// Parses the `netstat -i -b -n --libxo json` output, and returns the counters for the interfaces found in owners.
+func parseIfaceStats(netstatJson []byte, owners map[string]ifaceOwner) (r []IfaceStats, e error) {
+	netstat := netstatOutput{}
+	err := json.Unmarshal(netstatJson, &netstat)
+	if err != nil {
+		e = fmt.Errorf("could not parse the netstat output: %s", err.Error())
+		return
+	}
+
+	for _, v := range netstat.Statistics.Interface {
+		// Every interface is listed once per address, the link level entry holds the interface counters
+		if !strings.HasPrefix(v.Network, "<Link") {
+			continue
+		}
+		owner, found := owners[v.Name]
+		if !found {
+			continue
+		}
+
+		r = append(r, IfaceStats{
+			Interface:    v.Name,
+			Network:      owner.network,
+			ResourceType: owner.resourceType,
+			ResourceName: owner.resourceName,
+			RxBytes:      v.SentBytes,
+			TxBytes:      v.ReceivedBytes,
+			RxPackets:    v.SentPackets,
+			TxPackets:    v.ReceivedPackets,
+			RxErrors:     v.SendErrors,
+			TxErrors:     v.ReceivedErrors,
+		})
+	}
+
+	return
+}
+
+// Collects the network interface counters for all VMs and Jails running on this host.
+func GetIfaceStats() (r []IfaceStats, e error) {
+	out, err := exec.Command("ifconfig").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("%s; %s", strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+	owners := parseIfaceOwners(string(out))
+
+	out, err = exec.Command("netstat", "-i", "-b", "-n", "--libxo", "json").Output()
+	if err != nil {
+		e = fmt.Errorf("could not execute netstat: %s", err.Error())
+		return
+	}
+
+	return parseIfaceStats(out, owners)
+}
+
+// Periodically collects the interface counters, so the transfer rates can be calculated between the samples.
+type IfaceStatsCollector struct {
+	mu        sync.RWMutex
+	interval  time.Duration
+	stats     []IfaceStats
+	collected time.Time
+}
+
+func NewIfaceStatsCollector(interval time.Duration) *IfaceStatsCollector {
+	return &IfaceStatsCollector{interval: interval}
+}
+
+// Collects the interface counters every interval, blocks forever (so it should be run as a goroutine)
+func (c *IfaceStatsCollector) Run() {
+	for {
+		_ = c.Collect()
+		time.Sleep(c.interval)
+	}
+}
+
+// Takes a single sample, and calculates the transfer rates against the previous one
+func (c *IfaceStatsCollector) Collect() error {
+	stats, err := GetIfaceStats()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elapsed := now.Sub(c.collected).Seconds()
+	previous := make(map[string]IfaceStats)
+	for _, v := range c.stats {
+		previous[v.Interface] = v
+	}
+	for i, v := range stats {
+		prev, found := previous[v.Interface]
+		// Interfaces are re-used between the VM restarts, so the counters for a different owner (or reset counters) are ignored
+		if !found || prev.ResourceName != v.ResourceName || elapsed <= 0 || v.RxBytes < prev.RxBytes || v.TxBytes < prev.TxBytes {
+			continue
+		}
+		stats[i].RxBytesPerSec = float64(v.RxBytes-prev.RxBytes) / elapsed
+		stats[i].TxBytesPerSec = float64(v.TxBytes-prev.TxBytes) / elapsed
+	}
+
+	c.stats = stats
+	c.collected = now
+	return nil
+}
+
+// Returns the last collected interface counters for the VM or Jail
+func (c *IfaceStatsCollector) Get(resourceType string, resourceName string) (r []IfaceStats) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, v := range c.stats {
+		if v.ResourceType == resourceType && v.ResourceName == resourceName {
+			r = append(r, v)
+		}
+	}
+	return
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseIfaceOwners(t *testing.T) {
+	tests := []struct {
+		name     string
+		ifconfig string
+		want     map[string]ifaceOwner
+	}{
+		{
+			name:     "ifconfig fixture",
+			ifconfig: string(readFixture(t, "ifconfig.txt")),
+			want: map[string]ifaceOwner{
+				"tap0":    {resourceType: "vm", resourceName: "test-vm-1", network: "internal"},
+				"tap1":    {resourceType: "vm", resourceName: "test-vm-2"},
+				"epair0a": {resourceType: "jail", resourceName: "test-jail-1", network: "internal"},
+			},
+		},
+		{
+			name:     "description before the first interface",
+			ifconfig: "\tdescription: \"vm::test-vm-1 iface::tap0\"\n",
+			want:     map[string]ifaceOwner{},
+		},
+		{
+			name:     "empty output",
+			ifconfig: "",
+			want:     map[string]ifaceOwner{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseIfaceOwners(tt.ifconfig)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIfaceOwners() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIfaceStats(t *testing.T) {
+	owners := parseIfaceOwners(string(readFixture(t, "ifconfig.txt")))
+
+	tests := []struct {
+		name    string
+		netstat []byte
+		owners  map[string]ifaceOwner
+		want    []IfaceStats
+		wantErr string
+	}{
+		{
+			name:    "netstat fixture",
+			netstat: readFixture(t, "netstat.json"),
+			owners:  owners,
+			// Counters are flipped to the VM/Jail point of view, the address level entries are skipped
+			want: []IfaceStats{
+				{Interface: "tap0", Network: "internal", ResourceType: "vm", ResourceName: "test-vm-1", RxBytes: 5200000, TxBytes: 350000, RxPackets: 4000, TxPackets: 2500, RxErrors: 2, TxErrors: 1},
+				{Interface: "tap1", ResourceType: "vm", ResourceName: "test-vm-2"},
+				{Interface: "epair0a", Network: "internal", ResourceType: "jail", ResourceName: "test-jail-1", RxBytes: 1200000, TxBytes: 91000, RxPackets: 900, TxPackets: 700},
+			},
+		},
+		{
+			name:    "no owners",
+			netstat: readFixture(t, "netstat.json"),
+			owners:  map[string]ifaceOwner{},
+		},
+		{
+			name:    "invalid JSON",
+			netstat: []byte("netstat: illegal option"),
+			owners:  owners,
+			wantErr: "could not parse the netstat output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIfaceStats(tt.netstat, tt.owners)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseIfaceStats() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseIfaceStats() error = %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIfaceStats() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
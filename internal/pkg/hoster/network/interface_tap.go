@@ -56,8 +56,9 @@ func VmNetworkCleanup(vmName string) (r []Iface, e error) {
 		}
 	}
 
-	// Loop over the list of the interfaces that match our description, and destroy them.
+	// Loop over the list of the interfaces that match our description, and destroy them (together with the traffic shaping).
 	for i, v := range r {
+		RemoveShaping(v.IfaceName)
 		err := exec.Command("ifconfig", v.IfaceName, "destroy").Run()
 		if err != nil {
 			r[i].Failure = true
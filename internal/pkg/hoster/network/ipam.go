@@ -0,0 +1,552 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"fmt"
+	"math/rand"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	IP_ALLOCATION_RANDOM     = "random"
+	IP_ALLOCATION_SEQUENTIAL = "sequential"
+
+	IP_STATE_IN_USE      = "in-use"
+	IP_STATE_RESERVED    = "reserved"
+	IP_STATE_QUARANTINED = "quarantined"
+	IP_STATE_EXCLUDED    = "excluded"
+	IP_STATE_CONFLICT    = "conflict"
+
+	// Default amount of time (in seconds) a released IP address stays unavailable for new allocations
+	DEFAULT_IP_QUARANTINE = 3600
+	// Amount of time (in seconds) an allocated IP address is held for it's owner, until it shows up in the VM or Jail config
+	IP_ALLOCATION_HOLD = 900
+)
+
+// IP address that is (or was) used by a VM or a Jail
+type IpLease struct {
+	Network    string `json:"network"`
+	IpAddress  string `json:"ip_address"`
+	Owner      string `json:"owner"`      // VM or Jail name
+	OwnerType  string `json:"owner_type"` // "vm" or "jail"
+	ReleasedAt int64  `json:"released_at,omitempty"`
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+}
+
+// IP address that is put aside, and can only be allocated to it's owner (if one is set)
+type IpReservation struct {
+	Network   string `json:"network"`
+	IpAddress string `json:"ip_address"`
+	Owner     string `json:"owner"`
+	Comment   string `json:"comment"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// IPAM state, which is persisted between the allocations
+type IpamState struct {
+	Leases       []IpLease       `json:"leases"`     // last known list of used addresses, used to detect the released ones
+	Quarantine   []IpLease       `json:"quarantine"` // recently released addresses
+	Reservations []IpReservation `json:"reservations"`
+	Allocations  []IpLease       `json:"allocations,omitempty"` // addresses handed out, but not yet found in the inventory (deployment is in progress)
+}
+
+// Single entry in the IPAM address list
+type IpamAddress struct {
+	Network   string `json:"network"`
+	IpAddress string `json:"ip_address"`
+	State     string `json:"state"`
+	Owner     string `json:"owner"`
+	OwnerType string `json:"owner_type"`
+	Comment   string `json:"comment"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+// Returns the quarantine period (in seconds) for this network
+func (n NetworkConfig) IpQuarantinePeriod() int {
+	if n.IpQuarantine < 0 {
+		return 0
+	}
+	if n.IpQuarantine == 0 {
+		return DEFAULT_IP_QUARANTINE
+	}
+	return n.IpQuarantine
+}
+
+// Updates the state using the current inventory (list of addresses used by the VMs and Jails):
+// the addresses that are no longer in use get quarantined, and the expired quarantine entries are removed.
+func (s *IpamState) Sync(networks []NetworkConfig, inventory []IpLease, now time.Time) {
+	inUse := make(map[string]bool)
+	for _, v := range inventory {
+		inUse[v.Network+"/"+v.IpAddress] = true
+	}
+
+	quarantine := []IpLease{}
+	for _, v := range s.Quarantine {
+		if v.ExpiresAt > now.Unix() && !inUse[v.Network+"/"+v.IpAddress] {
+			quarantine = append(quarantine, v)
+		}
+	}
+
+	for _, v := range s.Leases {
+		if inUse[v.Network+"/"+v.IpAddress] {
+			continue
+		}
+
+		period := DEFAULT_IP_QUARANTINE
+		for _, vv := range networks {
+			if vv.NetworkName == v.Network {
+				period = vv.IpQuarantinePeriod()
+				break
+			}
+		}
+		if period < 1 {
+			continue
+		}
+
+		v.ReleasedAt = now.Unix()
+		v.ExpiresAt = now.Add(time.Duration(period) * time.Second).Unix()
+		quarantine = append(quarantine, v)
+	}
+
+	s.Quarantine = quarantine
+	s.Leases = append([]IpLease{}, inventory...)
+
+	allocations := []IpLease{}
+	for _, v := range s.Allocations {
+		if v.ExpiresAt > now.Unix() && !inUse[v.Network+"/"+v.IpAddress] {
+			allocations = append(allocations, v)
+		}
+	}
+	s.Allocations = allocations
+}
+
+// Holds the freshly allocated address for it's owner, so the concurrent deployments can't get it too
+func (s *IpamState) Hold(network NetworkConfig, ipAddress string, owner string, now time.Time) {
+	s.Allocations = slices.DeleteFunc(s.Allocations, func(v IpLease) bool {
+		return v.Network == network.NetworkName && v.IpAddress == ipAddress
+	})
+
+	s.Allocations = append(s.Allocations, IpLease{
+		Network:   network.NetworkName,
+		IpAddress: ipAddress,
+		Owner:     owner,
+		ExpiresAt: now.Add(IP_ALLOCATION_HOLD * time.Second).Unix(),
+	})
+}
+
+// Puts the address into the quarantine, and removes any reservations for it
+func (s *IpamState) Release(network NetworkConfig, ipAddress string, now time.Time) {
+	s.Reservations = slices.DeleteFunc(s.Reservations, func(v IpReservation) bool {
+		return v.Network == network.NetworkName && v.IpAddress == ipAddress
+	})
+
+	period := network.IpQuarantinePeriod()
+	if period < 1 {
+		return
+	}
+
+	for i, v := range s.Quarantine {
+		if v.Network == network.NetworkName && v.IpAddress == ipAddress {
+			s.Quarantine[i].ExpiresAt = now.Add(time.Duration(period) * time.Second).Unix()
+			return
+		}
+	}
+
+	s.Quarantine = append(s.Quarantine, IpLease{
+		Network:    network.NetworkName,
+		IpAddress:  ipAddress,
+		ReleasedAt: now.Unix(),
+		ExpiresAt:  now.Add(time.Duration(period) * time.Second).Unix(),
+	})
+}
+
+// IP address manager for a single network.
+//
+// It doesn't touch any files or system resources, the inventory and the state are passed in by the caller,
+// so it can be used (and tested) with the in-memory data.
+type Ipam struct {
+	network  NetworkConfig
+	gateway  string
+	prefix   netip.Prefix
+	start    netip.Addr
+	end      netip.Addr
+	excluded [][2]netip.Addr
+	used     map[netip.Addr]IpLease
+	state    IpamState
+}
+
+// Creates a new IPv4 IPAM for the network. Inventory is a list of addresses used by the VMs and Jails (on any network).
+func NewIpam(network NetworkConfig, inventory []IpLease, state IpamState) (r Ipam, e error) {
+	return newIpam(network, network.Subnet, network.Gateway, network.RangeStart, network.RangeEnd, inventory, state)
+}
+
+// Creates a new IPv6 IPAM for the dual-stack network.
+func NewIpam6(network NetworkConfig, inventory []IpLease, state IpamState) (r Ipam, e error) {
+	if !network.Ipv6Enabled() {
+		e = fmt.Errorf("IPv6 is not enabled for the network %s", network.NetworkName)
+		return
+	}
+
+	rangeStart, rangeEnd, err := network.Ipv6Range()
+	if err != nil {
+		e = err
+		return
+	}
+
+	return newIpam(network, network.Subnet6, network.Gateway6, rangeStart, rangeEnd, inventory, state)
+}
+
+func newIpam(network NetworkConfig, subnet string, gateway string, rangeStart string, rangeEnd string, inventory []IpLease, state IpamState) (r Ipam, e error) {
+	r.network = network
+	r.gateway = gateway
+	r.state = state
+
+	r.prefix, e = netip.ParsePrefix(subnet)
+	if e != nil {
+		e = fmt.Errorf("invalid subnet %s: %s", subnet, e.Error())
+		return
+	}
+	r.prefix = r.prefix.Masked()
+
+	r.start, e = netip.ParseAddr(rangeStart)
+	if e != nil {
+		e = fmt.Errorf("invalid range start %s: %s", rangeStart, e.Error())
+		return
+	}
+	r.end, e = netip.ParseAddr(rangeEnd)
+	if e != nil {
+		e = fmt.Errorf("invalid range end %s: %s", rangeEnd, e.Error())
+		return
+	}
+	if !r.prefix.Contains(r.start) || !r.prefix.Contains(r.end) || r.start.Compare(r.end) > 0 {
+		e = fmt.Errorf("invalid range %s - %s for the subnet %s", rangeStart, rangeEnd, subnet)
+		return
+	}
+
+	for _, v := range network.ExcludedIps {
+		first, last, err := parseAddrRange(v)
+		if err != nil {
+			e = fmt.Errorf("invalid excluded address %s: %s", v, err.Error())
+			return
+		}
+		if first.Is4() != r.prefix.Addr().Is4() {
+			continue
+		}
+		r.excluded = append(r.excluded, [2]netip.Addr{first, last})
+	}
+
+	r.used = make(map[netip.Addr]IpLease)
+	for _, v := range inventory {
+		if v.Network != network.NetworkName {
+			continue
+		}
+		addr, err := netip.ParseAddr(v.IpAddress)
+		if err != nil || addr.Is4() != r.prefix.Addr().Is4() {
+			continue
+		}
+		r.used[addr] = v
+	}
+
+	for _, v := range state.Allocations {
+		if v.Network != network.NetworkName {
+			continue
+		}
+		addr, err := netip.ParseAddr(v.IpAddress)
+		if err != nil || addr.Is4() != r.prefix.Addr().Is4() {
+			continue
+		}
+		if _, ok := r.used[addr]; !ok {
+			r.used[addr] = v
+		}
+	}
+
+	return
+}
+
+// Checks if this IPAM manages the same address family as the IP address
+func (i Ipam) SameFamily(ipAddress string) bool {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return false
+	}
+	return addr.Is4() == i.prefix.Addr().Is4()
+}
+
+// Parses a single address, or a range of addresses, e.g. "10.0.0.5-10.0.0.10"
+func parseAddrRange(input string) (first netip.Addr, last netip.Addr, e error) {
+	split := strings.SplitN(input, "-", 2)
+
+	first, e = netip.ParseAddr(strings.TrimSpace(split[0]))
+	if e != nil {
+		return
+	}
+	last = first
+
+	if len(split) > 1 {
+		last, e = netip.ParseAddr(strings.TrimSpace(split[1]))
+		if e != nil {
+			return
+		}
+		if first.Compare(last) > 0 {
+			e = fmt.Errorf("range start is higher than the range end")
+			return
+		}
+	}
+
+	return
+}
+
+func (i Ipam) isExcluded(addr netip.Addr) bool {
+	for _, v := range i.excluded {
+		if addr.Compare(v[0]) >= 0 && addr.Compare(v[1]) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the address can be given to the owner (VM or Jail name, can be empty).
+//
+// Addresses in quarantine are only checked if strict is set, so that the quarantine can be
+// bypassed by setting the address explicitly.
+func (i Ipam) check(addr netip.Addr, owner string, strict bool, now time.Time) error {
+	if !i.prefix.Contains(addr) {
+		return fmt.Errorf("IP address %s is not within the network subnet %s", addr, i.prefix)
+	}
+	if addr.Compare(i.start) < 0 || addr.Compare(i.end) > 0 {
+		return fmt.Errorf("IP address %s is not within the network range", addr)
+	}
+	if gateway, err := netip.ParseAddr(i.gateway); err == nil && addr == gateway {
+		return fmt.Errorf("IP address %s is used by the network gateway", addr)
+	}
+	if addr == i.prefix.Addr() {
+		return fmt.Errorf("IP address %s is the network address", addr)
+	}
+	if addr.Is4() && addr == lastAddr(i.prefix) {
+		return fmt.Errorf("IP address %s is the broadcast address", addr)
+	}
+	if i.isExcluded(addr) {
+		return fmt.Errorf("IP address %s is excluded from the allocation", addr)
+	}
+
+	if lease, ok := i.used[addr]; ok {
+		// The address held for the owner during it's own (repeated) allocation is still available to it
+		if lease.ExpiresAt == 0 || len(owner) < 1 || lease.Owner != owner {
+			return fmt.Errorf("IP address %s is already in use by %s", addr, lease.Owner)
+		}
+	}
+
+	// Reservations without an owner are only released explicitly, they never match an allocation
+	for _, v := range i.state.Reservations {
+		if v.Network == i.network.NetworkName && v.IpAddress == addr.String() && (len(v.Owner) < 1 || v.Owner != owner) {
+			return fmt.Errorf("IP address %s is reserved", addr)
+		}
+	}
+
+	if strict {
+		for _, v := range i.state.Quarantine {
+			if v.Network == i.network.NetworkName && v.IpAddress == addr.String() && v.ExpiresAt > now.Unix() {
+				return fmt.Errorf("IP address %s was recently released, and is in quarantine", addr)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Checks if the address can be assigned to the owner explicitly (ignores the quarantine)
+func (i Ipam) Check(ipAddress string, owner string) error {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return fmt.Errorf("invalid IP address %s", ipAddress)
+	}
+	return i.check(addr, owner, false, time.Now())
+}
+
+// Allocates a new address for the owner (VM or Jail name, can be empty).
+//
+// Returns the owner's reservation if one exists, otherwise picks the first available address,
+// starting at the beginning of the range (sequential allocation) or at a random offset (random allocation).
+func (i Ipam) Allocate(owner string, now time.Time) (r string, e error) {
+	if len(owner) > 0 {
+		for _, v := range i.state.Reservations {
+			if v.Network != i.network.NetworkName || v.Owner != owner {
+				continue
+			}
+			addr, err := netip.ParseAddr(v.IpAddress)
+			if err == nil && i.check(addr, owner, false, now) == nil {
+				r = v.IpAddress
+				return
+			}
+		}
+	}
+
+	size := rangeSize(i.start, i.end)
+	var offset uint64
+	if i.network.IpAllocation == IP_ALLOCATION_RANDOM || len(i.network.IpAllocation) < 1 {
+		offset = rand.Uint64() % size
+	}
+
+	addr := addrAdd(i.start, offset)
+	for n := uint64(0); n < size; n++ {
+		if i.check(addr, owner, true, now) == nil {
+			r = addr.String()
+			return
+		}
+
+		if addr == i.end {
+			addr = i.start
+		} else {
+			addr = addr.Next()
+		}
+	}
+
+	e = fmt.Errorf("ran out of available IP addresses within the %s network range", i.network.NetworkName)
+	return
+}
+
+// Lists all addresses in this network that can't be allocated: used, reserved, in quarantine or excluded
+func (i Ipam) List(now time.Time) (r []IpamAddress) {
+	for _, v := range i.used {
+		r = append(r, IpamAddress{Network: i.network.NetworkName, IpAddress: v.IpAddress, State: IP_STATE_IN_USE, Owner: v.Owner, OwnerType: v.OwnerType})
+	}
+
+	for _, v := range i.state.Reservations {
+		if v.Network == i.network.NetworkName && i.SameFamily(v.IpAddress) {
+			r = append(r, IpamAddress{Network: v.Network, IpAddress: v.IpAddress, State: IP_STATE_RESERVED, Owner: v.Owner, Comment: v.Comment})
+		}
+	}
+
+	for _, v := range i.state.Quarantine {
+		if v.Network == i.network.NetworkName && v.ExpiresAt > now.Unix() && i.SameFamily(v.IpAddress) {
+			r = append(r, IpamAddress{Network: v.Network, IpAddress: v.IpAddress, State: IP_STATE_QUARANTINED, Owner: v.Owner, OwnerType: v.OwnerType, ExpiresAt: v.ExpiresAt})
+		}
+	}
+
+	if len(i.gateway) > 0 {
+		r = append(r, IpamAddress{Network: i.network.NetworkName, IpAddress: i.gateway, State: IP_STATE_EXCLUDED, Comment: "Network gateway"})
+	}
+	for _, v := range i.network.ExcludedIps {
+		if !i.SameFamily(strings.SplitN(v, "-", 2)[0]) {
+			continue
+		}
+		r = append(r, IpamAddress{Network: i.network.NetworkName, IpAddress: v, State: IP_STATE_EXCLUDED, Comment: "Excluded in the network config"})
+	}
+
+	sort.SliceStable(r, func(a, b int) bool {
+		addrA, errA := netip.ParseAddr(strings.SplitN(r[a].IpAddress, "-", 2)[0])
+		addrB, errB := netip.ParseAddr(strings.SplitN(r[b].IpAddress, "-", 2)[0])
+		if errA != nil || errB != nil {
+			return r[a].IpAddress < r[b].IpAddress
+		}
+		return addrA.Compare(addrB) < 0
+	})
+
+	return
+}
+
+// Finds the addresses that are used by more than one VM or Jail within the same network
+func FindIpConflicts(inventory []IpLease) (r []IpamAddress) {
+	owners := make(map[string][]IpLease)
+	keys := []string{}
+	for _, v := range inventory {
+		key := v.Network + "/" + v.IpAddress
+		if _, ok := owners[key]; !ok {
+			keys = append(keys, key)
+		}
+		owners[key] = append(owners[key], v)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if len(owners[k]) < 2 {
+			continue
+		}
+		for _, v := range owners[k] {
+			r = append(r, IpamAddress{
+				Network:   v.Network,
+				IpAddress: v.IpAddress,
+				State:     IP_STATE_CONFLICT,
+				Owner:     v.Owner,
+				OwnerType: v.OwnerType,
+				Comment:   fmt.Sprintf("Used by %d resources", len(owners[k])),
+			})
+		}
+	}
+
+	return
+}
+
+// Returns the number of addresses between start and end (inclusive), capped at the max uint64 value
+func rangeSize(start netip.Addr, end netip.Addr) uint64 {
+	a := start.As16()
+	b := end.As16()
+
+	var diff [16]byte
+	var borrow int
+	for i := 15; i >= 0; i-- {
+		d := int(b[i]) - int(a[i]) - borrow
+		borrow = 0
+		if d < 0 {
+			d += 256
+			borrow = 1
+		}
+		diff[i] = byte(d)
+	}
+
+	for i := 0; i < 8; i++ {
+		if diff[i] != 0 {
+			return ^uint64(0)
+		}
+	}
+
+	var r uint64
+	for i := 8; i < 16; i++ {
+		r = r<<8 | uint64(diff[i])
+	}
+	if r == ^uint64(0) {
+		return r
+	}
+	return r + 1
+}
+
+// Adds n to the address (the overflow is not checked, the caller must stay within the range)
+func addrAdd(addr netip.Addr, n uint64) netip.Addr {
+	b := addr.As16()
+
+	carry := n
+	for i := 15; i >= 0 && carry > 0; i-- {
+		sum := uint64(b[i]) + carry&0xff
+		b[i] = byte(sum)
+		carry = carry>>8 + sum>>8
+	}
+
+	r := netip.AddrFrom16(b)
+	if addr.Is4() {
+		return r.Unmap()
+	}
+	return r
+}
+
+// Returns the last address of the prefix (broadcast address in case of IPv4)
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	bits := prefix.Bits()
+	for i := range b {
+		for j := 0; j < 8; j++ {
+			if i*8+j >= bits {
+				b[i] |= 0x80 >> j
+			}
+		}
+	}
+
+	r, _ := netip.AddrFromSlice(b)
+	return r
+}
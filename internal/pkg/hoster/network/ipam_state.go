@@ -0,0 +1,101 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	FileExists "HosterCore/internal/pkg/file_exists"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const ipamStateFileName = "ipam_state.json"
+
+// IPAM state lives next to the network_config.json
+func getIpamStateLocation() (r string, e error) {
+	confFile, err := getNetworkConfigLocation()
+	if err != nil {
+		e = err
+		return
+	}
+
+	r = filepath.Dir(confFile) + "/" + ipamStateFileName
+	return
+}
+
+// Reads the IPAM state file. Returns an empty state if the file doesn't exist yet.
+func GetIpamState() (r IpamState, e error) {
+	stateFile, err := getIpamStateLocation()
+	if err != nil {
+		e = err
+		return
+	}
+	if !FileExists.CheckUsingOsStat(stateFile) {
+		return
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		e = err
+		return
+	}
+
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		e = err
+		return
+	}
+
+	return
+}
+
+// Saves the IPAM state. The file is replaced atomically, so a crash never leaves a half written state behind.
+func SaveIpamState(state IpamState) error {
+	stateFile, err := getIpamStateLocation()
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(state, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(stateFile+".tmp", jsonData, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(stateFile+".tmp", stateFile)
+}
+
+// Takes an exclusive lock on the IPAM state, so the concurrent deployments can't allocate the same address.
+// The lock is held until the returned func is called, it must wrap the whole read-allocate-save cycle.
+func LockIpamState() (unlock func(), e error) {
+	stateFile, err := getIpamStateLocation()
+	if err != nil {
+		e = err
+		return
+	}
+
+	file, err := os.OpenFile(stateFile+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		e = err
+		return
+	}
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		file.Close()
+		e = err
+		return
+	}
+
+	unlock = func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}
+	return
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNetwork() NetworkConfig {
+	return NetworkConfig{
+		NetworkName:  "internal",
+		Gateway:      "10.0.101.1",
+		Subnet:       "10.0.101.0/29",
+		RangeStart:   "10.0.101.2",
+		RangeEnd:     "10.0.101.6",
+		IpAllocation: IP_ALLOCATION_SEQUENTIAL,
+	}
+}
+
+func TestIpamAllocate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	network := testNetwork()
+
+	tests := []struct {
+		name      string
+		owner     string
+		inventory []IpLease
+		state     IpamState
+		excluded  []string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:  "first free address",
+			owner: "test-vm-1",
+			want:  "10.0.101.2",
+		},
+		{
+			name:      "used address is skipped",
+			owner:     "test-vm-1",
+			inventory: []IpLease{{Network: "internal", IpAddress: "10.0.101.2", Owner: "test-vm-2"}},
+			want:      "10.0.101.3",
+		},
+		{
+			name:      "address used on another network is ignored",
+			owner:     "test-vm-1",
+			inventory: []IpLease{{Network: "external", IpAddress: "10.0.101.2", Owner: "test-vm-2"}},
+			want:      "10.0.101.2",
+		},
+		{
+			name:  "unowned reservation blocks the unowned allocation",
+			owner: "",
+			state: IpamState{Reservations: []IpReservation{{Network: "internal", IpAddress: "10.0.101.2"}}},
+			want:  "10.0.101.3",
+		},
+		{
+			name:  "unowned reservation blocks the owned allocation",
+			owner: "test-vm-1",
+			state: IpamState{Reservations: []IpReservation{{Network: "internal", IpAddress: "10.0.101.2"}}},
+			want:  "10.0.101.3",
+		},
+		{
+			name:  "reservation of another owner is skipped",
+			owner: "test-vm-1",
+			state: IpamState{Reservations: []IpReservation{{Network: "internal", IpAddress: "10.0.101.2", Owner: "test-vm-2"}}},
+			want:  "10.0.101.3",
+		},
+		{
+			name:  "owner gets it's reservation",
+			owner: "test-vm-1",
+			state: IpamState{Reservations: []IpReservation{{Network: "internal", IpAddress: "10.0.101.5", Owner: "test-vm-1"}}},
+			want:  "10.0.101.5",
+		},
+		{
+			name:  "address held for another owner is skipped",
+			owner: "test-jail-1",
+			state: IpamState{Allocations: []IpLease{{Network: "internal", IpAddress: "10.0.101.2", Owner: "test-vm-2", ExpiresAt: now.Unix() + 60}}},
+			want:  "10.0.101.3",
+		},
+		{
+			name:  "address held for the owner is given back to it",
+			owner: "test-jail-1",
+			state: IpamState{Allocations: []IpLease{{Network: "internal", IpAddress: "10.0.101.2", Owner: "test-jail-1", ExpiresAt: now.Unix() + 60}}},
+			want:  "10.0.101.2",
+		},
+		{
+			name:  "address held for the unowned allocation is skipped",
+			owner: "",
+			state: IpamState{Allocations: []IpLease{{Network: "internal", IpAddress: "10.0.101.2", ExpiresAt: now.Unix() + 60}}},
+			want:  "10.0.101.3",
+		},
+		{
+			name:  "quarantined address is skipped",
+			owner: "test-vm-1",
+			state: IpamState{Quarantine: []IpLease{{Network: "internal", IpAddress: "10.0.101.2", ExpiresAt: now.Unix() + 60}}},
+			want:  "10.0.101.3",
+		},
+		{
+			name:  "expired quarantine is ignored",
+			owner: "test-vm-1",
+			state: IpamState{Quarantine: []IpLease{{Network: "internal", IpAddress: "10.0.101.2", ExpiresAt: now.Unix() - 60}}},
+			want:  "10.0.101.2",
+		},
+		{
+			name:     "excluded range is skipped",
+			owner:    "test-vm-1",
+			excluded: []string{"10.0.101.2-10.0.101.4"},
+			want:     "10.0.101.5",
+		},
+		{
+			name:  "range is exhausted",
+			owner: "test-vm-1",
+			inventory: []IpLease{
+				{Network: "internal", IpAddress: "10.0.101.2", Owner: "test-vm-2"},
+				{Network: "internal", IpAddress: "10.0.101.3", Owner: "test-vm-3"},
+				{Network: "internal", IpAddress: "10.0.101.4", Owner: "test-vm-4"},
+			},
+			state: IpamState{
+				Reservations: []IpReservation{{Network: "internal", IpAddress: "10.0.101.5"}},
+				Allocations:  []IpLease{{Network: "internal", IpAddress: "10.0.101.6", Owner: "test-jail-2", ExpiresAt: now.Unix() + 60}},
+			},
+			wantErr: "ran out of available IP addresses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := network
+			n.ExcludedIps = tt.excluded
+
+			ipam, err := NewIpam(n, tt.inventory, tt.state)
+			if err != nil {
+				t.Fatalf("NewIpam() error = %s", err)
+			}
+
+			got, err := ipam.Allocate(tt.owner, now)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Allocate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Allocate() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Allocate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIpamCheck(t *testing.T) {
+	network := testNetwork()
+	state := IpamState{
+		Reservations: []IpReservation{
+			{Network: "internal", IpAddress: "10.0.101.3"},
+			{Network: "internal", IpAddress: "10.0.101.4", Owner: "test-vm-1"},
+		},
+		Quarantine: []IpLease{{Network: "internal", IpAddress: "10.0.101.5", ExpiresAt: time.Now().Unix() + 60}},
+	}
+	inventory := []IpLease{{Network: "internal", IpAddress: "10.0.101.2", Owner: "test-vm-2"}}
+
+	ipam, err := NewIpam(network, inventory, state)
+	if err != nil {
+		t.Fatalf("NewIpam() error = %s", err)
+	}
+
+	tests := []struct {
+		ip      string
+		owner   string
+		wantErr string
+	}{
+		{ip: "10.0.101.1", owner: "test-vm-1", wantErr: "not within the network range"},
+		{ip: "10.0.101.0", owner: "test-vm-1", wantErr: "not within the network range"},
+		{ip: "10.0.102.2", owner: "test-vm-1", wantErr: "not within the network subnet"},
+		{ip: "10.0.101.2", owner: "test-vm-1", wantErr: "already in use"},
+		{ip: "10.0.101.2", owner: "test-vm-2", wantErr: "already in use"},
+		{ip: "10.0.101.3", owner: "", wantErr: "reserved"},
+		{ip: "10.0.101.3", owner: "test-vm-1", wantErr: "reserved"},
+		{ip: "10.0.101.4", owner: "", wantErr: "reserved"},
+		{ip: "10.0.101.4", owner: "test-vm-2", wantErr: "reserved"},
+		{ip: "10.0.101.4", owner: "test-vm-1"},
+		// The quarantine is bypassed when the address is set explicitly
+		{ip: "10.0.101.5", owner: "test-vm-1"},
+		{ip: "10.0.101.6", owner: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip+"/"+tt.owner, func(t *testing.T) {
+			err := ipam.Check(tt.ip, tt.owner)
+			if len(tt.wantErr) < 1 {
+				if err != nil {
+					t.Errorf("Check() error = %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIpamStateHold(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	network := testNetwork()
+	state := IpamState{}
+
+	// Two deployments in a row must not get the same address, even before any of them shows up in the inventory
+	ipam, _ := NewIpam(network, nil, state)
+	first, err := ipam.Allocate("test-jail-1", now)
+	if err != nil {
+		t.Fatalf("Allocate() error = %s", err)
+	}
+	state.Hold(network, first, "test-jail-1", now)
+
+	ipam, _ = NewIpam(network, nil, state)
+	second, err := ipam.Allocate("test-jail-2", now)
+	if err != nil {
+		t.Fatalf("Allocate() error = %s", err)
+	}
+	if first == second {
+		t.Fatalf("the held address %s was allocated twice", first)
+	}
+
+	// The hold is dropped once the address shows up in the inventory
+	state.Hold(network, second, "test-jail-2", now)
+	inventory := []IpLease{{Network: "internal", IpAddress: first, Owner: "test-jail-1"}}
+	state.Sync([]NetworkConfig{network}, inventory, now)
+	if len(state.Allocations) != 1 || state.Allocations[0].IpAddress != second {
+		t.Fatalf("Sync() allocations = %+v, want only %s", state.Allocations, second)
+	}
+
+	// And once it expires
+	state.Sync([]NetworkConfig{network}, inventory, now.Add((IP_ALLOCATION_HOLD+1)*time.Second))
+	if len(state.Allocations) != 0 {
+		t.Fatalf("Sync() allocations = %+v, want none", state.Allocations)
+	}
+}
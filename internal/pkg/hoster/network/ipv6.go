@@ -0,0 +1,99 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+)
+
+const (
+	IPV6_MODE_STATIC = "static"
+	IPV6_MODE_SLAAC  = "slaac"
+)
+
+// Checks if the network is dual-stack
+func (n NetworkConfig) Ipv6Enabled() bool {
+	return len(n.Subnet6) > 0
+}
+
+// Checks if the IPv6 addresses are assigned using the router advertisements (instead of the Hoster IPAM)
+func (n NetworkConfig) Ipv6Slaac() bool {
+	return n.Ipv6Enabled() && n.Ipv6Mode == IPV6_MODE_SLAAC
+}
+
+// Returns the IPv6 allocation range, which defaults to "<prefix>::10 - <prefix>::ffff"
+func (n NetworkConfig) Ipv6Range() (start string, end string, e error) {
+	prefix, err := netip.ParsePrefix(n.Subnet6)
+	if err != nil {
+		e = fmt.Errorf("invalid IPv6 subnet %s: %s", n.Subnet6, err.Error())
+		return
+	}
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		e = fmt.Errorf("invalid IPv6 subnet %s: not an IPv6 prefix", n.Subnet6)
+		return
+	}
+	prefix = prefix.Masked()
+
+	start = n.RangeStart6
+	if len(start) < 1 {
+		start = addrAdd(prefix.Addr(), 0x10).String()
+	}
+
+	end = n.RangeEnd6
+	if len(end) < 1 {
+		last := addrAdd(prefix.Addr(), 0xffff)
+		if !prefix.Contains(last) {
+			last = lastAddr(prefix)
+		}
+		end = last.String()
+	}
+
+	return
+}
+
+// Returns the prefix length of the IPv4 or IPv6 subnet, e.g. "24" for "10.0.100.0/24"
+func PrefixLength(subnet string) string {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return ""
+	}
+	return strconv.Itoa(prefix.Bits())
+}
+
+// Generates the SLAAC (modified EUI-64) address for the MAC address, which is used to publish the AAAA records
+// for the VMs on the SLAAC networks. Requires a /64 prefix.
+func Eui64Address(subnet6 string, mac string) (r string, e error) {
+	prefix, err := netip.ParsePrefix(subnet6)
+	if err != nil {
+		e = fmt.Errorf("invalid IPv6 subnet %s: %s", subnet6, err.Error())
+		return
+	}
+	if prefix.Bits() != 64 {
+		e = fmt.Errorf("SLAAC requires a /64 prefix, got %s", subnet6)
+		return
+	}
+
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		e = fmt.Errorf("invalid MAC address %s", mac)
+		return
+	}
+
+	b := prefix.Masked().Addr().As16()
+	b[8] = hw[0] ^ 0x02
+	b[9] = hw[1]
+	b[10] = hw[2]
+	b[11] = 0xff
+	b[12] = 0xfe
+	b[13] = hw[3]
+	b[14] = hw[4]
+	b[15] = hw[5]
+
+	r = netip.AddrFrom16(b).String()
+	return
+}
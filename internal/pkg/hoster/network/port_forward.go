@@ -0,0 +1,235 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	FileExists "HosterCore/internal/pkg/file_exists"
+	HosterFirewall "HosterCore/internal/pkg/hoster/firewall"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	portForwardsFileName = "port_forwards.json"
+
+	// Referenced from /etc/pf.conf as `rdr-anchor "hoster/*"`
+	PORT_FORWARD_ANCHOR = HosterFirewall.ANCHOR_ROOT + "/forwards"
+)
+
+// NAT port forward (PF rdr) from the host's public interface to a VM or Jail.
+//
+// Only the target name is stored: the target IP address is looked up every time the rules are rendered,
+// so the forward follows the VM or Jail if it's IP address changes.
+type PortForward struct {
+	Protocol   string `json:"protocol"` // tcp or udp
+	PublicPort int    `json:"public_port"`
+	Target     string `json:"target"` // VM or Jail name
+	TargetPort int    `json:"target_port"`
+	Interface  string `json:"interface,omitempty"` // public interface, defaults to the interface of the default route
+	Comment    string `json:"comment,omitempty"`
+}
+
+// Port forwards live next to the network_config.json
+func getPortForwardsLocation() (r string, e error) {
+	confFile, err := getNetworkConfigLocation()
+	if err != nil {
+		e = err
+		return
+	}
+
+	r = filepath.Dir(confFile) + "/" + portForwardsFileName
+	return
+}
+
+// Reads the port forwards config file. Returns an empty list if the file doesn't exist yet.
+func GetPortForwards() (r []PortForward, e error) {
+	confFile, err := getPortForwardsLocation()
+	if err != nil {
+		e = err
+		return
+	}
+	if !FileExists.CheckUsingOsStat(confFile) {
+		return
+	}
+
+	data, err := os.ReadFile(confFile)
+	if err != nil {
+		e = err
+		return
+	}
+
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		e = err
+		return
+	}
+
+	return
+}
+
+// Saves the port forwards config file (atomically)
+func SavePortForwards(forwards []PortForward) error {
+	confFile, err := getPortForwardsLocation()
+	if err != nil {
+		return err
+	}
+	if forwards == nil {
+		forwards = []PortForward{}
+	}
+
+	jsonData, err := json.MarshalIndent(forwards, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(confFile+".tmp", jsonData, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(confFile+".tmp", confFile)
+}
+
+// Returns the port forwards pointing to a particular VM or Jail
+func PortForwardsFor(target string) (r []PortForward, e error) {
+	forwards, err := GetPortForwards()
+	if err != nil {
+		e = err
+		return
+	}
+
+	for _, v := range forwards {
+		if v.Target == target {
+			r = append(r, v)
+		}
+	}
+	return
+}
+
+// Parses the "<vm>:<port>" forward target
+func ParsePortForwardTarget(input string) (target string, port int, e error) {
+	idx := strings.LastIndex(input, ":")
+	if idx < 1 {
+		e = fmt.Errorf("forward target must be set as <name>:<port>, got %s", input)
+		return
+	}
+
+	target = input[:idx]
+	port, err := strconv.Atoi(input[idx+1:])
+	if err != nil {
+		e = fmt.Errorf("invalid forward target port %s", input[idx+1:])
+		return
+	}
+
+	return
+}
+
+func (f PortForward) protocol() string {
+	if len(f.Protocol) < 1 {
+		return "tcp"
+	}
+	return strings.ToLower(f.Protocol)
+}
+
+var reInterfaceName = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Validates the port forward, and checks that it doesn't collide with any of the existing ones
+func ValidatePortForward(f PortForward, existing []PortForward) error {
+	if f.protocol() != "tcp" && f.protocol() != "udp" {
+		return fmt.Errorf("unsupported protocol %s, only tcp and udp can be forwarded", f.Protocol)
+	}
+	if f.PublicPort < 1 || f.PublicPort > 65535 {
+		return fmt.Errorf("invalid public port %d", f.PublicPort)
+	}
+	if f.TargetPort < 1 || f.TargetPort > 65535 {
+		return fmt.Errorf("invalid target port %d", f.TargetPort)
+	}
+	if len(f.Target) < 1 {
+		return fmt.Errorf("forward target can't be empty")
+	}
+	if len(f.Interface) > 0 && !reInterfaceName.MatchString(f.Interface) {
+		return fmt.Errorf("invalid interface name %s", f.Interface)
+	}
+	if strings.ContainsAny(f.Comment, "\n\r") {
+		return fmt.Errorf("comment must be a single line")
+	}
+
+	for _, v := range existing {
+		if v.protocol() != f.protocol() || v.PublicPort != f.PublicPort {
+			continue
+		}
+		// The forwards without an interface set use the default route interface, which may be any of them
+		if len(v.Interface) > 0 && len(f.Interface) > 0 && v.Interface != f.Interface {
+			continue
+		}
+		return fmt.Errorf("public port %s/%d is already forwarded to %s:%d", f.protocol(), f.PublicPort, v.Target, v.TargetPort)
+	}
+
+	return nil
+}
+
+// Renders the PF rdr ruleset for the port forwards anchor. Doesn't touch the system.
+//
+// Targets map the VM and Jail names to their current IP addresses. Forwards which point to
+// the unknown targets are not rendered, and returned as skipped.
+func RenderPortForwards(forwards []PortForward, targets map[string]string, defaultInterface string) (r string, skipped []PortForward) {
+	lines := []string{"# Hoster port forwards (do not edit, generated automatically)"}
+
+	for _, v := range forwards {
+		ipAddress, found := targets[v.Target]
+		iface := v.Interface
+		if len(iface) < 1 {
+			iface = defaultInterface
+		}
+		if !found || len(ipAddress) < 1 || len(iface) < 1 {
+			skipped = append(skipped, v)
+			continue
+		}
+
+		line := fmt.Sprintf("rdr pass on %s inet proto %s from any to (%s) port %d -> %s port %d",
+			iface, v.protocol(), iface, v.PublicPort, ipAddress, v.TargetPort)
+		comment := v.Target
+		if len(v.Comment) > 0 {
+			comment = comment + ": " + v.Comment
+		}
+		lines = append(lines, line+"  # "+comment)
+	}
+
+	r = strings.Join(lines, "\n") + "\n"
+	return
+}
+
+// Loads the rendered port forwards into PF
+func LoadPortForwards(ruleset string) error {
+	return HosterFirewall.Load(PORT_FORWARD_ANCHOR, ruleset)
+}
+
+var reRouteInterface = regexp.MustCompile(`^\s*interface:\s+(\S+)`)
+
+// Returns the name of the interface used by the default IPv4 route
+func DefaultRouteInterface() (r string, e error) {
+	out, err := exec.Command("route", "-4", "-n", "get", "default").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("could not find the default route: %s; %s", strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	for _, v := range strings.Split(string(out), "\n") {
+		match := reRouteInterface.FindStringSubmatch(v)
+		if len(match) > 1 {
+			r = match[1]
+			return
+		}
+	}
+
+	e = fmt.Errorf("could not find the default route interface")
+	return
+}
@@ -0,0 +1,353 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"fmt"
+	"math/bits"
+	"net/netip"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	NETWORK_CHANGE_CREATE         = "create"
+	NETWORK_CHANGE_REMOVE         = "remove"
+	NETWORK_CHANGE_ADD_UPLINK     = "add-uplink"
+	NETWORK_CHANGE_REMOVE_UPLINK  = "remove-uplink"
+	NETWORK_CHANGE_SET_ADDRESS    = "set-address"
+	NETWORK_CHANGE_REMOVE_ADDRESS = "remove-address"
+	NETWORK_CHANGE_SET_MTU        = "set-mtu"
+	NETWORK_CHANGE_UNCHANGED      = "unchanged"
+)
+
+// Network bridge ("vm-<networkName>") as it's currently configured on the host
+type LiveBridge struct {
+	Network string
+	Members []string
+	Inet    []string // "address/prefix"
+	Inet6   []string // "address/prefix", link-local addresses are not included
+	Mtu     int
+}
+
+type LiveNetworkState struct {
+	Bridges      map[string]LiveBridge // network name -> bridge
+	Descriptions map[string]string     // interface -> description
+}
+
+// A single step required to bring the host networking in line with network_config.json
+type NetworkChange struct {
+	Network string `json:"network"`
+	Action  string `json:"action"`
+	Target  string `json:"target"` // interface, address or MTU the action applies to
+	Detail  string `json:"detail"`
+	Blocked bool   `json:"blocked"` // the change would disrupt running VMs or Jails, and will not be applied
+}
+
+var reIfconfigMtu = regexp.MustCompile(`\smtu\s+(\d+)`)
+var reIfconfigInet = regexp.MustCompile(`^\s+inet\s+(\S+)\s+netmask\s+(0x[0-9a-fA-F]+)`)
+var reIfconfigInet6 = regexp.MustCompile(`^\s+inet6\s+(\S+)\s+prefixlen\s+(\d+)`)
+
+// Parses the `ifconfig` output, and returns the Hoster network bridges together with all interface descriptions
+func parseLiveNetworkState(ifconfigOutput string) LiveNetworkState {
+	r := LiveNetworkState{Bridges: make(map[string]LiveBridge), Descriptions: make(map[string]string)}
+
+	iface := ""
+	for _, v := range strings.Split(ifconfigOutput, "\n") {
+		if match := reIfconfigHeader.FindStringSubmatch(v); len(match) > 1 {
+			iface = match[1]
+			if strings.HasPrefix(iface, "vm-") {
+				bridge := LiveBridge{Network: strings.TrimPrefix(iface, "vm-")}
+				if mtu := reIfconfigMtu.FindStringSubmatch(v); len(mtu) > 1 {
+					bridge.Mtu, _ = strconv.Atoi(mtu[1])
+				}
+				r.Bridges[bridge.Network] = bridge
+			}
+			continue
+		}
+		if len(iface) < 1 {
+			continue
+		}
+
+		if match := reIfconfigDescription.FindStringSubmatch(v); len(match) > 1 {
+			r.Descriptions[iface] = strings.Trim(strings.TrimSpace(match[1]), "\"")
+			continue
+		}
+
+		bridge, found := r.Bridges[strings.TrimPrefix(iface, "vm-")]
+		if !found || !strings.HasPrefix(iface, "vm-") {
+			continue
+		}
+
+		if match := reBridgeMember.FindStringSubmatch(v); len(match) > 1 {
+			bridge.Members = append(bridge.Members, match[1])
+		} else if match := reIfconfigInet.FindStringSubmatch(v); len(match) > 2 {
+			mask, err := strconv.ParseUint(strings.TrimPrefix(match[2], "0x"), 16, 32)
+			addr, errAddr := netip.ParseAddr(match[1])
+			if err == nil && errAddr == nil {
+				bridge.Inet = append(bridge.Inet, netip.PrefixFrom(addr, bits.OnesCount32(uint32(mask))).String())
+			}
+		} else if match := reIfconfigInet6.FindStringSubmatch(v); len(match) > 2 {
+			addr, err := netip.ParseAddr(match[1])
+			prefixLen, _ := strconv.Atoi(match[2])
+			if err == nil && !addr.IsLinkLocalUnicast() {
+				bridge.Inet6 = append(bridge.Inet6, netip.PrefixFrom(addr.WithZone(""), prefixLen).String())
+			}
+		}
+		r.Bridges[bridge.Network] = bridge
+	}
+
+	return r
+}
+
+// Returns the current state of the Hoster network bridges on this host
+func GetLiveNetworkState() (r LiveNetworkState, e error) {
+	out, err := exec.Command("ifconfig").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("%s; %s", strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	r = parseLiveNetworkState(string(out))
+	return
+}
+
+// VM TAP and Jail epair interfaces are connected to the bridges at runtime, and must never be touched by the network reload
+func isResourceInterface(iface string) bool {
+	return reTapNumber.MatchString(iface) || reEpairNumber.MatchString(iface)
+}
+
+// Returns the bridge address for the network in the "address/prefix" format, or an empty string if it's not set
+func bridgeAddress(address string, subnet string) string {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return ""
+	}
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return ""
+	}
+	return netip.PrefixFrom(addr, prefix.Bits()).String()
+}
+
+func uplinkDetail(n NetworkConfig) string {
+	if n.VlanId > 0 {
+		return fmt.Sprintf("VLAN %d on %s", n.VlanId, n.BridgeInterface)
+	}
+	if n.VxlanId > 0 {
+		return fmt.Sprintf("VXLAN %d", n.VxlanId)
+	}
+	return "physical interface"
+}
+
+// Compares the network config against the live host state, and returns the list of changes that
+// `hoster network init` has to make. Doesn't touch the system.
+//
+// Networks that didn't change are left alone (reported as "unchanged"), so the VMs and Jails connected to them are not disrupted.
+// Stale networks that still have VMs or Jails connected to them are reported as blocked.
+func PlanNetworkChanges(desired []NetworkConfig, live LiveNetworkState) (r []NetworkChange) {
+	desiredNames := make(map[string]bool)
+	for _, v := range desired {
+		desiredNames[v.NetworkName] = true
+	}
+
+	// Stale networks are removed first, so their addresses and uplinks can be re-used by the new ones
+	stale := []string{}
+	for name := range live.Bridges {
+		if !desiredNames[name] {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	for _, name := range stale {
+		change := NetworkChange{Network: name, Action: NETWORK_CHANGE_REMOVE, Target: "vm-" + name}
+		inUse := []string{}
+		for _, v := range live.Bridges[name].Members {
+			if isResourceInterface(v) {
+				inUse = append(inUse, v)
+			}
+		}
+		if len(inUse) > 0 {
+			change.Blocked = true
+			change.Detail = "still in use by " + strings.Join(inUse, ", ")
+		}
+		r = append(r, change)
+	}
+
+	for _, n := range desired {
+		bridge, exists := live.Bridges[n.NetworkName]
+		uplink := n.UplinkInterface()
+		address := bridgeAddress(n.Gateway, n.Subnet)
+		address6 := ""
+		if n.Ipv6Enabled() {
+			address6 = bridgeAddress(n.Gateway6, n.Subnet6)
+		}
+
+		changes := []NetworkChange{}
+		if !exists {
+			changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_CREATE, Target: "vm-" + n.NetworkName})
+		}
+
+		for _, v := range bridge.Members {
+			if v == uplink || isResourceInterface(v) {
+				continue
+			}
+			changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_REMOVE_UPLINK, Target: v})
+		}
+
+		if n.ApplyBridgeAddr {
+			for _, v := range bridge.Inet {
+				if v != address {
+					changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_REMOVE_ADDRESS, Target: v})
+				}
+			}
+			for _, v := range bridge.Inet6 {
+				if v != address6 {
+					changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_REMOVE_ADDRESS, Target: v})
+				}
+			}
+		}
+
+		if n.Mtu > 0 && bridge.Mtu != n.Mtu {
+			detail := ""
+			if exists {
+				detail = fmt.Sprintf("currently %d", bridge.Mtu)
+			}
+			changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_SET_MTU, Target: strconv.Itoa(n.Mtu), Detail: detail})
+		}
+
+		if len(uplink) > 0 && !slices.Contains(bridge.Members, uplink) {
+			changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_ADD_UPLINK, Target: uplink, Detail: uplinkDetail(n)})
+		}
+
+		if n.ApplyBridgeAddr {
+			if len(address) > 0 && !slices.Contains(bridge.Inet, address) {
+				changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_SET_ADDRESS, Target: address})
+			}
+			if len(address6) > 0 && !slices.Contains(bridge.Inet6, address6) {
+				changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_SET_ADDRESS, Target: address6})
+			}
+		}
+
+		if len(changes) < 1 {
+			changes = append(changes, NetworkChange{Network: n.NetworkName, Action: NETWORK_CHANGE_UNCHANGED, Target: "vm-" + n.NetworkName})
+		}
+		r = append(r, changes...)
+	}
+
+	return
+}
+
+// Applies a single change produced by PlanNetworkChanges. Blocked and "unchanged" changes are ignored.
+func ApplyNetworkChange(networks []NetworkConfig, change NetworkChange) error {
+	if change.Blocked || change.Action == NETWORK_CHANGE_UNCHANGED {
+		return nil
+	}
+
+	bridge := "vm-" + change.Network
+	switch change.Action {
+	case NETWORK_CHANGE_CREATE:
+		return ifconfig("bridge", "create", "name", bridge, "up")
+
+	case NETWORK_CHANGE_REMOVE:
+		members, err := BridgeMembers(bridge)
+		if err != nil {
+			return err
+		}
+		for _, v := range members {
+			if isResourceInterface(v) {
+				return fmt.Errorf("network %s is still in use by the interface %s", change.Network, v)
+			}
+		}
+		err = ifconfig(bridge, "destroy")
+		if err != nil {
+			return err
+		}
+		for _, v := range members {
+			destroyHosterUplink(v, change.Network)
+		}
+		return nil
+
+	case NETWORK_CHANGE_ADD_UPLINK:
+		network, err := findNetwork(networks, change.Network)
+		if err != nil {
+			return err
+		}
+		uplink, err := CreateUplinkInterface(network)
+		if err != nil {
+			return err
+		}
+		return ifconfig(bridge, "addm", uplink)
+
+	case NETWORK_CHANGE_REMOVE_UPLINK:
+		err := ifconfig(bridge, "deletem", change.Target)
+		if err != nil {
+			return err
+		}
+		destroyHosterUplink(change.Target, change.Network)
+		return nil
+
+	case NETWORK_CHANGE_SET_ADDRESS:
+		prefix, err := netip.ParsePrefix(change.Target)
+		if err != nil {
+			return err
+		}
+		if prefix.Addr().Is4() {
+			return ifconfig(bridge, "inet", change.Target, "alias")
+		}
+		return ifconfig(bridge, "inet6", "-ifdisabled", change.Target, "alias")
+
+	case NETWORK_CHANGE_REMOVE_ADDRESS:
+		prefix, err := netip.ParsePrefix(change.Target)
+		if err != nil {
+			return err
+		}
+		if prefix.Addr().Is4() {
+			return ifconfig(bridge, "inet", prefix.Addr().String(), "-alias")
+		}
+		return ifconfig(bridge, "inet6", prefix.Addr().String(), "-alias")
+
+	case NETWORK_CHANGE_SET_MTU:
+		return ifconfig(bridge, "mtu", change.Target)
+	}
+
+	return fmt.Errorf("unknown network change: %s", change.Action)
+}
+
+// Destroys the VLAN or VXLAN interface which was created by Hoster for the network, physical interfaces are left untouched
+func destroyHosterUplink(iface string, networkName string) {
+	out, err := exec.Command("ifconfig", iface).CombinedOutput()
+	if err != nil {
+		return
+	}
+	state := parseLiveNetworkState(string(out))
+	if state.Descriptions[iface] != "hoster::network::"+networkName {
+		return
+	}
+	_ = exec.Command("ifconfig", iface, "destroy").Run()
+}
+
+func findNetwork(networks []NetworkConfig, networkName string) (r NetworkConfig, e error) {
+	for _, v := range networks {
+		if v.NetworkName == networkName {
+			r = v
+			return
+		}
+	}
+	e = fmt.Errorf("network with the name %s does not exist", networkName)
+	return
+}
+
+func ifconfig(args ...string) error {
+	out, err := exec.Command("ifconfig", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ifconfig %s: %s; %s", strings.Join(args, " "), strings.TrimSpace(string(out)), err.Error())
+	}
+	return nil
+}
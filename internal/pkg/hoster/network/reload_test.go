@@ -0,0 +1,150 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testIfconfigOutput = `em0: flags=1008843<UP,BROADCAST,RUNNING,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 1500
+	description: uplink
+	options=4e524bb<RXCSUM,TXCSUM,VLAN_MTU,VLAN_HWTAGGING,JUMBO_MTU>
+	ether 58:9c:fc:00:00:01
+	inet 192.168.1.10 netmask 0xffffff00 broadcast 192.168.1.255
+	media: Ethernet autoselect (1000baseT <full-duplex>)
+	status: active
+vm-external: flags=1008843<UP,BROADCAST,RUNNING,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 9000
+	description: "Hoster external network"
+	ether 58:9c:fc:10:ff:a1
+	inet 10.0.102.1 netmask 0xffffff00 broadcast 10.0.102.255
+	inet6 fe80::5a9c:fcff:fe10:ffa1%vm-external prefixlen 64 scopeid 0x5
+	inet6 fd00:102::1 prefixlen 64
+	id 00:00:00:00:00:00 priority 32768 hellotime 2 fwddelay 15
+	maxage 20 holdcnt 6 proto rstp maxaddr 2000 timeout 1200
+	member: tap0 flags=143<LEARNING,DISCOVER,AUTOEDGE,AUTOPTP>
+	        ifmaxaddr 0 port 6 priority 128 path cost 2000000
+	member: em0 flags=143<LEARNING,DISCOVER,AUTOEDGE,AUTOPTP>
+	        ifmaxaddr 0 port 1 priority 128 path cost 20000
+	groups: bridge
+	nd6 options=1<PERFORMNUD>
+vm-internal: flags=8843<UP,BROADCAST,RUNNING,SIMPLEX,MULTICAST> metric 0 mtu 1500
+	ether 58:9c:fc:10:ff:a2
+	inet 10.0.101.1 netmask 0xffffff00 broadcast 10.0.101.255
+	inet 10.0.99.1 netmask 0xffffff00 broadcast 10.0.99.255
+	member: epair3a flags=143<LEARNING,DISCOVER,AUTOEDGE,AUTOPTP>
+	        ifmaxaddr 0 port 9 priority 128 path cost 2000
+	groups: bridge
+tap0: flags=1008943<UP,BROADCAST,RUNNING,PROMISC,SIMPLEX,MULTICAST,LOWER_UP> metric 0 mtu 9000
+	description: "vmnet/test-vm-1/0/external"
+	ether 58:9c:fc:10:ff:b1
+`
+
+func TestParseLiveNetworkState(t *testing.T) {
+	got := parseLiveNetworkState(testIfconfigOutput)
+
+	wantBridges := map[string]LiveBridge{
+		"external": {Network: "external", Members: []string{"tap0", "em0"}, Inet: []string{"10.0.102.1/24"}, Inet6: []string{"fd00:102::1/64"}, Mtu: 9000},
+		"internal": {Network: "internal", Members: []string{"epair3a"}, Inet: []string{"10.0.101.1/24", "10.0.99.1/24"}, Mtu: 1500},
+	}
+	if !reflect.DeepEqual(got.Bridges, wantBridges) {
+		t.Errorf("parseLiveNetworkState() bridges = %+v, want %+v", got.Bridges, wantBridges)
+	}
+
+	wantDescriptions := map[string]string{"em0": "uplink", "vm-external": "Hoster external network", "tap0": "vmnet/test-vm-1/0/external"}
+	if !reflect.DeepEqual(got.Descriptions, wantDescriptions) {
+		t.Errorf("parseLiveNetworkState() descriptions = %v, want %v", got.Descriptions, wantDescriptions)
+	}
+
+	if empty := parseLiveNetworkState(""); len(empty.Bridges) != 0 || len(empty.Descriptions) != 0 {
+		t.Errorf("parseLiveNetworkState(\"\") = %+v, want an empty state", empty)
+	}
+}
+
+func TestPlanNetworkChanges(t *testing.T) {
+	external := bridgedNetwork("external", "102", "em0")
+	external.ApplyBridgeAddr = true
+	external.Subnet6 = "fd00:102::/64"
+	external.Gateway6 = "fd00:102::1"
+	external.Mtu = 9000
+
+	tests := []struct {
+		name    string
+		desired []NetworkConfig
+		live    string // ifconfig output
+		want    []NetworkChange
+	}{
+		{
+			name:    "nothing changed",
+			desired: []NetworkConfig{external},
+			live:    testIfconfigOutput,
+			want: []NetworkChange{
+				{Network: "internal", Action: NETWORK_CHANGE_REMOVE, Target: "vm-internal", Blocked: true, Detail: "still in use by epair3a"},
+				{Network: "external", Action: NETWORK_CHANGE_UNCHANGED, Target: "vm-external"},
+			},
+		},
+		{
+			name: "new networks are created",
+			desired: []NetworkConfig{
+				func() NetworkConfig {
+					n := bridgedNetwork("vlan10", "103", "em0")
+					n.VlanId = 10
+					n.ApplyBridgeAddr = true
+					return n
+				}(),
+				bridgedNetwork("nat", "104", "None"),
+			},
+			live: "",
+			want: []NetworkChange{
+				{Network: "vlan10", Action: NETWORK_CHANGE_CREATE, Target: "vm-vlan10"},
+				{Network: "vlan10", Action: NETWORK_CHANGE_ADD_UPLINK, Target: "em0.10", Detail: "VLAN 10 on em0"},
+				{Network: "vlan10", Action: NETWORK_CHANGE_SET_ADDRESS, Target: "10.0.103.1/24"},
+				{Network: "nat", Action: NETWORK_CHANGE_CREATE, Target: "vm-nat"},
+			},
+		},
+		{
+			name: "uplink, addresses and MTU are updated in place",
+			desired: []NetworkConfig{
+				func() NetworkConfig {
+					n := bridgedNetwork("internal", "100", "igb0")
+					n.ApplyBridgeAddr = true
+					n.Mtu = 9000
+					return n
+				}(),
+				external,
+			},
+			live: testIfconfigOutput,
+			want: []NetworkChange{
+				{Network: "internal", Action: NETWORK_CHANGE_REMOVE_ADDRESS, Target: "10.0.101.1/24"},
+				{Network: "internal", Action: NETWORK_CHANGE_REMOVE_ADDRESS, Target: "10.0.99.1/24"},
+				{Network: "internal", Action: NETWORK_CHANGE_SET_MTU, Target: "9000", Detail: "currently 1500"},
+				{Network: "internal", Action: NETWORK_CHANGE_ADD_UPLINK, Target: "igb0", Detail: "physical interface"},
+				{Network: "internal", Action: NETWORK_CHANGE_SET_ADDRESS, Target: "10.0.100.1/24"},
+				{Network: "external", Action: NETWORK_CHANGE_UNCHANGED, Target: "vm-external"},
+			},
+		},
+		{
+			name: "stale uplink is removed, the resource interfaces are kept",
+			desired: []NetworkConfig{
+				bridgedNetwork("internal", "101", "None"),
+				bridgedNetwork("external", "102", "None"),
+			},
+			live: testIfconfigOutput,
+			want: []NetworkChange{
+				{Network: "internal", Action: NETWORK_CHANGE_UNCHANGED, Target: "vm-internal"},
+				{Network: "external", Action: NETWORK_CHANGE_REMOVE_UPLINK, Target: "em0"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanNetworkChanges(tt.desired, parseLiveNetworkState(tt.live))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanNetworkChanges() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	HosterFirewall "HosterCore/internal/pkg/hoster/firewall"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	SHAPING_DIRECTION_INGRESS = "ingress"
+	SHAPING_DIRECTION_EGRESS  = "egress"
+
+	// Dummynet pipe numbers are derived from the interface number, so they never collide and survive the restarts
+	shapingPipeBaseTap   = 10000
+	shapingPipeBaseEpair = 40000
+	shapingPipeMax       = 65535
+)
+
+// Dummynet pipe applied to a VM TAP or a Jail epair interface
+type ShapingState struct {
+	Interface string `json:"interface"`
+	Direction string `json:"direction"` // ingress (traffic towards the VM/Jail) or egress (traffic from the VM/Jail)
+	Pipe      int    `json:"pipe"`
+	Bandwidth string `json:"bandwidth"` // as reported by dnctl, e.g. "100.000 Mbit/s"
+}
+
+var reBandwidth = regexp.MustCompile(`^[0-9]+[KMG]?(bit|Byte)/s$`)
+
+// Validates the bandwidth cap, which uses the dnctl syntax, e.g. "100Mbit/s" or "10MByte/s"
+func ValidateBandwidth(bandwidth string) error {
+	if len(bandwidth) < 1 {
+		return nil
+	}
+	if !reBandwidth.MatchString(bandwidth) {
+		return fmt.Errorf("invalid bandwidth %s, use the dnctl format, e.g. 100Mbit/s or 10MByte/s", bandwidth)
+	}
+	return nil
+}
+
+var reTapNumber = regexp.MustCompile(`^tap(\d+)$`)
+var reEpairNumber = regexp.MustCompile(`^epair(\d+)a$`)
+
+// Returns the ingress and egress dummynet pipe numbers for the interface
+func shapingPipes(iface string) (ingress int, egress int, e error) {
+	base := 0
+	number := ""
+	if match := reTapNumber.FindStringSubmatch(iface); len(match) > 1 {
+		base = shapingPipeBaseTap
+		number = match[1]
+	} else if match := reEpairNumber.FindStringSubmatch(iface); len(match) > 1 {
+		base = shapingPipeBaseEpair
+		number = match[1]
+	} else {
+		e = fmt.Errorf("traffic shaping is not supported on %s", iface)
+		return
+	}
+
+	n, _ := strconv.Atoi(number)
+	ingress = base + n*2
+	egress = ingress + 1
+	if egress > shapingPipeMax || (base == shapingPipeBaseTap && egress >= shapingPipeBaseEpair) {
+		e = fmt.Errorf("no dummynet pipe numbers left for %s", iface)
+		return
+	}
+
+	return
+}
+
+func shapingAnchor(iface string) string {
+	// "bw-" sorts before the "jail-" and "vm-" firewall anchors, so the pipes are assigned before any quick rule matches
+	return HosterFirewall.ANCHOR_ROOT + "/bw-" + iface
+}
+
+// Renders the PF rules which send the interface traffic through the dummynet pipes. Doesn't touch the system.
+//
+// The interface is a bridge member on the host side, so the traffic leaving the VM/Jail comes "in" on it.
+func RenderShaping(iface string, ingressPipe int, egressPipe int, ingress string, egress string) string {
+	lines := []string{fmt.Sprintf("# Hoster traffic shaping: %s (do not edit, generated automatically)", iface)}
+	if len(ingress) > 0 {
+		lines = append(lines, fmt.Sprintf("match out on %s all dnpipe %d", iface, ingressPipe))
+	}
+	if len(egress) > 0 {
+		lines = append(lines, fmt.Sprintf("match in on %s all dnpipe %d", iface, egressPipe))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Creates the dummynet pipes for the interface, and loads the PF rules which use them.
+// Empty bandwidth means no cap in that direction. Does nothing if both are empty.
+func ApplyShaping(iface string, ingress string, egress string) error {
+	if len(ingress) < 1 && len(egress) < 1 {
+		return nil
+	}
+	if err := ValidateBandwidth(ingress); err != nil {
+		return err
+	}
+	if err := ValidateBandwidth(egress); err != nil {
+		return err
+	}
+
+	ingressPipe, egressPipe, err := shapingPipes(iface)
+	if err != nil {
+		return err
+	}
+
+	// Dummynet module is not loaded by default
+	out, err := exec.Command("kldload", "-n", "dummynet").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not load dummynet: %s; %s", strings.TrimSpace(string(out)), err.Error())
+	}
+
+	pipes := []struct {
+		pipe      int
+		bandwidth string
+	}{{ingressPipe, ingress}, {egressPipe, egress}}
+	for _, v := range pipes {
+		if len(v.bandwidth) < 1 {
+			continue
+		}
+		out, err := exec.Command("dnctl", "pipe", strconv.Itoa(v.pipe), "config", "bw", v.bandwidth).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("could not configure the dummynet pipe %d: %s; %s", v.pipe, strings.TrimSpace(string(out)), err.Error())
+		}
+	}
+
+	return HosterFirewall.Load(shapingAnchor(iface), RenderShaping(iface, ingressPipe, egressPipe, ingress, egress))
+}
+
+// Removes the dummynet pipes and PF rules for the interface (best effort, the shaping may not be configured at all)
+func RemoveShaping(iface string) {
+	ingressPipe, egressPipe, err := shapingPipes(iface)
+	if err != nil {
+		return
+	}
+
+	_ = HosterFirewall.Flush(shapingAnchor(iface))
+	_ = exec.Command("dnctl", "pipe", "delete", strconv.Itoa(ingressPipe)).Run()
+	_ = exec.Command("dnctl", "pipe", "delete", strconv.Itoa(egressPipe)).Run()
+}
+
+// Removes the traffic shaping from all interfaces of the VM or Jail
+func RemoveResourceShaping(resourceType string, resourceName string) error {
+	ifaces, err := ResourceInterfaces(resourceType, resourceName)
+	if err != nil {
+		return err
+	}
+
+	for iface := range ifaces {
+		RemoveShaping(iface)
+	}
+	return nil
+}
+
+var reDnctlPipe = regexp.MustCompile(`^(\d+):\s+(.+?)\s+\d+ ms`)
+
+// Returns the dummynet pipes currently applied to the interface, as reported by dnctl
+func ShapingStatus(iface string) (r []ShapingState) {
+	ingressPipe, egressPipe, err := shapingPipes(iface)
+	if err != nil {
+		return
+	}
+
+	pipes := []ShapingState{
+		{Interface: iface, Direction: SHAPING_DIRECTION_INGRESS, Pipe: ingressPipe},
+		{Interface: iface, Direction: SHAPING_DIRECTION_EGRESS, Pipe: egressPipe},
+	}
+	for _, v := range pipes {
+		out, err := exec.Command("dnctl", "pipe", strconv.Itoa(v.Pipe), "show").CombinedOutput()
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(out), "\n") {
+			match := reDnctlPipe.FindStringSubmatch(strings.TrimSpace(line))
+			if len(match) > 2 && match[1] == strconv.Itoa(v.Pipe) {
+				v.Bandwidth = strings.Join(strings.Fields(match[2]), " ")
+				r = append(r, v)
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// Returns the host side interfaces of the VM or Jail, mapped to the network names
+func ResourceInterfaces(resourceType string, resourceName string) (r map[string]string, e error) {
+	out, err := exec.Command("ifconfig").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("%s; %s", strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	r = make(map[string]string)
+	for iface, owner := range parseIfaceOwners(string(out)) {
+		if owner.resourceType == resourceType && owner.resourceName == resourceName {
+			r[iface] = owner.network
+		}
+	}
+	return
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+)
+
+// Bridge interface names are limited to 15 characters (IFNAMSIZ - 1), and the "vm-" prefix is added to the network name
+const NETWORK_NAME_MAX_LENGTH = 12
+
+var reNetworkName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Validates a single network config: addresses, ranges, IPAM, IPv6 and overlay settings
+func (n NetworkConfig) Validate() error {
+	if len(n.NetworkName) < 1 {
+		return fmt.Errorf("network_name can't be empty")
+	}
+	if len(n.NetworkName) > NETWORK_NAME_MAX_LENGTH || !reNetworkName.MatchString(n.NetworkName) {
+		return fmt.Errorf("network %s: network_name must be up to %d characters long, and only contain letters, numbers, dashes and underscores", n.NetworkName, NETWORK_NAME_MAX_LENGTH)
+	}
+
+	err := validateAddressing(n.Subnet, n.Gateway, n.RangeStart, n.RangeEnd, true)
+	if err != nil {
+		return fmt.Errorf("network %s: %s", n.NetworkName, err.Error())
+	}
+
+	if n.Ipv6Enabled() {
+		start, end, err := n.Ipv6Range()
+		if err != nil {
+			return fmt.Errorf("network %s: %s", n.NetworkName, err.Error())
+		}
+		err = validateAddressing(n.Subnet6, n.Gateway6, start, end, false)
+		if err != nil {
+			return fmt.Errorf("network %s: %s", n.NetworkName, err.Error())
+		}
+		if n.Ipv6Mode != "" && n.Ipv6Mode != IPV6_MODE_STATIC && n.Ipv6Mode != IPV6_MODE_SLAAC {
+			return fmt.Errorf("network %s: ipv6_mode must be set to %s or %s", n.NetworkName, IPV6_MODE_STATIC, IPV6_MODE_SLAAC)
+		}
+	} else if len(n.Gateway6) > 0 || len(n.RangeStart6) > 0 || len(n.RangeEnd6) > 0 || len(n.Ipv6Mode) > 0 {
+		return fmt.Errorf("network %s: IPv6 settings require network_subnet6 to be set", n.NetworkName)
+	}
+
+	if n.IpAllocation != "" && n.IpAllocation != IP_ALLOCATION_RANDOM && n.IpAllocation != IP_ALLOCATION_SEQUENTIAL {
+		return fmt.Errorf("network %s: ip_allocation must be set to %s or %s", n.NetworkName, IP_ALLOCATION_RANDOM, IP_ALLOCATION_SEQUENTIAL)
+	}
+	for _, v := range n.ExcludedIps {
+		if _, _, err := parseAddrRange(v); err != nil {
+			return fmt.Errorf("network %s: invalid excluded address %s: %s", n.NetworkName, v, err.Error())
+		}
+	}
+
+	return n.ValidateOverlay()
+}
+
+// Checks that the gateway and the allocation range are within the subnet, and the range is in the correct order
+func validateAddressing(subnet string, gateway string, rangeStart string, rangeEnd string, ipv4 bool) error {
+	family := "IPv6"
+	if ipv4 {
+		family = "IPv4"
+	}
+
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return fmt.Errorf("invalid %s subnet %s", family, subnet)
+	}
+	if prefix.Addr().Is4() != ipv4 {
+		return fmt.Errorf("subnet %s is not an %s subnet", subnet, family)
+	}
+	prefix = prefix.Masked()
+
+	addresses := []struct {
+		name  string
+		value string
+	}{{"gateway", gateway}, {"range start", rangeStart}, {"range end", rangeEnd}}
+	parsed := []netip.Addr{}
+	for _, v := range addresses {
+		addr, err := netip.ParseAddr(v.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %s %s", family, v.name, v.value)
+		}
+		if !prefix.Contains(addr) {
+			return fmt.Errorf("%s %s is outside of the subnet %s", v.name, v.value, prefix.String())
+		}
+		parsed = append(parsed, addr)
+	}
+
+	if parsed[1].Compare(parsed[2]) >= 0 {
+		return fmt.Errorf("range start %s must be lower than the range end %s", rangeStart, rangeEnd)
+	}
+
+	return nil
+}
+
+// Validates the whole network config: every network on it's own, and then the networks against each other
+// (duplicate names and bridges, overlapping subnets, re-used VLAN and VXLAN IDs, shared untagged uplinks).
+//
+// All problems found are returned together, so they can be fixed in one go.
+func ValidateNetworkConfig(networks []NetworkConfig) error {
+	errs := []error{}
+	if len(networks) < 1 {
+		errs = append(errs, fmt.Errorf("at least one network must be configured"))
+	}
+
+	for _, v := range networks {
+		err := v.Validate()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i, a := range networks {
+		for _, b := range networks[i+1:] {
+			if a.NetworkName == b.NetworkName {
+				errs = append(errs, fmt.Errorf("network %s: duplicate network (bridge vm-%s)", a.NetworkName, a.NetworkName))
+				continue
+			}
+			if subnetsOverlap(a.Subnet, b.Subnet) {
+				errs = append(errs, fmt.Errorf("network %s: subnet %s overlaps with the network %s (%s)", a.NetworkName, a.Subnet, b.NetworkName, b.Subnet))
+			}
+			if a.Ipv6Enabled() && b.Ipv6Enabled() && subnetsOverlap(a.Subnet6, b.Subnet6) {
+				errs = append(errs, fmt.Errorf("network %s: subnet %s overlaps with the network %s (%s)", a.NetworkName, a.Subnet6, b.NetworkName, b.Subnet6))
+			}
+			if a.VlanId > 0 && a.VlanId == b.VlanId && a.BridgeInterface == b.BridgeInterface {
+				errs = append(errs, fmt.Errorf("network %s: VLAN %d on %s is already used by the network %s", a.NetworkName, a.VlanId, a.BridgeInterface, b.NetworkName))
+			}
+			// An interface can only be a member of one bridge, so the untagged uplink can't be shared
+			if untaggedUplink(a) != "" && untaggedUplink(a) == untaggedUplink(b) {
+				errs = append(errs, fmt.Errorf("network %s: bridge_interface %s is already used (untagged) by the network %s", a.NetworkName, a.BridgeInterface, b.NetworkName))
+			}
+			if a.VxlanId > 0 && a.VxlanId == b.VxlanId {
+				errs = append(errs, fmt.Errorf("network %s: VXLAN %d is already used by the network %s", a.NetworkName, a.VxlanId, b.NetworkName))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Returns the physical interface bridged without a VLAN or VXLAN, or an empty string
+func untaggedUplink(n NetworkConfig) string {
+	if n.VlanId > 0 || n.VxlanId > 0 {
+		return ""
+	}
+	return n.UplinkInterface()
+}
+
+func subnetsOverlap(a string, b string) bool {
+	prefixA, errA := netip.ParsePrefix(a)
+	prefixB, errB := netip.ParsePrefix(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return prefixA.Masked().Overlaps(prefixB.Masked())
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterNetwork
+
+import (
+	"strings"
+	"testing"
+)
+
+// Returns a valid network on the 10.0.<octet>.0/24 subnet, bridged to the given interface
+func bridgedNetwork(name string, octet string, iface string) NetworkConfig {
+	return NetworkConfig{
+		NetworkName:     name,
+		Gateway:         "10.0." + octet + ".1",
+		Subnet:          "10.0." + octet + ".0/24",
+		RangeStart:      "10.0." + octet + ".10",
+		RangeEnd:        "10.0." + octet + ".200",
+		BridgeInterface: iface,
+	}
+}
+
+func TestValidateNetworkConfig(t *testing.T) {
+	vlan := func(n NetworkConfig, id int) NetworkConfig { n.VlanId = id; return n }
+	vxlan := func(n NetworkConfig, id int) NetworkConfig {
+		n.VxlanId = id
+		n.VxlanLocal = "192.168.1.10"
+		n.VxlanRemote = "192.168.1.11"
+		return n
+	}
+
+	tests := []struct {
+		name     string
+		networks []NetworkConfig
+		wantErr  []string // empty means the config is valid
+	}{
+		{
+			name:     "no networks",
+			networks: []NetworkConfig{},
+			wantErr:  []string{"at least one network must be configured"},
+		},
+		{
+			name: "valid NAT, bridged, VLAN and VXLAN networks",
+			networks: []NetworkConfig{
+				bridgedNetwork("internal", "101", "None"),
+				bridgedNetwork("external", "102", "em0"),
+				vlan(bridgedNetwork("vlan10", "103", "em0"), 10),
+				vlan(bridgedNetwork("vlan20", "104", "em0"), 20),
+				vxlan(bridgedNetwork("overlay", "105", "em0"), 100),
+			},
+		},
+		{
+			name:     "NAT networks don't share an uplink",
+			networks: []NetworkConfig{bridgedNetwork("internal", "101", "None"), bridgedNetwork("internal2", "102", "None"), bridgedNetwork("internal3", "103", "")},
+		},
+		{
+			name:     "shared untagged uplink",
+			networks: []NetworkConfig{bridgedNetwork("external", "101", "em0"), bridgedNetwork("external2", "102", "em0")},
+			wantErr:  []string{"network external: bridge_interface em0 is already used (untagged) by the network external2"},
+		},
+		{
+			name:     "duplicate network",
+			networks: []NetworkConfig{bridgedNetwork("internal", "101", "None"), bridgedNetwork("internal", "102", "None")},
+			wantErr:  []string{"network internal: duplicate network (bridge vm-internal)"},
+		},
+		{
+			name:     "overlapping subnets",
+			networks: []NetworkConfig{bridgedNetwork("internal", "101", "None"), bridgedNetwork("internal2", "101", "None")},
+			wantErr:  []string{"network internal: subnet 10.0.101.0/24 overlaps with the network internal2"},
+		},
+		{
+			name:     "re-used VLAN",
+			networks: []NetworkConfig{vlan(bridgedNetwork("vlan10", "101", "em0"), 10), vlan(bridgedNetwork("vlan10b", "102", "em0"), 10)},
+			wantErr:  []string{"network vlan10: VLAN 10 on em0 is already used by the network vlan10b"},
+		},
+		{
+			name:     "re-used VXLAN",
+			networks: []NetworkConfig{vxlan(bridgedNetwork("overlay", "101", ""), 100), vxlan(bridgedNetwork("overlay2", "102", ""), 100)},
+			wantErr:  []string{"network overlay: VXLAN 100 is already used by the network overlay2"},
+		},
+		{
+			name: "all problems are returned together",
+			networks: []NetworkConfig{
+				bridgedNetwork("network-name-too-long", "101", "None"),
+				bridgedNetwork("external", "102", "em0"),
+				bridgedNetwork("external2", "102", "em0"),
+			},
+			wantErr: []string{"network_name must be up to 12 characters long", "overlaps with the network external2", "bridge_interface em0 is already used"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNetworkConfig(tt.networks)
+			if len(tt.wantErr) < 1 {
+				if err != nil {
+					t.Errorf("ValidateNetworkConfig() error = %s, want none", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateNetworkConfig() error = nil, want %q", tt.wantErr)
+			}
+			for _, v := range tt.wantErr {
+				if !strings.Contains(err.Error(), v) {
+					t.Errorf("ValidateNetworkConfig() error = %s, want %q", err, v)
+				}
+			}
+		})
+	}
+}
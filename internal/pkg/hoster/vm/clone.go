@@ -5,6 +5,7 @@
 package HosterVm
 
 import (
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
 	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
 	zfsutils "HosterCore/internal/pkg/zfs_utils"
 	"fmt"
@@ -67,5 +68,12 @@ func Clone(vmName string, newVmName string, snapshotName string) error {
 	}
 
 	log.Warn("vm has been cloned: " + vmName + "; cloned vm name: " + newVmName)
+
+	// The clone is already there, a failed DNS reload must not report it as failed
+	err = HosterHostUtils.ReloadDns()
+	if err != nil {
+		log.Warn("could not reload the DNS server after cloning the vm: " + newVmName + "; error: " + err.Error())
+	}
+
 	return nil
 }
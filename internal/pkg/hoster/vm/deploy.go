@@ -32,6 +32,7 @@ type VmDeployInput struct {
 	RAM             string `json:"ram"`
 	NetworkName     string `json:"network_name"`
 	IpAddress       string `json:"ip_address"`
+	Ipv6Address     string `json:"ipv6_address"` // only used on the dual-stack networks, allocated automatically if empty
 	CustomDnsServer string `json:"custom_dns_server"`
 	OsType          string `json:"os_type"`
 	TargetDataset   string `json:"target_dataset"`
@@ -75,17 +76,18 @@ func Deploy(input VmDeployInput) error {
 
 	// emojlog.PrintLogMessage("Deploying new VM: "+c.VmName, emojlog.Info)
 
-	// Generate and set random MAC address
-	c.MacAddress, err = HosterVmUtils.GenerateMacAddress()
+	// Generate the VM UUID, and derive the MAC address from it
+	vmUuid := uuid.New().String()
+	c.MacAddress, err = HosterVmUtils.AllocateMacAddress(vmUuid, 0, "")
 	if err != nil {
-		return errors.New("could not generate vm name: " + err.Error())
+		return errors.New("could not generate the MAC address: " + err.Error())
 	}
 
 	if len(input.IpAddress) > 1 {
 		c.IpAddress = input.IpAddress
 	} else {
 		// Generate and set random IP address (which is free in the pool of addresses)
-		c.IpAddress, err = HosterHostUtils.GenerateNewRandomIp(input.NetworkName)
+		c.IpAddress, err = HosterHostUtils.AllocateIp(input.NetworkName, c.VmName)
 		if err != nil {
 			return errors.New("could not generate the IP: " + err.Error())
 		}
@@ -95,26 +97,40 @@ func Deploy(input VmDeployInput) error {
 	if err != nil {
 		return errors.New("could not read the network config")
 	}
+	network := HosterNetwork.NetworkConfig{}
 	if len(input.NetworkName) < 1 {
-		c.NetworkName = networkInfo[0].NetworkName
-		c.Subnet = networkInfo[0].Subnet
-		c.NakedSubnet = strings.Split(networkInfo[0].Subnet, "/")[1]
-		c.Gateway = networkInfo[0].Gateway
-		c.NetworkComment = networkInfo[0].Comment
+		network = networkInfo[0]
 	} else {
 		for _, v := range networkInfo {
 			if input.NetworkName == v.NetworkName {
-				c.NetworkName = v.NetworkName
-				c.Subnet = v.Subnet
-				c.NakedSubnet = strings.Split(v.Subnet, "/")[1]
-				c.Gateway = v.Gateway
-				c.NetworkComment = v.Comment
+				network = v
 			}
 		}
-		if len(c.NetworkName) < 1 {
+		if len(network.NetworkName) < 1 {
 			return errors.New("network name supplied doesn't exist")
 		}
 	}
+	c.NetworkName = network.NetworkName
+	c.Subnet = network.Subnet
+	c.NakedSubnet = strings.Split(network.Subnet, "/")[1]
+	c.Gateway = network.Gateway
+	c.NetworkComment = network.Comment
+
+	// Dual-stack networks
+	if network.Ipv6Enabled() {
+		c.Ipv6Slaac = network.Ipv6Slaac()
+		c.NakedSubnet6 = HosterNetwork.PrefixLength(network.Subnet6)
+		c.Gateway6 = network.Gateway6
+
+		if len(input.Ipv6Address) > 0 && !c.Ipv6Slaac {
+			c.Ipv6Address = input.Ipv6Address
+		} else {
+			c.Ipv6Address, err = HosterHostUtils.AllocateIp6(c.NetworkName, c.VmName, c.MacAddress)
+			if err != nil {
+				return errors.New("could not generate the IPv6 address: " + err.Error())
+			}
+		}
+	}
 
 	if len(input.CustomDnsServer) > 1 {
 		c.DnsServer = input.CustomDnsServer
@@ -219,6 +235,7 @@ func Deploy(input VmDeployInput) error {
 	networkConfig.NetworkBridge = c.NetworkName
 	networkConfig.NetworkMac = c.MacAddress
 	networkConfig.IPAddress = c.IpAddress
+	networkConfig.IPv6Address = c.Ipv6Address
 	networkConfig.Comment = c.NetworkComment
 	vmConfig.Networks = append(vmConfig.Networks, networkConfig)
 
@@ -238,7 +255,7 @@ func Deploy(input VmDeployInput) error {
 	vmConfig.VmSshKeys = c.SshKeys
 	vmConfig.VncPort = c.VncPort
 	vmConfig.VncPassword = c.VncPassword
-	vmConfig.UUID = uuid.New().String()
+	vmConfig.UUID = vmUuid
 	vmConfig.Description = "-"
 
 	err = HosterVmUtils.ConfigFileWriter(vmConfig, vmConfigFileLocation)
@@ -356,6 +373,10 @@ type ConfigOutput struct {
 	Subnet            string
 	NakedSubnet       string
 	Gateway           string
+	Ipv6Address       string
+	NakedSubnet6      string
+	Gateway6          string
+	Ipv6Slaac         bool
 	DnsServer         string
 	DnsSearchDomain   string
 	Production        bool
@@ -5,6 +5,7 @@
 package HosterVm
 
 import (
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
 	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
 	"fmt"
 	"os/exec"
@@ -82,5 +83,11 @@ func Destroy(vmName string) error {
 	}
 	// EOF Remove the parent dataset if it exists
 
+	// The VM is already gone, a failed DNS reload must not report the destroy as failed
+	err = HosterHostUtils.ReloadDns()
+	if err != nil {
+		log.Warn("could not reload the DNS server after destroying the vm: " + vmName + "; error: " + err.Error())
+	}
+
 	return nil
 }
@@ -0,0 +1,58 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterVm
+
+import (
+	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
+)
+
+// Fences the production VMs this host is a parent of, by replacing the parent with an HA lock.
+//
+// Returns the list of VMs locked, which can't be started again until unlocked.
+func LockAllVms() (r []string, e error) {
+	vms, err := HosterVmUtils.ListJsonApi()
+	if err != nil {
+		e = err
+		return
+	}
+
+	haLockedString := HosterVmUtils.HaLockString()
+	for _, v := range vms {
+		if v.Production && !v.Backup {
+			err := ChangeParent(v.Name, haLockedString, true)
+			if err != nil {
+				log.Error("could not lock the VM " + v.Name + ": " + err.Error())
+				continue
+			}
+			r = append(r, v.Name)
+		}
+	}
+
+	return
+}
+
+// Returns the HA locked VMs back to this host. Returns the list of VMs unlocked.
+func UnlockAllVms() (r []string, e error) {
+	vms, err := HosterVmUtils.ListJsonApi()
+	if err != nil {
+		e = err
+		return
+	}
+
+	hostname, _ := FreeBSDsysctls.SysctlKernHostname()
+	for _, v := range vms {
+		if v.Production && HosterVmUtils.IsHaLocked(v.ParentHost) {
+			err := ChangeParent(v.Name, hostname, true)
+			if err != nil {
+				log.Error("could not unlock the VM " + v.Name + ": " + err.Error())
+				continue
+			}
+			r = append(r, v.Name)
+		}
+	}
+
+	return
+}
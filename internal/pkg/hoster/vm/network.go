@@ -8,20 +8,27 @@ import (
 )
 
 func AddNewVmNetwork(vmName string, network HosterVmUtils.VmNetwork) error {
+	vm, err := HosterVmUtils.InfoJsonApi(vmName)
+	if err != nil {
+		return err
+	}
+
 	if len(network.NetworkMac) < 1 {
-		var err error
-		network.NetworkMac, err = HosterVmUtils.GenerateMacAddress()
+		network.NetworkMac, err = HosterVmUtils.AllocateMacAddress(HosterVmUtils.MacSeed(vm.VmConfig, vmName), len(vm.Networks), "")
 		if err != nil {
 			return err
 		}
 	}
 
-	if !HosterVmUtils.IsMacAddressValid(network.NetworkMac) {
-		return errors.New("invalid MAC address")
+	// Checks the format, and the conflicts with all VMs (including the replicated backups) and Jails
+	err = HosterVmUtils.CheckMacAddress(network.NetworkMac, "")
+	if err != nil {
+		return err
 	}
+
 	if len(network.IPAddress) < 1 {
 		var err error
-		network.IPAddress, err = HosterHostUtils.GenerateNewRandomIp(network.NetworkBridge)
+		network.IPAddress, err = HosterHostUtils.AllocateIp(network.NetworkBridge, vmName)
 		if err != nil {
 			return err
 		}
@@ -37,19 +44,6 @@ func AddNewVmNetwork(vmName string, network HosterVmUtils.VmNetwork) error {
 		network.Comment = "New VM network"
 	}
 
-	vm, err := HosterVmUtils.InfoJsonApi(vmName)
-	if err != nil {
-		return err
-	}
-
-	ipConflict := false
-	macConflict := false
-
-	vms, err := HosterVmUtils.ListJsonApi()
-	if err != nil {
-		return err
-	}
-
 	netConfig, err := HosterNetwork.GetNetworkConfig()
 	if err != nil {
 		return err
@@ -68,29 +62,10 @@ func AddNewVmNetwork(vmName string, network HosterVmUtils.VmNetwork) error {
 		return errors.New("network bridge not found")
 	}
 
-OUTER:
-	for _, v := range vms {
-		for _, vv := range v.Networks {
-			if vv.IPAddress == network.IPAddress {
-				ipConflict = true
-				break OUTER
-			}
-			if vv.NetworkMac == network.NetworkMac {
-				macConflict = true
-				break OUTER
-			}
-		}
-	}
-
-	if !HosterHostUtils.IsIpWithinRange(network.IPAddress, net.Subnet, net.RangeStart, net.RangeEnd) {
-		return errors.New("IP address is not within the network range")
-	}
-
-	if ipConflict {
-		return errors.New("IP address is already in use")
-	}
-	if macConflict {
-		return errors.New("MAC address is already in use")
+	// Checks the range, exclusions, reservations and conflicts with the other VMs and Jails
+	err = HosterHostUtils.CheckIpAvailable(net.NetworkName, network.IPAddress, vmName)
+	if err != nil {
+		return err
 	}
 
 	vm.VmConfig.Networks = append(vm.VmConfig.Networks, network)
@@ -9,6 +9,7 @@ package HosterVm
 
 import (
 	ErrorMappings "HosterCore/internal/app/rest_api_v2/pkg/error_mappings"
+	HosterHostUtils "HosterCore/internal/pkg/hoster/host/utils"
 	HosterLocations "HosterCore/internal/pkg/hoster/locations"
 	HosterVmUtils "HosterCore/internal/pkg/hoster/vm/utils"
 	"fmt"
@@ -89,6 +90,15 @@ func Start(vmName string, waitVnc bool, debugRun bool) error {
 		return err
 	}
 
+	err = HosterVmUtils.ApplyFirewall(vmName, vmInfo.VmConfig)
+	if err != nil {
+		return err
+	}
+	err = HosterHostUtils.RefreshPortForwards(vmName)
+	if err != nil {
+		log.Error("could not apply the port forwards: " + err.Error())
+	}
+
 	if !debugRun {
 		cmd := exec.Command(binaryLoc, "for", vmName)
 		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -134,8 +134,10 @@ func SendShutdownSignal(vmName string, forceKill bool, forceCleanup bool) error
 		log.Info(message)
 	}
 
-	// Clean-up the network interfaces, if they still exist, and log
+	// Clean-up the network interfaces and firewall rules, if they still exist, and log
 	if forceCleanup {
+		_ = HosterVmUtils.FlushFirewall(vmName)
+
 		ifaces, err := HosterNetwork.VmNetworkCleanup(vmName)
 		if err != nil {
 			return err
@@ -0,0 +1,32 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterVmUtils
+
+import (
+	HosterFirewall "HosterCore/internal/pkg/hoster/firewall"
+)
+
+// Returns all IPv4 and IPv6 addresses assigned to the VM
+func (c VmConfig) IpAddresses() (r []string) {
+	for _, v := range c.Networks {
+		if len(v.IPAddress) > 0 {
+			r = append(r, v.IPAddress)
+		}
+		if len(v.IPv6Address) > 0 {
+			r = append(r, v.IPv6Address)
+		}
+	}
+	return
+}
+
+// Loads the VM firewall rules into it's own PF anchor (or flushes the anchor if the firewall is disabled)
+func ApplyFirewall(vmName string, conf VmConfig) error {
+	return HosterFirewall.Apply(HosterFirewall.RESOURCE_VM, vmName, conf.IpAddresses(), conf.Firewall)
+}
+
+// Removes the VM firewall rules from PF
+func FlushFirewall(vmName string) error {
+	return HosterFirewall.Flush(HosterFirewall.AnchorName(HosterFirewall.RESOURCE_VM, vmName))
+}
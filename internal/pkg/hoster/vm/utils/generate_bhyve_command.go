@@ -57,6 +57,11 @@ func GenerateBhyveStartCmd(vmName string, vmLocation string, restoreVmState bool
 			e = err
 			return
 		}
+		err = HosterNetwork.ApplyShaping(tap, v.BandwidthIngress, v.BandwidthEgress)
+		if err != nil {
+			e = err
+			return
+		}
 
 		// If there is more than one network adapter increment the PCI slot by 1
 		if i > 0 {
@@ -6,6 +6,8 @@ package HosterVmUtils
 
 import (
 	FileExists "HosterCore/internal/pkg/file_exists"
+	HosterFirewall "HosterCore/internal/pkg/hoster/firewall"
+	HaPlacement "HosterCore/internal/pkg/hoster/ha/placement"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -29,6 +31,9 @@ type VmNetwork struct {
 	NetworkBridge      string `json:"network_bridge"`       // this is a network name
 	NetworkMac         string `json:"network_mac"`          // rename to mac_address in the v2 release
 	IPAddress          string `json:"ip_address"`
+	IPv6Address        string `json:"ipv6_address,omitempty"`      // static or SLAAC (EUI-64) address on the dual-stack networks
+	BandwidthIngress   string `json:"bandwidth_ingress,omitempty"` // dummynet cap for the traffic towards the VM, e.g. 100Mbit/s
+	BandwidthEgress    string `json:"bandwidth_egress,omitempty"`  // dummynet cap for the traffic from the VM, e.g. 100Mbit/s
 	Comment            string `json:"comment"`
 }
 
@@ -45,34 +50,36 @@ type Virtio9P struct {
 }
 
 type VmConfig struct {
-	Production         bool        `json:"production"`
-	IgnoreHostClock    bool        `json:"ignore_host_clock,omitempty"`
-	IncludeHostSSHKeys bool        `json:"include_host_ssh_keys"`
-	DisableXHCI        bool        `json:"disable_xhci,omitempty"`
-	CPUSockets         int         `json:"cpu_sockets"`
-	CPUCores           int         `json:"cpu_cores"`
-	CPUThreads         int         `json:"cpu_threads,omitempty"`
-	VncResolution      int         `json:"vnc_resolution,omitempty"`
-	VncPort            int         `json:"vnc_port"`
-	VncPassword        string      `json:"vnc_password"`
-	Memory             string      `json:"memory"`
-	Loader             string      `json:"loader"`
-	OsType             string      `json:"os_type"`
-	OsComment          string      `json:"os_comment"`
-	Owner              string      `json:"owner"`
-	ParentHost         string      `json:"parent_host"`
-	FailoverStrategy   string      `json:"failover_strategy,omitempty"` // Only 2 options are allowed: cireset or change_parent
-	VGA                string      `json:"vga,omitempty"`
-	UUID               string      `json:"uuid,omitempty"`
-	Description        string      `json:"description"`
-	DnsSearchDomain    string      `json:"dns_search_domain,omitempty"`
-	Networks           []VmNetwork `json:"networks"`
-	Disks              []VmDisk    `json:"disks"`
-	VmSshKeys          []VmSshKey  `json:"vm_ssh_keys"`
-	Tags               []string    `json:"tags"`
-	Passthru           []string    `json:"passthru,omitempty"`
-	Shares             []Virtio9P  `json:"9p_shares,omitempty"`
-	CustomOptions      []string    `json:"custom_options,omitempty"`
+	Production         bool                   `json:"production"`
+	IgnoreHostClock    bool                   `json:"ignore_host_clock,omitempty"`
+	IncludeHostSSHKeys bool                   `json:"include_host_ssh_keys"`
+	DisableXHCI        bool                   `json:"disable_xhci,omitempty"`
+	CPUSockets         int                    `json:"cpu_sockets"`
+	CPUCores           int                    `json:"cpu_cores"`
+	CPUThreads         int                    `json:"cpu_threads,omitempty"`
+	VncResolution      int                    `json:"vnc_resolution,omitempty"`
+	VncPort            int                    `json:"vnc_port"`
+	VncPassword        string                 `json:"vnc_password"`
+	Memory             string                 `json:"memory"`
+	Loader             string                 `json:"loader"`
+	OsType             string                 `json:"os_type"`
+	OsComment          string                 `json:"os_comment"`
+	Owner              string                 `json:"owner"`
+	ParentHost         string                 `json:"parent_host"`
+	FailoverStrategy   string                 `json:"failover_strategy,omitempty"` // Only 2 options are allowed: cireset or change_parent
+	VGA                string                 `json:"vga,omitempty"`
+	UUID               string                 `json:"uuid,omitempty"`
+	Description        string                 `json:"description"`
+	DnsSearchDomain    string                 `json:"dns_search_domain,omitempty"`
+	Networks           []VmNetwork            `json:"networks"`
+	Disks              []VmDisk               `json:"disks"`
+	VmSshKeys          []VmSshKey             `json:"vm_ssh_keys"`
+	Tags               []string               `json:"tags"`
+	Passthru           []string               `json:"passthru,omitempty"`
+	Shares             []Virtio9P             `json:"9p_shares,omitempty"`
+	CustomOptions      []string               `json:"custom_options,omitempty"`
+	Firewall           *HosterFirewall.Config `json:"firewall,omitempty"`
+	Ha                 *HaPlacement.Settings  `json:"ha,omitempty"`
 }
 
 // Reads and returns the vm_config.json as Go struct.
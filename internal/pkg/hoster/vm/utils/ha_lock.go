@@ -0,0 +1,23 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterVmUtils
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var reHaLocked = regexp.MustCompile(`^__HA_LOCKED_.*__$`)
+
+// Returns a new HA lock, which is set as a parent host for the fenced resources, so they can't be started until unlocked
+func HaLockString() string {
+	return fmt.Sprintf("__HA_LOCKED_%s__", time.Now().Format("2006-01-02_15-04-05"))
+}
+
+// Checks if the parent host value is an HA lock
+func IsHaLocked(parentHost string) bool {
+	return reHaLocked.MatchString(parentHost)
+}
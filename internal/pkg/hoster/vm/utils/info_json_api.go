@@ -7,6 +7,7 @@ package HosterVmUtils
 import (
 	FreeBSDps "HosterCore/internal/pkg/freebsd/ps"
 	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
 	timeconversion "HosterCore/internal/pkg/time_conversion"
 	"fmt"
 	"regexp"
@@ -56,6 +57,11 @@ func InfoJsonApi(vmName string) (r VmApi, e error) {
 					break
 				}
 			}
+
+			ifaces, _ := HosterNetwork.ResourceInterfaces("vm", v.VmName)
+			for iface := range ifaces {
+				r.Shaping = append(r.Shaping, HosterNetwork.ShapingStatus(iface)...)
+			}
 		} else {
 			r.Uptime = "0s"
 		}
@@ -65,6 +71,8 @@ func InfoJsonApi(vmName string) (r VmApi, e error) {
 			r.Backup = true
 		}
 
+		r.PortForwards, _ = HosterNetwork.PortForwardsFor(v.VmName)
+
 		for ii, vv := range conf.Disks {
 			diskInfo, err := DiskInfo(v.Mountpoint + "/" + v.VmName + "/" + vv.DiskImage)
 			if err != nil {
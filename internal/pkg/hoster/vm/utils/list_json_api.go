@@ -7,6 +7,7 @@ package HosterVmUtils
 import (
 	FreeBSDps "HosterCore/internal/pkg/freebsd/ps"
 	FreeBSDsysctls "HosterCore/internal/pkg/freebsd/sysctls"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
 	timeconversion "HosterCore/internal/pkg/time_conversion"
 	"regexp"
 	"slices"
@@ -21,14 +22,16 @@ type DiskSize struct {
 
 type VmApi struct {
 	VmConfig
-	Simple      VmListSimple `json:"simple"`
-	Name        string       `json:"name"`
-	Uptime      string       `json:"uptime"`
-	UptimeUnix  int64        `json:"uptime_unix"`
-	Running     bool         `json:"running"`
-	Backup      bool         `json:"backup"`
-	Encrypted   bool         `json:"encrypted"`
-	CurrentHost string       `json:"current_host"`
+	Simple       VmListSimple                 `json:"simple"`
+	Name         string                       `json:"name"`
+	Uptime       string                       `json:"uptime"`
+	UptimeUnix   int64                        `json:"uptime_unix"`
+	Running      bool                         `json:"running"`
+	Backup       bool                         `json:"backup"`
+	Encrypted    bool                         `json:"encrypted"`
+	CurrentHost  string                       `json:"current_host"`
+	PortForwards []HosterNetwork.PortForward  `json:"port_forwards,omitempty"` // only populated by the InfoJsonApi
+	Shaping      []HosterNetwork.ShapingState `json:"shaping,omitempty"`       // only populated by the InfoJsonApi
 	// Metrics     rctl.RctMetrics `json:"rctl_metrics,omitempty"`
 }
 
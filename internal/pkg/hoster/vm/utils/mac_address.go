@@ -5,45 +5,158 @@
 package HosterVmUtils
 
 import (
-	"crypto/rand"
+	HosterHost "HosterCore/internal/pkg/hoster/host"
+	HosterNetwork "HosterCore/internal/pkg/hoster/network"
+	"crypto/sha256"
 	"fmt"
 	"net"
 	"regexp"
-	"slices"
+	"strconv"
+	"strings"
 )
 
-func GenerateMacAddress() (r string, e error) {
-	var existing []string
+const (
+	DEFAULT_MAC_OUI = "58:9c:fc"
 
+	// Every attempt re-hashes the seed, so the chance of running out of attempts is negligible
+	macAllocationAttempts = 256
+)
+
+var reMacOui = regexp.MustCompile(`^[0-9a-f]{2}:[0-9a-f]{2}:[0-9a-f]{2}$`)
+
+// Validates the MAC address prefix (OUI), which must be a unicast address prefix in the "xx:xx:xx" format
+func ValidateMacOui(oui string) error {
+	if !reMacOui.MatchString(oui) {
+		return fmt.Errorf("invalid MAC OUI %s, use the xx:xx:xx format", oui)
+	}
+
+	first, _ := strconv.ParseUint(oui[:2], 16, 8)
+	if first&1 == 1 {
+		return fmt.Errorf("invalid MAC OUI %s, multicast prefixes can't be used", oui)
+	}
+
+	return nil
+}
+
+// Returns the VM MAC address prefix set in the host_config.json, or the default one
+func MacOui() (r string, e error) {
+	hostConfig, err := HosterHost.GetHostConfig()
+	if err != nil {
+		e = err
+		return
+	}
+
+	r = strings.ToLower(hostConfig.MacOui)
+	if len(r) < 1 {
+		r = DEFAULT_MAC_OUI
+	}
+
+	e = ValidateMacOui(r)
+	return
+}
+
+// Derives the MAC address from the VM UUID and the NIC index, so the same VM config always produces the same MAC.
+// Attempt is increased on conflicts, which picks a different (but still reproducible) address.
+func DeriveMacAddress(oui string, vmUuid string, nicIndex int, attempt int) string {
+	seed := vmUuid + "/" + strconv.Itoa(nicIndex)
+	if attempt > 0 {
+		seed = seed + "/" + strconv.Itoa(attempt)
+	}
+
+	sum := sha256.Sum256([]byte(seed))
+	return fmt.Sprintf("%s:%02x:%02x:%02x", oui, sum[0], sum[1], sum[2])
+}
+
+// Returns all MAC addresses in use (MAC -> owner): VMs, including the backups replicated from the other cluster members, and the Jail epairs.
+func UsedMacAddresses() (r map[string]string, e error) {
 	vms, err := ListJsonApi()
 	if err != nil {
-		return "", err
+		e = err
+		return
+	}
+
+	r, err = HosterNetwork.EpairMacAddresses()
+	if err != nil {
+		e = err
+		return
 	}
+
 	for _, v := range vms {
 		for _, vv := range v.Networks {
-			existing = append(existing, vv.NetworkMac)
+			if len(vv.NetworkMac) > 0 {
+				r[strings.ToLower(vv.NetworkMac)] = v.Name
+			}
 		}
 	}
 
-	for {
-		if slices.Contains(existing, r) || len(r) < 1 {
-			// Generate a random MAC address
-			mac := make([]byte, 3)
-			_, err := rand.Read(mac)
-			if err != nil {
-				return "", err
-			}
+	return
+}
+
+// Returns the seed used to derive the VM MAC addresses: the VM UUID, or the VM name for the older VMs deployed without one
+func MacSeed(vmConfig VmConfig, vmName string) string {
+	if len(vmConfig.UUID) > 0 {
+		return vmConfig.UUID
+	}
+	return vmName
+}
+
+// Allocates a MAC address for the VM NIC, derived from the VM UUID (or the VM name, for the older VMs without a UUID) and the NIC index.
+//
+// MAC addresses already used by the owner are treated as free, so re-building the config of an existing VM reproduces it's MACs.
+// Leave the owner empty for the brand new NICs.
+func AllocateMacAddress(vmUuid string, nicIndex int, owner string) (r string, e error) {
+	oui, err := MacOui()
+	if err != nil {
+		e = err
+		return
+	}
+
+	used, err := UsedMacAddresses()
+	if err != nil {
+		e = err
+		return
+	}
+
+	return pickMacAddress(oui, used, vmUuid, nicIndex, owner)
+}
 
-			// Format the MAC address as a string with the desired prefix
-			r = fmt.Sprintf("58:9c:fc:%02x:%02x:%02x", mac[0], mac[1], mac[2])
-		} else {
-			break
+// Returns the first derived MAC address which is not in use (MAC -> owner), or is used by the owner itself
+func pickMacAddress(oui string, used map[string]string, vmUuid string, nicIndex int, owner string) (r string, e error) {
+	for attempt := 0; attempt < macAllocationAttempts; attempt++ {
+		r = DeriveMacAddress(oui, vmUuid, nicIndex, attempt)
+		usedBy, found := used[r]
+		if !found || (len(owner) > 0 && usedBy == owner) {
+			return
 		}
 	}
 
+	r = ""
+	e = fmt.Errorf("could not find a free MAC address for NIC %d", nicIndex)
 	return
 }
 
+// Checks that the MAC address is valid, and it's not used by any other VM or Jail (owner's own MAC addresses are ignored)
+func CheckMacAddress(mac string, owner string) error {
+	if !IsMacAddressValid(mac) {
+		return fmt.Errorf("invalid MAC address %s", mac)
+	}
+	parsed, _ := net.ParseMAC(mac)
+	if parsed[0]&1 == 1 {
+		return fmt.Errorf("invalid MAC address %s, multicast addresses can't be used", mac)
+	}
+
+	used, err := UsedMacAddresses()
+	if err != nil {
+		return err
+	}
+	usedBy, found := used[strings.ToLower(mac)]
+	if found && (len(owner) < 1 || usedBy != owner) {
+		return fmt.Errorf("MAC address %s is already in use by %s", mac, usedBy)
+	}
+
+	return nil
+}
+
 func IsMacAddressValid(mac string) bool {
 	// Use net.ParseMAC to check if the MAC address can be parsed
 	_, err := net.ParseMAC(mac)
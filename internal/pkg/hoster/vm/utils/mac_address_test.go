@@ -0,0 +1,113 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterVmUtils
+
+import (
+	"strings"
+	"testing"
+)
+
+const testVmUuid = "7b8e1f4c-2f4a-4d3e-9c61-0a1b2c3d4e5f"
+
+func TestValidateMacOui(t *testing.T) {
+	tests := []struct {
+		oui     string
+		wantErr string // empty means the OUI is valid
+	}{
+		{oui: DEFAULT_MAC_OUI},
+		{oui: "02:00:00"}, // Locally administered
+		{oui: "58:9C:FC", wantErr: "use the xx:xx:xx format"},
+		{oui: "58:9c", wantErr: "use the xx:xx:xx format"},
+		{oui: "58-9c-fc", wantErr: "use the xx:xx:xx format"},
+		{oui: "58:9c:fc:00", wantErr: "use the xx:xx:xx format"},
+		{oui: "01:00:5e", wantErr: "multicast prefixes can't be used"},
+		{oui: "ff:ff:ff", wantErr: "multicast prefixes can't be used"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.oui, func(t *testing.T) {
+			err := ValidateMacOui(tt.oui)
+			if len(tt.wantErr) < 1 {
+				if err != nil {
+					t.Errorf("ValidateMacOui() error = %s, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateMacOui() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeriveMacAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		oui      string
+		seed     string
+		nicIndex int
+		attempt  int
+		want     string
+	}{
+		{name: "first NIC", oui: DEFAULT_MAC_OUI, seed: testVmUuid, want: "58:9c:fc:52:bb:be"},
+		{name: "second NIC", oui: DEFAULT_MAC_OUI, seed: testVmUuid, nicIndex: 1, want: "58:9c:fc:7c:99:ae"},
+		{name: "first conflict", oui: DEFAULT_MAC_OUI, seed: testVmUuid, attempt: 1, want: "58:9c:fc:73:1f:2f"},
+		{name: "custom OUI", oui: "02:00:00", seed: testVmUuid, want: "02:00:00:52:bb:be"},
+		{name: "VM name seed", oui: DEFAULT_MAC_OUI, seed: "test-vm-1", want: "58:9c:fc:5b:10:f5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DeriveMacAddress(tt.oui, tt.seed, tt.nicIndex, tt.attempt)
+			if got != tt.want {
+				t.Errorf("DeriveMacAddress() = %s, want %s", got, tt.want)
+			}
+			if !IsMacAddressValid(got) {
+				t.Errorf("DeriveMacAddress() = %s is not a valid MAC address", got)
+			}
+		})
+	}
+}
+
+func TestPickMacAddress(t *testing.T) {
+	first := DeriveMacAddress(DEFAULT_MAC_OUI, testVmUuid, 0, 0)
+	second := DeriveMacAddress(DEFAULT_MAC_OUI, testVmUuid, 0, 1)
+	third := DeriveMacAddress(DEFAULT_MAC_OUI, testVmUuid, 0, 2)
+
+	tests := []struct {
+		name  string
+		used  map[string]string
+		owner string
+		want  string
+	}{
+		{name: "free", used: map[string]string{}, want: first},
+		{name: "used by another VM", used: map[string]string{first: "test-vm-2"}, want: second},
+		{name: "used by a Jail epair", used: map[string]string{first: "test-jail", second: "test-vm-2"}, want: third},
+		{name: "owner's own MAC is reproduced", used: map[string]string{first: "test-vm-1"}, owner: "test-vm-1", want: first},
+		{name: "new NIC skips the MAC of it's own VM", used: map[string]string{first: "test-vm-1"}, want: second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pickMacAddress(DEFAULT_MAC_OUI, tt.used, testVmUuid, 0, tt.owner)
+			if err != nil {
+				t.Fatalf("pickMacAddress() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("pickMacAddress() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// Every derived address is taken
+	used := make(map[string]string)
+	for attempt := 0; attempt < macAllocationAttempts; attempt++ {
+		used[DeriveMacAddress(DEFAULT_MAC_OUI, testVmUuid, 0, attempt)] = "test-vm-2"
+	}
+	got, err := pickMacAddress(DEFAULT_MAC_OUI, used, testVmUuid, 0, "")
+	if err == nil || got != "" || !strings.Contains(err.Error(), "could not find a free MAC address for NIC 0") {
+		t.Errorf("pickMacAddress() = %q, %v, want no free MAC address", got, err)
+	}
+}
@@ -52,8 +52,19 @@ ethernets:
     {{- end }}
     addresses:
     - {{ .IpAddress }}/{{ .NakedSubnet }}
+    {{- if and .Ipv6Address (not .Ipv6Slaac) }}
+    - {{ .Ipv6Address }}/{{ .NakedSubnet6 }}
+    {{- end }}
  
     gateway4: {{ .Gateway }}
+    {{- if and .Gateway6 (not .Ipv6Slaac) }}
+    gateway6: {{ .Gateway6 }}
+    {{- end }}
+    {{- if .Ipv6Slaac }}
+    dhcp6: false
+    accept-ra: true
+    ipv6-address-generation: eui64
+    {{- end }}
  
     nameservers:
       search: [ {{ .DnsSearchDomain }}, ]
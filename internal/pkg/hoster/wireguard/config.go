@@ -0,0 +1,176 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterWireGuard
+
+import (
+	FileExists "HosterCore/internal/pkg/file_exists"
+	HosterLocations "HosterCore/internal/pkg/hoster/locations"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const confFileName = "wireguard_config.json"
+
+// WireGuard config lives next to the network_config.json (the file may not exist yet)
+func getConfigLocation() (r string, e error) {
+	r, err := HosterLocations.LocateConfig(confFileName)
+	if err == nil {
+		return
+	}
+
+	networkConfig, err := HosterLocations.LocateConfig("network_config.json")
+	if err != nil {
+		e = err
+		return
+	}
+
+	r = filepath.Dir(networkConfig) + "/" + confFileName
+	return
+}
+
+// Checks if the WireGuard mesh was initialized on this node
+func Initialized() bool {
+	confFile, err := getConfigLocation()
+	if err != nil {
+		return false
+	}
+	return FileExists.CheckUsingOsStat(confFile)
+}
+
+// Parses the wireguard_config.json, and returns the underlying struct or an error
+func GetConfig() (r Config, e error) {
+	confFile, err := getConfigLocation()
+	if err != nil {
+		e = err
+		return
+	}
+	if !FileExists.CheckUsingOsStat(confFile) {
+		e = fmt.Errorf("WireGuard is not initialized on this node, run `hoster wireguard init` first")
+		return
+	}
+
+	data, err := os.ReadFile(confFile)
+	if err != nil {
+		e = err
+		return
+	}
+
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		e = err
+		return
+	}
+
+	return
+}
+
+// Validates and saves the WireGuard config (atomically). The file holds the private key, so it's only readable by root.
+func SaveConfig(c Config) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+
+	confFile, err := getConfigLocation()
+	if err != nil {
+		return err
+	}
+	if c.Peers == nil {
+		c.Peers = []Peer{}
+	}
+
+	jsonData, err := json.MarshalIndent(c, "", "   ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(confFile+".tmp", jsonData, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(confFile+".tmp", confFile)
+}
+
+// Takes an exclusive lock on the WireGuard config, so the concurrent peer updates (REST API and CLI) don't overwrite each other.
+// The lock is held until the returned func is called, it must wrap the whole read-modify-save cycle.
+func lockConfig() (unlock func(), e error) {
+	confFile, err := getConfigLocation()
+	if err != nil {
+		e = err
+		return
+	}
+
+	file, err := os.OpenFile(confFile+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		e = err
+		return
+	}
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		file.Close()
+		e = err
+		return
+	}
+
+	unlock = func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}
+	return
+}
+
+// Creates a new WireGuard config for this node, with a freshly generated private key
+func NewConfig(name string, group string, address string, endpoint string, listenPort int) (r Config, e error) {
+	privateKey, err := GeneratePrivateKey()
+	if err != nil {
+		e = err
+		return
+	}
+	if listenPort == 0 {
+		listenPort = DEFAULT_LISTEN_PORT
+	}
+
+	r = Config{
+		Name:       name,
+		Group:      groupName(group),
+		Interface:  DEFAULT_INTERFACE,
+		ListenPort: listenPort,
+		Address:    address,
+		Endpoint:   endpoint,
+		PrivateKey: privateKey,
+		Peers:      []Peer{},
+	}
+
+	e = r.Validate()
+	return
+}
+
+// Adds the peer to the inventory, or replaces the existing peer with the same name and group
+func (c *Config) SetPeer(peer Peer) {
+	peer.Group = groupName(peer.Group)
+	for i, v := range c.Peers {
+		if v.Name == peer.Name && groupName(v.Group) == peer.Group {
+			c.Peers[i] = peer
+			return
+		}
+	}
+	c.Peers = append(c.Peers, peer)
+}
+
+// Removes the peer from the inventory
+func (c *Config) RemovePeer(name string, group string) error {
+	group = groupName(group)
+	for i, v := range c.Peers {
+		if v.Name == name && groupName(v.Group) == group {
+			c.Peers = append(c.Peers[:i], c.Peers[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("peer %s doesn't exist in the group %s", name, group)
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterWireGuard
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+const keyLength = 32
+
+// Generates a new WireGuard private key (Curve25519, clamped the same way `wg genkey` does it), base64 encoded
+func GeneratePrivateKey() (r string, e error) {
+	key := make([]byte, keyLength)
+	_, err := rand.Read(key)
+	if err != nil {
+		e = fmt.Errorf("could not generate a private key: %s", err.Error())
+		return
+	}
+
+	key[0] &= 248
+	key[31] = (key[31] & 127) | 64
+
+	r = base64.StdEncoding.EncodeToString(key)
+	return
+}
+
+// Returns the public key for the private key, the same way `wg pubkey` does it
+func PublicKey(privateKey string) (r string, e error) {
+	key, err := decodeKey(privateKey)
+	if err != nil {
+		e = err
+		return
+	}
+
+	private, err := ecdh.X25519().NewPrivateKey(key)
+	if err != nil {
+		e = err
+		return
+	}
+
+	r = base64.StdEncoding.EncodeToString(private.PublicKey().Bytes())
+	return
+}
+
+// Checks that the key is a base64 encoded 32 byte WireGuard key
+func ValidateKey(key string) error {
+	_, err := decodeKey(key)
+	return err
+}
+
+func decodeKey(key string) (r []byte, e error) {
+	r, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		e = fmt.Errorf("key is not base64 encoded")
+		return
+	}
+	if len(r) != keyLength {
+		e = fmt.Errorf("key must be %d bytes long", keyLength)
+		return
+	}
+	return
+}
@@ -0,0 +1,219 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterWireGuard
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// wg-quick looks up the interface configs in this folder on FreeBSD
+const WG_QUICK_CONFIG_FOLDER = "/usr/local/etc/wireguard"
+
+// Writes the rendered wg-quick config, and brings the mesh interface up.
+//
+// If the interface is already up, only the keys and peers are synced (`wg syncconf`), so the existing tunnels are not interrupted.
+// `wg syncconf` doesn't touch the routing table, so the routes are added (or removed) for the allowed IPs that have changed.
+func Apply(c Config) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(WG_QUICK_CONFIG_FOLDER, 0700)
+	if err != nil {
+		return err
+	}
+
+	confFile := WG_QUICK_CONFIG_FOLDER + "/" + c.Interface + ".conf"
+	err = os.WriteFile(confFile, []byte(Render(c)), 0600)
+	if err != nil {
+		return err
+	}
+
+	if exec.Command("ifconfig", c.Interface).Run() != nil {
+		out, err := exec.Command("wg-quick", "up", c.Interface).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("could not bring up %s: %s; %s", c.Interface, strings.TrimSpace(string(out)), err.Error())
+		}
+		return nil
+	}
+
+	syncFile, err := os.CreateTemp("", "hoster_wg_*.conf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(syncFile.Name())
+
+	_, err = syncFile.WriteString(RenderSyncConf(c))
+	syncFile.Close()
+	if err != nil {
+		return err
+	}
+
+	current, err := interfaceAllowedIps(c.Interface)
+	if err != nil {
+		return err
+	}
+
+	out, err := exec.Command("wg", "syncconf", c.Interface, syncFile.Name()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not sync the %s config: %s; %s", c.Interface, strings.TrimSpace(string(out)), err.Error())
+	}
+
+	wanted := []string{}
+	for _, v := range c.MeshPeers() {
+		wanted = append(wanted, peerAllowedIps(v)...)
+	}
+
+	errs := []error{}
+	add, remove := routeChanges(current, wanted)
+	for _, v := range remove {
+		err := updateRoute("delete", c.Interface, v)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, v := range add {
+		err := updateRoute("add", c.Interface, v)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Returns the allowed IPs of all peers currently set on the running interface
+func interfaceAllowedIps(iface string) (r []string, e error) {
+	out, err := exec.Command("wg", "show", iface, "allowed-ips").CombinedOutput()
+	if err != nil {
+		e = fmt.Errorf("could not read the %s allowed IPs: %s; %s", iface, strings.TrimSpace(string(out)), err.Error())
+		return
+	}
+
+	r = parseAllowedIps(string(out))
+	return
+}
+
+// Parses the `wg show <interface> allowed-ips` output, one peer per line: "<public key>\t<prefix> <prefix>" or "<public key>\t(none)"
+func parseAllowedIps(output string) (r []string) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		for i, v := range fields {
+			if i == 0 || v == "(none)" {
+				continue
+			}
+			r = append(r, v)
+		}
+	}
+	return
+}
+
+// Returns the routes to add and to remove, so the routing table follows the allowed IPs of the peers
+func routeChanges(current []string, wanted []string) (add []string, remove []string) {
+	normalize := func(list []string) (r []string) {
+		for _, v := range list {
+			prefix, err := netip.ParsePrefix(v)
+			if err != nil {
+				continue
+			}
+			if value := prefix.Masked().String(); !slices.Contains(r, value) {
+				r = append(r, value)
+			}
+		}
+		return
+	}
+
+	currentList := normalize(current)
+	wantedList := normalize(wanted)
+	for _, v := range wantedList {
+		if !slices.Contains(currentList, v) {
+			add = append(add, v)
+		}
+	}
+	for _, v := range currentList {
+		if !slices.Contains(wantedList, v) {
+			remove = append(remove, v)
+		}
+	}
+
+	return
+}
+
+// Adds (or deletes) the route through the mesh interface, the same way wg-quick does it
+func updateRoute(action string, iface string, prefix string) error {
+	family := "-inet"
+	if strings.Contains(prefix, ":") {
+		family = "-inet6"
+	}
+
+	out, err := exec.Command("route", "-q", "-n", action, family, prefix, "-interface", iface).CombinedOutput()
+	if err != nil {
+		// The route is already in the desired state
+		if strings.Contains(string(out), "File exists") || strings.Contains(string(out), "not in table") {
+			return nil
+		}
+		return fmt.Errorf("could not %s the route %s via %s: %s; %s", action, prefix, iface, strings.TrimSpace(string(out)), err.Error())
+	}
+	return nil
+}
+
+// Applies the change to the peer inventory, saves it, and applies the updated mesh config.
+// The previous config is restored if the updated one could not be applied.
+func UpdatePeers(update func(c *Config) error) error {
+	unlock, err := lockConfig()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	previous, err := GetConfig()
+	if err != nil {
+		return err
+	}
+	conf, err := GetConfig()
+	if err != nil {
+		return err
+	}
+
+	err = update(&conf)
+	if err != nil {
+		return err
+	}
+
+	err = SaveConfig(conf)
+	if err != nil {
+		return err
+	}
+
+	err = Apply(conf)
+	if err != nil {
+		errRestore := SaveConfig(previous)
+		if errRestore == nil {
+			errRestore = Apply(previous)
+		}
+		if errRestore != nil {
+			return errors.Join(err, fmt.Errorf("could not restore the previous config: %s", errRestore.Error()))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Takes the mesh interface down
+func Down(c Config) error {
+	out, err := exec.Command("wg-quick", "down", c.Interface).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not take down %s: %s; %s", c.Interface, strings.TrimSpace(string(out)), err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterWireGuard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DEFAULT_INTERFACE   = "wg0"
+	DEFAULT_LISTEN_PORT = 51820
+	DEFAULT_GROUP       = "default"
+)
+
+// WireGuard settings of this Hoster node, together with the inventory of the other nodes (peers).
+//
+// Every node keeps the same peer inventory, and connects to all peers from it's own cluster group,
+// which results in a full mesh between the nodes of the group.
+type Config struct {
+	Name       string   `json:"name"`                  // node name, defaults to the hostname
+	Group      string   `json:"group"`                 // cluster group, only the peers from the same group are connected
+	Interface  string   `json:"interface"`             // e.g. "wg0"
+	ListenPort int      `json:"listen_port"`           // e.g. 51820
+	Address    string   `json:"address"`               // mesh address of this node, e.g. "10.254.0.1/24"
+	Endpoint   string   `json:"endpoint"`              // address the other nodes use to reach this node, e.g. "203.0.113.10:51820"
+	Mtu        int      `json:"mtu,omitempty"`         // 0 leaves the wg-quick default
+	PrivateKey string   `json:"private_key"`           // never exposed over the API
+	AllowedIps []string `json:"allowed_ips,omitempty"` // extra subnets routed to this node by the peers, e.g. it's Hoster networks
+	Peers      []Peer   `json:"peers"`
+}
+
+// Another Hoster node in the mesh
+type Peer struct {
+	Name                string   `json:"name"`
+	Group               string   `json:"group"`
+	PublicKey           string   `json:"public_key"`
+	Endpoint            string   `json:"endpoint,omitempty"` // peers without an endpoint can only be reached after they connect first
+	Address             string   `json:"address"`            // mesh address of the peer, e.g. "10.254.0.2/24"
+	AllowedIps          []string `json:"allowed_ips,omitempty"`
+	PersistentKeepalive int      `json:"persistent_keepalive,omitempty"` // seconds, 0 disables keepalives
+}
+
+var reInterfaceName = regexp.MustCompile(`^wg[0-9]+$`)
+var rePeerName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Returns the cluster group, falling back to the default one
+func groupName(group string) string {
+	if len(group) < 1 {
+		return DEFAULT_GROUP
+	}
+	return group
+}
+
+// Returns the public part of this node's config, which is shared with the other nodes
+func (c Config) Self() (r Peer, e error) {
+	publicKey, err := PublicKey(c.PrivateKey)
+	if err != nil {
+		e = err
+		return
+	}
+
+	r = Peer{
+		Name:       c.Name,
+		Group:      groupName(c.Group),
+		PublicKey:  publicKey,
+		Endpoint:   c.Endpoint,
+		Address:    c.Address,
+		AllowedIps: c.AllowedIps,
+	}
+	return
+}
+
+// Returns the peers this node connects to: all other nodes in the same cluster group, sorted by name.
+// The node itself is skipped, so the same inventory can be pushed to every node.
+func (c Config) MeshPeers() (r []Peer) {
+	publicKey, _ := PublicKey(c.PrivateKey)
+
+	for _, v := range c.Peers {
+		if groupName(v.Group) != groupName(c.Group) {
+			continue
+		}
+		if v.PublicKey == publicKey || (len(v.Name) > 0 && v.Name == c.Name) {
+			continue
+		}
+		r = append(r, v)
+	}
+
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return
+}
+
+// Validates the WireGuard config, and the peer inventory. All problems found are returned together.
+func (c Config) Validate() error {
+	errs := []error{}
+
+	if !rePeerName.MatchString(c.Name) {
+		errs = append(errs, fmt.Errorf("invalid node name %s", c.Name))
+	}
+	if !reInterfaceName.MatchString(c.Interface) {
+		errs = append(errs, fmt.Errorf("invalid interface name %s, use wgN, e.g. wg0", c.Interface))
+	}
+	if c.ListenPort < 1 || c.ListenPort > 65535 {
+		errs = append(errs, fmt.Errorf("invalid listen_port %d", c.ListenPort))
+	}
+	if _, err := netip.ParsePrefix(c.Address); err != nil {
+		errs = append(errs, fmt.Errorf("invalid address %s, use the CIDR notation, e.g. 10.254.0.1/24", c.Address))
+	}
+	if len(c.Endpoint) > 0 {
+		if err := validateEndpoint(c.Endpoint); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Mtu != 0 && (c.Mtu < 576 || c.Mtu > 9216) {
+		errs = append(errs, fmt.Errorf("mtu must be within 576-9216"))
+	}
+	if _, err := PublicKey(c.PrivateKey); err != nil {
+		errs = append(errs, fmt.Errorf("invalid private_key: %s", err.Error()))
+	}
+	for _, v := range c.AllowedIps {
+		if _, err := netip.ParsePrefix(v); err != nil {
+			errs = append(errs, fmt.Errorf("invalid allowed_ips entry %s", v))
+		}
+	}
+
+	err := ValidatePeers(c.Peers)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validates the peer inventory: keys, addresses, and the names, keys and routed subnets being unique within the group
+func ValidatePeers(peers []Peer) error {
+	errs := []error{}
+
+	names := make(map[string]bool)
+	keys := make(map[string]string)
+	routes := make(map[string]map[netip.Prefix]string)
+	for _, v := range peers {
+		if !rePeerName.MatchString(v.Name) {
+			errs = append(errs, fmt.Errorf("invalid peer name %s", v.Name))
+			continue
+		}
+		group := groupName(v.Group)
+		if names[group+"/"+v.Name] {
+			errs = append(errs, fmt.Errorf("peer %s: duplicate peer name in the group %s", v.Name, group))
+		}
+		names[group+"/"+v.Name] = true
+
+		if err := ValidateKey(v.PublicKey); err != nil {
+			errs = append(errs, fmt.Errorf("peer %s: invalid public_key: %s", v.Name, err.Error()))
+		} else if other, found := keys[v.PublicKey]; found {
+			errs = append(errs, fmt.Errorf("peer %s: public_key is already used by the peer %s", v.Name, other))
+		}
+		keys[v.PublicKey] = v.Name
+
+		if _, err := netip.ParsePrefix(v.Address); err != nil {
+			errs = append(errs, fmt.Errorf("peer %s: invalid address %s, use the CIDR notation, e.g. 10.254.0.2/24", v.Name, v.Address))
+		}
+		if len(v.Endpoint) > 0 {
+			if err := validateEndpoint(v.Endpoint); err != nil {
+				errs = append(errs, fmt.Errorf("peer %s: %s", v.Name, err.Error()))
+			}
+		}
+		if v.PersistentKeepalive < 0 || v.PersistentKeepalive > 65535 {
+			errs = append(errs, fmt.Errorf("peer %s: invalid persistent_keepalive", v.Name))
+		}
+
+		// WireGuard routes the traffic by the allowed IPs, so the same subnet can't belong to two peers
+		if routes[group] == nil {
+			routes[group] = make(map[netip.Prefix]string)
+		}
+		for _, vv := range peerAllowedIps(v) {
+			prefix, err := netip.ParsePrefix(vv)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("peer %s: invalid address %s", v.Name, vv))
+				continue
+			}
+			if other, found := routes[group][prefix.Masked()]; found {
+				errs = append(errs, fmt.Errorf("peer %s: %s is already routed to the peer %s", v.Name, vv, other))
+				continue
+			}
+			routes[group][prefix.Masked()] = v.Name
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateEndpoint(endpoint string) error {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil || len(host) < 1 {
+		return fmt.Errorf("invalid endpoint %s, use host:port, e.g. 203.0.113.10:51820", endpoint)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil || portNumber < 1 || portNumber > 65535 {
+		return fmt.Errorf("invalid endpoint port %s", port)
+	}
+	return nil
+}
+
+// Returns the subnets routed to the peer: it's own mesh address (as a single host route), and any extra subnets
+func peerAllowedIps(p Peer) (r []string) {
+	if prefix, err := netip.ParsePrefix(p.Address); err == nil {
+		r = append(r, netip.PrefixFrom(prefix.Addr(), prefix.Addr().BitLen()).String())
+	}
+	r = append(r, p.AllowedIps...)
+	return
+}
+
+// Renders the wg-quick config for this node, connecting it to every peer in it's cluster group.
+// Doesn't touch the system, and doesn't require the wg binary.
+func Render(c Config) string {
+	lines := []string{
+		"# Hoster WireGuard mesh: " + groupName(c.Group) + " (do not edit, generated automatically)",
+		"[Interface]",
+		"PrivateKey = " + c.PrivateKey,
+		"ListenPort = " + strconv.Itoa(c.ListenPort),
+		"Address = " + c.Address,
+	}
+	if c.Mtu > 0 {
+		lines = append(lines, "MTU = "+strconv.Itoa(c.Mtu))
+	}
+
+	return strings.Join(append(lines, renderPeers(c)...), "\n") + "\n"
+}
+
+// Renders the config in the `wg setconf` format (wg-quick specific settings are left out),
+// which is used to update the peers of a running interface without taking it down.
+func RenderSyncConf(c Config) string {
+	lines := []string{
+		"[Interface]",
+		"PrivateKey = " + c.PrivateKey,
+		"ListenPort = " + strconv.Itoa(c.ListenPort),
+	}
+
+	return strings.Join(append(lines, renderPeers(c)...), "\n") + "\n"
+}
+
+func renderPeers(c Config) (r []string) {
+	for _, v := range c.MeshPeers() {
+		r = append(r, "", "# "+v.Name, "[Peer]", "PublicKey = "+v.PublicKey, "AllowedIPs = "+strings.Join(peerAllowedIps(v), ", "))
+		if len(v.Endpoint) > 0 {
+			r = append(r, "Endpoint = "+v.Endpoint)
+		}
+		if v.PersistentKeepalive > 0 {
+			r = append(r, "PersistentKeepalive = "+strconv.Itoa(v.PersistentKeepalive))
+		}
+	}
+	return
+}
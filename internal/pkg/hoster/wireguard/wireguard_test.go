@@ -0,0 +1,183 @@
+// Copyright 2024 Hoster Authors. All rights reserved.
+// Use of this source code is governed by an Apache License 2.0
+// license that can be found in the LICENSE file.
+
+package HosterWireGuard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Returns a valid (but predictable) WireGuard key, filled with the given byte
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keyLength))
+}
+
+func testConfig() Config {
+	return Config{
+		Name:       "node1",
+		Group:      "eu",
+		Interface:  "wg0",
+		ListenPort: 51820,
+		Address:    "10.254.0.1/24",
+		Endpoint:   "203.0.113.1:51820",
+		PrivateKey: testKey(1),
+		Peers: []Peer{
+			{Name: "node3", Group: "eu", PublicKey: testKey(3), Address: "10.254.0.3/24", AllowedIps: []string{"10.0.103.0/24"}, PersistentKeepalive: 25},
+			{Name: "node2", Group: "eu", PublicKey: testKey(2), Endpoint: "203.0.113.2:51820", Address: "10.254.0.2/24"},
+			{Name: "node4", Group: "us", PublicKey: testKey(4), Endpoint: "198.51.100.4:51820", Address: "10.254.1.4/24"},
+			{Name: "node1", Group: "eu", PublicKey: testKey(1), Address: "10.254.0.1/24"},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	c := testConfig()
+	c.Mtu = 1420
+
+	want := strings.Join([]string{
+		"# Hoster WireGuard mesh: eu (do not edit, generated automatically)",
+		"[Interface]",
+		"PrivateKey = " + testKey(1),
+		"ListenPort = 51820",
+		"Address = 10.254.0.1/24",
+		"MTU = 1420",
+		"",
+		"# node2",
+		"[Peer]",
+		"PublicKey = " + testKey(2),
+		"AllowedIPs = 10.254.0.2/32",
+		"Endpoint = 203.0.113.2:51820",
+		"",
+		"# node3",
+		"[Peer]",
+		"PublicKey = " + testKey(3),
+		"AllowedIPs = 10.254.0.3/32, 10.0.103.0/24",
+		"PersistentKeepalive = 25",
+	}, "\n") + "\n"
+
+	if got := Render(c); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenderSyncConf(t *testing.T) {
+	got := RenderSyncConf(testConfig())
+
+	if !strings.HasPrefix(got, "[Interface]\nPrivateKey = "+testKey(1)+"\nListenPort = 51820\n") {
+		t.Errorf("RenderSyncConf() has an unexpected interface section:\n%s", got)
+	}
+	// wg-quick only settings are not understood by `wg syncconf`
+	for _, v := range []string{"Address", "MTU", "# Hoster"} {
+		if strings.Contains(got, v) {
+			t.Errorf("RenderSyncConf() contains the wg-quick setting %s", v)
+		}
+	}
+	if strings.Count(got, "[Peer]") != 2 {
+		t.Errorf("RenderSyncConf() = %d peers, want 2", strings.Count(got, "[Peer]"))
+	}
+}
+
+func TestValidatePeers(t *testing.T) {
+	tests := []struct {
+		name    string
+		peers   []Peer
+		wantErr string
+	}{
+		{
+			name:  "valid peers",
+			peers: testConfig().Peers[:3],
+		},
+		{
+			name:  "same name in different groups",
+			peers: []Peer{{Name: "node2", Group: "eu", PublicKey: testKey(2), Address: "10.254.0.2/24"}, {Name: "node2", Group: "us", PublicKey: testKey(5), Address: "10.254.1.2/24"}},
+		},
+		{
+			name:    "invalid name",
+			peers:   []Peer{{Name: "node 2", PublicKey: testKey(2), Address: "10.254.0.2/24"}},
+			wantErr: "invalid peer name",
+		},
+		{
+			name:    "duplicate name",
+			peers:   []Peer{{Name: "node2", PublicKey: testKey(2), Address: "10.254.0.2/24"}, {Name: "node2", PublicKey: testKey(3), Address: "10.254.0.3/24"}},
+			wantErr: "duplicate peer name in the group default",
+		},
+		{
+			name:    "invalid key",
+			peers:   []Peer{{Name: "node2", PublicKey: "c2hvcnQ=", Address: "10.254.0.2/24"}},
+			wantErr: "invalid public_key",
+		},
+		{
+			name:    "duplicate key",
+			peers:   []Peer{{Name: "node2", PublicKey: testKey(2), Address: "10.254.0.2/24"}, {Name: "node3", PublicKey: testKey(2), Address: "10.254.0.3/24"}},
+			wantErr: "public_key is already used by the peer node2",
+		},
+		{
+			name:    "address without a prefix",
+			peers:   []Peer{{Name: "node2", PublicKey: testKey(2), Address: "10.254.0.2"}},
+			wantErr: "invalid address 10.254.0.2",
+		},
+		{
+			name:    "invalid endpoint",
+			peers:   []Peer{{Name: "node2", PublicKey: testKey(2), Address: "10.254.0.2/24", Endpoint: "203.0.113.2"}},
+			wantErr: "invalid endpoint",
+		},
+		{
+			name:    "invalid keepalive",
+			peers:   []Peer{{Name: "node2", PublicKey: testKey(2), Address: "10.254.0.2/24", PersistentKeepalive: -1}},
+			wantErr: "invalid persistent_keepalive",
+		},
+		{
+			name: "subnet routed to two peers",
+			peers: []Peer{
+				{Name: "node2", PublicKey: testKey(2), Address: "10.254.0.2/24", AllowedIps: []string{"10.0.100.0/24"}},
+				{Name: "node3", PublicKey: testKey(3), Address: "10.254.0.3/24", AllowedIps: []string{"10.0.100.1/24"}},
+			},
+			wantErr: "10.0.100.1/24 is already routed to the peer node2",
+		},
+		{
+			name: "same mesh address",
+			peers: []Peer{
+				{Name: "node2", PublicKey: testKey(2), Address: "10.254.0.2/24"},
+				{Name: "node3", PublicKey: testKey(3), Address: "10.254.0.2/24"},
+			},
+			wantErr: "10.254.0.2/32 is already routed to the peer node2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePeers(tt.peers)
+			if len(tt.wantErr) < 1 {
+				if err != nil {
+					t.Errorf("ValidatePeers() error = %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePeers() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouteChanges(t *testing.T) {
+	current := parseAllowedIps(testKey(2) + "\t10.254.0.2/32 10.0.102.0/24\n" + testKey(3) + "\t(none)\n" + testKey(4) + "\t10.254.0.4/32 fd00:100::/64\n")
+	if want := []string{"10.254.0.2/32", "10.0.102.0/24", "10.254.0.4/32", "fd00:100::/64"}; !slices.Equal(current, want) {
+		t.Fatalf("parseAllowedIps() = %v, want %v", current, want)
+	}
+
+	// node3 got a subnet, node4 has left, and node2 hasn't changed (the host bits are ignored)
+	wanted := []string{"10.254.0.2/32", "10.0.102.1/24", "10.254.0.3/32", "10.0.103.0/24"}
+	add, remove := routeChanges(current, wanted)
+	if want := []string{"10.254.0.3/32", "10.0.103.0/24"}; !slices.Equal(add, want) {
+		t.Errorf("routeChanges() add = %v, want %v", add, want)
+	}
+	if want := []string{"10.254.0.4/32", "fd00:100::/64"}; !slices.Equal(remove, want) {
+		t.Errorf("routeChanges() remove = %v, want %v", remove, want)
+	}
+}
@@ -277,6 +277,9 @@ scrub in all fragment reassemble max-mss 1440
 ### OUTBOUND NAT ###
 nat on { ${PUBLIC_INTERFACE} } from { ${NETWORK_SUBNET} } to any -> { ${PUBLIC_INTERFACE} }
 
+### INBOUND NAT ###
+rdr-anchor "hoster/*"  # Port forwards managed by "hoster network forward"
+
 ### INBOUND NAT EXAMPLES ###
 # rdr pass on { ${PUBLIC_INTERFACE} } proto { tcp } from any to EXTERNAL_INTERFACE_IP port 80 -> { VM or Jail name, or hardcoded IP address } port 80  # HTTP NAT Forwarding
 # rdr pass on { vm-${NETWORK_NAME} } proto { tcp } from any to EXTERNAL_INTERFACE_IP port 80 -> { VM or Jail name, or hardcoded IP address } port 80  # HTTP RDR Reflection 
@@ -290,6 +293,9 @@ antispoof quick for { ${PUBLIC_INTERFACE} }  # COMMENT OUT IF YOU USE ANY VM-bas
 block in all
 pass out all keep state
 
+# Per VM/Jail firewall rules, generated from the "firewall" section in vm_config.json or jail_config.json #
+anchor "hoster/*"
+
 # Allow internal NAT networks to go out + examples #
 # pass in proto tcp to port 5900:5950 keep state  # Allow access to VNC ports from any IP
 # pass in quick inet proto { tcp udp icmp } from { ${NETWORK_SUBNET} } to any  # Uncomment this rule to allow any traffic out